@@ -122,7 +122,7 @@ const CommentCard = ({
                   ) : (
                     <div className="w-8 h-8 rounded-full bg-purple-100 flex items-center justify-center">
                       <span className="text-purple-600 font-medium text-sm">
-                        {comment.author?.display_name?.[0] || comment.author?.username?.[0] || "Anonymous"}
+                        {comment.author?.display_name?.[0] || comment.author?.username?.[0] || comment.guest_name?.[0] || "Anonymous"}
                       </span>
                     </div>
                   )}
@@ -131,7 +131,7 @@ const CommentCard = ({
                 <div className="flex flex-col">
                   <div className="flex items-center gap-2">
                     <span className="font-medium text-gray-900">
-                      {comment.author?.display_name || comment.author?.username || 'Anonymous'}
+                      {comment.author?.display_name || comment.author?.username || comment.guest_name || 'Anonymous'}
                     </span>
                     <span className="text-xs text-gray-500">
                       {formatDate(comment.created_at)}
@@ -23,6 +23,9 @@ export interface APIResponseData<T> {
   success: boolean;
   error_message?: string | null;
   data?: T;
+  error_code?: string;
+  validation_errors?: { field: string; message: string }[];
+  request_id?: string;
 }
 
 export interface CustomAxiosError<T = any> extends AxiosError {
@@ -1,7 +1,20 @@
 import apiHandler from '@/handlers/api-handlers';
-import { Post, Comment, PostsResponse, CommentsResponse, AuthResponse, SecureAuthResponse, LoginRequest, RegisterRequest } from '@/types';
+import { Post, Comment, PostsResponse, CommentsResponse, AuthResponse, SecureAuthResponse, LoginRequest, RegisterRequest, PaginatedData, PaginationMeta } from '@/types';
 import { toast } from 'sonner';
 
+// Convert the server's page details to the frontend pagination format
+const toPaginationMeta = (page: number, limit: number, data?: PaginatedData<unknown>): PaginationMeta => {
+  const total = data?.page?.total || 0;
+  return {
+    page: page,
+    limit: limit,
+    total: total,
+    totalPages: data?.page?.total_pages ?? Math.ceil(total / limit),
+    hasNext: data?.page?.has_more || false,
+    hasPrev: page > 1
+  };
+};
+
 export class AuthAPI {
   static async login(credentials: LoginRequest): Promise<AuthResponse> {
     try {
@@ -102,20 +115,12 @@ export class PostsAPI {
   static async getPosts(page: number = 1, limit: number = 10): Promise<PostsResponse> {
     try {
       const offset = (page - 1) * limit;
-      const response = await apiHandler.get<any>(`/posts?limit=${limit}&offset=${offset}`);
+      const response = await apiHandler.get<PaginatedData<Post>>(`/posts?limit=${limit}&offset=${offset}`);
       if (response.success) {
         // Transform backend response to frontend format
-        const backendData = response.data!;
         const transformedResponse: PostsResponse = {
-          posts: backendData.posts || [],
-          meta: {
-            page: page,
-            limit: limit,
-            total: backendData.count || 0,
-            totalPages: Math.ceil((backendData.count || 0) / limit),
-            hasNext: (backendData.count || 0) > offset + limit,
-            hasPrev: page > 1
-          }
+          posts: response.data?.items || [],
+          meta: toPaginationMeta(page, limit, response.data)
         };
         return transformedResponse;
       } else {
@@ -131,7 +136,7 @@ export class PostsAPI {
 
   static async getPost(postId: string): Promise<Post> {
     try {
-      const response = await apiHandler.get<Post>(`/posts/post/${postId}`);
+      const response = await apiHandler.get<Post>(`/posts/${postId}`);
       if (response.success) {
         return response.data!;
       } else {
@@ -164,7 +169,7 @@ export class PostsAPI {
 
   static async updatePost(postId: string, postData: { title?: string; content?: string }): Promise<Post> {
     try {
-      const response = await apiHandler.put<Post>(`/posts/post/${postId}`, postData);
+      const response = await apiHandler.put<Post>(`/posts/${postId}`, postData);
       if (response.success) {
         toast.success('Post updated successfully');
         return response.data!;
@@ -181,7 +186,7 @@ export class PostsAPI {
 
   static async deletePost(postId: string): Promise<void> {
     try {
-      const response = await apiHandler.delete(`/posts/post/${postId}`);
+      const response = await apiHandler.delete(`/posts/${postId}`);
       if (response.success) {
         toast.success('Post deleted successfully');
       } else {
@@ -200,20 +205,12 @@ export class CommentsAPI {
   static async getComments(postId: string, page: number = 1, limit: number = 10): Promise<CommentsResponse> {
     try {
       const offset = (page - 1) * limit;
-      const response = await apiHandler.get<any>(`/posts/post-comments/${postId}?limit=${limit}&offset=${offset}`);
+      const response = await apiHandler.get<PaginatedData<Comment>>(`/posts/${postId}/comments?limit=${limit}&offset=${offset}`);
       if (response.success) {
         // Transform backend response to frontend format
-        const backendData = response.data!;
         const transformedResponse: CommentsResponse = {
-          comments: backendData.comments || [],
-          meta: {
-            page: page,
-            limit: limit,
-            total: backendData.count || 0,
-            totalPages: Math.ceil((backendData.count || 0) / limit),
-            hasNext: (backendData.count || 0) > offset + limit,
-            hasPrev: page > 1
-          }
+          comments: response.data?.items || [],
+          meta: toPaginationMeta(page, limit, response.data)
         };
         return transformedResponse;
       } else {
@@ -230,20 +227,12 @@ export class CommentsAPI {
   static async getReplies(commentId: string, page: number = 1, limit: number = 5): Promise<CommentsResponse> {
     try {
       const offset = (page - 1) * limit;
-      const response = await apiHandler.get<any>(`/comments/${commentId}/replies?limit=${limit}&offset=${offset}`);
+      const response = await apiHandler.get<PaginatedData<Comment>>(`/comments/${commentId}/replies?limit=${limit}&offset=${offset}`);
       if (response.success) {
         // Transform backend response to frontend format
-        const backendData = response.data!;
         const transformedResponse: CommentsResponse = {
-          comments: backendData.replies || [],
-          meta: {
-            page: page,
-            limit: limit,
-            total: backendData.count || 0,
-            totalPages: Math.ceil((backendData.count || 0) / limit),
-            hasNext: (backendData.count || 0) > offset + limit,
-            hasPrev: page > 1
-          }
+          comments: response.data?.items || [],
+          meta: toPaginationMeta(page, limit, response.data)
         };
         return transformedResponse;
       } else {
@@ -264,7 +253,7 @@ export class CommentsAPI {
         post_id: postId,
         ...(parentId && { parent_id: parentId })
       };
-      const response = await apiHandler.post<Comment>(`/posts/post-comments/${postId}`, commentData);
+      const response = await apiHandler.post<Comment>(`/posts/${postId}/comments`, commentData);
       if (response.success) {
         toast.success('Comment added successfully');
         return response.data!;
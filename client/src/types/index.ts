@@ -17,6 +17,8 @@ export interface Post {
   created_at: string;
   updated_at: string;
   commentsCount?: number;
+  allow_anonymous_comments?: boolean;
+  publish_at?: string | null;
 }
 
 export interface Comment {
@@ -28,6 +30,7 @@ export interface Comment {
   thread_id: string;
   created_by?: string;
   author?: User;
+  guest_name?: string;
   children?: Comment[];
   created_at: string;
   updated_at: string;
@@ -43,6 +46,22 @@ export interface PaginationMeta {
   hasPrev: boolean;
 }
 
+// Page details sent by the server with every paginated list
+export interface PageInfo {
+  limit: number;
+  offset: number;
+  total: number;
+  page: number;
+  total_pages: number;
+  has_more: boolean;
+}
+
+// Data payload of every paginated list response from the server
+export interface PaginatedData<T> {
+  items: T[];
+  page: PageInfo;
+}
+
 export interface PostsResponse {
   posts: Post[];
   meta: PaginationMeta;
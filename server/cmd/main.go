@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/TejasThombare20/post-comments-service/config"
 	"github.com/TejasThombare20/post-comments-service/controllers"
@@ -16,14 +22,32 @@ import (
 )
 
 func main() {
-	// Initialize logger first
-	utils.InitLogger()
-
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		utils.LogWarn("No .env file found, using system environment variables", nil)
 	}
 
+	// Load application configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		utils.LogError("Failed to load configuration", err, nil)
+		os.Exit(1)
+	}
+
+	// Initialize logger now that its output and level are known; until here it writes to stdout
+	err = utils.InitLogger(utils.LoggerOptions{
+		Level:          cfg.App.LogLevel,
+		Output:         cfg.App.LogOutput,
+		FilePath:       cfg.App.LogFilePath,
+		FileMaxSizeMB:  cfg.App.LogFileMaxSizeMB,
+		FileMaxBackups: cfg.App.LogFileMaxBackups,
+		FileMaxAgeDays: cfg.App.LogFileMaxAgeDays,
+	})
+	if err != nil {
+		utils.LogError("Failed to initialize logger", err, nil)
+		os.Exit(1)
+	}
+
 	// Initialize database connection
 	db, err := config.InitDB()
 	if err != nil {
@@ -31,46 +55,183 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.Database.MigrateOnStart {
+		applied, err := config.RunMigrations(db, cfg.Database.MigrationsDir)
+		if err != nil {
+			utils.LogError("Failed to run database migrations", err, nil)
+			os.Exit(1)
+		}
+		utils.LogInfo("Database migrations complete", utils.LogFields{
+			"applied": applied,
+		})
+	}
+
+	if err := repository.CheckRepliesCountMode(context.Background(), db, cfg.Comments.RepliesCountMode); err != nil {
+		utils.LogError("Invalid COMMENT_REPLIES_COUNT_MODE for this database", err, nil)
+		os.Exit(1)
+	}
+
+	utils.SetMaxPageLimit(cfg.Server.MaxPageLimit)
+	utils.SetMaxPaginationOffset(cfg.Server.MaxPaginationOffset)
+
 	// Initialize validator
 	validator := validator.NewValidator()
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	postRepo := repository.NewPostRepository(db)
-	commentRepo := repository.NewCommentRepository(db)
+	commentRepo := repository.NewCommentRepository(db, cfg.Comments.RepliesCountMode)
+	searchRepo := repository.NewSearchRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+
+	passwordHasher, err := utils.NewPasswordHasher(cfg.Password.Algorithm, cfg.Password.BcryptCost)
+	if err != nil {
+		utils.LogError("Failed to initialize password hasher", err, nil)
+		os.Exit(1)
+	}
+
+	avatarStore, err := services.NewLocalBlobStore(cfg.Uploads.Dir, cfg.Uploads.URLPrefix)
+	if err != nil {
+		utils.LogError("Failed to initialize upload storage", err, nil)
+		os.Exit(1)
+	}
 
 	// Initialize services
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(userRepo, passwordHasher, validator, avatarStore, cfg.Users.DeletePolicy)
 	postService := services.NewPostService(postRepo, userRepo)
-	commentService := services.NewCommentService(commentRepo, postRepo, userRepo, validator)
-	jwtService := services.NewJWTService()
-	authService := services.NewAuthService(userRepo, jwtService, userService, validator)
+	commentHub := services.NewCommentHub()
+	var contentFilter *utils.ContentFilter
+	if cfg.Comments.BannedWordsFile != "" {
+		contentFilter, err = utils.LoadContentFilter(cfg.Comments.BannedWordsFile)
+		if err != nil {
+			utils.LogError("Failed to load banned words list", err, nil)
+			os.Exit(1)
+		}
+	}
+	commentService := services.NewCommentService(commentRepo, postRepo, userRepo, validator, cfg.Comments, contentFilter, commentHub)
+	searchService := services.NewSearchService(searchRepo)
+	metaService := services.NewMetaService(cfg.Comments)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo)
+	jwtService, err := services.NewJWTService(cfg.JWT, tokenRepo)
+	if err != nil {
+		utils.LogError("Failed to initialize JWT service", err, nil)
+		os.Exit(1)
+	}
+	authService := services.NewAuthService(userRepo, passwordResetRepo, jwtService, userService, services.NewLogEmailSender(), passwordHasher, validator, cfg.PasswordReset)
+
+	// Start background jobs
+	stopCommentPurge := services.StartCommentPurgeJob(commentService, cfg.Comments.PurgeInterval)
+	stopTokenCleanup := services.StartRevokedTokenCleanupJob(tokenRepo, time.Hour)
+	stopIdempotencyCleanup := services.StartIdempotencyKeyCleanupJob(idempotencyService, time.Hour)
 
 	// Initialize controllers
 	userController := controllers.NewUserController(userService)
-	postController := controllers.NewPostController(postService)
-	commentController := controllers.NewCommentController(commentService)
-	authController := controllers.NewAuthController(authService, validator)
+	postController := controllers.NewPostController(postService, idempotencyService)
+	commentController := controllers.NewCommentController(commentService, idempotencyService)
+	authController := controllers.NewAuthController(authService, userService, validator)
+	searchController := controllers.NewSearchController(searchService)
+	healthController := controllers.NewHealthController(db)
+	metaController := controllers.NewMetaController(metaService)
+	adminController := controllers.NewAdminController(db)
 
 	// Initialize Gin router
-	router := gin.New()
+	router, err := newRouter(cfg.Server)
+	if err != nil {
+		utils.LogError("Failed to configure trusted proxies", err, nil)
+		os.Exit(1)
+	}
 
 	// Add middleware
+	router.Use(middleware.RequestContext())
+	router.Use(middleware.Metrics())
 	router.Use(middleware.Logger())
-	router.Use(gin.Recovery())
+	if cfg.App.LogLevel == "debug" {
+		router.Use(middleware.BodyLogger(cfg.App.LogBodyMaxBytes))
+	}
+	router.Use(middleware.RecoveryWithLogger())
+	useSecureTransport(router, cfg.App, cfg.Security)
+	router.Use(middleware.StrictJSON(cfg.Server.StrictJSON))
+	router.Use(middleware.CORS(cfg.CORS))
+	router.Use(middleware.MaxBodySize(cfg.Server.MaxRequestBodyBytes, routes.BodySizeLimits()))
 
 	// Setup routes
-	routes.SetupRoutes(router, userController, postController, commentController, authController, jwtService)
+	routes.SetupRoutes(router, userController, postController, commentController, authController, searchController, healthController, metaController, adminController, jwtService, cfg.Uploads)
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	server := newHTTPServer(cfg.Server, router)
 
-	utils.LogInfo("Server starting", utils.LogFields{"port": port})
-	if err := router.Run(":" + port); err != nil {
-		utils.LogError("Failed to start server", err, utils.LogFields{"port": port})
+	serverErr := make(chan error, 1)
+	go func() {
+		utils.LogInfo("Server starting", utils.LogFields{"port": cfg.Server.Port})
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Wait for a shutdown signal or for the server to fail
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		utils.LogError("Failed to start server", err, utils.LogFields{"port": cfg.Server.Port})
 		os.Exit(1)
+	case sig := <-quit:
+		utils.LogInfo("Shutdown signal received", utils.LogFields{"signal": sig.String()})
+	}
+
+	// Fail readiness first so load balancers stop routing here, then let in-flight
+	// requests finish before the background jobs and database go away
+	healthController.SetShuttingDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		utils.LogError("Server did not shut down cleanly", err, utils.LogFields{
+			"timeout": cfg.Server.ShutdownTimeout.String(),
+		})
+	}
+
+	stopCommentPurge()
+	stopTokenCleanup()
+	stopIdempotencyCleanup()
+
+	if err := db.Close(); err != nil {
+		utils.LogError("Failed to close database connection", err, nil)
+	}
+
+	utils.LogInfo("Server stopped", nil)
+}
+
+// newHTTPServer builds the HTTP server for handler with the configured port and timeouts
+func newHTTPServer(serverConfig *config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + serverConfig.Port,
+		Handler:      handler,
+		ReadTimeout:  serverConfig.ReadTimeout,
+		WriteTimeout: serverConfig.WriteTimeout,
+		IdleTimeout:  serverConfig.IdleTimeout,
+	}
+}
+
+// newRouter creates the gin engine. Only the configured proxies are trusted to report the
+// client IP in X-Forwarded-For; with none configured, ClientIP is the connection's address,
+// so clients cannot pick their own IP to get around per-IP limits.
+func newRouter(serverConfig *config.ServerConfig) (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// useSecureTransport adds the HTTPS redirect, HSTS and security headers to router,
+// except in development where the service is usually reached over plain HTTP
+func useSecureTransport(router *gin.Engine, appConfig *config.AppConfig, securityConfig *config.SecurityConfig) {
+	if appConfig.Environment == "development" {
+		return
 	}
+	router.Use(middleware.SecureTransport(securityConfig))
 }
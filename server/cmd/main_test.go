@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewHTTPServerUsesConfiguredTimeouts(t *testing.T) {
+	for key, value := range map[string]string{
+		"DB_HOST":              "localhost",
+		"DB_PORT":              "5432",
+		"DB_USER":              "postgres",
+		"DB_NAME":              "post_comments",
+		"JWT_SECRET_KEY":       "test-secret-key-that-is-long-enough-for-validation",
+		"PORT":                 "9090",
+		"SERVER_READ_TIMEOUT":  "7s",
+		"SERVER_WRITE_TIMEOUT": "11s",
+		"SERVER_IDLE_TIMEOUT":  "2m",
+	} {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	server := newHTTPServer(cfg.Server, http.NotFoundHandler())
+	if server.Addr != ":9090" {
+		t.Errorf("Addr = %q, want %q", server.Addr, ":9090")
+	}
+	if server.ReadTimeout != 7*time.Second {
+		t.Errorf("ReadTimeout = %v, want 7s", server.ReadTimeout)
+	}
+	if server.WriteTimeout != 11*time.Second {
+		t.Errorf("WriteTimeout = %v, want 11s", server.WriteTimeout)
+	}
+	if server.IdleTimeout != 2*time.Minute {
+		t.Errorf("IdleTimeout = %v, want 2m", server.IdleTimeout)
+	}
+}
+
+func TestSecureTransportOnlyOutsideDevelopment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	security := &config.SecurityConfig{
+		HTTPSRedirect:         true,
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentTypeOptions:    "nosniff",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+	}
+	wantHeaders := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+	}
+
+	tests := []struct {
+		environment string
+		enabled     bool
+	}{
+		{"production", true},
+		{"staging", true},
+		{"development", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			router := gin.New()
+			useSecureTransport(router, &config.AppConfig{Environment: tt.environment}, security)
+			router.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			for header, want := range wantHeaders {
+				got := w.Header().Get(header)
+				if tt.enabled && got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+				if !tt.enabled && got != "" {
+					t.Errorf("%s = %q in development, want it unset", header, got)
+				}
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/posts?page=2", nil)
+			req.Header.Set("X-Forwarded-Proto", "http")
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.enabled && (w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/posts?page=2") {
+				t.Errorf("plain HTTP request: got %d to %q, want a 301 to https://example.com/posts?page=2", w.Code, w.Header().Get("Location"))
+			}
+			if !tt.enabled && w.Code != http.StatusOK {
+				t.Errorf("plain HTTP request in development: got %d, want 200", w.Code)
+			}
+		})
+	}
+}
+
+func TestNewRouterTrustsOnlyConfiguredProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"no proxies configured", nil, "192.0.2.10"},
+		{"request from a trusted proxy", []string{"192.0.2.0/24"}, "203.0.113.7"},
+		{"request from another address", []string{"198.51.100.1"}, "192.0.2.10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := newRouter(&config.ServerConfig{TrustedProxies: tt.proxies})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var got string
+			router.GET("/ip", func(c *gin.Context) { got = c.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = "192.0.2.10:41000"
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := newRouter(&config.ServerConfig{TrustedProxies: []string{"not-an-ip"}}); err == nil {
+		t.Error("invalid proxy: got no error")
+	}
+}
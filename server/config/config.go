@@ -3,6 +3,7 @@ package config
 import (
 	"database/sql"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -18,6 +19,14 @@ type Config struct {
 	Server   *ServerConfig
 	JWT      *JWTConfig
 	App      *AppConfig
+	Comments *CommentConfig
+	Password *PasswordConfig
+
+	PasswordReset *PasswordResetConfig
+	Security      *SecurityConfig
+	Users         *UserConfig
+	CORS          *CORSConfig
+	Uploads       *UploadsConfig
 }
 
 // DBConfig holds database configuration
@@ -31,6 +40,10 @@ type DBConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+
+	// Apply pending migrations from MigrationsDir before serving
+	MigrateOnStart bool
+	MigrationsDir  string
 }
 
 // ServerConfig holds server configuration
@@ -39,6 +52,22 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	StrictJSON   bool
+
+	// How long shutdown waits for in-flight requests before closing connections
+	ShutdownTimeout time.Duration
+
+	// Largest limit and offset accepted by list endpoints; beyond them requests are
+	// rejected with 400
+	MaxPageLimit        int
+	MaxPaginationOffset int
+
+	// Largest request body accepted, in bytes; larger bodies are rejected with 413
+	MaxRequestBodyBytes int64
+
+	// IPs or CIDR ranges of the reverse proxies whose X-Forwarded-For header is believed
+	// when working out the client IP. Empty trusts none, so the connection's address is used.
+	TrustedProxies []string
 }
 
 // JWTConfig holds JWT configuration
@@ -50,9 +79,124 @@ type JWTConfig struct {
 
 // AppConfig holds general application configuration
 type AppConfig struct {
-	Environment string
-	LogLevel    string
-	Debug       bool
+	Environment       string
+	LogLevel          string
+	Debug             bool
+	LogBodyMaxBytes   int
+	LogOutput         string
+	LogFilePath       string
+	LogFileMaxSizeMB  int
+	LogFileMaxBackups int
+	LogFileMaxAgeDays int
+}
+
+// CommentConfig holds comment behaviour configuration
+type CommentConfig struct {
+	DuplicateCheckEnabled bool
+	DuplicateWindow       time.Duration
+	AutolinkEnabled       bool
+
+	// Who the duplicate check skips or covers: moderators and admins, who repeat
+	// boilerplate notices by design, and guests, matched by IP address
+	DuplicateCheckExemptModerators bool
+	DuplicateCheckGuests           bool
+
+	// Soft-deleted comments older than PurgeRetention are hard-deleted by the
+	// purge job every PurgeInterval (0 disables the background job). Until then they
+	// can be restored, so PurgeRetention is also the restore window.
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+	PurgeBatchSize int
+
+	// Which events move a comment and its ancestors up in "activity" ordering
+	BumpOnReply bool
+	BumpOnEdit  bool
+
+	// Deepest a reply may be nested; a top-level comment has depth 1
+	MaxReplyDepth int
+
+	// Fewest characters of visible text (markup stripped, trimmed) a comment must have,
+	// and whether replies are held to it too
+	MinContentLength        int
+	MinContentLengthReplies bool
+
+	// Most characters a comment may have once sanitized, markup included. The request
+	// tags cap submitted content at 10000, so this can only lower the limit.
+	MaxContentLength int
+
+	// Banned word list (one word or phrase per line; empty disables the filter) and
+	// whether a comment containing one is rejected or has the word masked
+	BannedWordsFile string
+	BannedWordsMode string
+
+	// Who keeps parents' replies_count current: the database triggers from migration
+	// 002 ("trigger") or the comment repository ("app"). Use "app" only where the
+	// triggers are not installed, or every reply is counted twice.
+	RepliesCountMode string
+
+	// Most comments visitors who are not signed in may post from one IP address within
+	// GuestWindow, on posts that allow guest comments
+	GuestMaxPerIP int
+	GuestWindow   time.Duration
+}
+
+// PasswordConfig holds password hashing configuration
+type PasswordConfig struct {
+	Algorithm  string
+	BcryptCost int
+}
+
+// PasswordResetConfig holds throttling for password reset emails. Requests over a limit
+// still get the generic success response; the email is just not sent.
+type PasswordResetConfig struct {
+	// Minimum time between two reset emails to the same account
+	Cooldown time.Duration
+
+	// Most reset emails per account, and per requesting IP, within Window
+	MaxPerEmail int
+	MaxPerIP    int
+	Window      time.Duration
+}
+
+// UserConfig holds user account lifecycle configuration
+type UserConfig struct {
+	// What happens to a deleted user's posts and comments: "anonymize" keeps them
+	// with no author, "cascade_delete" soft-deletes them along with the account
+	DeletePolicy string
+}
+
+// SecurityConfig holds transport security and response header configuration.
+// It is only applied outside development; an empty header value disables that header.
+type SecurityConfig struct {
+	// Redirect requests that reached the proxy over plain HTTP (X-Forwarded-Proto: http)
+	HTTPSRedirect bool
+
+	// Strict-Transport-Security on HTTPS responses (0 disables)
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+
+	ContentTypeOptions string
+	FrameOptions       string
+	ReferrerPolicy     string
+}
+
+// CORSConfig holds cross-origin request configuration
+type CORSConfig struct {
+	// Origins allowed to make cross-origin requests; "*" allows any origin (without credentials)
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// Send Access-Control-Allow-Credentials for allowlisted origins (cookie auth)
+	AllowCredentials bool
+}
+
+// UploadsConfig holds storage configuration for user uploads such as avatars
+type UploadsConfig struct {
+	// Local directory uploaded files are written to
+	Dir string
+	// URL path the files in Dir are served under
+	URLPrefix string
 }
 
 // ValidationError represents a configuration validation error
@@ -72,6 +216,14 @@ func LoadConfig() (*Config, error) {
 		Server:   loadServerConfig(),
 		JWT:      loadJWTConfig(),
 		App:      loadAppConfig(),
+		Comments: loadCommentConfig(),
+		Password: loadPasswordConfig(),
+
+		PasswordReset: loadPasswordResetConfig(),
+		Security:      loadSecurityConfig(),
+		Users:         loadUserConfig(),
+		CORS:          loadCORSConfig(),
+		Uploads:       loadUploadsConfig(),
 	}
 
 	if err := validateConfig(config); err != nil {
@@ -86,6 +238,7 @@ func loadDBConfig() *DBConfig {
 	maxOpenConns, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
 	maxIdleConns, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "5"))
 	connMaxLifetime, _ := time.ParseDuration(getEnv("DB_CONN_MAX_LIFETIME", "5m"))
+	migrateOnStart, _ := strconv.ParseBool(getEnv("MIGRATE_ON_START", "false"))
 
 	return &DBConfig{
 		Host:            getEnv("DB_HOST", "localhost"),
@@ -97,6 +250,8 @@ func loadDBConfig() *DBConfig {
 		MaxOpenConns:    maxOpenConns,
 		MaxIdleConns:    maxIdleConns,
 		ConnMaxLifetime: connMaxLifetime,
+		MigrateOnStart:  migrateOnStart,
+		MigrationsDir:   getEnv("MIGRATIONS_DIR", "migrations"),
 	}
 }
 
@@ -105,12 +260,23 @@ func loadServerConfig() *ServerConfig {
 	readTimeout, _ := time.ParseDuration(getEnv("SERVER_READ_TIMEOUT", "15s"))
 	writeTimeout, _ := time.ParseDuration(getEnv("SERVER_WRITE_TIMEOUT", "15s"))
 	idleTimeout, _ := time.ParseDuration(getEnv("SERVER_IDLE_TIMEOUT", "60s"))
+	shutdownTimeout, _ := time.ParseDuration(getEnv("SERVER_SHUTDOWN_TIMEOUT", "30s"))
+	strictJSON, _ := strconv.ParseBool(getEnv("STRICT_JSON", "false"))
+	maxPageLimit, _ := strconv.Atoi(getEnv("PAGINATION_MAX_LIMIT", "100"))
+	maxPaginationOffset, _ := strconv.Atoi(getEnv("PAGINATION_MAX_OFFSET", "10000"))
+	maxRequestBodyBytes, _ := strconv.ParseInt(getEnv("MAX_REQUEST_BODY_BYTES", "1048576"), 10, 64)
 
 	return &ServerConfig{
-		Port:         getEnv("PORT", "8080"),
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		IdleTimeout:  idleTimeout,
+		Port:                getEnv("PORT", "8080"),
+		ReadTimeout:         readTimeout,
+		WriteTimeout:        writeTimeout,
+		IdleTimeout:         idleTimeout,
+		ShutdownTimeout:     shutdownTimeout,
+		StrictJSON:          strictJSON,
+		MaxPageLimit:        maxPageLimit,
+		MaxPaginationOffset: maxPaginationOffset,
+		MaxRequestBodyBytes: maxRequestBodyBytes,
+		TrustedProxies:      getEnvList("TRUSTED_PROXIES", ""),
 	}
 }
 
@@ -129,11 +295,131 @@ func loadJWTConfig() *JWTConfig {
 // loadAppConfig loads general application configuration from environment variables
 func loadAppConfig() *AppConfig {
 	debug, _ := strconv.ParseBool(getEnv("DEBUG", "false"))
+	logBodyMaxBytes, _ := strconv.Atoi(getEnv("LOG_BODY_MAX_BYTES", "4096"))
+	logFileMaxSizeMB, _ := strconv.Atoi(getEnv("LOG_FILE_MAX_SIZE_MB", "100"))
+	logFileMaxBackups, _ := strconv.Atoi(getEnv("LOG_FILE_MAX_BACKUPS", "5"))
+	logFileMaxAgeDays, _ := strconv.Atoi(getEnv("LOG_FILE_MAX_AGE_DAYS", "30"))
 
 	return &AppConfig{
-		Environment: getEnv("ENVIRONMENT", "development"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		Debug:       debug,
+		Environment:       getEnv("ENVIRONMENT", "development"),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		Debug:             debug,
+		LogBodyMaxBytes:   logBodyMaxBytes,
+		LogOutput:         getEnv("LOG_OUTPUT", "stdout"),
+		LogFilePath:       getEnv("LOG_FILE_PATH", ""),
+		LogFileMaxSizeMB:  logFileMaxSizeMB,
+		LogFileMaxBackups: logFileMaxBackups,
+		LogFileMaxAgeDays: logFileMaxAgeDays,
+	}
+}
+
+// loadCommentConfig loads comment behaviour configuration from environment variables
+func loadCommentConfig() *CommentConfig {
+	duplicateCheckEnabled, _ := strconv.ParseBool(getEnv("COMMENT_DUPLICATE_CHECK_ENABLED", "true"))
+	duplicateWindow, _ := time.ParseDuration(getEnv("COMMENT_DUPLICATE_WINDOW", "1m"))
+	duplicateCheckExemptModerators, _ := strconv.ParseBool(getEnv("COMMENT_DUPLICATE_CHECK_EXEMPT_MODERATORS", "true"))
+	duplicateCheckGuests, _ := strconv.ParseBool(getEnv("COMMENT_DUPLICATE_CHECK_GUESTS", "false"))
+	autolinkEnabled, _ := strconv.ParseBool(getEnv("COMMENT_AUTOLINK_ENABLED", "true"))
+	purgeRetention, _ := time.ParseDuration(getEnv("COMMENT_PURGE_RETENTION", "720h"))
+	purgeInterval, _ := time.ParseDuration(getEnv("COMMENT_PURGE_INTERVAL", "24h"))
+	purgeBatchSize, _ := strconv.Atoi(getEnv("COMMENT_PURGE_BATCH_SIZE", "500"))
+	bumpOnReply, _ := strconv.ParseBool(getEnv("COMMENT_BUMP_ON_REPLY", "true"))
+	bumpOnEdit, _ := strconv.ParseBool(getEnv("COMMENT_BUMP_ON_EDIT", "false"))
+	maxReplyDepth, _ := strconv.Atoi(getEnv("COMMENT_MAX_REPLY_DEPTH", "8"))
+	minContentLength, _ := strconv.Atoi(getEnv("COMMENT_MIN_CONTENT_LENGTH", "1"))
+	minContentLengthReplies, _ := strconv.ParseBool(getEnv("COMMENT_MIN_CONTENT_LENGTH_REPLIES", "true"))
+	maxContentLength, _ := strconv.Atoi(getEnv("COMMENT_MAX_CONTENT_LENGTH", "10000"))
+	guestMaxPerIP, _ := strconv.Atoi(getEnv("COMMENT_GUEST_MAX_PER_IP", "5"))
+	guestWindow, _ := time.ParseDuration(getEnv("COMMENT_GUEST_WINDOW", "1h"))
+
+	return &CommentConfig{
+		DuplicateCheckEnabled:          duplicateCheckEnabled,
+		DuplicateWindow:                duplicateWindow,
+		AutolinkEnabled:                autolinkEnabled,
+		DuplicateCheckExemptModerators: duplicateCheckExemptModerators,
+		DuplicateCheckGuests:           duplicateCheckGuests,
+		PurgeRetention:                 purgeRetention,
+		PurgeInterval:                  purgeInterval,
+		PurgeBatchSize:                 purgeBatchSize,
+		BumpOnReply:                    bumpOnReply,
+		BumpOnEdit:                     bumpOnEdit,
+		MaxReplyDepth:                  maxReplyDepth,
+		MinContentLength:               minContentLength,
+		MinContentLengthReplies:        minContentLengthReplies,
+		MaxContentLength:               maxContentLength,
+		BannedWordsFile:                getEnv("COMMENT_BANNED_WORDS_FILE", ""),
+		BannedWordsMode:                strings.ToLower(getEnv("COMMENT_BANNED_WORDS_MODE", "reject")),
+		RepliesCountMode:               strings.ToLower(getEnv("COMMENT_REPLIES_COUNT_MODE", "trigger")),
+		GuestMaxPerIP:                  guestMaxPerIP,
+		GuestWindow:                    guestWindow,
+	}
+}
+
+// loadPasswordConfig loads password hashing configuration from environment variables
+func loadPasswordConfig() *PasswordConfig {
+	bcryptCost, _ := strconv.Atoi(getEnv("PASSWORD_BCRYPT_COST", "10"))
+
+	return &PasswordConfig{
+		Algorithm:  getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost: bcryptCost,
+	}
+}
+
+// loadPasswordResetConfig loads password reset throttling configuration from environment variables
+func loadPasswordResetConfig() *PasswordResetConfig {
+	cooldown, _ := time.ParseDuration(getEnv("PASSWORD_RESET_COOLDOWN", "1m"))
+	maxPerEmail, _ := strconv.Atoi(getEnv("PASSWORD_RESET_MAX_PER_EMAIL", "5"))
+	maxPerIP, _ := strconv.Atoi(getEnv("PASSWORD_RESET_MAX_PER_IP", "20"))
+	window, _ := time.ParseDuration(getEnv("PASSWORD_RESET_WINDOW", "1h"))
+
+	return &PasswordResetConfig{
+		Cooldown:    cooldown,
+		MaxPerEmail: maxPerEmail,
+		MaxPerIP:    maxPerIP,
+		Window:      window,
+	}
+}
+
+// loadSecurityConfig loads transport security configuration from environment variables
+func loadSecurityConfig() *SecurityConfig {
+	httpsRedirect, _ := strconv.ParseBool(getEnv("HTTPS_REDIRECT", "true"))
+	hstsMaxAge, _ := time.ParseDuration(getEnv("HSTS_MAX_AGE", "8760h"))
+	hstsIncludeSubdomains, _ := strconv.ParseBool(getEnv("HSTS_INCLUDE_SUBDOMAINS", "true"))
+
+	return &SecurityConfig{
+		HTTPSRedirect:         httpsRedirect,
+		HSTSMaxAge:            hstsMaxAge,
+		HSTSIncludeSubdomains: hstsIncludeSubdomains,
+		ContentTypeOptions:    getEnvAllowEmpty("SECURITY_CONTENT_TYPE_OPTIONS", "nosniff"),
+		FrameOptions:          getEnvAllowEmpty("SECURITY_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getEnvAllowEmpty("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+	}
+}
+
+// loadUserConfig loads user account configuration from environment variables
+func loadUserConfig() *UserConfig {
+	return &UserConfig{
+		DeletePolicy: strings.ToLower(getEnv("USER_DELETE_POLICY", "anonymize")),
+	}
+}
+
+// loadCORSConfig loads cross-origin request configuration from environment variables
+func loadCORSConfig() *CORSConfig {
+	allowCredentials, _ := strconv.ParseBool(getEnv("CORS_ALLOW_CREDENTIALS", "true"))
+
+	return &CORSConfig{
+		AllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", "http://localhost:5173"),
+		AllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
+		AllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", "Origin,Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,X-Strict,Prefer,X-Request-ID,Idempotency-Key,If-None-Match,If-Modified-Since"),
+		AllowCredentials: allowCredentials,
+	}
+}
+
+// loadUploadsConfig loads upload storage configuration from environment variables
+func loadUploadsConfig() *UploadsConfig {
+	return &UploadsConfig{
+		Dir:       getEnv("UPLOADS_DIR", "uploads"),
+		URLPrefix: getEnv("UPLOADS_URL_PREFIX", "/uploads"),
 	}
 }
 
@@ -169,6 +455,26 @@ func validateConfig(config *Config) error {
 		errors = append(errors, ValidationError{"PORT", "must be a valid port number (1-65535)"})
 	}
 
+	if config.Server.ShutdownTimeout <= 0 {
+		errors = append(errors, ValidationError{"SERVER_SHUTDOWN_TIMEOUT", "must be greater than 0"})
+	}
+	if config.Server.MaxPageLimit <= 0 {
+		errors = append(errors, ValidationError{"PAGINATION_MAX_LIMIT", "must be greater than 0"})
+	}
+	if config.Server.MaxPaginationOffset <= 0 {
+		errors = append(errors, ValidationError{"PAGINATION_MAX_OFFSET", "must be greater than 0"})
+	}
+	if config.Server.MaxRequestBodyBytes <= 0 {
+		errors = append(errors, ValidationError{"MAX_REQUEST_BODY_BYTES", "must be greater than 0"})
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errors = append(errors, ValidationError{"TRUSTED_PROXIES", fmt.Sprintf("%q is not an IP address or CIDR range", proxy)})
+			}
+		}
+	}
+
 	// Validate JWT configuration
 	if config.JWT.SecretKey == "" {
 		errors = append(errors, ValidationError{"JWT_SECRET_KEY", "JWT secret key is required"})
@@ -194,6 +500,126 @@ func validateConfig(config *Config) error {
 		errors = append(errors, ValidationError{"LOG_LEVEL", fmt.Sprintf("must be one of: %s", strings.Join(validLogLevels, ", "))})
 	}
 
+	if config.App.LogBodyMaxBytes <= 0 {
+		errors = append(errors, ValidationError{"LOG_BODY_MAX_BYTES", "must be greater than 0"})
+	}
+
+	validLogOutputs := []string{"stdout", "stderr", "file"}
+	if !contains(validLogOutputs, config.App.LogOutput) {
+		errors = append(errors, ValidationError{"LOG_OUTPUT", fmt.Sprintf("must be one of: %s", strings.Join(validLogOutputs, ", "))})
+	}
+
+	if config.App.LogOutput == "file" {
+		if config.App.LogFilePath == "" {
+			errors = append(errors, ValidationError{"LOG_FILE_PATH", "is required when LOG_OUTPUT is file"})
+		}
+		if config.App.LogFileMaxSizeMB <= 0 {
+			errors = append(errors, ValidationError{"LOG_FILE_MAX_SIZE_MB", "must be greater than 0"})
+		}
+		if config.App.LogFileMaxBackups < 0 {
+			errors = append(errors, ValidationError{"LOG_FILE_MAX_BACKUPS", "must not be negative"})
+		}
+		if config.App.LogFileMaxAgeDays < 0 {
+			errors = append(errors, ValidationError{"LOG_FILE_MAX_AGE_DAYS", "must not be negative"})
+		}
+	}
+
+	// Validate comment configuration
+	if config.Comments.DuplicateCheckEnabled && config.Comments.DuplicateWindow <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_DUPLICATE_WINDOW", "must be greater than 0 when duplicate check is enabled"})
+	}
+	if config.Comments.PurgeRetention <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_PURGE_RETENTION", "must be greater than 0"})
+	}
+	if config.Comments.PurgeInterval < 0 {
+		errors = append(errors, ValidationError{"COMMENT_PURGE_INTERVAL", "must not be negative"})
+	}
+	if config.Comments.PurgeBatchSize <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_PURGE_BATCH_SIZE", "must be greater than 0"})
+	}
+	if config.Comments.MaxReplyDepth <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_MAX_REPLY_DEPTH", "must be greater than 0"})
+	}
+	if config.Comments.MinContentLength < 0 {
+		errors = append(errors, ValidationError{"COMMENT_MIN_CONTENT_LENGTH", "must not be negative"})
+	}
+	if config.Comments.MaxContentLength <= 0 || config.Comments.MaxContentLength > 10000 {
+		errors = append(errors, ValidationError{"COMMENT_MAX_CONTENT_LENGTH", "must be between 1 and 10000"})
+	}
+	if config.Comments.MaxContentLength < config.Comments.MinContentLength {
+		errors = append(errors, ValidationError{"COMMENT_MAX_CONTENT_LENGTH", "must not be less than COMMENT_MIN_CONTENT_LENGTH"})
+	}
+	validBannedWordsModes := []string{"reject", "mask"}
+	if !contains(validBannedWordsModes, config.Comments.BannedWordsMode) {
+		errors = append(errors, ValidationError{"COMMENT_BANNED_WORDS_MODE", fmt.Sprintf("must be one of: %s", strings.Join(validBannedWordsModes, ", "))})
+	}
+	validRepliesCountModes := []string{"trigger", "app"}
+	if !contains(validRepliesCountModes, config.Comments.RepliesCountMode) {
+		errors = append(errors, ValidationError{"COMMENT_REPLIES_COUNT_MODE", fmt.Sprintf("must be one of: %s", strings.Join(validRepliesCountModes, ", "))})
+	}
+	if config.Comments.GuestMaxPerIP <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_GUEST_MAX_PER_IP", "must be greater than 0"})
+	}
+	if config.Comments.GuestWindow <= 0 {
+		errors = append(errors, ValidationError{"COMMENT_GUEST_WINDOW", "must be greater than 0"})
+	}
+
+	// Validate password configuration
+	validHashAlgorithms := []string{"bcrypt", "argon2id"}
+	if !contains(validHashAlgorithms, config.Password.Algorithm) {
+		errors = append(errors, ValidationError{"PASSWORD_HASH_ALGORITHM", fmt.Sprintf("must be one of: %s", strings.Join(validHashAlgorithms, ", "))})
+	}
+	if config.Password.BcryptCost < 4 || config.Password.BcryptCost > 31 {
+		errors = append(errors, ValidationError{"PASSWORD_BCRYPT_COST", "must be between 4 and 31"})
+	}
+
+	// Validate password reset configuration
+	if config.PasswordReset.Cooldown < 0 {
+		errors = append(errors, ValidationError{"PASSWORD_RESET_COOLDOWN", "must not be negative"})
+	}
+	if config.PasswordReset.MaxPerEmail <= 0 {
+		errors = append(errors, ValidationError{"PASSWORD_RESET_MAX_PER_EMAIL", "must be greater than 0"})
+	}
+	if config.PasswordReset.MaxPerIP <= 0 {
+		errors = append(errors, ValidationError{"PASSWORD_RESET_MAX_PER_IP", "must be greater than 0"})
+	}
+	if config.PasswordReset.Window <= 0 {
+		errors = append(errors, ValidationError{"PASSWORD_RESET_WINDOW", "must be greater than 0"})
+	}
+
+	// Validate security configuration
+	if config.Security.HSTSMaxAge < 0 {
+		errors = append(errors, ValidationError{"HSTS_MAX_AGE", "must not be negative"})
+	}
+	validFrameOptions := []string{"", "DENY", "SAMEORIGIN"}
+	if !contains(validFrameOptions, config.Security.FrameOptions) {
+		errors = append(errors, ValidationError{"SECURITY_FRAME_OPTIONS", "must be DENY, SAMEORIGIN or empty"})
+	}
+
+	// Validate CORS configuration
+	if len(config.CORS.AllowedOrigins) == 0 {
+		errors = append(errors, ValidationError{"CORS_ALLOWED_ORIGINS", "must list at least one origin, or * to allow any"})
+	}
+	if len(config.CORS.AllowedMethods) == 0 {
+		errors = append(errors, ValidationError{"CORS_ALLOWED_METHODS", "must list at least one method"})
+	}
+
+	// Validate uploads configuration
+	if config.Uploads.Dir == "" {
+		errors = append(errors, ValidationError{"UPLOADS_DIR", "upload directory is required"})
+	}
+	if !strings.HasPrefix(config.Uploads.URLPrefix, "/") || strings.Trim(config.Uploads.URLPrefix, "/") == "" {
+		errors = append(errors, ValidationError{"UPLOADS_URL_PREFIX", "must be a path starting with / and other than /"})
+	} else if strings.HasPrefix(config.Uploads.URLPrefix+"/", "/api/") {
+		errors = append(errors, ValidationError{"UPLOADS_URL_PREFIX", "must not be under /api"})
+	}
+
+	// Validate user configuration
+	validDeletePolicies := []string{"anonymize", "cascade_delete"}
+	if !contains(validDeletePolicies, config.Users.DeletePolicy) {
+		errors = append(errors, ValidationError{"USER_DELETE_POLICY", fmt.Sprintf("must be one of: %s", strings.Join(validDeletePolicies, ", "))})
+	}
+
 	if len(errors) > 0 {
 		return &ConfigValidationError{Errors: errors}
 	}
@@ -269,6 +695,27 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList gets a comma-separated environment variable as a list, trimming spaces and
+// dropping empty entries
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAllowEmpty gets an environment variable, falling back to defaultValue only when it
+// is unset, so it can be explicitly set to "" to disable a feature
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// migrationFilePattern matches migration files such as 007_add_post_tags.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_[A-Za-z0-9_]+\.sql$`)
+
+// migrationLockID is the advisory lock key held while migrating, so that several
+// instances starting at once do not apply the same migration concurrently
+const migrationLockID = 7_340_021_563
+
+// migration is a single SQL file in the migrations directory
+type migration struct {
+	version int
+	name    string
+	path    string
+}
+
+// RunMigrations applies, in version order, every migration in dir newer than the latest
+// version recorded in schema_migrations, and returns how many were applied. Each file runs
+// in its own transaction, so a failing migration leaves the database at the previous version.
+//
+// A database that has tables but no schema_migrations was set up before migration 017 and
+// its version cannot be told; apply migrations up to 017 by hand before enabling this.
+func RunMigrations(db *sql.DB, dir string) (int, error) {
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to get database connection for migrations")
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, utils.WrapError(err, "failed to acquire migration lock")
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	current, err := currentSchemaVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return applied, err
+		}
+		applied++
+
+		utils.LogInfo("Applied migration", utils.LogFields{
+			"version": m.version,
+			"name":    m.name,
+		})
+	}
+
+	return applied, nil
+}
+
+// loadMigrations lists the migration files in dir, sorted by version
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to read migrations directory")
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			path:    filepath.Join(dir, entry.Name()),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// currentSchemaVersion returns the latest recorded migration, or 0 for an empty database
+func currentSchemaVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var hasVersions, hasTables bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT to_regclass('schema_migrations') IS NOT NULL,
+		       to_regclass('users') IS NOT NULL`,
+	).Scan(&hasVersions, &hasTables)
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to inspect database schema")
+	}
+
+	if !hasVersions {
+		if hasTables {
+			return 0, fmt.Errorf("database has tables but no schema_migrations table; apply migrations up to 017 manually before using MIGRATE_ON_START")
+		}
+		return 0, nil
+	}
+
+	var version int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, utils.WrapError(err, "failed to read schema version")
+	}
+
+	return version, nil
+}
+
+// applyMigration runs one migration file and records its version in the same transaction.
+// Migrations from 017 on record themselves; the insert here covers any that do not.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	content, err := os.ReadFile(m.path)
+	if err != nil {
+		return utils.WrapError(err, fmt.Sprintf("failed to read migration %s", m.name))
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.WrapError(err, "failed to begin migration transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+		return utils.WrapError(err, fmt.Sprintf("failed to apply migration %s", m.name))
+	}
+
+	// schema_migrations only exists from migration 017 on
+	var tracked bool
+	if err := tx.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&tracked); err != nil {
+		return utils.WrapError(err, "failed to inspect database schema")
+	}
+	if tracked {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT DO NOTHING`, m.version)
+		if err != nil {
+			return utils.WrapError(err, fmt.Sprintf("failed to record migration %s", m.name))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return utils.WrapError(err, fmt.Sprintf("failed to commit migration %s", m.name))
+	}
+
+	return nil
+}
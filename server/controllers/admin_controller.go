@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController handles operational endpoints for administrators
+type AdminController struct {
+	db *sql.DB
+}
+
+// NewAdminController creates a new admin controller instance
+func NewAdminController(db *sql.DB) *AdminController {
+	return &AdminController{
+		db: db,
+	}
+}
+
+// dbStatsResponse is sql.DBStats with JSON field names. Durations are reported in
+// milliseconds.
+type dbStatsResponse struct {
+	MaxOpenConnections int `json:"max_open_connections"`
+
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMs    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// DBStats handles GET /admin/db-stats. It reports the connection pool counters so pool
+// exhaustion (a growing wait_count with in_use at max_open_connections) can be spotted.
+func (ac *AdminController) DBStats(c *gin.Context) {
+	stats := ac.db.Stats()
+
+	utils.SuccessResponse(c, http.StatusOK, dbStatsResponse{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	})
+}
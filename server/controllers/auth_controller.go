@@ -8,19 +8,20 @@ import (
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/TejasThombare20/post-comments-service/validator"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // AuthController handles authentication-related HTTP requests
 type AuthController struct {
 	authService services.AuthService
+	userService services.UserService
 	validator   *validator.Validator
 }
 
 // NewAuthController creates a new authentication controller instance
-func NewAuthController(authService services.AuthService, validator *validator.Validator) *AuthController {
+func NewAuthController(authService services.AuthService, userService services.UserService, validator *validator.Validator) *AuthController {
 	return &AuthController{
 		authService: authService,
+		userService: userService,
 		validator:   validator,
 	}
 }
@@ -30,22 +31,22 @@ func (ac *AuthController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 
 	// Bind JSON request
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format: "+err.Error())
 		return
 	}
 
 	// Validate request
 	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
-		utils.ValidationErrorResponse(c, validationErrors.Error())
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
 		return
 	}
 
 	// Register user
-	authResponse, err := ac.authService.Register(&req)
+	authResponse, err := ac.authService.Register(c.Request.Context(), &req)
 	if err != nil {
 		if err == utils.ErrUserExists {
-			utils.ConflictResponse(c, "User already exists")
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "User already exists")
 			return
 		}
 		utils.InternalServerErrorResponse(c, "Failed to register user")
@@ -67,15 +68,15 @@ func (ac *AuthController) Login(c *gin.Context) {
 
 	// Validate request
 	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
-		utils.ValidationErrorResponse(c, validationErrors.Error())
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
 		return
 	}
 
 	// Login user
-	authResponse, err := ac.authService.Login(&req)
+	authResponse, err := ac.authService.Login(c.Request.Context(), &req)
 	if err != nil {
 		if err == utils.ErrInvalidCredentials {
-			utils.UnauthorizedResponse(c, "Invalid username or password")
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, utils.ErrorCode(err), "Invalid username or password")
 			return
 		}
 		utils.InternalServerErrorResponse(c, "Failed to login user")
@@ -97,12 +98,12 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 
 	// Validate request
 	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
-		utils.ValidationErrorResponse(c, validationErrors.Error())
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
 		return
 	}
 
 	// Refresh token
-	authResponse, err := ac.authService.RefreshToken(req.RefreshToken)
+	authResponse, err := ac.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
 		utils.UnauthorizedResponse(c, "Invalid refresh token")
 		return
@@ -113,12 +114,12 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 
 // ChangePassword handles password change requests
 func (ac *AuthController) ChangePassword(c *gin.Context) {
-	utils.LogInfo("Change password request received", utils.LogFields{})
+	utils.LogRequest(c, "Change password request received", utils.LogFields{})
 
 	// Get user ID from context (set by auth middleware)
 	userID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		utils.LogError("User not authenticated for password change", err, utils.LogFields{})
+		utils.LogRequestError(c, "User not authenticated for password change", err, utils.LogFields{})
 		utils.UnauthorizedResponse(c, "User not authenticated")
 		return
 	}
@@ -126,49 +127,49 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 	var req models.ChangePasswordRequest
 
 	// Bind JSON request
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.LogError("Invalid request payload for password change", err, utils.LogFields{
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.LogRequestError(c, "Invalid request payload for password change", err, utils.LogFields{
 			"user_id": userID,
 		})
-		utils.ValidationErrorResponse(c, "Invalid request format")
+		utils.ValidationErrorResponse(c, "Invalid request format: "+err.Error())
 		return
 	}
 
 	// Validate request
 	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
-		utils.LogError("Validation failed for password change", nil, utils.LogFields{
+		utils.LogRequestError(c, "Validation failed for password change", nil, utils.LogFields{
 			"user_id": userID,
-			"errors":  validationErrors,
+			"errors":  validationErrors.Error(),
 		})
-		utils.ValidationErrorResponse(c, validationErrors.Error())
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
 		return
 	}
 
 	// Change password
-	err = ac.authService.ChangePassword(userID, &req)
+	err = ac.authService.ChangePassword(c.Request.Context(), userID, &req)
 	if err != nil {
 		if utils.IsValidationError(err) {
-			utils.LogError("Invalid current password", err, utils.LogFields{
+			utils.LogRequestError(c, "Invalid current password", err, utils.LogFields{
 				"user_id": userID,
 			})
-			utils.ValidationErrorResponse(c, err.Error())
+			utils.ValidationErrorResponseFromError(c, err)
 			return
 		}
 		if utils.IsNotFoundError(err) {
-			utils.LogError("User not found for password change", err, utils.LogFields{
+			utils.LogRequestError(c, "User not found for password change", err, utils.LogFields{
 				"user_id": userID,
 			})
-			utils.NotFoundResponse(c, "User")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
 			return
 		}
-		utils.LogError("Failed to change password", err, utils.LogFields{
+		utils.LogRequestError(c, "Failed to change password", err, utils.LogFields{
 			"user_id": userID,
 		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	utils.LogInfo("Password changed successfully", utils.LogFields{
+	utils.LogRequest(c, "Password changed successfully", utils.LogFields{
 		"user_id": userID,
 	})
 
@@ -177,36 +178,114 @@ func (ac *AuthController) ChangePassword(c *gin.Context) {
 	})
 }
 
-// Logout handles user logout (client-side token invalidation)
+// Logout handles user logout by revoking the current access token and, if provided, the refresh token
 func (ac *AuthController) Logout(c *gin.Context) {
-	// in logout call we are removing the access_token from the browser local storage
+	claimsInterface, exists := c.Get("token_claims")
+	claims, ok := claimsInterface.(*models.JWTClaims)
+	if !exists || !ok {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	// The body is optional; an empty body only revokes the access token
+	var req models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := utils.BindJSON(c, &req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format: "+err.Error())
+			return
+		}
+	}
+
+	if err := ac.authService.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		if utils.IsUnauthorizedError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, utils.ErrorCode(err), "Invalid refresh token")
+			return
+		}
+		utils.LogRequestError(c, "Failed to logout user", err, utils.LogFields{
+			"user_id": claims.UserID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Logout successful"})
 }
 
-// GetProfile returns the current user's profile
-func (ac *AuthController) GetProfile(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userIDInterface, exists := c.Get("user_id")
-	if !exists {
-		utils.UnauthorizedResponse(c, "User not authenticated")
+// ForgotPassword handles POST /auth/forgot-password.
+// It always responds 200 for a well-formed request so it cannot be used to discover registered emails.
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format: "+err.Error())
+		return
+	}
+
+	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
+		return
+	}
+
+	if err := ac.authService.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		utils.LogRequestError(c, "Failed to process password reset request", err, utils.LogFields{})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "If an account with that email exists, a password reset email has been sent",
+	})
+}
+
+// ResetPassword handles POST /auth/reset-password
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format: "+err.Error())
+		return
+	}
+
+	if validationErrors := ac.validator.ValidateStruct(&req); validationErrors != nil {
+		utils.ValidationErrorResponseStructured(c, validationErrors.FieldErrors())
 		return
 	}
 
-	userID, ok := userIDInterface.(uuid.UUID)
-	if !ok {
-		utils.InternalServerErrorResponse(c, "Invalid user ID format")
+	if err := ac.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		// A token whose account was deleted since it was issued is treated as invalid
+		if utils.IsValidationError(err) || utils.IsNotFoundError(err) {
+			utils.ValidationErrorResponse(c, "Invalid or expired reset token")
+			return
+		}
+		utils.LogRequestError(c, "Failed to reset password", err, utils.LogFields{})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	// This would require access to user service - for now, return basic info from token
-	username, _ := c.Get("username")
-	email, _ := c.Get("user_email")
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "Password reset successfully",
+	})
+}
 
-	profile := gin.H{
-		"id":       userID,
-		"username": username,
-		"email":    email,
+// GetProfile returns the current user's profile as currently stored, not as captured in the token
+func (ac *AuthController) GetProfile(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	user, err := ac.userService.GetUserByID(userID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get user profile", err, utils.LogFields{
+			"user_id": userID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, profile)
+	utils.SuccessResponse(c, http.StatusOK, user.ToProfileResponse())
 }
@@ -1,62 +1,166 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/services"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
 // CommentController handles comment-related HTTP requests
 type CommentController struct {
-	commentService services.CommentService
+	commentService     services.CommentService
+	idempotencyService services.IdempotencyService
 }
 
 // NewCommentController creates a new comment controller instance
-func NewCommentController(commentService services.CommentService) *CommentController {
+func NewCommentController(commentService services.CommentService, idempotencyService services.IdempotencyService) *CommentController {
 	return &CommentController{
-		commentService: commentService,
+		commentService:     commentService,
+		idempotencyService: idempotencyService,
 	}
 }
 
-// CreateComment handles POST /posts/:postId/comments
+// CreateComment handles POST /posts/:id/comments. Signed-in users comment as themselves;
+// visitors without a token comment as guests on posts that allow it.
 func (cc *CommentController) CreateComment(c *gin.Context) {
-	postIDParam := c.Param("postId")
-	_, err := uuid.Parse(postIDParam)
+	postIDParam := c.Param("id")
+	postID, err := uuid.Parse(postIDParam)
 	if err != nil {
 		utils.ValidationErrorResponse(c, "Invalid post ID format")
 		return
 	}
 
+	var req models.CreateCommentRequest
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	// The URL names the post; a post_id in the body is redundant but must agree with it
+	if req.PostID != uuid.Nil && req.PostID != postID {
+		utils.ValidationErrorResponse(c, "post_id in the request body does not match the post in the URL")
+		return
+	}
+	req.PostID = postID
+
 	userID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		utils.UnauthorizedResponse(c, "User not authenticated")
+		// The route only authenticates optionally, so a token that was sent but did not
+		// check out must not quietly turn the request into a guest comment
+		if c.GetHeader("Authorization") != "" {
+			utils.UnauthorizedResponse(c, "Invalid or expired token")
+			return
+		}
+		cc.createGuestComment(c, &req)
 		return
 	}
 
-	var req models.CreateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+	idempotencyKey, replay, ok := beginIdempotentCreate(c, cc.idempotencyService, userID, models.IdempotencyResourceComment, &req)
+	if !ok {
+		return
+	}
+	if replay != nil {
+		cc.replayCreatedComment(c, replay)
+		return
+	}
+
+	var createdID *uuid.UUID
+	defer func() {
+		finishIdempotentCreate(c, cc.idempotencyService, userID, idempotencyKey, createdID, http.StatusCreated)
+	}()
+
+	comment, err := cc.commentService.CreateComment(c.Request.Context(), userID, &req)
+	if err != nil {
+		cc.createCommentErrorResponse(c, err, utils.LogFields{
+			"post_id": postIDParam,
+			"user_id": userID,
+		})
+		return
+	}
+
+	createdID = &comment.ID
+
+	utils.CreatedResponse(c, comment.ID, "/api/v1/comments/"+comment.ID.String(), comment)
+}
+
+// createGuestComment creates the comment in the request as a guest. Idempotency keys are
+// scoped to a user, so guests do not get replay protection.
+func (cc *CommentController) createGuestComment(c *gin.Context, req *models.CreateCommentRequest) {
+	comment, err := cc.commentService.CreateGuestComment(c.Request.Context(), c.ClientIP(), req)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnauthorized) {
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, utils.ErrorCode(err), "Sign in to comment on this post")
+			return
+		}
+		if errors.Is(err, utils.ErrGuestRateLimited) {
+			utils.ErrorResponseWithCode(c, http.StatusTooManyRequests, utils.ErrorCode(err), "Too many guest comments from this address, please try again later")
+			return
+		}
+		cc.createCommentErrorResponse(c, err, utils.LogFields{
+			"post_id":  req.PostID,
+			"guest_ip": c.ClientIP(),
+		})
 		return
 	}
 
-	req.PostID = &postIDParam
+	utils.CreatedResponse(c, comment.ID, "/api/v1/comments/"+comment.ID.String(), comment)
+}
+
+// createCommentErrorResponse writes the response for an error from creating a comment
+func (cc *CommentController) createCommentErrorResponse(c *gin.Context, err error, fields utils.LogFields) {
+	if errors.Is(err, utils.ErrCommentNotFound) {
+		utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Parent comment not found")
+		return
+	}
+	if utils.IsNotFoundError(err) {
+		utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+		return
+	}
+	if errors.Is(err, utils.ErrParentMismatch) {
+		utils.ErrorResponseWithCode(c, http.StatusBadRequest, utils.ErrorCode(err), "Parent comment does not belong to this post")
+		return
+	}
+	if utils.IsValidationError(err) {
+		utils.ValidationErrorResponseFromError(c, err)
+		return
+	}
+	if errors.Is(err, utils.ErrDuplicateComment) {
+		utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "You already posted this comment")
+		return
+	}
+	if errors.Is(err, utils.ErrCommentsLocked) {
+		utils.ErrorResponseWithCode(c, http.StatusLocked, utils.ErrorCode(err), "Comments are locked on this post")
+		return
+	}
+	utils.LogRequestError(c, "Failed to create comment", err, fields)
+	utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+}
 
-	comment, err := cc.commentService.CreateComment(userID, &req)
+// replayCreatedComment answers a repeated Idempotency-Key with the comment the original request created
+func (cc *CommentController) replayCreatedComment(c *gin.Context, replay *models.IdempotencyKey) {
+	comment, err := cc.commentService.GetCommentByID(&models.GetCommentRequest{ID: replay.ResourceID.String()})
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
 			return
 		}
+		utils.LogRequestError(c, "Failed to get comment for idempotent replay", err, utils.LogFields{
+			"comment_id": replay.ResourceID,
+		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusCreated, comment)
+	utils.CreatedResponseWithStatus(c, replayStatus(replay), comment.ID, "/api/v1/comments/"+comment.ID.String(), comment)
 }
 
 // GetComment handles GET /comments/:id
@@ -72,7 +176,7 @@ func (cc *CommentController) GetComment(c *gin.Context) {
 	comment, err := cc.commentService.GetCommentByID(req)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Comment")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -98,21 +202,33 @@ func (cc *CommentController) UpdateComment(c *gin.Context) {
 	}
 
 	var req models.UpdateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := utils.BindJSON(c, &req); err != nil {
 		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
 		return
 	}
 
-	comment, err := cc.commentService.UpdateComment(commentID, userID, &req)
+	comment, err := cc.commentService.UpdateComment(c.Request.Context(), commentID, userID, &req)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Comment")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
 			return
 		}
 		if utils.IsForbiddenError(err) {
-			utils.ForbiddenResponse(c, "You can only update your own comments")
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "You can only update your own comments")
+			return
+		}
+		if errors.Is(err, utils.ErrVersionConflict) {
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Comment was modified by someone else; reload it and try again")
+			return
+		}
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
 			return
 		}
+		utils.LogRequestError(c, "Failed to update comment", err, utils.LogFields{
+			"comment_id": commentID,
+			"user_id":    userID,
+		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
@@ -136,14 +252,14 @@ func (cc *CommentController) DeleteComment(c *gin.Context) {
 	}
 
 	req := &models.DeleteCommentRequest{ID: idParam}
-	err = cc.commentService.DeleteComment(req, userID)
+	err = cc.commentService.DeleteComment(c.Request.Context(), req, userID, utils.GetUserRoleFromContext(c))
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Comment")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
 			return
 		}
 		if utils.IsForbiddenError(err) {
-			utils.ForbiddenResponse(c, "You can only delete your own comments")
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "You can only delete your own comments")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -153,41 +269,178 @@ func (cc *CommentController) DeleteComment(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
 
-// ListCommentsByPost handles GET /posts/:postId/comments
+// ListCommentsByPost handles GET /posts/:id/comments
 func (cc *CommentController) ListCommentsByPost(c *gin.Context) {
-	postIDParam := c.Param("postId")
+	postIDParam := c.Param("id")
 	if postIDParam == "" {
 		utils.ValidationErrorResponse(c, "Post ID is required")
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
+	postID, err := uuid.Parse(postIDParam)
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
+	// Answer conditional requests before loading the page of comments
+	stamp, err := cc.commentService.GetPostCommentsStamp(c.Request.Context(), postID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get post comments stamp", err, utils.LogFields{
+			"post_id": postIDParam,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	etag, lastModified := commentsValidators(stamp)
+	if utils.NotModified(c, etag, lastModified) {
 		return
 	}
 
 	req := &models.ListCommentsRequest{
 		PostID: postIDParam,
+		Sort:   c.Query("sort"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	comments, total, err := cc.commentService.ListCommentsByPost(c.Request.Context(), req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	commentResponses := make([]models.CommentResponse, len(comments))
+	for i, comment := range comments {
+		commentResponses[i] = comment.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, commentResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	})
+}
+
+// ListThreads handles GET /posts/:id/threads
+func (cc *CommentController) ListThreads(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	threads, total, err := cc.commentService.ListThreads(c.Request.Context(), postID, limit, offset)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to list comment threads", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	threadResponses := make([]models.CommentThreadResponse, len(threads))
+	for i, thread := range threads {
+		threadResponses[i] = thread.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, threadResponses, utils.PageInfo{
 		Limit:  limit,
 		Offset: offset,
+		Total:  total,
+	})
+}
+
+// GetCommentTree handles GET /posts/:id/comments/full
+func (cc *CommentController) GetCommentTree(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	maxDepth, err := strconv.Atoi(c.DefaultQuery("max_depth", "10"))
+	if err != nil || maxDepth < 1 {
+		utils.ValidationErrorResponse(c, "Invalid max_depth parameter")
+		return
+	}
+
+	comments, truncated, err := cc.commentService.GetCommentTree(c.Request.Context(), postID, maxDepth)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get comment tree", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	commentResponses := make([]models.CommentResponse, len(comments))
+	for i, comment := range comments {
+		commentResponses[i] = comment.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"comments":  commentResponses,
+		"count":     len(commentResponses),
+		"truncated": truncated,
+	})
+}
+
+// GetEmbedCommentTree handles GET /posts/:id/comments/embed
+func (cc *CommentController) GetEmbedCommentTree(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	maxDepth, err := strconv.Atoi(c.DefaultQuery("max_depth", "10"))
+	if err != nil || maxDepth < 1 {
+		utils.ValidationErrorResponse(c, "Invalid max_depth parameter")
+		return
 	}
 
-	comments, err := cc.commentService.ListCommentsByPost(req)
+	comments, truncated, err := cc.commentService.GetEmbedCommentTree(c.Request.Context(), postID, maxDepth)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
 			return
 		}
+		utils.LogRequestError(c, "Failed to get embed comment tree", err, utils.LogFields{
+			"post_id": postID,
+		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
@@ -198,10 +451,9 @@ func (cc *CommentController) ListCommentsByPost(c *gin.Context) {
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, gin.H{
-		"comments": commentResponses,
-		"limit":    limit,
-		"offset":   offset,
-		"count":    len(commentResponses),
+		"comments":  commentResponses,
+		"count":     len(commentResponses),
+		"truncated": truncated,
 	})
 }
 
@@ -214,27 +466,25 @@ func (cc *CommentController) GetCommentReplies(c *gin.Context) {
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
-		return
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	replies, err := cc.commentService.GetCommentReplies(commentID, limit, offset)
+	replies, total, err := cc.commentService.GetCommentReplies(c.Request.Context(), commentID, limit, offset)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Comment")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
 			return
 		}
+		utils.LogRequestError(c, "Failed to get comment replies", err, utils.LogFields{
+			"comment_id": commentID,
+		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
@@ -244,82 +494,713 @@ func (cc *CommentController) GetCommentReplies(c *gin.Context) {
 		replyResponses[i] = reply.ToResponse()
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, gin.H{
-		"replies": replyResponses,
-		"limit":   limit,
-		"offset":  offset,
-		"count":   len(replyResponses),
+	utils.PaginatedResponse(c, replyResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
 	})
 }
 
-// GetCommentsByPost handles GET /posts/:postId/comments
-func (cc *CommentController) GetCommentsByPost(c *gin.Context) {
-	postIDParam := c.Param("postId")
-	utils.LogInfo("Getting comments by post", utils.LogFields{
-		"post_id": postIDParam,
-	})
+// GetCommentDescendants handles GET /comments/:id/descendants
+func (cc *CommentController) GetCommentDescendants(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
 
-	postID, err := uuid.Parse(postIDParam)
+	limit, offset, err := utils.ParsePagination(c, 20)
 	if err != nil {
-		utils.LogError("Invalid post ID format for comments query", err, utils.LogFields{
-			"post_id": postIDParam,
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	descendants, total, err := cc.commentService.GetCommentDescendants(c.Request.Context(), commentID, limit, offset)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get comment descendants", err, utils.LogFields{
+			"comment_id": commentID,
 		})
-		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
+	descendantResponses := make([]models.CommentResponse, len(descendants))
+	for i, descendant := range descendants {
+		descendantResponses[i] = descendant.ToResponse()
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.LogError("Invalid limit parameter for comments", err, utils.LogFields{
-			"post_id": postID,
-			"limit":   limitStr,
-		})
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
+	utils.PaginatedResponse(c, descendantResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	})
+}
+
+// ListCommentsByUser handles GET /users/:userId/comments
+func (cc *CommentController) ListCommentsByUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid user ID format")
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.LogError("Invalid offset parameter for comments", err, utils.LogFields{
-			"post_id": postID,
-			"offset":  offsetStr,
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	comments, total, err := cc.commentService.ListCommentsByUser(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to list comments by user", err, utils.LogFields{
+			"user_id": userID,
 		})
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	req := &models.ListCommentsRequest{
-		PostID: postIDParam,
+	commentResponses := make([]models.UserCommentResponse, len(comments))
+	for i, comment := range comments {
+		commentResponses[i] = comment.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, commentResponses, utils.PageInfo{
 		Limit:  limit,
 		Offset: offset,
+		Total:  total,
+	})
+}
+
+// BatchGetComments handles POST /comments/batch
+func (cc *CommentController) BatchGetComments(c *gin.Context) {
+	var req models.BatchGetCommentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+		return
 	}
 
-	comments, err := cc.commentService.ListCommentsByPost(req)
+	comments, err := cc.commentService.GetCommentsByIDs(&req)
 	if err != nil {
-		utils.LogError("Failed to get comments by post", err, utils.LogFields{
-			"post_id": postID,
-			"limit":   limit,
-			"offset":  offset,
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		utils.LogRequestError(c, "Failed to batch get comments", err, utils.LogFields{
+			"count": len(req.IDs),
 		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	utils.LogInfo("Comments retrieved successfully", utils.LogFields{
-		"post_id": postID,
-		"count":   len(comments),
-		"limit":   limit,
-		"offset":  offset,
-	})
+	commentResponses := make([]models.CommentResponse, len(comments))
+	for i, comment := range comments {
+		commentResponses[i] = comment.ToResponse()
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, gin.H{
-		"comments": comments,
-		"limit":    limit,
-		"offset":   offset,
-		"count":    len(comments),
+		"comments": commentResponses,
+		"count":    len(commentResponses),
 	})
 }
+
+// GetUserMentions handles GET /users/:userId/mentions
+func (cc *CommentController) GetUserMentions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid user ID format")
+		return
+	}
+
+	authenticatedUserID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	if userID != authenticatedUserID {
+		utils.ForbiddenResponse(c, "You can only view your own mentions")
+		return
+	}
+
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	comments, err := cc.commentService.GetMentionsForUser(userID, limit, offset)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get user mentions", err, utils.LogFields{
+			"user_id": userID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	commentResponses := make([]models.CommentResponse, len(comments))
+	for i, comment := range comments {
+		commentResponses[i] = comment.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"comments": commentResponses,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(commentResponses),
+	})
+}
+
+// ReconcileRepliesCounts handles POST /admin/comments/reconcile-counts
+func (cc *CommentController) ReconcileRepliesCounts(c *gin.Context) {
+	batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", "500"))
+	if err != nil || batchSize < 1 || batchSize > 5000 {
+		utils.ValidationErrorResponse(c, "Invalid batch_size parameter (1-5000)")
+		return
+	}
+
+	result, err := cc.commentService.ReconcileRepliesCounts(c.Request.Context(), batchSize)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to reconcile replies counts", err, utils.LogFields{
+			"batch_size": batchSize,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, result)
+}
+
+// GetCommentsByPost lists a post's comments. It is not routed: GET /posts/:id/comments
+// is served by ListCommentsByPost.
+func (cc *CommentController) GetCommentsByPost(c *gin.Context) {
+	postIDParam := c.Param("id")
+	utils.LogRequest(c, "Getting comments by post", utils.LogFields{
+		"post_id": postIDParam,
+	})
+
+	postID, err := uuid.Parse(postIDParam)
+	if err != nil {
+		utils.LogRequestError(c, "Invalid post ID format for comments query", err, utils.LogFields{
+			"post_id": postIDParam,
+		})
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	// Parse query parameters
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.LogRequestError(c, "Invalid pagination parameters for comments", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	req := &models.ListCommentsRequest{
+		PostID: postIDParam,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	comments, total, err := cc.commentService.ListCommentsByPost(c.Request.Context(), req)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to get comments by post", err, utils.LogFields{
+			"post_id": postID,
+			"limit":   limit,
+			"offset":  offset,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.LogRequest(c, "Comments retrieved successfully", utils.LogFields{
+		"post_id": postID,
+		"count":   len(comments),
+		"limit":   limit,
+		"offset":  offset,
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"comments": comments,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(comments),
+		"total":    total,
+	})
+}
+
+// PurgeDeletedComments handles POST /admin/comments/purge-deleted
+func (cc *CommentController) PurgeDeletedComments(c *gin.Context) {
+	result, err := cc.commentService.PurgeDeletedComments(c.Request.Context())
+	if err != nil {
+		utils.LogRequestError(c, "Failed to purge deleted comments", err, utils.LogFields{})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, result)
+}
+
+// GetCommentPermissions handles GET /comments/:id/permissions
+func (cc *CommentController) GetCommentPermissions(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
+
+	// Anonymous callers get uuid.Nil and no permissions
+	userID, _ := utils.GetUserIDFromContext(c)
+
+	permissions, err := cc.commentService.GetCommentPermissions(commentID, userID, utils.GetUserRoleFromContext(c))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get comment permissions", err, utils.LogFields{
+			"comment_id": commentID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, permissions)
+}
+
+// GetCommentSource handles GET /comments/:id/source
+func (cc *CommentController) GetCommentSource(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
+
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	source, err := cc.commentService.GetCommentSource(commentID, userID, utils.GetUserRoleFromContext(c))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		if utils.IsForbiddenError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "You can only view the source of your own comments")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get comment source", err, utils.LogFields{
+			"comment_id": commentID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, source)
+}
+
+// SearchAllComments handles GET /admin/comments/search
+func (cc *CommentController) SearchAllComments(c *gin.Context) {
+	query := c.Query("q")
+
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	results, total, err := cc.commentService.SearchAllComments(query, limit, offset)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		utils.LogRequestError(c, "Failed to search all comments", err, utils.LogFields{
+			"query": query,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	resultResponses := make([]models.AdminCommentSearchResultResponse, len(results))
+	for i, result := range results {
+		resultResponses[i] = result.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"results": resultResponses,
+		"query":   query,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(resultResponses),
+		"total":   total,
+	})
+}
+
+// MoveComment handles PUT /comments/:id/parent
+func (cc *CommentController) MoveComment(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
+
+	callerID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	var req models.ReparentCommentRequest
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	newParentID, err := uuid.Parse(req.NewParentID)
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid new_parent_id format")
+		return
+	}
+
+	comment, err := cc.commentService.MoveComment(c.Request.Context(), commentID, newParentID, callerID)
+	if err != nil {
+		if errors.Is(err, utils.ErrParentMismatch) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, utils.ErrorCode(err), "New parent comment does not belong to the same post")
+			return
+		}
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to move comment", err, utils.LogFields{
+			"comment_id":    commentID,
+			"new_parent_id": newParentID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, comment.ToResponse())
+}
+
+// GetParticipantStats handles GET /admin/posts/:id/participant-stats
+func (cc *CommentController) GetParticipantStats(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	stats, err := cc.commentService.GetParticipantStats(postID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get participant stats", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	if stats == nil {
+		stats = []models.ParticipantStats{}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"post_id":      postID,
+		"participants": stats,
+		"count":        len(stats),
+	})
+}
+
+// ImportComments handles POST /posts/:id/comments/import
+func (cc *CommentController) ImportComments(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	var req models.ImportCommentsRequest
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+		return
+	}
+
+	result, err := cc.commentService.ImportComments(c.Request.Context(), postID, userID, &req)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to import comments", err, utils.LogFields{
+			"post_id":  postID,
+			"user_id":  userID,
+			"comments": len(req.Comments),
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, result)
+}
+
+// RestoreComment handles POST /admin/comments/:id/restore
+func (cc *CommentController) RestoreComment(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
+
+	if err := cc.commentService.RestoreComment(commentID); err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		if utils.IsConflictError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Comment is not deleted")
+			return
+		}
+		utils.LogRequestError(c, "Failed to restore comment", err, utils.LogFields{
+			"comment_id": commentID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	adminID, _ := utils.GetUserIDFromContext(c)
+	utils.LogRequest(c, "Comment restored", utils.LogFields{
+		"comment_id": commentID,
+		"admin_id":   adminID,
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Comment restored successfully"})
+}
+
+// Live comment connection timings
+const (
+	liveWriteTimeout      = 10 * time.Second
+	livePongTimeout       = 60 * time.Second
+	livePingInterval      = 50 * time.Second
+	liveHeartbeatInterval = 15 * time.Second
+)
+
+// liveUpgrader upgrades live comment requests to WebSockets. Any origin is accepted,
+// matching the CORS policy, since the feed only carries public comments.
+var liveUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// StreamComments handles GET /posts/:id/comments/live, pushing each comment created on
+// the post to the client as a JSON text message until either side closes the connection
+func (cc *CommentController) StreamComments(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	updates, unsubscribe, err := cc.commentService.SubscribeToPost(postID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to subscribe to live comments", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	// The upgrader writes its own error response if the handshake fails
+	conn, err := liveUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to upgrade live comments connection", err, utils.LogFields{
+			"post_id": postID,
+		})
+		return
+	}
+	defer conn.Close()
+
+	// The client only sends control frames; reading is what notices a disconnect
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case comment, ok := <-updates:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteJSON(comment); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// GetCommentPermalink handles GET /comments/:id/full
+func (cc *CommentController) GetCommentPermalink(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid comment ID format")
+		return
+	}
+
+	permalink, err := cc.commentService.GetCommentPermalink(commentID)
+	if err != nil {
+		if errors.Is(err, utils.ErrPostNotFound) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Comment not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get comment permalink", err, utils.LogFields{
+			"comment_id": commentID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, permalink)
+}
+
+// StreamCommentsSSE handles GET /posts/:id/comments/stream, the Server-Sent Events
+// alternative to StreamComments for clients behind proxies that block WebSockets. Each
+// new comment is sent as a data: line of JSON, with comment-line heartbeats in between.
+func (cc *CommentController) StreamCommentsSSE(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	updates, unsubscribe, err := cc.commentService.SubscribeToPost(postID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to subscribe to comment stream", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout; each write gets its own deadline
+	rc := http.NewResponseController(c.Writer)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	write := func(chunk []byte) bool {
+		rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		if _, err := c.Writer.Write(chunk); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	if !write([]byte(": connected\n\n")) {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case comment, ok := <-updates:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(comment)
+			if err != nil {
+				utils.LogRequestError(c, "Failed to encode streamed comment", err, utils.LogFields{
+					"comment_id": comment.ID,
+				})
+				continue
+			}
+			if !write([]byte("data: " + string(payload) + "\n\n")) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write([]byte(": heartbeat\n\n")) {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeCommentService records the request it was asked to create a comment for and
+// reports the post as missing, which is enough to see what reached the service
+type fakeCommentService struct {
+	services.CommentService
+	created *models.CreateCommentRequest
+}
+
+func (s *fakeCommentService) CreateComment(ctx context.Context, userID uuid.UUID, req *models.CreateCommentRequest) (*models.Comment, error) {
+	s.created = req
+	return nil, utils.ErrPostNotFound
+}
+
+func TestCreateCommentPostIDMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	postID := uuid.New()
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantCreated bool
+		wantMessage string
+	}{
+		{"post_id omitted", `{"content":"Hi"}`, http.StatusNotFound, true, "Post not found"},
+		{"post_id matches the URL", `{"content":"Hi","post_id":"` + postID.String() + `"}`, http.StatusNotFound, true, "Post not found"},
+		{"post_id differs from the URL", `{"content":"Hi","post_id":"` + uuid.NewString() + `"}`, http.StatusBadRequest, false, "does not match the post in the URL"},
+		{"post_id is not a UUID", `{"content":"Hi","post_id":"abc"}`, http.StatusBadRequest, false, "Invalid request payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commentService := &fakeCommentService{}
+			controller := NewCommentController(commentService, &fakeIdempotencyService{})
+
+			router := gin.New()
+			router.POST("/posts/:id/comments", func(c *gin.Context) {
+				c.Set("user_id", uuid.New())
+				controller.CreateComment(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/posts/"+postID.String()+"/comments", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if (commentService.created != nil) != tt.wantCreated {
+				t.Fatalf("service called = %v, want %v", commentService.created != nil, tt.wantCreated)
+			}
+			if tt.wantCreated && commentService.created.PostID != postID {
+				t.Errorf("service got post_id %s, want the URL's %s", commentService.created.PostID, postID)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantMessage) {
+				t.Errorf("got body %s, want one containing %q", w.Body.String(), tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// postValidators returns the ETag and Last-Modified of a post as GET /posts/:id serves
+// it. The author is embedded in the response, so a change to their profile counts as a
+// new version of the post.
+func postValidators(post *models.Post) (string, time.Time) {
+	lastModified := post.UpdatedAt
+	var authorUpdatedAt time.Time
+	if post.Author != nil {
+		authorUpdatedAt = post.Author.UpdatedAt
+		if authorUpdatedAt.After(lastModified) {
+			lastModified = authorUpdatedAt
+		}
+	}
+
+	return utils.ETag(post.ID, post.Version, post.UpdatedAt, post.CommentsLocked, authorUpdatedAt), lastModified
+}
+
+// commentsValidators returns the ETag and Last-Modified of the endpoints that serve a
+// post's comments, which change whenever the post, any of its comments, or the profile
+// of its author or a commenter does
+func commentsValidators(stamp *models.PostCommentsStamp) (string, time.Time) {
+	var commentsUpdatedAt, authorsUpdatedAt time.Time
+	if stamp.CommentsUpdatedAt != nil {
+		commentsUpdatedAt = *stamp.CommentsUpdatedAt
+	}
+	if stamp.AuthorsUpdatedAt != nil {
+		authorsUpdatedAt = *stamp.AuthorsUpdatedAt
+	}
+
+	etag := utils.ETag(stamp.PostID, stamp.PostVersion, stamp.PostUpdatedAt, stamp.CommentsLocked, stamp.CommentCount, commentsUpdatedAt, authorsUpdatedAt)
+	return etag, stamp.LastModified()
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/google/uuid"
+)
+
+func TestCommentsValidatorsTrackAuthors(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	commentsUpdatedAt := updatedAt.Add(time.Hour)
+	base := models.PostCommentsStamp{PostID: uuid.New(), PostVersion: 1, PostUpdatedAt: updatedAt, CommentCount: 2, CommentsUpdatedAt: &commentsUpdatedAt}
+
+	withAuthors := func(t time.Time) *models.PostCommentsStamp {
+		stamp := base
+		stamp.AuthorsUpdatedAt = &t
+		return &stamp
+	}
+
+	etag, _ := commentsValidators(withAuthors(updatedAt))
+	sameEtag, _ := commentsValidators(withAuthors(updatedAt))
+	if etag != sameEtag {
+		t.Errorf("the same version got different ETags: %s and %s", etag, sameEtag)
+	}
+
+	renamed := updatedAt.Add(2 * time.Hour)
+	renamedEtag, lastModified := commentsValidators(withAuthors(renamed))
+	if renamedEtag == etag {
+		t.Error("ETag did not change after a commenter updated their profile")
+	}
+	if !lastModified.Equal(renamed) {
+		t.Errorf("Last-Modified = %v, want the profile change at %v", lastModified, renamed)
+	}
+}
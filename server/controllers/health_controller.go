@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// healthPingTimeout bounds how long the health check waits on the database, so a hung
+// connection reports as down instead of stalling the probe
+const healthPingTimeout = 2 * time.Second
+
+// HealthController handles the service health, liveness and readiness checks
+type HealthController struct {
+	db           *sql.DB
+	shuttingDown atomic.Bool
+}
+
+// NewHealthController creates a new health controller instance
+func NewHealthController(db *sql.DB) *HealthController {
+	return &HealthController{
+		db: db,
+	}
+}
+
+// SetShuttingDown marks the service as draining so readiness checks fail and load
+// balancers stop routing new requests to it
+func (hc *HealthController) SetShuttingDown() {
+	hc.shuttingDown.Store(true)
+}
+
+// Liveness handles GET /healthz. It reports that the process is up and serving, without
+// checking dependencies, so a database outage does not get the process restarted.
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness handles GET /readyz. It returns 503 while the database cannot be reached and
+// once shutdown has begun.
+func (hc *HealthController) Readiness(c *gin.Context) {
+	if hc.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+
+	if err := hc.pingDB(c.Request.Context()); err != nil {
+		utils.LogRequestError(c, "Readiness check database ping failed", err, nil)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "not_ready",
+			"database": "down",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// Health handles GET /health. It returns 503 when the database cannot be reached.
+func (hc *HealthController) Health(c *gin.Context) {
+	if err := hc.pingDB(c.Request.Context()); err != nil {
+		utils.LogRequestError(c, "Health check database ping failed", err, nil)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "degraded",
+			"database": "down",
+		})
+		return
+	}
+
+	stats := hc.db.Stats()
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "ok",
+		"message":  "Post-Comments Service is running",
+		"database": "up",
+		"pool": gin.H{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		},
+	})
+}
+
+// Schema handles GET /health/schema. It reports the applied migration version and returns
+// 503 when it differs from the version this build expects, so a deploy against an
+// un-migrated (or newer) database is caught before queries start failing.
+func (hc *HealthController) Schema(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthPingTimeout)
+	defer cancel()
+
+	current, err := repository.SchemaVersion(ctx, hc.db)
+	if err != nil {
+		utils.LogRequestError(c, "Schema version check failed", err, nil)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "degraded",
+			"database": "down",
+		})
+		return
+	}
+
+	matches := current == repository.ExpectedSchemaVersion
+	status, code := "ok", http.StatusOK
+	if !matches {
+		status, code = "mismatch", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":           status,
+		"current_version":  current,
+		"expected_version": repository.ExpectedSchemaVersion,
+		"matches":          matches,
+	})
+}
+
+// pingDB pings the database, giving up after healthPingTimeout
+func (hc *HealthController) pingDB(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+
+	return hc.db.PingContext(ctx)
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func TestSchemaHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		version     int
+		queryErr    error
+		wantCode    int
+		wantStatus  string
+		wantMatches bool
+	}{
+		{"matching version", repository.ExpectedSchemaVersion, nil, http.StatusOK, "ok", true},
+		{"database behind the code", repository.ExpectedSchemaVersion - 1, nil, http.StatusServiceUnavailable, "mismatch", false},
+		{"database ahead of the code", repository.ExpectedSchemaVersion + 1, nil, http.StatusServiceUnavailable, "mismatch", false},
+		{"no migrations table yet", 0, &pq.Error{Code: "42P01"}, http.StatusServiceUnavailable, "mismatch", false},
+		{"database unreachable", 0, errors.New("connection refused"), http.StatusServiceUnavailable, "degraded", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			query := mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`)
+			if tt.queryErr != nil {
+				query.WillReturnError(tt.queryErr)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(tt.version))
+			}
+
+			router := gin.New()
+			router.GET("/health/schema", NewHealthController(db).Schema)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/schema", nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("got status %d, want %d", w.Code, tt.wantCode)
+			}
+			var body struct {
+				Status         string `json:"status"`
+				CurrentVersion int    `json:"current_version"`
+				Matches        bool   `json:"matches"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if body.Status != tt.wantStatus || body.Matches != tt.wantMatches {
+				t.Errorf("got status %q matches %v, want %q %v", body.Status, body.Matches, tt.wantStatus, tt.wantMatches)
+			}
+			if tt.queryErr == nil && body.CurrentVersion != tt.version {
+				t.Errorf("got current_version %d, want %d", body.CurrentVersion, tt.version)
+			}
+		})
+	}
+}
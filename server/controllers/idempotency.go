@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// beginIdempotentCreate claims the request's Idempotency-Key, if it sent one, for the bound
+// request body req. It returns the key ("" when none was sent) and, when the key was already
+// used for the same body, the record to replay. ok is false when an error response has
+// already been written.
+func beginIdempotentCreate(c *gin.Context, idempotencyService services.IdempotencyService, userID uuid.UUID, resourceType string, req interface{}) (key string, replay *models.IdempotencyKey, ok bool) {
+	key = utils.GetIdempotencyKey(c)
+	if key == "" || idempotencyService == nil {
+		return "", nil, true
+	}
+
+	requestHash, err := idempotencyRequestHash(req)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to fingerprint idempotent request", err, utils.LogFields{
+			"user_id": userID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return "", nil, false
+	}
+
+	replay, err = idempotencyService.Begin(userID, key, resourceType, requestHash)
+	if err != nil {
+		switch {
+		case utils.IsValidationError(err):
+			utils.ValidationErrorResponseFromError(c, err)
+		case errors.Is(err, utils.ErrIdempotencyKeyInUse):
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), err.Error())
+		default:
+			utils.LogRequestError(c, "Failed to reserve idempotency key", err, utils.LogFields{
+				"user_id": userID,
+			})
+			utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		}
+		return "", nil, false
+	}
+
+	if replay != nil {
+		c.Header(utils.IdempotentReplayedHeader, "true")
+	}
+
+	return key, replay, true
+}
+
+// idempotencyRequestHash fingerprints a bound create request. Hashing the decoded request
+// rather than the raw bytes means whitespace and key order do not make a retry look like
+// a different request.
+func idempotencyRequestHash(req interface{}) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// finishIdempotentCreate records the created resource under key, or releases the key when
+// resourceID is nil because the request failed. Handlers defer it right after claiming the
+// key, so the key is released on every early return and on panic. Failures are only
+// logged: the response for the original request has already been decided.
+func finishIdempotentCreate(c *gin.Context, idempotencyService services.IdempotencyService, userID uuid.UUID, key string, resourceID *uuid.UUID, statusCode int) {
+	if key == "" {
+		return
+	}
+
+	var err error
+	if resourceID == nil {
+		err = idempotencyService.Release(userID, key)
+	} else {
+		err = idempotencyService.Complete(userID, key, *resourceID, statusCode)
+	}
+
+	if err != nil {
+		utils.LogRequestError(c, "Failed to record idempotency key outcome", err, utils.LogFields{
+			"user_id": userID,
+		})
+	}
+}
+
+// replayStatus is the status code the original request was answered with
+func replayStatus(replay *models.IdempotencyKey) int {
+	if replay.StatusCode == nil {
+		return http.StatusCreated
+	}
+	return *replay.StatusCode
+}
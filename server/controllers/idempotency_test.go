@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeIdempotencyService replays a fixed record, or records how the key was finished
+type fakeIdempotencyService struct {
+	services.IdempotencyService
+	replay    *models.IdempotencyKey
+	completed *uuid.UUID
+	released  bool
+}
+
+func (s *fakeIdempotencyService) Begin(userID uuid.UUID, key, resourceType, requestHash string) (*models.IdempotencyKey, error) {
+	return s.replay, nil
+}
+
+func (s *fakeIdempotencyService) Complete(userID uuid.UUID, key string, resourceID uuid.UUID, statusCode int) error {
+	s.completed = &resourceID
+	return nil
+}
+
+func (s *fakeIdempotencyService) Release(userID uuid.UUID, key string) error {
+	s.released = true
+	return nil
+}
+
+func TestIdempotencyRequestHashIgnoresFormatting(t *testing.T) {
+	var compact, spaced, other models.CreatePostRequest
+	for body, req := range map[string]*models.CreatePostRequest{
+		`{"title":"Hello","content":"World"}`:                 &compact,
+		"{ \"content\": \"World\",\n  \"title\": \"Hello\" }": &spaced,
+		`{"title":"Hello","content":"Everyone"}`:              &other,
+	} {
+		if err := utils.BindJSON(jsonContext(body), req); err != nil {
+			t.Fatalf("binding %s: %v", body, err)
+		}
+	}
+
+	compactHash, _ := idempotencyRequestHash(&compact)
+	spacedHash, _ := idempotencyRequestHash(&spaced)
+	otherHash, _ := idempotencyRequestHash(&other)
+	if compactHash != spacedHash {
+		t.Errorf("reformatted body hashed differently: %s != %s", compactHash, spacedHash)
+	}
+	if compactHash == otherHash {
+		t.Errorf("different bodies hashed the same: %s", compactHash)
+	}
+}
+
+func TestIdempotentCreateReleasesKeyOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idempotency := &fakeIdempotencyService{}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/posts", func(c *gin.Context) {
+		userID := uuid.New()
+		key, _, ok := beginIdempotentCreate(c, idempotency, userID, models.IdempotencyResourcePost, gin.H{"title": "Hello"})
+		if !ok {
+			return
+		}
+		var createdID *uuid.UUID
+		defer func() {
+			finishIdempotentCreate(c, idempotency, userID, key, createdID, http.StatusCreated)
+		}()
+		panic("create failed")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set(utils.IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500", w.Code)
+	}
+	if !idempotency.released || idempotency.completed != nil {
+		t.Errorf("key was not released after the handler panicked (released=%v, completed=%v)", idempotency.released, idempotency.completed)
+	}
+}
+
+func TestReplayStatus(t *testing.T) {
+	ok := http.StatusOK
+	if got := replayStatus(&models.IdempotencyKey{StatusCode: &ok}); got != http.StatusOK {
+		t.Errorf("stored status: got %d, want 200", got)
+	}
+	if got := replayStatus(&models.IdempotencyKey{}); got != http.StatusCreated {
+		t.Errorf("no stored status: got %d, want 201", got)
+	}
+}
+
+// jsonContext returns a gin context for a POST request with the given JSON body
+func jsonContext(body string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
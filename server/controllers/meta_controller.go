@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MetaController handles requests describing the API itself
+type MetaController struct {
+	metaService services.MetaService
+}
+
+// NewMetaController creates a new meta controller instance
+func NewMetaController(metaService services.MetaService) *MetaController {
+	return &MetaController{
+		metaService: metaService,
+	}
+}
+
+// GetConstraints handles GET /meta/constraints
+func (mc *MetaController) GetConstraints(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, mc.metaService.GetConstraints())
+}
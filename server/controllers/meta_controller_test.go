@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetConstraintsReflectsConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	utils.SetMaxPageLimit(50)
+	utils.SetMaxPaginationOffset(2000)
+	defer utils.SetMaxPageLimit(utils.DefaultMaxPageLimit)
+	defer utils.SetMaxPaginationOffset(utils.DefaultMaxPaginationOffset)
+
+	tests := []struct {
+		name         string
+		cfg          *config.CommentConfig
+		wantMin      int
+		wantReplyMin int
+		wantMax      int
+		wantDepth    int
+	}{
+		{
+			name:         "configured limits",
+			cfg:          &config.CommentConfig{MinContentLength: 5, MinContentLengthReplies: true, MaxContentLength: 2000, MaxReplyDepth: 4},
+			wantMin:      5,
+			wantReplyMin: 5,
+			wantMax:      2000,
+			wantDepth:    4,
+		},
+		{
+			name:         "minimum not applied to replies",
+			cfg:          &config.CommentConfig{MinContentLength: 5, MinContentLengthReplies: false, MaxContentLength: 2000, MaxReplyDepth: 4},
+			wantMin:      5,
+			wantReplyMin: 1,
+			wantMax:      2000,
+			wantDepth:    4,
+		},
+		{
+			name:         "maximum above the request tag keeps the tag",
+			cfg:          &config.CommentConfig{MinContentLength: 1, MaxContentLength: 50000, MaxReplyDepth: 8},
+			wantMin:      1,
+			wantReplyMin: 1,
+			wantMax:      10000,
+			wantDepth:    8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/meta/constraints", NewMetaController(services.NewMetaService(tt.cfg)).GetConstraints)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/meta/constraints", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d, want 200", w.Code)
+			}
+
+			var body struct {
+				Data models.ValidationConstraints `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+
+			comment := body.Data.Comment
+			if got := derefInt(comment.Content.Min); got != tt.wantMin {
+				t.Errorf("comment content min = %d, want %d", got, tt.wantMin)
+			}
+			if got := derefInt(comment.ReplyContent.Min); got != tt.wantReplyMin {
+				t.Errorf("reply content min = %d, want %d", got, tt.wantReplyMin)
+			}
+			if got := derefInt(comment.Content.Max); got != tt.wantMax {
+				t.Errorf("comment content max = %d, want %d", got, tt.wantMax)
+			}
+			if comment.MaxReplyDepth != tt.wantDepth {
+				t.Errorf("max reply depth = %d, want %d", comment.MaxReplyDepth, tt.wantDepth)
+			}
+			if body.Data.Pagination.MaxLimit != 50 || body.Data.Pagination.MaxOffset != 2000 {
+				t.Errorf("pagination = %+v, want max_limit 50 and max_offset 2000", body.Data.Pagination)
+			}
+		})
+	}
+}
+
+func derefInt(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/services"
@@ -13,39 +15,59 @@ import (
 
 // PostController handles post-related HTTP requests
 type PostController struct {
-	postService services.PostService
+	postService        services.PostService
+	idempotencyService services.IdempotencyService
 }
 
 // NewPostController creates a new post controller instance
-func NewPostController(postService services.PostService) *PostController {
+func NewPostController(postService services.PostService, idempotencyService services.IdempotencyService) *PostController {
 	return &PostController{
-		postService: postService,
+		postService:        postService,
+		idempotencyService: idempotencyService,
 	}
 }
 
 // CreatePost handles POST /posts
 func (pc *PostController) CreatePost(c *gin.Context) {
-	utils.LogInfo("Creating new post", utils.LogFields{})
+	utils.LogRequest(c, "Creating new post", utils.LogFields{})
 
 	userID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		utils.LogError("User not authenticated for post creation", err, utils.LogFields{})
+		utils.LogRequestError(c, "User not authenticated for post creation", err, utils.LogFields{})
 		utils.UnauthorizedResponse(c, "User not authenticated")
 		return
 	}
 
 	var req models.CreatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.LogError("Invalid request payload for post creation", err, utils.LogFields{
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.LogRequestError(c, "Invalid request payload for post creation", err, utils.LogFields{
 			"user_id": userID,
 		})
 		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
 		return
 	}
 
-	post, err := pc.postService.CreatePost(&req, userID)
+	idempotencyKey, replay, ok := beginIdempotentCreate(c, pc.idempotencyService, userID, models.IdempotencyResourcePost, &req)
+	if !ok {
+		return
+	}
+	if replay != nil {
+		pc.replayCreatedPost(c, replay)
+		return
+	}
+
+	var createdID *uuid.UUID
+	defer func() {
+		finishIdempotentCreate(c, pc.idempotencyService, userID, idempotencyKey, createdID, http.StatusCreated)
+	}()
+
+	post, err := pc.postService.CreatePost(&req, userID, utils.GetReturnPreference(c) != utils.PreferReturnMinimal)
 	if err != nil {
-		utils.LogError("Failed to create post", err, utils.LogFields{
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		utils.LogRequestError(c, "Failed to create post", err, utils.LogFields{
 			"user_id": userID,
 			"title":   req.Title,
 		})
@@ -53,48 +75,77 @@ func (pc *PostController) CreatePost(c *gin.Context) {
 		return
 	}
 
-	utils.LogInfo("Post created successfully", utils.LogFields{
+	createdID = &post.ID
+
+	utils.LogRequest(c, "Post created successfully", utils.LogFields{
 		"post_id": post.ID,
 		"user_id": userID,
 		"title":   post.Title,
 	})
 
-	utils.SuccessResponse(c, http.StatusCreated, post)
+	utils.CreatedResponse(c, post.ID, "/api/v1/posts/"+post.ID.String(), post)
+}
+
+// replayCreatedPost answers a repeated Idempotency-Key with the post the original request created
+func (pc *PostController) replayCreatedPost(c *gin.Context, replay *models.IdempotencyKey) {
+	userID, _ := utils.GetUserIDFromContext(c)
+	post, err := pc.postService.GetPostByID(*replay.ResourceID, userID)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get post for idempotent replay", err, utils.LogFields{
+			"post_id": replay.ResourceID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.CreatedResponseWithStatus(c, replayStatus(replay), post.ID, "/api/v1/posts/"+post.ID.String(), post)
 }
 
 // GetPost handles GET /posts/:id
 func (pc *PostController) GetPost(c *gin.Context) {
 	idParam := c.Param("id")
-	utils.LogInfo("Getting post by ID", utils.LogFields{
+	utils.LogRequest(c, "Getting post by ID", utils.LogFields{
 		"post_id": idParam,
 	})
 
 	postID, err := uuid.Parse(idParam)
 	if err != nil {
-		utils.LogError("Invalid post ID format", err, utils.LogFields{
+		utils.LogRequestError(c, "Invalid post ID format", err, utils.LogFields{
 			"post_id": idParam,
 		})
 		utils.ValidationErrorResponse(c, "Invalid post ID format")
 		return
 	}
 
-	post, err := pc.postService.GetPostByID(postID)
+	// Anonymous callers get uuid.Nil and never see scheduled posts
+	viewerID, _ := utils.GetUserIDFromContext(c)
+
+	post, err := pc.postService.GetPostByID(postID, viewerID)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.LogError("Post not found", err, utils.LogFields{
+			utils.LogRequestError(c, "Post not found", err, utils.LogFields{
 				"post_id": postID,
 			})
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
 			return
 		}
-		utils.LogError("Failed to get post", err, utils.LogFields{
+		utils.LogRequestError(c, "Failed to get post", err, utils.LogFields{
 			"post_id": postID,
 		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	utils.LogInfo("Post retrieved successfully", utils.LogFields{
+	etag, lastModified := postValidators(post)
+	if utils.NotModified(c, etag, lastModified) {
+		return
+	}
+
+	utils.LogRequest(c, "Post retrieved successfully", utils.LogFields{
 		"post_id": postID,
 		"title":   post.Title,
 		"author":  post.Author.Username,
@@ -103,7 +154,7 @@ func (pc *PostController) GetPost(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, post)
 }
 
-// GetPostWithComments handles GET /posts/:id/comments
+// GetPostWithComments handles GET /posts/:id/with-comments
 func (pc *PostController) GetPostWithComments(c *gin.Context) {
 	idParam := c.Param("id")
 	postID, err := uuid.Parse(idParam)
@@ -112,10 +163,34 @@ func (pc *PostController) GetPostWithComments(c *gin.Context) {
 		return
 	}
 
-	post, err := pc.postService.GetPostWithComments(postID)
+	limit, offset, err := utils.ParsePagination(c, 50)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	// Answer conditional requests before loading the comments
+	stamp, err := pc.postService.GetPostCommentsStamp(c.Request.Context(), postID)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get post comments stamp", err, utils.LogFields{
+			"post_id": idParam,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	etag, lastModified := commentsValidators(stamp)
+	if utils.NotModified(c, etag, lastModified) {
+		return
+	}
+
+	post, err := pc.postService.GetPostWithComments(c.Request.Context(), postID, limit, offset)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -128,13 +203,13 @@ func (pc *PostController) GetPostWithComments(c *gin.Context) {
 // UpdatePost handles PUT /posts/:id
 func (pc *PostController) UpdatePost(c *gin.Context) {
 	idParam := c.Param("id")
-	utils.LogInfo("Updating post", utils.LogFields{
+	utils.LogRequest(c, "Updating post", utils.LogFields{
 		"post_id": idParam,
 	})
 
 	postID, err := uuid.Parse(idParam)
 	if err != nil {
-		utils.LogError("Invalid post ID format for update", err, utils.LogFields{
+		utils.LogRequestError(c, "Invalid post ID format for update", err, utils.LogFields{
 			"post_id": idParam,
 		})
 		utils.ValidationErrorResponse(c, "Invalid post ID format")
@@ -143,7 +218,7 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 
 	userID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		utils.LogError("User not authenticated for post update", err, utils.LogFields{
+		utils.LogRequestError(c, "User not authenticated for post update", err, utils.LogFields{
 			"post_id": postID,
 		})
 		utils.UnauthorizedResponse(c, "User not authenticated")
@@ -151,8 +226,8 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 	}
 
 	var req models.UpdatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.LogError("Invalid request payload for post update", err, utils.LogFields{
+	if err := utils.BindJSON(c, &req); err != nil {
+		utils.LogRequestError(c, "Invalid request payload for post update", err, utils.LogFields{
 			"post_id": postID,
 			"user_id": userID,
 		})
@@ -162,23 +237,31 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 
 	post, err := pc.postService.UpdatePost(postID, &req, userID)
 	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
 		if utils.IsNotFoundError(err) {
-			utils.LogError("Post not found for update", err, utils.LogFields{
+			utils.LogRequestError(c, "Post not found for update", err, utils.LogFields{
 				"post_id": postID,
 				"user_id": userID,
 			})
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
 			return
 		}
 		if utils.IsForbiddenError(err) {
-			utils.LogError("User not authorized to update post", err, utils.LogFields{
+			utils.LogRequestError(c, "User not authorized to update post", err, utils.LogFields{
 				"post_id": postID,
 				"user_id": userID,
 			})
-			utils.ForbiddenResponse(c, "You can only update your own posts")
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "You can only update your own posts")
 			return
 		}
-		utils.LogError("Failed to update post", err, utils.LogFields{
+		if errors.Is(err, utils.ErrVersionConflict) {
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Post was modified by someone else; reload it and try again")
+			return
+		}
+		utils.LogRequestError(c, "Failed to update post", err, utils.LogFields{
 			"post_id": postID,
 			"user_id": userID,
 		})
@@ -186,7 +269,7 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	utils.LogInfo("Post updated successfully", utils.LogFields{
+	utils.LogRequest(c, "Post updated successfully", utils.LogFields{
 		"post_id": postID,
 		"user_id": userID,
 		"title":   post.Title,
@@ -198,13 +281,13 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 // DeletePost handles DELETE /posts/:id
 func (pc *PostController) DeletePost(c *gin.Context) {
 	idParam := c.Param("id")
-	utils.LogInfo("Deleting post", utils.LogFields{
+	utils.LogRequest(c, "Deleting post", utils.LogFields{
 		"post_id": idParam,
 	})
 
 	postID, err := uuid.Parse(idParam)
 	if err != nil {
-		utils.LogError("Invalid post ID format for deletion", err, utils.LogFields{
+		utils.LogRequestError(c, "Invalid post ID format for deletion", err, utils.LogFields{
 			"post_id": idParam,
 		})
 		utils.ValidationErrorResponse(c, "Invalid post ID format")
@@ -213,7 +296,7 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 
 	userID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		utils.LogError("User not authenticated for post deletion", err, utils.LogFields{
+		utils.LogRequestError(c, "User not authenticated for post deletion", err, utils.LogFields{
 			"post_id": postID,
 		})
 		utils.UnauthorizedResponse(c, "User not authenticated")
@@ -223,22 +306,22 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 	err = pc.postService.DeletePost(postID, userID)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.LogError("Post not found for deletion", err, utils.LogFields{
+			utils.LogRequestError(c, "Post not found for deletion", err, utils.LogFields{
 				"post_id": postID,
 				"user_id": userID,
 			})
-			utils.NotFoundResponse(c, "Post")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
 			return
 		}
 		if utils.IsForbiddenError(err) {
-			utils.LogError("User not authorized to delete post", err, utils.LogFields{
+			utils.LogRequestError(c, "User not authorized to delete post", err, utils.LogFields{
 				"post_id": postID,
 				"user_id": userID,
 			})
-			utils.ForbiddenResponse(c, "You can only delete your own posts")
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "You can only delete your own posts")
 			return
 		}
-		utils.LogError("Failed to delete post", err, utils.LogFields{
+		utils.LogRequestError(c, "Failed to delete post", err, utils.LogFields{
 			"post_id": postID,
 			"user_id": userID,
 		})
@@ -246,7 +329,7 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 		return
 	}
 
-	utils.LogInfo("Post deleted successfully", utils.LogFields{
+	utils.LogRequest(c, "Post deleted successfully", utils.LogFields{
 		"post_id": postID,
 		"user_id": userID,
 	})
@@ -257,35 +340,21 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 // ListPosts handles GET /posts
 func (pc *PostController) ListPosts(c *gin.Context) {
 	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	utils.LogInfo("Listing posts", utils.LogFields{
-		"limit":  limitStr,
-		"offset": offsetStr,
-	})
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.LogError("Invalid limit parameter", err, utils.LogFields{
-			"limit": limitStr,
-		})
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.LogRequestError(c, "Invalid pagination parameters", err, utils.LogFields{})
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.LogError("Invalid offset parameter", err, utils.LogFields{
-			"offset": offsetStr,
-		})
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
-		return
-	}
+	utils.LogRequest(c, "Listing posts", utils.LogFields{
+		"limit":  limit,
+		"offset": offset,
+	})
 
-	posts, err := pc.postService.ListPosts(limit, offset)
+	posts, total, err := pc.postService.ListPosts(c.Request.Context(), limit, offset)
 	if err != nil {
-		utils.LogError("Failed to list posts", err, utils.LogFields{
+		utils.LogRequestError(c, "Failed to list posts", err, utils.LogFields{
 			"limit":  limit,
 			"offset": offset,
 		})
@@ -293,17 +362,16 @@ func (pc *PostController) ListPosts(c *gin.Context) {
 		return
 	}
 
-	utils.LogInfo("Posts listed successfully", utils.LogFields{
+	utils.LogRequest(c, "Posts listed successfully", utils.LogFields{
 		"count":  len(posts),
 		"limit":  limit,
 		"offset": offset,
 	})
 
-	utils.SuccessResponse(c, http.StatusOK, gin.H{
-		"posts":  posts,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(posts),
+	utils.PaginatedResponse(c, posts, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
 	})
 }
 
@@ -317,24 +385,15 @@ func (pc *PostController) ListPostsByUser(c *gin.Context) {
 	}
 
 	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
-		return
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	posts, err := pc.postService.ListPostsByUser(userID, limit, offset)
+	posts, err := pc.postService.ListPostsByUser(c.Request.Context(), userID, limit, offset)
 	if err != nil {
-		utils.LogError("Failed to get posts by user", err, utils.LogFields{
+		utils.LogRequestError(c, "Failed to get posts by user", err, utils.LogFields{
 			"user_id": userID,
 			"limit":   limit,
 			"offset":  offset,
@@ -357,3 +416,269 @@ func (pc *PostController) ListPostsByUser(c *gin.Context) {
 		"count":   len(postResponses),
 	})
 }
+
+// ListScheduledPosts handles GET /posts/scheduled, listing the caller's own posts that
+// are scheduled to go public later
+func (pc *PostController) ListScheduledPosts(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	posts, total, err := pc.postService.ListScheduledPosts(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to get scheduled posts", err, utils.LogFields{
+			"user_id": userID,
+			"limit":   limit,
+			"offset":  offset,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	postResponses := make([]models.PostResponse, len(posts))
+	for i, post := range posts {
+		postResponses[i] = post.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, postResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	})
+}
+
+// ListPostsByTag handles GET /posts/tag/:tag
+func (pc *PostController) ListPostsByTag(c *gin.Context) {
+	tag := c.Param("tag")
+
+	// Parse query parameters
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	posts, err := pc.postService.ListPostsByTag(c.Request.Context(), tag, limit, offset)
+	if err != nil {
+		utils.LogRequestError(c, "Failed to get posts by tag", err, utils.LogFields{
+			"tag":    tag,
+			"limit":  limit,
+			"offset": offset,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	// Convert to response format
+	postResponses := make([]models.PostResponse, len(posts))
+	for i, post := range posts {
+		postResponses[i] = post.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"posts":  postResponses,
+		"tag":    strings.ToLower(strings.TrimSpace(tag)),
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(postResponses),
+	})
+}
+
+// ListTrending handles GET /posts/trending
+func (pc *PostController) ListTrending(c *gin.Context) {
+	var window time.Duration
+	if raw := c.Query("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			utils.ValidationErrorResponse(c, "Invalid window, expected a duration such as 24h")
+			return
+		}
+		window = parsed
+	}
+
+	limit, offset, err := utils.ParsePagination(c, 10)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	posts, total, err := pc.postService.ListTrending(c.Request.Context(), window, limit, offset)
+	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, utils.ErrorCode(err), "Window must be between 1h and 720h")
+			return
+		}
+
+		utils.LogRequestError(c, "Failed to list trending posts", err, utils.LogFields{
+			"window": window.String(),
+			"limit":  limit,
+			"offset": offset,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	postResponses := make([]models.PostResponse, len(posts))
+	for i, post := range posts {
+		postResponses[i] = post.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, postResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	})
+}
+
+// GetPostPermissions handles GET /posts/:id/permissions
+func (pc *PostController) GetPostPermissions(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	// Anonymous callers get uuid.Nil and no permissions
+	userID, _ := utils.GetUserIDFromContext(c)
+
+	permissions, err := pc.postService.GetPostPermissions(postID, userID, utils.GetUserRoleFromContext(c))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get post permissions", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, permissions)
+}
+
+// RestorePost handles POST /admin/posts/:id/restore
+func (pc *PostController) RestorePost(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	if err := pc.postService.RestorePost(postID); err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		if utils.IsConflictError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Post is not deleted")
+			return
+		}
+		utils.LogRequestError(c, "Failed to restore post", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	adminID, _ := utils.GetUserIDFromContext(c)
+	utils.LogRequest(c, "Post restored", utils.LogFields{
+		"post_id":  postID,
+		"admin_id": adminID,
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Post restored successfully"})
+}
+
+// LockComments handles PUT /posts/:id/lock
+func (pc *PostController) LockComments(c *gin.Context) {
+	pc.setCommentsLocked(c, true)
+}
+
+// UnlockComments handles PUT /posts/:id/unlock
+func (pc *PostController) UnlockComments(c *gin.Context) {
+	pc.setCommentsLocked(c, false)
+}
+
+// setCommentsLocked closes or reopens the post in the path to new comments
+func (pc *PostController) setCommentsLocked(c *gin.Context, locked bool) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	post, err := pc.postService.SetCommentsLocked(postID, userID, utils.GetUserRoleFromContext(c), locked)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		if utils.IsForbiddenError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "Only the post author or an admin can lock or unlock comments")
+			return
+		}
+		utils.LogRequestError(c, "Failed to update post comment lock", err, utils.LogFields{
+			"post_id": postID,
+			"locked":  locked,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.LogRequest(c, "Post comment lock updated", utils.LogFields{
+		"post_id": postID,
+		"user_id": userID,
+		"locked":  locked,
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, post)
+}
+
+// GetDeleteImpact handles GET /posts/:id/delete-impact
+func (pc *PostController) GetDeleteImpact(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid post ID format")
+		return
+	}
+
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	impact, err := pc.postService.GetDeleteImpact(postID, userID, utils.GetUserRoleFromContext(c))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "Post not found")
+			return
+		}
+		if utils.IsForbiddenError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusForbidden, utils.ErrorCode(err), "Only the post author or a moderator can view delete impact")
+			return
+		}
+		utils.LogRequestError(c, "Failed to get post delete impact", err, utils.LogFields{
+			"post_id": postID,
+		})
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, impact)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchController handles search-related HTTP requests
+type SearchController struct {
+	searchService services.SearchService
+}
+
+// NewSearchController creates a new search controller instance
+func NewSearchController(searchService services.SearchService) *SearchController {
+	return &SearchController{
+		searchService: searchService,
+	}
+}
+
+// Search handles GET /search
+func (sc *SearchController) Search(c *gin.Context) {
+	query := c.Query("q")
+
+	searchType := c.DefaultQuery("type", models.SearchTypePosts)
+	if searchType != models.SearchTypePosts && searchType != models.SearchTypeComments {
+		utils.ValidationErrorResponse(c, "Invalid type parameter (posts or comments)")
+		return
+	}
+
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var results interface{}
+	var count int
+
+	if searchType == models.SearchTypeComments {
+		comments, err := sc.searchService.SearchComments(query, limit, offset)
+		if err != nil {
+			sc.handleSearchError(c, err, query, searchType)
+			return
+		}
+
+		responses := make([]models.CommentSearchResultResponse, len(comments))
+		for i, result := range comments {
+			responses[i] = result.ToResponse()
+		}
+		results, count = responses, len(responses)
+	} else {
+		posts, err := sc.searchService.SearchPosts(query, limit, offset)
+		if err != nil {
+			sc.handleSearchError(c, err, query, searchType)
+			return
+		}
+
+		responses := make([]models.PostSearchResultResponse, len(posts))
+		for i, result := range posts {
+			responses[i] = result.ToResponse()
+		}
+		results, count = responses, len(responses)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"results": results,
+		"type":    searchType,
+		"query":   query,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   count,
+	})
+}
+
+// handleSearchError maps search service errors to HTTP responses
+func (sc *SearchController) handleSearchError(c *gin.Context, err error, query, searchType string) {
+	if utils.IsValidationError(err) {
+		utils.ValidationErrorResponseFromError(c, err)
+		return
+	}
+
+	utils.LogRequestError(c, "Failed to search", err, utils.LogFields{
+		"query": query,
+		"type":  searchType,
+	})
+	utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/services"
@@ -35,7 +37,7 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 	user, err := uc.userService.GetUserByID(userID)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "User")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -56,7 +58,7 @@ func (uc *UserController) GetUserByUsername(c *gin.Context) {
 	user, err := uc.userService.GetUserByUsername(username)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "User")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -66,7 +68,8 @@ func (uc *UserController) GetUserByUsername(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, user.ToResponse())
 }
 
-// UpdateUser handles PUT /users/:id
+// UpdateUser handles PUT and PATCH /users/:id. Both are partial updates: fields left
+// out of the body are unchanged, and a null display_name or avatar_url clears it.
 func (uc *UserController) UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := uuid.Parse(idParam)
@@ -87,21 +90,104 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := utils.BindJSON(c, &req); err != nil {
 		utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
 		return
 	}
 
-	user, err := uc.userService.UpdateUser(userID, &req)
+	user, err := uc.userService.UpdateUser(c.Request.Context(), userID, &req)
 	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "User")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
+			return
+		}
+		if errors.Is(err, utils.ErrUsernameAlreadyExists) {
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Username already exists")
+			return
+		}
+		if errors.Is(err, utils.ErrUsernameChangeTooSoon) {
+			utils.ErrorResponseWithCode(c, http.StatusTooManyRequests, utils.ErrorCode(err), "Username can only be changed once every 30 days")
 			return
 		}
 		if utils.IsConflictError(err) {
-			utils.ConflictResponse(c, "Email already exists")
+			utils.ErrorResponseWithCode(c, http.StatusConflict, utils.ErrorCode(err), "Email already exists")
+			return
+		}
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, user.ToResponse())
+}
+
+// UploadAvatar handles POST /users/:userId/avatar. The image is sent as the "avatar"
+// field of a multipart form.
+func (uc *UserController) UploadAvatar(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ValidationErrorResponse(c, "Invalid user ID format")
+		return
+	}
+
+	authenticatedUserID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	if userID != authenticatedUserID {
+		utils.ForbiddenResponse(c, "You can only update your own avatar")
+		return
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		utils.ErrorResponseWithCode(c, http.StatusBadRequest, "", "An image file is required in the avatar field")
+		return
+	}
+
+	if fileHeader.Size > services.MaxAvatarBytes {
+		utils.ErrorResponseWithCode(c, http.StatusRequestEntityTooLarge, utils.ErrorCodeRequestTooLarge, "Avatar image must be at most 2MB")
+		return
+	}
+
+	if !utils.IsAllowedImageType(fileHeader.Header.Get("Content-Type")) {
+		utils.ErrorResponseWithCode(c, http.StatusUnsupportedMediaType, utils.ErrorCodeUnsupportedMediaType, "Avatar must be a PNG, JPEG or WebP image")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxAvatarBytes+1))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+		return
+	}
+	if len(data) > services.MaxAvatarBytes {
+		utils.ErrorResponseWithCode(c, http.StatusRequestEntityTooLarge, utils.ErrorCodeRequestTooLarge, "Avatar image must be at most 2MB")
+		return
+	}
+
+	user, err := uc.userService.UpdateAvatar(c.Request.Context(), userID, data)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidInput) {
+			utils.ErrorResponseWithCode(c, http.StatusBadRequest, utils.ErrorCodeInvalidImage, "File is not a valid PNG, JPEG or WebP image")
 			return
 		}
+		if utils.IsNotFoundError(err) {
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
+			return
+		}
+		utils.LogErrorContext(c.Request.Context(), "Failed to update avatar", err, utils.LogFields{"user_id": userID})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
@@ -129,10 +215,10 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = uc.userService.DeleteUser(userID)
+	err = uc.userService.DeleteUser(c.Request.Context(), userID)
 	if err != nil {
 		if utils.IsNotFoundError(err) {
-			utils.NotFoundResponse(c, "User")
+			utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.ErrorCode(err), "User not found")
 			return
 		}
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
@@ -144,36 +230,124 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 
 // ListUsers handles GET /users
 func (uc *UserController) ListUsers(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
+	limit, offset, err := utils.ParsePagination(c, 20)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		utils.ValidationErrorResponse(c, "Invalid limit parameter")
+	users, total, err := uc.userService.ListUsers(limit, offset)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		utils.ValidationErrorResponse(c, "Invalid offset parameter")
+	userResponses := make([]models.UserResponse, len(users))
+	for i, user := range users {
+		userResponses[i] = user.ToResponse()
+	}
+
+	utils.PaginatedResponse(c, userResponses, utils.PageInfo{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	})
+}
+
+// AdminListUsers handles GET /admin/users
+func (uc *UserController) AdminListUsers(c *gin.Context) {
+	var req models.AdminListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid query parameters: "+err.Error())
+		return
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+	if req.Limit < 0 || req.Offset < 0 {
+		utils.ValidationErrorResponse(c, "Invalid pagination parameters")
+		return
+	}
+	if err := utils.ValidatePaginationOffset(req.Offset); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	users, err := uc.userService.ListUsers(limit, offset)
+	filter := &models.AdminUserFilter{
+		Banned:        req.Banned,
+		EmailVerified: req.Verified,
+	}
+
+	if req.Role != "" {
+		switch req.Role {
+		case models.RoleUser, models.RoleModerator, models.RoleAdmin:
+			filter.Role = &req.Role
+		default:
+			utils.ValidationErrorResponse(c, "role must be one of: user, moderator, admin")
+			return
+		}
+	}
+
+	if req.From != "" {
+		from, err := parseDateParam(req.From, false)
+		if err != nil {
+			utils.ValidationErrorResponse(c, "Invalid from parameter, expected RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.CreatedFrom = &from
+	}
+
+	if req.To != "" {
+		to, err := parseDateParam(req.To, true)
+		if err != nil {
+			utils.ValidationErrorResponse(c, "Invalid to parameter, expected RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.CreatedTo = &to
+	}
+
+	users, total, err := uc.userService.AdminListUsers(filter, req.Limit, req.Offset)
 	if err != nil {
+		if utils.IsValidationError(err) {
+			utils.ValidationErrorResponseFromError(c, err)
+			return
+		}
+		utils.LogRequestError(c, "Failed to list users for admin", err, utils.LogFields{
+			"limit":  req.Limit,
+			"offset": req.Offset,
+		})
 		utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
 		return
 	}
 
-	userResponses := make([]models.UserResponse, len(users))
+	userResponses := make([]models.AdminUserResponse, len(users))
 	for i, user := range users {
-		userResponses[i] = user.ToResponse()
+		userResponses[i] = user.ToAdminResponse()
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, gin.H{
 		"users":  userResponses,
-		"limit":  limit,
-		"offset": offset,
+		"limit":  req.Limit,
+		"offset": req.Offset,
 		"count":  len(userResponses),
+		"total":  total,
 	})
 }
+
+// parseDateParam parses a query parameter given either as RFC3339 or as a plain date.
+// A plain date used as an upper bound covers the whole day.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -1,8 +1,7 @@
 package middleware
 
 import (
-	"net/http"
-
+	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/services"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/gin-gonic/gin"
@@ -27,7 +26,7 @@ func AuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 		}
 
 		// Validate token
-		claims, err := jwtService.ValidateToken(token)
+		claims, err := jwtService.ValidateToken(c.Request.Context(), token)
 		if err != nil {
 			utils.UnauthorizedResponse(c, "Invalid token")
 			c.Abort()
@@ -45,6 +44,9 @@ func AuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
+		c.Set("token_claims", claims)
+		setRequestUserID(c, claims)
 
 		c.Next()
 	}
@@ -59,12 +61,14 @@ func OptionalAuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 			token, err := jwtService.ExtractTokenFromHeader(authHeader)
 			if err == nil {
 				// Validate token
-				claims, err := jwtService.ValidateToken(token)
+				claims, err := jwtService.ValidateToken(c.Request.Context(), token)
 				if err == nil && claims.Type == "access" {
 					// Set user information in context
 					c.Set("user_id", claims.UserID)
 					c.Set("username", claims.Username)
 					c.Set("user_email", claims.Email)
+					c.Set("user_role", claims.Role)
+					setRequestUserID(c, claims)
 				}
 			}
 		}
@@ -72,18 +76,26 @@ func OptionalAuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 	}
 }
 
-// CORS middleware for handling cross-origin requests
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+// setRequestUserID records the authenticated user on the request context used for logging
+func setRequestUserID(c *gin.Context, claims *models.JWTClaims) {
+	if reqCtx := utils.RequestContextFromContext(c.Request.Context()); reqCtx != nil {
+		reqCtx.UserID = claims.UserID.String()
+	}
+}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// RequireRole restricts access to authenticated users holding one of the given roles.
+// It must be registered after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := utils.GetUserRoleFromContext(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
 		}
 
-		c.Next()
+		utils.ForbiddenResponse(c, "Insufficient permissions")
+		c.Abort()
 	}
 }
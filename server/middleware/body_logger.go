@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue replaces the value of any sensitive field in a logged body
+const redactedValue = "[REDACTED]"
+
+// redactedBodyFields are the JSON keys whose values never reach the logs, at any depth.
+// "token" covers the password reset token.
+var redactedBodyFields = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"refresh_token":    true,
+	"access_token":     true,
+	"token":            true,
+}
+
+// BodyLogger returns a gin.HandlerFunc that logs request and response bodies at debug
+// level, capturing at most maxBytes of each. Sensitive JSON fields are redacted; bodies
+// that are not JSON, or were cut off at maxBytes, are summarised by size instead of logged,
+// since they cannot be redacted safely. Only register it when LOG_LEVEL=debug.
+func BodyLogger(maxBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requestBody []byte
+		requestTruncated := false
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			// Read one byte past the cap to tell whether the body was cut off, then put
+			// what was read back in front of the rest so handlers see the full body
+			original := c.Request.Body
+			requestBody, _ = io.ReadAll(io.LimitReader(original, int64(maxBytes)+1))
+			c.Request.Body = &replayedBody{
+				Reader: io.MultiReader(bytes.NewReader(requestBody), original),
+				Closer: original,
+			}
+			if len(requestBody) > maxBytes {
+				requestBody = requestBody[:maxBytes]
+				requestTruncated = true
+			}
+		}
+
+		writer := &bodyLogWriter{ResponseWriter: c.Writer, maxBytes: maxBytes}
+		c.Writer = writer
+
+		c.Next()
+
+		fields := utils.LogFields{
+			"request_id":  c.GetString("request_id"),
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+			"status_code": writer.Status(),
+		}
+		if len(requestBody) > 0 {
+			fields["request_body"] = loggableBody(requestBody, requestTruncated, c.ContentType())
+		}
+		if writer.body.Len() > 0 {
+			fields["response_body"] = loggableBody(writer.body.Bytes(), writer.truncated, writer.Header().Get("Content-Type"))
+		}
+
+		utils.LogDebug("HTTP Body", fields)
+	}
+}
+
+// replayedBody serves the bytes already consumed for logging followed by the unread
+// remainder, and closes the original request body
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter copies up to maxBytes of the response into body as it is written
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	maxBytes  int
+	truncated bool
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController can reach the
+// underlying connection for flushing and deadlines
+func (w *bodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bodyLogWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyLogWriter) capture(data []byte) {
+	remaining := w.maxBytes - w.body.Len()
+	if len(data) > remaining {
+		data = data[:remaining]
+		w.truncated = true
+	}
+	w.body.Write(data)
+}
+
+// loggableBody returns the body with sensitive fields redacted, or a size summary when
+// the body cannot be parsed as JSON
+func loggableBody(body []byte, truncated bool, contentType string) interface{} {
+	if truncated {
+		return fmt.Sprintf("[omitted: body exceeds %d bytes]", len(body))
+	}
+
+	if !strings.Contains(contentType, "json") {
+		return fmt.Sprintf("[omitted: %d bytes of %q]", len(body), contentType)
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Sprintf("[omitted: %d bytes of invalid JSON]", len(body))
+	}
+
+	return redactBody(parsed)
+}
+
+// redactBody replaces the values of sensitive keys in decoded JSON, recursing into
+// nested objects and arrays
+func redactBody(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedBodyFields[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactBody(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactBody(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "top-level fields",
+			body: `{"username":"alice","password":"hunter2","refresh_token":"abc"}`,
+			want: `{"username":"alice","password":"[REDACTED]","refresh_token":"[REDACTED]"}`,
+		},
+		{
+			name: "keys match in any case",
+			body: `{"Password":"hunter2","ACCESS_TOKEN":"abc"}`,
+			want: `{"Password":"[REDACTED]","ACCESS_TOKEN":"[REDACTED]"}`,
+		},
+		{
+			name: "nested objects and arrays",
+			body: `{"data":{"tokens":[{"access_token":"a","expires_in":900}]},"users":[{"new_password":"x"}]}`,
+			want: `{"data":{"tokens":[{"access_token":"[REDACTED]","expires_in":900}]},"users":[{"new_password":"[REDACTED]"}]}`,
+		},
+		{
+			name: "non-string secrets",
+			body: `{"token":{"value":"abc"},"current_password":12345}`,
+			want: `{"token":"[REDACTED]","current_password":"[REDACTED]"}`,
+		},
+		{
+			name: "nothing sensitive",
+			body: `["a",1,null,{"title":"Hello"}]`,
+			want: `["a",1,null,{"title":"Hello"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body, want interface{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := redactBody(body); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBodyLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	previous, previousLevel := utils.Logger.Out, utils.Logger.GetLevel()
+	utils.Logger.SetOutput(&buf)
+	utils.Logger.SetLevel(logrus.DebugLevel)
+	defer utils.Logger.SetOutput(previous)
+	defer utils.Logger.SetLevel(previousLevel)
+
+	var handlerSaw string
+	router := gin.New()
+	router.Use(BodyLogger(64))
+	router.POST("/auth/login", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		handlerSaw = string(body)
+		c.JSON(http.StatusOK, gin.H{"access_token": "secret-access", "user": "alice"})
+	})
+
+	tests := []struct {
+		name        string
+		body        string
+		wantRequest interface{}
+	}{
+		{
+			name:        "small body is redacted",
+			body:        `{"username":"alice","password":"hunter2"}`,
+			wantRequest: map[string]interface{}{"username": "alice", "password": redactedValue},
+		},
+		{
+			name:        "body over the cap is summarised",
+			body:        `{"username":"alice","password":"` + strings.Repeat("x", 100) + `"}`,
+			wantRequest: "[omitted: body exceeds 64 bytes]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if handlerSaw != tt.body {
+				t.Errorf("handler read %q, want the full body %q", handlerSaw, tt.body)
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("log output %q is not a single JSON entry: %v", buf.String(), err)
+			}
+			if !reflect.DeepEqual(entry["request_body"], tt.wantRequest) {
+				t.Errorf("logged request_body %v, want %v", entry["request_body"], tt.wantRequest)
+			}
+			wantResponse := map[string]interface{}{"access_token": redactedValue, "user": "alice"}
+			if !reflect.DeepEqual(entry["response_body"], wantResponse) {
+				t.Errorf("logged response_body %v, want %v", entry["response_body"], wantResponse)
+			}
+			if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "secret-access") {
+				t.Errorf("log output leaks a secret: %s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers browsers may read from cross-origin responses
+const corsExposedHeaders = "Location, Preference-Applied, X-Request-ID, Idempotent-Replayed, ETag"
+
+// CORS handles cross-origin requests. The request Origin is echoed back only when it is in
+// the allowlist, together with Access-Control-Allow-Credentials when enabled. An allowlist
+// entry of "*" opts in to allowing every origin; credentials are never allowed then, since
+// browsers reject them with a wildcard origin. Requests from other origins get no CORS
+// headers, so the browser blocks them.
+func CORS(cfg *config.CORSConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	wildcard := false
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			wildcard = true
+			continue
+		}
+		allowed[strings.ToLower(origin)] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+
+		if origin != "" {
+			// The response depends on Origin, so caches must not share it across origins
+			c.Writer.Header().Add("Vary", "Origin")
+
+			allowOrigin := ""
+			if allowed[strings.ToLower(origin)] {
+				allowOrigin = origin
+				if cfg.AllowCredentials {
+					c.Header("Access-Control-Allow-Credentials", "true")
+				}
+			} else if wildcard {
+				allowOrigin = "*"
+			}
+
+			if allowOrigin != "" {
+				c.Header("Access-Control-Allow-Origin", allowOrigin)
+				c.Header("Access-Control-Allow-Methods", methods)
+				c.Header("Access-Control-Allow-Headers", headers)
+				c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
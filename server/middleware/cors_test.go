@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	allowlist := &config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+	}
+	wildcard := &config.CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+	}
+
+	tests := []struct {
+		name            string
+		cfg             *config.CORSConfig
+		method          string
+		origin          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+		wantMethods     string
+	}{
+		{"allowed origin", allowlist, http.MethodGet, "https://app.example.com", http.StatusOK, "https://app.example.com", "true", "GET, POST"},
+		{"allowed origin in another case", allowlist, http.MethodGet, "https://APP.example.com", http.StatusOK, "https://APP.example.com", "true", "GET, POST"},
+		{"disallowed origin", allowlist, http.MethodGet, "https://evil.example.com", http.StatusOK, "", "", ""},
+		{"no origin", allowlist, http.MethodGet, "", http.StatusOK, "", "", ""},
+		{"preflight from allowed origin", allowlist, http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com", "true", "GET, POST"},
+		{"preflight from disallowed origin", allowlist, http.MethodOptions, "https://evil.example.com", http.StatusNoContent, "", "", ""},
+		{"wildcard opt-in never allows credentials", wildcard, http.MethodGet, "https://any.example.com", http.StatusOK, "*", "", "GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.cfg))
+			handled := false
+			router.Handle(tt.method, "/posts", func(c *gin.Context) {
+				handled = true
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/posts", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if handled != (tt.method != http.MethodOptions) {
+				t.Errorf("handler ran = %v for %s", handled, tt.method)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			if wantVary := tt.origin != ""; (w.Header().Get("Vary") == "Origin") != wantVary {
+				t.Errorf("Vary = %q, want Origin only when the request has one", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
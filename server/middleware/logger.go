@@ -21,6 +21,7 @@ func Logger() gin.HandlerFunc {
 				"status_code": param.StatusCode,
 				"latency":     param.Latency.String(),
 				"user_agent":  param.Request.UserAgent(),
+				"request_id":  param.Keys["request_id"],
 			}
 
 			if param.ErrorMessage != "" {
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodySize returns a gin.HandlerFunc that limits request bodies to limit bytes, or to
+// the entry in routeLimits for the matched route template (c.FullPath()), such as an
+// upload endpoint. A body whose Content-Length is over the limit is rejected up front with
+// 413; one sent without a length is cut off at the limit, and the handler's bind error is
+// then answered as 413.
+func MaxBodySize(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := limit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			limit = routeLimit
+		}
+
+		if c.Request.ContentLength > limit {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Request body is too large")
+			c.Abort()
+			return
+		}
+
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = &limitedBody{
+				ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit),
+				c:          c,
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// limitedBody flags the request once its body has been read past the size limit
+type limitedBody struct {
+	io.ReadCloser
+	c *gin.Context
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.c.Set(utils.BodyTooLargeContextKey, true)
+	}
+	return n, err
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(MaxBodySize(32, map[string]int64{"/uploads": 128}))
+	handler := func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := utils.BindJSON(c, &body); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request payload: "+err.Error())
+			return
+		}
+		utils.SuccessResponse(c, http.StatusOK, body)
+	}
+	router.POST("/posts", handler)
+	router.POST("/uploads", handler)
+
+	large := `{"content":"` + strings.Repeat("a", 64) + `"}`
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		unknownLength bool
+		wantStatus    int
+	}{
+		{"body within the limit", "/posts", `{"title":"Hi"}`, false, http.StatusOK},
+		{"declared length over the limit", "/posts", large, false, http.StatusRequestEntityTooLarge},
+		{"unknown length over the limit", "/posts", large, true, http.StatusRequestEntityTooLarge},
+		{"route with a higher limit", "/uploads", large, false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.unknownLength {
+				// Hide the length so the request is sent as if chunked
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var envelope utils.APIResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("response %q is not the JSON envelope: %v", w.Body.String(), err)
+			}
+			if envelope.StatusCode != tt.wantStatus {
+				t.Errorf("envelope status_code = %d, want %d", envelope.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && envelope.ErrorCode != utils.ErrorCodeRequestTooLarge {
+				t.Errorf("error_code = %q, want %q", envelope.ErrorCode, utils.ErrorCodeRequestTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoutePath labels requests that matched no route, so probes for random URLs
+// all share one series
+const unmatchedRoutePath = "unmatched"
+
+// Metrics returns a gin.HandlerFunc that records request counts and latency for
+// Prometheus, labelled by the matched route template rather than the raw path
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = unmatchedRoutePath
+		}
+
+		utils.ObserveHTTPRequest(c.Request.Method, path, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryWithLogger returns a gin.HandlerFunc that recovers from panics in later
+// handlers, logs the panic and its stack trace through logrus with the request ID, and
+// responds with the standard 500 error envelope. It replaces gin.Recovery, which writes
+// unstructured traces to stderr.
+func RecoveryWithLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+
+			fields := utils.LogFields{
+				"request_id": c.GetString("request_id"),
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+			}
+
+			// A client that hung up mid-response is not a server fault, and there is no
+			// one left to send the 500 to
+			if isBrokenPipe(err) {
+				utils.LogWarn("Connection closed by client: "+err.Error(), fields)
+				c.Abort()
+				return
+			}
+
+			fields["stack"] = string(debug.Stack())
+			utils.LogError("Panic recovered", err, fields)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			utils.InternalServerErrorResponse(c, utils.GetErrorMessages().InternalServerError)
+			c.Abort()
+		}()
+
+		c.Next()
+	}
+}
+
+// isBrokenPipe reports whether err comes from writing to a connection the client closed
+func isBrokenPipe(err error) bool {
+	if errors.Is(err, http.ErrAbortHandler) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+
+	var syscallErr *os.SyscallError
+	if errors.As(opErr.Err, &syscallErr) {
+		return errors.Is(syscallErr.Err, syscall.EPIPE) || errors.Is(syscallErr.Err, syscall.ECONNRESET)
+	}
+	message := strings.ToLower(opErr.Err.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
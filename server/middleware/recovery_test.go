@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryWithLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	previous := utils.Logger.Out
+	utils.Logger.SetOutput(&buf)
+	defer utils.Logger.SetOutput(previous)
+
+	router := gin.New()
+	router.Use(RequestContext())
+	router.Use(RecoveryWithLogger())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-panic-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500", w.Code)
+	}
+	var body utils.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response %q is not the JSON envelope: %v", w.Body.String(), err)
+	}
+	if body.Success || body.StatusCode != http.StatusInternalServerError || body.ErrorMessage == nil || body.Data != nil {
+		t.Errorf("got envelope %+v, want a failed 500 with an error message and no data", body)
+	}
+	if strings.Contains(w.Body.String(), "something broke") {
+		t.Errorf("response leaks the panic value: %s", w.Body.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log output %q is not a single JSON entry: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["request_id"] != "req-panic-1" || entry["path"] != "/panic" {
+		t.Errorf("got log entry %v, want an error with the request ID and path", entry)
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("logged stack does not include the panicking handler: %q", stack)
+	}
+}
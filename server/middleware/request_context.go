@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestContext seeds the request's context.Context with a RequestContext (request ID,
+// method, path and client IP) so service logs can be correlated with the HTTP request.
+// The request ID is taken from the X-Request-ID header or generated, and echoed back.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqCtx := utils.GetRequestContext(c)
+		c.Request = c.Request.WithContext(utils.ContextWithRequestContext(c.Request.Context(), reqCtx))
+		c.Set("request_id", reqCtx.RequestID)
+
+		c.Next()
+	}
+}
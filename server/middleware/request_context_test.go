@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestContextPropagatesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	previous := utils.Logger.Out
+	utils.Logger.SetOutput(&buf)
+	defer utils.Logger.SetOutput(previous)
+
+	router := gin.New()
+	router.Use(RequestContext())
+	router.GET("/posts", func(c *gin.Context) {
+		utils.LogInfoContext(c.Request.Context(), "listing posts", nil)
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{"client supplied request ID", "client-req-1"},
+		{"generated request ID", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			echoed := w.Header().Get("X-Request-ID")
+			if echoed == "" || (tt.requestID != "" && echoed != tt.requestID) {
+				t.Fatalf("X-Request-ID header = %q, want %q", echoed, tt.requestID)
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("log output %q is not a single JSON entry: %v", buf.String(), err)
+			}
+			if entry["request_id"] != echoed {
+				t.Errorf("logged request_id = %v, want %q", entry["request_id"], echoed)
+			}
+			if entry["method"] != http.MethodGet || entry["path"] != "/posts" {
+				t.Errorf("logged method/path = %v %v, want GET /posts", entry["method"], entry["path"])
+			}
+		})
+	}
+}
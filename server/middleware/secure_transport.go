@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/gin-gonic/gin"
+)
+
+// SecureTransport redirects requests that reached the proxy over plain HTTP to HTTPS and
+// sets HSTS and baseline security headers on every response. The scheme is taken from
+// X-Forwarded-Proto, so requests without it (e.g. health probes) are never redirected.
+func SecureTransport(cfg *config.SecurityConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(c *gin.Context) {
+		proto := strings.ToLower(strings.TrimSpace(strings.Split(c.GetHeader("X-Forwarded-Proto"), ",")[0]))
+
+		if cfg.HTTPSRedirect && proto == "http" {
+			// 308 keeps the method and body for non-GET requests
+			status := http.StatusPermanentRedirect
+			if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+			c.Redirect(status, "https://"+c.Request.Host+c.Request.URL.RequestURI())
+			c.Abort()
+			return
+		}
+
+		// Browsers ignore HSTS received over plain HTTP
+		if hsts != "" && (proto == "https" || c.Request.TLS != nil) {
+			c.Header("Strict-Transport-Security", hsts)
+		}
+		if cfg.ContentTypeOptions != "" {
+			c.Header("X-Content-Type-Options", cfg.ContentTypeOptions)
+		}
+		if cfg.FrameOptions != "" {
+			c.Header("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// StrictJSON enables strict JSON decoding (unknown fields rejected) for every request when
+// enabled is true, or per request when the client sends an "X-Strict: true" header
+func StrictJSON(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		strict := enabled
+		if header := c.GetHeader("X-Strict"); header != "" {
+			if value, err := strconv.ParseBool(header); err == nil && value {
+				strict = true
+			}
+		}
+
+		c.Set(utils.StrictJSONContextKey, strict)
+		c.Next()
+	}
+}
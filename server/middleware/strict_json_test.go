@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStrictJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		header     string
+		body       string
+		wantStatus int
+	}{
+		{"unknown field ignored by default", false, "", `{"content":"hi","contnet":"typo"}`, http.StatusOK},
+		{"unknown field rejected when enabled", true, "", `{"content":"hi","contnet":"typo"}`, http.StatusBadRequest},
+		{"unknown field rejected with X-Strict header", false, "true", `{"content":"hi","contnet":"typo"}`, http.StatusBadRequest},
+		{"invalid X-Strict header is ignored", false, "maybe", `{"content":"hi","contnet":"typo"}`, http.StatusOK},
+		{"known fields accepted when enabled", true, "", `{"content":"hi"}`, http.StatusOK},
+		{"validation still applies when enabled", true, "", `{}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(StrictJSON(tt.enabled))
+			router.POST("/", func(c *gin.Context) {
+				var req struct {
+					Content string `json:"content" binding:"required"`
+				}
+				if err := utils.BindJSON(c, &req); err != nil {
+					c.String(http.StatusBadRequest, err.Error())
+					return
+				}
+				c.String(http.StatusOK, req.Content)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set("X-Strict", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
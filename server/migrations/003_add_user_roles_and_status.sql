@@ -0,0 +1,17 @@
+-- Migration: 003_add_user_roles_and_status.sql
+-- Description: Add role, ban and email verification status to users
+-- Created: 2024
+
+-- Add role field to users table ("user", "moderator" or "admin")
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- Add moderation and verification status fields to users table
+ALTER TABLE users ADD COLUMN banned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Restrict role to known values
+ALTER TABLE users ADD CONSTRAINT chk_users_role CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- Create indexes for admin user filtering
+CREATE INDEX idx_users_role ON users(role);
+CREATE INDEX idx_users_banned ON users(banned);
@@ -0,0 +1,16 @@
+-- Migration: 004_add_username_history.sql
+-- Description: Record previous usernames when a user changes their username
+-- Created: 2024
+
+-- Create username_history table
+CREATE TABLE username_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username TEXT NOT NULL,
+    new_username TEXT NOT NULL,
+    changed_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Username history indexes
+CREATE INDEX idx_username_history_user_id ON username_history(user_id, changed_at DESC);
+CREATE INDEX idx_username_history_old_username ON username_history(LOWER(old_username));
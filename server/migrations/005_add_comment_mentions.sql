@@ -0,0 +1,14 @@
+-- Migration: 005_add_comment_mentions.sql
+-- Description: Track users @mentioned in comments
+-- Created: 2024
+
+-- Create comment_mentions table
+CREATE TABLE comment_mentions (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    mentioned_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (comment_id, mentioned_user_id)
+);
+
+-- Comment mentions indexes
+CREATE INDEX idx_comment_mentions_mentioned_user_id ON comment_mentions(mentioned_user_id, created_at DESC);
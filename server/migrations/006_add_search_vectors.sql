@@ -0,0 +1,17 @@
+-- Migration: 006_add_search_vectors.sql
+-- Description: Add full-text search vectors for posts and comments
+-- Created: 2024
+
+-- Add generated search vector columns (titles rank above body text)
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'B')
+) STORED;
+
+ALTER TABLE comments ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(content, ''))
+) STORED;
+
+-- Search vector indexes
+CREATE INDEX idx_posts_search_vector ON posts USING gin(search_vector);
+CREATE INDEX idx_comments_search_vector ON comments USING gin(search_vector);
@@ -0,0 +1,21 @@
+-- Migration: 007_add_post_tags.sql
+-- Description: Add tags and post-tag links for categorizing posts
+-- Created: 2024
+
+-- Create tags table (names are stored normalized to lowercase)
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Create post_tags join table
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+-- Post tags indexes
+CREATE INDEX idx_post_tags_tag_id ON post_tags(tag_id);
@@ -0,0 +1,15 @@
+-- Migration: 008_add_revoked_tokens.sql
+-- Description: Add a denylist of revoked JWT ids so logout and refresh rotation invalidate tokens
+-- Created: 2024
+
+-- Create revoked tokens table (rows are only needed until the token would have expired anyway)
+CREATE TABLE revoked_tokens (
+    jti UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    token_type VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Revoked tokens indexes
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
@@ -0,0 +1,17 @@
+-- Migration: 009_add_password_reset_tokens.sql
+-- Description: Add single-use password reset tokens for the forgot-password flow
+-- Created: 2024
+
+-- Create password reset tokens table (only a SHA-256 hash of the token is stored)
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Password reset tokens indexes
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+CREATE INDEX idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
@@ -0,0 +1,19 @@
+-- Migration: 010_add_comment_bumped_at.sql
+-- Description: Add bumped_at to comments so activity ordering ignores content edits
+-- Created: 2024
+
+-- Add bumped_at field to comments table (set on creation and on new replies, not on edits)
+ALTER TABLE comments ADD COLUMN bumped_at TIMESTAMP;
+
+-- Backfill from the newest non-deleted comment in each subtree (path includes the comment itself)
+UPDATE comments c
+SET bumped_at = COALESCE(
+    (SELECT MAX(d.created_at) FROM comments d WHERE d.path @> ARRAY[c.id] AND d.deleted_at IS NULL),
+    c.created_at
+);
+
+ALTER TABLE comments ALTER COLUMN bumped_at SET DEFAULT NOW();
+ALTER TABLE comments ALTER COLUMN bumped_at SET NOT NULL;
+
+-- Index for activity-ordered top-level comment listing
+CREATE INDEX idx_comments_post_bumped_at ON comments(post_id, bumped_at DESC) WHERE parent_id IS NULL AND deleted_at IS NULL;
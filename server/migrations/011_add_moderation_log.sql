@@ -0,0 +1,19 @@
+-- Migration: 011_add_moderation_log.sql
+-- Description: Add moderation_log to record moderator actions taken on other users' content
+-- Created: 2024
+
+-- Create moderation log table (one row per moderator action)
+CREATE TABLE moderation_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    moderator_id UUID NOT NULL REFERENCES users(id),
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id UUID NOT NULL,
+    target_author_id UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Moderation log indexes
+CREATE INDEX idx_moderation_log_moderator_id ON moderation_log(moderator_id);
+CREATE INDEX idx_moderation_log_target ON moderation_log(target_type, target_id);
+CREATE INDEX idx_moderation_log_created_at ON moderation_log(created_at);
@@ -0,0 +1,9 @@
+-- Migration: 012_add_password_reset_requested_ip.sql
+-- Description: Record the requesting IP on password reset tokens so reset emails can be throttled per IP
+-- Created: 2024
+
+ALTER TABLE password_reset_tokens ADD COLUMN requested_ip TEXT NOT NULL DEFAULT '';
+
+-- Supports the per-account and per-IP send counts over a recent window
+CREATE INDEX idx_password_reset_tokens_user_created ON password_reset_tokens(user_id, created_at DESC);
+CREATE INDEX idx_password_reset_tokens_ip_created ON password_reset_tokens(requested_ip, created_at DESC);
@@ -0,0 +1,18 @@
+-- Migration: 013_add_idempotency_keys.sql
+-- Description: Add idempotency_keys so retried create requests return the original resource
+-- Created: 2024
+
+-- One row per (user, Idempotency-Key). resource_id and status_code stay NULL while the
+-- original request is still being processed.
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID,
+    status_code INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+-- Idempotency keys indexes
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1,6 @@
+-- Migration: 014_add_comment_content_raw.sql
+-- Description: Keep the content a comment was written with, alongside the sanitized content that is displayed
+-- Created: 2024
+
+-- NULL for comments written before this column existed; their sanitized content is used as the source
+ALTER TABLE comments ADD COLUMN content_raw TEXT;
@@ -0,0 +1,7 @@
+-- Migration: 015_add_post_comment_versions.sql
+-- Description: Add a version counter to posts and comments for optimistic concurrency on updates
+-- Created: 2024
+
+-- Every update increments version; clients send the version they edited to detect lost updates
+ALTER TABLE posts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
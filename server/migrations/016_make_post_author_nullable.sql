@@ -0,0 +1,6 @@
+-- Migration: 016_make_post_author_nullable.sql
+-- Description: Allow posts to outlive their author when a deleted user's content is anonymized
+-- Created: 2024
+
+-- Anonymized posts keep their content with no author; comments.created_by is already nullable
+ALTER TABLE posts ALTER COLUMN created_by DROP NOT NULL;
@@ -0,0 +1,14 @@
+-- Migration: 017_add_schema_migrations.sql
+-- Description: Track applied migration versions so the service can detect an un-migrated database
+-- Created: 2024
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Backfill every migration up to and including this one. From now on each migration
+-- ends with: INSERT INTO schema_migrations (version) VALUES (<n>) ON CONFLICT DO NOTHING;
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 17)
+ON CONFLICT DO NOTHING;
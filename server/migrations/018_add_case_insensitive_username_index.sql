@@ -0,0 +1,12 @@
+-- Migration: 018_add_case_insensitive_username_index.sql
+-- Description: Enforce case-insensitive username uniqueness among active users
+-- Created: 2024
+
+-- Usernames keep their original casing for display; uniqueness and lookups use LOWER(username).
+-- Fails if active users already differ only by case; rename one of them before migrating.
+CREATE UNIQUE INDEX idx_users_username_lower ON users (LOWER(username)) WHERE deleted_at IS NULL;
+
+-- Lookups now go through LOWER(username), so the plain index is no longer used
+DROP INDEX IF EXISTS idx_users_username;
+
+INSERT INTO schema_migrations (version) VALUES (18) ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- Migration: 019_add_post_comments_locked.sql
+-- Description: Let post authors and admins close a post to new comments
+-- Created: 2024
+
+-- Locked posts reject new comments and replies; existing comments stay readable
+ALTER TABLE posts ADD COLUMN comments_locked BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT INTO schema_migrations (version) VALUES (19) ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+-- Migration: 020_add_comment_attachments.sql
+-- Description: Let comments carry image attachments
+-- Created: 2024
+
+-- Each element is {"url", "type", "width", "height"}; URLs are validated and
+-- normalized by the service before they are stored
+ALTER TABLE comments ADD COLUMN attachments JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+INSERT INTO schema_migrations (version) VALUES (20) ON CONFLICT DO NOTHING;
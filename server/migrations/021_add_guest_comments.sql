@@ -0,0 +1,14 @@
+-- Migration: 021_add_guest_comments.sql
+-- Description: Let post authors open a post to comments from visitors who are not signed in
+-- Created: 2024
+
+ALTER TABLE posts ADD COLUMN allow_anonymous_comments BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Guest comments have no author (created_by is NULL) and carry the name the visitor
+-- gave instead. guest_ip is the address they posted from, used to rate-limit guests.
+ALTER TABLE comments ADD COLUMN guest_name TEXT;
+ALTER TABLE comments ADD COLUMN guest_ip TEXT;
+
+CREATE INDEX idx_comments_guest_ip_created_at ON comments(guest_ip, created_at) WHERE guest_ip IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (21) ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Migration: 022_add_post_publish_at.sql
+-- Description: Let authors schedule a post to go public at a later time
+-- Created: 2024
+
+-- NULL publishes the post immediately. A post with a future publish_at is hidden from
+-- everyone but its author until that time passes; nothing needs to run at publish time.
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP;
+
+CREATE INDEX idx_posts_publish_at ON posts(publish_at) WHERE publish_at IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (22) ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- Migration: 023_limit_comment_updated_at_trigger.sql
+-- Description: Only move comments.updated_at when the comment's content changes
+-- Created: 2024
+
+-- The trigger from 002 fired on every UPDATE, so bumps, replies_count maintenance,
+-- reparenting path rewrites and soft deletes all looked like edits. updated_at now only
+-- changes when the content a reader sees does.
+DROP TRIGGER IF EXISTS update_comments_updated_at ON comments;
+
+CREATE TRIGGER update_comments_updated_at
+    BEFORE UPDATE OF content, content_raw, attachments ON comments
+    FOR EACH ROW
+    WHEN (OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.content_raw IS DISTINCT FROM NEW.content_raw
+       OR OLD.attachments IS DISTINCT FROM NEW.attachments)
+    EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO schema_migrations (version) VALUES (23) ON CONFLICT DO NOTHING;
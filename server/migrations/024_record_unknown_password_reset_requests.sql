@@ -0,0 +1,9 @@
+-- Migration: 024_record_unknown_password_reset_requests.sql
+-- Description: Record forgot-password requests for unknown emails so they count against the requesting IP
+-- Created: 2024
+
+-- Such requests are stored as already-used tokens with no account, whose token is never
+-- sent to anyone. They only feed the per-IP send count.
+ALTER TABLE password_reset_tokens ALTER COLUMN user_id DROP NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (24) ON CONFLICT DO NOTHING;
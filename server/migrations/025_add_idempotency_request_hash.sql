@@ -0,0 +1,9 @@
+-- Migration: 025_add_idempotency_request_hash.sql
+-- Description: Fingerprint the request body behind each Idempotency-Key
+-- Created: 2024
+
+-- A key reused with a different body is rejected instead of replaying the first result.
+-- Keys recorded before this column existed keep an empty hash and are not compared.
+ALTER TABLE idempotency_keys ADD COLUMN request_hash TEXT NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version) VALUES (25) ON CONFLICT DO NOTHING;
@@ -14,7 +14,7 @@ type LoginRequest struct {
 
 // RegisterRequest represents the request payload for user registration
 type RegisterRequest struct {
-	Username    string  `json:"username" validate:"required,min=3,max=50"`
+	Username    string  `json:"username" validate:"required,min=3,max=50,username"`
 	Email       *string `json:"email" validate:"omitempty,email"`
 	Password    string  `json:"password" validate:"required,min=6"`
 	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
@@ -54,10 +54,18 @@ type RefreshTokenRequest struct {
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Username string    `json:"username"`
-	Email    *string   `json:"email"`
-	Type     string    `json:"type"` // "access" or "refresh"
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     *string   `json:"email"`
+	Role      string    `json:"role"`
+	Type      string    `json:"type"` // "access" or "refresh"
+	JTI       uuid.UUID `json:"jti"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// LogoutRequest represents the optional request payload for logout
+type LogoutRequest struct {
+	RefreshToken *string `json:"refresh_token"`
 }
 
 // ChangePasswordRequest represents the request payload for changing password
@@ -65,3 +73,34 @@ type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" validate:"required"`
 	NewPassword     string `json:"new_password" validate:"required,min=6"`
 }
+
+// ForgotPasswordRequest represents the request payload for starting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents the request payload for completing a password reset
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// PasswordResetToken represents a stored password reset token; only its hash is persisted.
+// A token with a nil UserID records a request for an unknown email and is never sent.
+type PasswordResetToken struct {
+	ID          uuid.UUID  `db:"id"`
+	UserID      uuid.UUID  `db:"user_id"`
+	TokenHash   string     `db:"token_hash"`
+	RequestedIP string     `db:"requested_ip"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	UsedAt      *time.Time `db:"used_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
+
+// PasswordResetSendHistory is what the reset email throttle looks at: the tokens recently
+// issued to an account, and the requests recently made from an IP
+type PasswordResetSendHistory struct {
+	SentToUser int
+	LastSentAt *time.Time
+	SentFromIP int
+}
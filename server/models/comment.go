@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +13,7 @@ import (
 type Comment struct {
 	ID           uuid.UUID   `json:"id" db:"id"`
 	Content      string      `json:"content" db:"content"`
+	ContentRaw   *string     `json:"-" db:"content_raw"`
 	PostID       uuid.UUID   `json:"post_id" db:"post_id"`
 	ParentID     *uuid.UUID  `json:"parent_id" db:"parent_id"`
 	Path         []uuid.UUID `json:"path" db:"path"`
@@ -18,8 +22,16 @@ type Comment struct {
 	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
 	RepliesCount int         `json:"replies_count" db:"replies_count"`
+	Version      int         `json:"version" db:"version"`
 	DeletedAt    *time.Time  `json:"-" db:"deleted_at"`
 
+	Attachments CommentAttachments `json:"attachments" db:"attachments"`
+
+	// GuestName is the display name given by an anonymous commenter; CreatedBy is nil for
+	// their comments. GuestIP is the address they posted from, kept for rate limiting.
+	GuestName *string `json:"guest_name,omitempty" db:"guest_name"`
+	GuestIP   *string `json:"-" db:"guest_ip"`
+
 	// Associations (loaded separately)
 	Post     *Post     `json:"post,omitempty"`
 	Parent   *Comment  `json:"parent,omitempty"`
@@ -27,16 +39,174 @@ type Comment struct {
 	Children []Comment `json:"children,omitempty"`
 }
 
-// CreateCommentRequest represents the request payload for creating a comment
+// Where parents' replies_count is maintained: by the database triggers from migration
+// 002, or by the comment repository when those triggers are not installed
+const (
+	RepliesCountModeTrigger = "trigger"
+	RepliesCountModeApp     = "app"
+)
+
+// Attachment kinds a comment can carry
+const (
+	CommentAttachmentTypeImage = "image"
+)
+
+// CommentAttachment is an externally hosted file linked from a comment. Width and
+// Height are optional hints for laying out images before they load.
+type CommentAttachment struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"`
+	Width  *int   `json:"width,omitempty"`
+	Height *int   `json:"height,omitempty"`
+}
+
+// CommentAttachments is stored as a JSONB array on the comment row
+type CommentAttachments []CommentAttachment
+
+// Value implements driver.Valuer
+func (a CommentAttachments) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (a *CommentAttachments) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*a = CommentAttachments{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into CommentAttachments", src)
+	}
+
+	var attachments CommentAttachments
+	if err := json.Unmarshal(data, &attachments); err != nil {
+		return err
+	}
+	if attachments == nil {
+		attachments = CommentAttachments{}
+	}
+	*a = attachments
+	return nil
+}
+
+// Formats a comment's source is returned in. Comments are written as HTML that is
+// sanitized for display; the source keeps what the author submitted, unsanitized, so it
+// is returned as plain text to be loaded into an editor and never rendered.
+const (
+	CommentContentFormatText = "text"
+)
+
+// CommentSource is a comment's content as its author submitted it, for loading into an
+// editor. ContentRaw is not sanitized and must be treated as plain text.
+type CommentSource struct {
+	ID            uuid.UUID  `json:"id"`
+	ContentRaw    string     `json:"content_raw"`
+	ContentFormat string     `json:"content_format"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CreatedBy     *uuid.UUID `json:"-"`
+}
+
+// Comment list orderings
+const (
+	CommentSortNewest   = "newest"
+	CommentSortOldest   = "oldest"
+	CommentSortActivity = "activity"
+)
+
+// CreateCommentRequest represents the request payload for creating a comment. The post
+// comes from the URL; a post_id in the body is optional and must match it.
 type CreateCommentRequest struct {
-	Content  *string `json:"content" validate:"omitempty,min=1"`
-	PostID   *string `json:"post_id" validate:"omitempty,uuid"`
-	ParentID *string `json:"parent_id" validate:"omitempty,uuid"`
+	Content     *string                    `json:"content" validate:"omitempty,min=1,max=10000"`
+	PostID      uuid.UUID                  `json:"post_id"`
+	ParentID    *string                    `json:"parent_id" validate:"omitempty,uuid"`
+	Attachments []CommentAttachmentRequest `json:"attachments" validate:"omitempty,max=4,dive"`
+
+	// GuestName is required when commenting without signing in, on posts that allow it
+	GuestName *string `json:"guest_name" validate:"omitempty,min=1,max=50"`
 }
 
-// UpdateCommentRequest represents the request payload for updating a comment
+// CommentAttachmentRequest is a single attachment on a new comment. Type defaults to image.
+type CommentAttachmentRequest struct {
+	URL    string `json:"url" validate:"required,max=2048,url,attachment_url"`
+	Type   string `json:"type" validate:"omitempty,oneof=image"`
+	Width  *int   `json:"width" validate:"omitempty,gte=1,lte=10000"`
+	Height *int   `json:"height" validate:"omitempty,gte=1,lte=10000"`
+}
+
+// UpdateCommentRequest represents the request payload for updating a comment.
+// When ExpectedVersion is set, the update only applies if the comment is still at that version.
 type UpdateCommentRequest struct {
-	Content *string `json:"content" validate:"omitempty,min=1"`
+	Content         *string `json:"content" validate:"omitempty,min=1,max=10000"`
+	ExpectedVersion *int    `json:"expected_version" validate:"omitempty,min=1"`
+
+	// ContentRaw is set by the service to the content as submitted, before sanitizing
+	ContentRaw *string `json:"-"`
+}
+
+// PostCommentsStamp identifies one version of a post and its comments, for the ETag and
+// Last-Modified headers of the comment list endpoints
+type PostCommentsStamp struct {
+	PostID         uuid.UUID
+	PostVersion    int
+	PostUpdatedAt  time.Time
+	CommentsLocked bool
+
+	// CommentCount includes deleted comments; CommentsUpdatedAt is the latest time any
+	// comment was created, edited or deleted, nil when the post has none
+	CommentCount      int
+	CommentsUpdatedAt *time.Time
+
+	// AuthorsUpdatedAt is the latest profile change or deletion of the post's author or
+	// any commenter, whose details are embedded in the responses; nil when there are none
+	AuthorsUpdatedAt *time.Time
+}
+
+// LastModified is the latest of the post's, its comments' and their authors' last change
+func (s *PostCommentsStamp) LastModified() time.Time {
+	lastModified := s.PostUpdatedAt
+	for _, t := range []*time.Time{s.CommentsUpdatedAt, s.AuthorsUpdatedAt} {
+		if t != nil && t.After(lastModified) {
+			lastModified = *t
+		}
+	}
+	return lastModified
+}
+
+// ReparentCommentRequest represents the request payload for moving a comment subtree
+type ReparentCommentRequest struct {
+	NewParentID string `json:"new_parent_id" validate:"required,uuid"`
+}
+
+// ImportCommentsRequest represents the request payload for bulk-importing a thread.
+// Comments reference their parent by the parent's temp_id within the same batch.
+type ImportCommentsRequest struct {
+	Comments []ImportCommentItem `json:"comments" validate:"required,min=1,max=1000,dive"`
+}
+
+// ImportCommentItem is a single comment in an import batch
+type ImportCommentItem struct {
+	TempID       string  `json:"temp_id" validate:"required,max=100"`
+	ParentTempID *string `json:"parent_temp_id" validate:"omitempty,max=100"`
+	Content      string  `json:"content" validate:"required,min=1,max=10000"`
+}
+
+// ImportCommentsResult reports the comments created by an import, keyed by temp_id
+type ImportCommentsResult struct {
+	Created int                  `json:"created"`
+	IDs     map[string]uuid.UUID `json:"ids"`
 }
 
 // GetCommentRequest represents the request payload for getting a comment by ID
@@ -49,13 +219,90 @@ type DeleteCommentRequest struct {
 	ID string `json:"id" validate:"required,uuid" uri:"id"`
 }
 
+// Moderation log values recorded when a moderator removes someone else's comment
+const (
+	ModerationActionDeleteComment = "delete_comment"
+	ModerationTargetComment       = "comment"
+)
+
 // ListCommentsRequest represents the request payload for listing comments
 type ListCommentsRequest struct {
-	PostID string `json:"post_id" validate:"required,uuid" uri:"postId"`
-	Limit  int    `json:"limit" validate:"omitempty,gte=1,lte=100" form:"limit"`
+	PostID string `json:"post_id" validate:"required,uuid" uri:"id"`
+	Sort   string `json:"sort" form:"sort"`
+	Limit  int    `json:"limit" validate:"omitempty,gte=1" form:"limit"`
 	Offset int    `json:"offset" validate:"omitempty,gte=0" form:"offset"`
 }
 
+// BatchGetCommentsRequest represents the request payload for fetching comments by IDs
+type BatchGetCommentsRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=100,dive,uuid"`
+}
+
+// ReconcileRepliesCountResult summarizes a replies count reconciliation run
+type ReconcileRepliesCountResult struct {
+	Scanned   int `json:"scanned"`
+	Corrected int `json:"corrected"`
+}
+
+// PurgeDeletedCommentsResult summarizes a soft-deleted comment purge run
+type PurgeDeletedCommentsResult struct {
+	Purged int       `json:"purged"`
+	Cutoff time.Time `json:"cutoff"`
+}
+
+// ParticipantStats aggregates one author's non-deleted comments on a post.
+// UserID and Username are nil for comments whose author record no longer exists;
+// AuthorDeleted is also true when the author's account has been soft-deleted.
+type ParticipantStats struct {
+	UserID         *uuid.UUID `json:"user_id"`
+	Username       *string    `json:"username"`
+	DisplayName    *string    `json:"display_name"`
+	GuestName      *string    `json:"guest_name,omitempty"`
+	AuthorDeleted  bool       `json:"author_deleted"`
+	CommentCount   int        `json:"comment_count"`
+	ReplyCount     int        `json:"reply_count"`
+	FirstCommentAt time.Time  `json:"first_comment_at"`
+	LastCommentAt  time.Time  `json:"last_comment_at"`
+}
+
+// CommentPostSummary is the part of a post shown alongside one of its comments
+type CommentPostSummary struct {
+	ID        uuid.UUID    `json:"id"`
+	Title     string       `json:"title"`
+	CreatedBy *uuid.UUID   `json:"created_by"`
+	Author    UserResponse `json:"author"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// UserComment is a comment in a user's comment history, with the title of its post for context
+type UserComment struct {
+	Comment   Comment
+	PostTitle string
+}
+
+// UserCommentResponse represents the response payload for a comment in a user's history
+type UserCommentResponse struct {
+	CommentResponse
+	PostTitle string `json:"post_title"`
+}
+
+// ToResponse converts UserComment to UserCommentResponse
+func (uc *UserComment) ToResponse() UserCommentResponse {
+	return UserCommentResponse{
+		CommentResponse: uc.Comment.ToResponse(),
+		PostTitle:       uc.PostTitle,
+	}
+}
+
+// CommentPermalinkResponse is everything needed to render a single comment's page: the
+// comment with its author, a summary of its post, and its ancestors from the top-level
+// comment down to its direct parent
+type CommentPermalinkResponse struct {
+	Comment   CommentResponse    `json:"comment"`
+	Post      CommentPostSummary `json:"post"`
+	Ancestors []CommentResponse  `json:"ancestors"`
+}
+
 // CommentResponse represents the response payload for comment data
 type CommentResponse struct {
 	ID           uuid.UUID         `json:"id"`
@@ -70,6 +317,16 @@ type CommentResponse struct {
 	CreatedAt    time.Time         `json:"created_at"`
 	UpdatedAt    time.Time         `json:"updated_at"`
 	RepliesCount int               `json:"replies_count"`
+	Version      int               `json:"version"`
+	Depth        int               `json:"depth"`
+
+	Attachments CommentAttachments `json:"attachments"`
+	GuestName   *string            `json:"guest_name,omitempty"`
+}
+
+// Depth is how deeply the comment is nested: 1 for a top-level comment, 2 for a reply to it, and so on
+func (c *Comment) Depth() int {
+	return len(c.Path)
 }
 
 // ToResponse converts Comment model to CommentResponse
@@ -85,6 +342,11 @@ func (c *Comment) ToResponse() CommentResponse {
 		children[i] = child.ToResponse()
 	}
 
+	attachments := c.Attachments
+	if attachments == nil {
+		attachments = CommentAttachments{}
+	}
+
 	return CommentResponse{
 		ID:           c.ID,
 		Content:      c.Content,
@@ -98,5 +360,39 @@ func (c *Comment) ToResponse() CommentResponse {
 		CreatedAt:    c.CreatedAt,
 		UpdatedAt:    c.UpdatedAt,
 		RepliesCount: c.RepliesCount,
+		Version:      c.Version,
+		Depth:        c.Depth(),
+		Attachments:  attachments,
+		GuestName:    c.GuestName,
+	}
+}
+
+// CommentThread is a top-level comment with a preview of its most recent reply and
+// the number of non-deleted replies anywhere below it
+type CommentThread struct {
+	Root         Comment
+	LatestReply  *Comment
+	TotalReplies int
+}
+
+// CommentThreadResponse represents a thread in the thread index response
+type CommentThreadResponse struct {
+	Root         CommentResponse  `json:"root"`
+	LatestReply  *CommentResponse `json:"latest_reply"`
+	TotalReplies int              `json:"total_replies"`
+}
+
+// ToResponse converts CommentThread to CommentThreadResponse
+func (t *CommentThread) ToResponse() CommentThreadResponse {
+	var latestReply *CommentResponse
+	if t.LatestReply != nil {
+		reply := t.LatestReply.ToResponse()
+		latestReply = &reply
+	}
+
+	return CommentThreadResponse{
+		Root:         t.Root.ToResponse(),
+		LatestReply:  latestReply,
+		TotalReplies: t.TotalReplies,
 	}
 }
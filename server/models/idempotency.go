@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resource types an Idempotency-Key can be recorded against
+const (
+	IdempotencyResourcePost    = "post"
+	IdempotencyResourceComment = "comment"
+)
+
+// IdempotencyKey records the outcome of a create request sent with an Idempotency-Key header.
+// ResourceID and StatusCode are unset while the original request is still in flight.
+// RequestHash fingerprints the original request body; it is empty for keys recorded
+// before fingerprints were stored.
+type IdempotencyKey struct {
+	UserID       uuid.UUID  `db:"user_id"`
+	Key          string     `db:"idempotency_key"`
+	ResourceType string     `db:"resource_type"`
+	RequestHash  string     `db:"request_hash"`
+	ResourceID   *uuid.UUID `db:"resource_id"`
+	StatusCode   *int       `db:"status_code"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// IsPending reports whether the original request has not finished yet
+func (k *IdempotencyKey) IsPending() bool {
+	return k.ResourceID == nil
+}
@@ -0,0 +1,49 @@
+package models
+
+// LengthConstraint describes the accepted length of a text field or size of a list.
+// A nil bound means the server does not enforce one.
+type LengthConstraint struct {
+	Required bool `json:"required"`
+	Min      *int `json:"min"`
+	Max      *int `json:"max"`
+}
+
+// UserConstraints describes the limits on user profile fields
+type UserConstraints struct {
+	Username    LengthConstraint `json:"username"`
+	DisplayName LengthConstraint `json:"display_name"`
+	Password    LengthConstraint `json:"password"`
+}
+
+// PostConstraints describes the limits on post fields
+type PostConstraints struct {
+	Title     LengthConstraint `json:"title"`
+	Content   LengthConstraint `json:"content"`
+	Tags      LengthConstraint `json:"tags"`
+	TagLength LengthConstraint `json:"tag_length"`
+}
+
+// CommentConstraints describes the limits on comments and comment trees
+type CommentConstraints struct {
+	Content          LengthConstraint `json:"content"`
+	ReplyContent     LengthConstraint `json:"reply_content"`
+	MaxReplyDepth    int              `json:"max_reply_depth"`
+	DefaultTreeDepth int              `json:"default_tree_depth"`
+	MaxTreeDepth     int              `json:"max_tree_depth"`
+	MaxTreeSize      int              `json:"max_tree_size"`
+}
+
+// PaginationConstraints describes the limits on list query parameters
+type PaginationConstraints struct {
+	MaxLimit  int `json:"max_limit"`
+	MaxOffset int `json:"max_offset"`
+}
+
+// ValidationConstraints is the set of input limits the server enforces, published so
+// clients can validate with the same rules
+type ValidationConstraints struct {
+	User       UserConstraints       `json:"user"`
+	Post       PostConstraints       `json:"post"`
+	Comment    CommentConstraints    `json:"comment"`
+	Pagination PaginationConstraints `json:"pagination"`
+}
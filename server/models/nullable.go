@@ -0,0 +1,42 @@
+package models
+
+import "encoding/json"
+
+// NullableString is a field of a partial update request that tells a key left out of the
+// JSON body (Set is false, leave the column alone) apart from one sent as null (Set is
+// true and Valid is false, clear the column)
+type NullableString struct {
+	Value string
+	Valid bool
+	Set   bool
+}
+
+// NewNullableString returns a NullableString that sets the field to value
+func NewNullableString(value string) NullableString {
+	return NullableString{Value: value, Valid: true, Set: true}
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called for keys present in the
+// body, which is what marks the field as set.
+func (n *NullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = ""
+		n.Valid = false
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &n.Value); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Ptr returns the value to store, or nil when the field was sent as null
+func (n NullableString) Ptr() *string {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Value
+}
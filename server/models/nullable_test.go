@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNullableStringUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    NullableString
+		wantErr bool
+	}{
+		{"absent", `{}`, NullableString{}, false},
+		{"null", `{"display_name":null}`, NullableString{Set: true}, false},
+		{"value", `{"display_name":"Alice"}`, NullableString{Value: "Alice", Valid: true, Set: true}, false},
+		{"empty string", `{"display_name":""}`, NullableString{Valid: true, Set: true}, false},
+		{"not a string", `{"display_name":42}`, NullableString{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdateUserRequest
+			err := json.Unmarshal([]byte(tt.body), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if req.DisplayName != tt.want {
+				t.Errorf("got %+v, want %+v", req.DisplayName, tt.want)
+			}
+			if (req.DisplayName.Ptr() == nil) != !tt.want.Valid {
+				t.Errorf("Ptr() = %v, want nil only when the value is not valid", req.DisplayName.Ptr())
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+// CommentPermissions describes what the current user may do with a comment
+type CommentPermissions struct {
+	CanEdit     bool `json:"can_edit"`
+	CanDelete   bool `json:"can_delete"`
+	CanModerate bool `json:"can_moderate"`
+	CanReply    bool `json:"can_reply"`
+}
+
+// PostPermissions describes what the current user may do with a post
+type PostPermissions struct {
+	CanEdit     bool `json:"can_edit"`
+	CanDelete   bool `json:"can_delete"`
+	CanModerate bool `json:"can_moderate"`
+	CanComment  bool `json:"can_comment"`
+}
@@ -11,10 +11,29 @@ type Post struct {
 	ID        uuid.UUID  `json:"id" db:"id"`
 	Title     string     `json:"title" db:"title"`
 	Content   string     `json:"content" db:"content"`
-	CreatedBy uuid.UUID  `json:"created_by" db:"created_by"`
+	CreatedBy *uuid.UUID `json:"created_by" db:"created_by"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	Version   int        `json:"version" db:"version"`
 	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+	Tags      []string   `json:"tags" db:"-"`
+
+	// CommentsLocked closes the post to new comments and replies
+	CommentsLocked bool `json:"comments_locked" db:"comments_locked"`
+
+	// AllowAnonymousComments lets visitors who are not signed in comment under a guest name
+	AllowAnonymousComments bool `json:"allow_anonymous_comments" db:"allow_anonymous_comments"`
+
+	// PublishAt, when set, is when the post goes public. Until then only its author sees it.
+	PublishAt *time.Time `json:"publish_at" db:"publish_at"`
+
+	// CommentCount is the number of non-deleted comments, replies included. It is only
+	// loaded by the list queries and is omitted elsewhere.
+	CommentCount *int `json:"comment_count,omitempty" db:"-"`
+
+	// RecentCommentCount is the number of comments made within the trending window. It
+	// is only loaded by the trending query and is omitted elsewhere.
+	RecentCommentCount *int `json:"recent_comment_count,omitempty" db:"-"`
 
 	// Associations (loaded separately)
 	Author   *User     `json:"author,omitempty"`
@@ -23,14 +42,26 @@ type Post struct {
 
 // CreatePostRequest represents the request payload for creating a post
 type CreatePostRequest struct {
-	Title   string `json:"title" validate:"required,min=1,max=200"`
-	Content string `json:"content" validate:"required,min=1"`
+	Title   string   `json:"title" validate:"required,min=1,max=200"`
+	Content string   `json:"content" validate:"required,min=1"`
+	Tags    []string `json:"tags" validate:"omitempty,max=10,dive,min=1,max=30"`
+
+	AllowAnonymousComments bool `json:"allow_anonymous_comments"`
+
+	// PublishAt schedules the post to go public at a future time; nil publishes it now
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 // UpdatePostRequest represents the request payload for updating a post
+// A nil Tags leaves the post's tags unchanged; an empty list removes them all.
+// When ExpectedVersion is set, the update only applies if the post is still at that version.
 type UpdatePostRequest struct {
-	Title   *string `json:"title" validate:"omitempty,min=1,max=200"`
-	Content *string `json:"content" validate:"omitempty,min=1"`
+	Title           *string   `json:"title" validate:"omitempty,min=1,max=200"`
+	Content         *string   `json:"content" validate:"omitempty,min=1"`
+	Tags            *[]string `json:"tags" validate:"omitempty,max=10,dive,min=1,max=30"`
+	ExpectedVersion *int      `json:"expected_version" validate:"omitempty,min=1"`
+
+	AllowAnonymousComments *bool `json:"allow_anonymous_comments"`
 }
 
 // GetPostRequest represents the request payload for getting a post by ID
@@ -56,15 +87,32 @@ type ListPostsByUserRequest struct {
 	Offset int    `json:"offset" validate:"omitempty,gte=0" form:"offset"`
 }
 
+// PostDeleteImpact reports how much discussion deleting a post would hide
+type PostDeleteImpact struct {
+	PostID          uuid.UUID `json:"post_id"`
+	Comments        int       `json:"comments"`
+	TopLevel        int       `json:"top_level_comments"`
+	Replies         int       `json:"replies"`
+	AffectedAuthors int       `json:"affected_authors"`
+}
+
 // PostResponse represents the response payload for post data
 type PostResponse struct {
 	ID        uuid.UUID    `json:"id"`
 	Title     string       `json:"title"`
 	Content   string       `json:"content"`
-	CreatedBy uuid.UUID    `json:"created_by"`
+	CreatedBy *uuid.UUID   `json:"created_by"`
 	Author    UserResponse `json:"author"`
+	Tags      []string     `json:"tags"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
+	Version   int          `json:"version"`
+
+	CommentsLocked         bool       `json:"comments_locked"`
+	AllowAnonymousComments bool       `json:"allow_anonymous_comments"`
+	PublishAt              *time.Time `json:"publish_at"`
+	CommentCount           *int       `json:"comment_count,omitempty"`
+	RecentCommentCount     *int       `json:"recent_comment_count,omitempty"`
 }
 
 // PostWithCommentsResponse represents the response payload for post data with comments
@@ -72,11 +120,17 @@ type PostWithCommentsResponse struct {
 	ID        uuid.UUID         `json:"id"`
 	Title     string            `json:"title"`
 	Content   string            `json:"content"`
-	CreatedBy uuid.UUID         `json:"created_by"`
+	CreatedBy *uuid.UUID        `json:"created_by"`
 	Author    UserResponse      `json:"author"`
+	Tags      []string          `json:"tags"`
 	Comments  []CommentResponse `json:"comments"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
+	Version   int               `json:"version"`
+
+	CommentsLocked         bool       `json:"comments_locked"`
+	AllowAnonymousComments bool       `json:"allow_anonymous_comments"`
+	PublishAt              *time.Time `json:"publish_at"`
 }
 
 // ToResponse converts Post model to PostResponse
@@ -92,8 +146,16 @@ func (p *Post) ToResponse() PostResponse {
 		Content:   p.Content,
 		CreatedBy: p.CreatedBy,
 		Author:    author,
+		Tags:      p.tagsOrEmpty(),
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
+		Version:   p.Version,
+
+		CommentsLocked:         p.CommentsLocked,
+		AllowAnonymousComments: p.AllowAnonymousComments,
+		PublishAt:              p.PublishAt,
+		CommentCount:           p.CommentCount,
+		RecentCommentCount:     p.RecentCommentCount,
 	}
 }
 
@@ -115,8 +177,27 @@ func (p *Post) ToResponseWithComments() PostWithCommentsResponse {
 		Content:   p.Content,
 		CreatedBy: p.CreatedBy,
 		Author:    author,
+		Tags:      p.tagsOrEmpty(),
 		Comments:  comments,
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
+		Version:   p.Version,
+
+		CommentsLocked:         p.CommentsLocked,
+		AllowAnonymousComments: p.AllowAnonymousComments,
+		PublishAt:              p.PublishAt,
+	}
+}
+
+// IsScheduled reports whether the post is set to go public after now
+func (p *Post) IsScheduled(now time.Time) bool {
+	return p.PublishAt != nil && p.PublishAt.After(now)
+}
+
+// tagsOrEmpty returns the post's tags, never nil, so responses always carry a list
+func (p *Post) tagsOrEmpty() []string {
+	if p.Tags == nil {
+		return []string{}
 	}
+	return p.Tags
 }
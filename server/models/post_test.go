@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPostResponsesCarryVersion(t *testing.T) {
+	post := &Post{ID: uuid.New(), Title: "Title", Version: 4}
+
+	if got := post.ToResponse().Version; got != 4 {
+		t.Errorf("ToResponse version = %d, want 4", got)
+	}
+	if got := post.ToResponseWithComments().Version; got != 4 {
+		t.Errorf("ToResponseWithComments version = %d, want 4", got)
+	}
+}
+
+func TestPostResponsesCarryPublishAt(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	post := &Post{ID: uuid.New(), Title: "Title", PublishAt: &publishAt}
+
+	if got := post.ToResponse().PublishAt; got == nil || !got.Equal(publishAt) {
+		t.Errorf("ToResponse publish_at = %v, want %v", got, publishAt)
+	}
+	if got := post.ToResponseWithComments().PublishAt; got == nil || !got.Equal(publishAt) {
+		t.Errorf("ToResponseWithComments publish_at = %v, want %v", got, publishAt)
+	}
+}
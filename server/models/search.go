@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Comment statuses reported by the admin comment search
+const (
+	CommentStatusActive  = "active"
+	CommentStatusDeleted = "deleted"
+)
+
+// Search types supported by the search endpoint
+const (
+	SearchTypePosts    = "posts"
+	SearchTypeComments = "comments"
+)
+
+// PostSearchResult represents a post matched by a search query
+type PostSearchResult struct {
+	Post    Post
+	Rank    float64
+	Snippet string
+}
+
+// CommentSearchResult represents a comment matched by a search query
+type CommentSearchResult struct {
+	Comment Comment
+	Rank    float64
+	Snippet string
+}
+
+// PostSearchResultResponse represents the response payload for a post search hit
+type PostSearchResultResponse struct {
+	PostResponse
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// CommentSearchResultResponse represents the response payload for a comment search hit
+type CommentSearchResultResponse struct {
+	CommentResponse
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// ToResponse converts PostSearchResult to PostSearchResultResponse
+func (r *PostSearchResult) ToResponse() PostSearchResultResponse {
+	return PostSearchResultResponse{
+		PostResponse: r.Post.ToResponse(),
+		Rank:         r.Rank,
+		Snippet:      r.Snippet,
+	}
+}
+
+// ToResponse converts CommentSearchResult to CommentSearchResultResponse
+func (r *CommentSearchResult) ToResponse() CommentSearchResultResponse {
+	return CommentSearchResultResponse{
+		CommentResponse: r.Comment.ToResponse(),
+		Rank:            r.Rank,
+		Snippet:         r.Snippet,
+	}
+}
+
+// AdminCommentSearchResult represents a comment (including soft-deleted ones) matched by
+// an admin search, with the title of the post it belongs to
+type AdminCommentSearchResult struct {
+	Comment   Comment
+	PostTitle string
+	Rank      float64
+	Snippet   string
+}
+
+// AdminCommentSearchResultResponse represents the response payload for an admin comment search hit
+type AdminCommentSearchResultResponse struct {
+	CommentResponse
+	PostTitle string     `json:"post_title"`
+	Status    string     `json:"status"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Rank      float64    `json:"rank"`
+	Snippet   string     `json:"snippet"`
+}
+
+// ToResponse converts AdminCommentSearchResult to AdminCommentSearchResultResponse
+func (r *AdminCommentSearchResult) ToResponse() AdminCommentSearchResultResponse {
+	status := CommentStatusActive
+	if r.Comment.DeletedAt != nil {
+		status = CommentStatusDeleted
+	}
+
+	return AdminCommentSearchResultResponse{
+		CommentResponse: r.Comment.ToResponse(),
+		PostTitle:       r.PostTitle,
+		Status:          status,
+		DeletedAt:       r.Comment.DeletedAt,
+		Rank:            r.Rank,
+		Snippet:         r.Snippet,
+	}
+}
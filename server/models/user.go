@@ -6,33 +6,78 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// IsModeratorRole reports whether the role grants moderation privileges
+func IsModeratorRole(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
+
 // User represents a user in the system
 type User struct {
-	ID           uuid.UUID  `json:"id" db:"id"`
-	Username     string     `json:"username" db:"username"`
-	Email        *string    `json:"email" db:"email"`
-	PasswordHash *string    `json:"-" db:"password_hash"`
-	DisplayName  *string    `json:"display_name" db:"display_name"`
-	AvatarURL    *string    `json:"avatar_url" db:"avatar_url"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt    *time.Time `json:"-" db:"deleted_at"`
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Username      string     `json:"username" db:"username"`
+	Email         *string    `json:"email" db:"email"`
+	PasswordHash  *string    `json:"-" db:"password_hash"`
+	DisplayName   *string    `json:"display_name" db:"display_name"`
+	AvatarURL     *string    `json:"avatar_url" db:"avatar_url"`
+	Role          string     `json:"-" db:"role"`
+	Banned        bool       `json:"-" db:"banned"`
+	EmailVerified bool       `json:"-" db:"email_verified"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt     *time.Time `json:"-" db:"deleted_at"`
+}
+
+// DeletedUsername is shown in place of the author of content whose account was deleted
+const DeletedUsername = "[deleted user]"
+
+// DeletedUserPlaceholder returns the author shown for content by a deleted account.
+// Only the id is kept (uuid.Nil once the content has been anonymized); the deleted
+// user's profile is not exposed.
+func DeletedUserPlaceholder(id *uuid.UUID) *User {
+	placeholder := &User{Username: DeletedUsername}
+	if id != nil {
+		placeholder.ID = *id
+	}
+	return placeholder
+}
+
+// User deletion policies for the content a deleted account leaves behind
+const (
+	UserDeletePolicyAnonymize     = "anonymize"
+	UserDeletePolicyCascadeDelete = "cascade_delete"
+)
+
+// UserDeletionResult reports what a user deletion did to the account's content
+type UserDeletionResult struct {
+	UserID   uuid.UUID
+	Policy   string
+	Posts    int64
+	Comments int64
 }
 
 // CreateUserRequest represents the request payload for creating a user
 type CreateUserRequest struct {
-	Username    string  `json:"username" validate:"required,min=3,max=50"`
+	Username    string  `json:"username" validate:"required,min=3,max=50,username"`
 	Email       *string `json:"email" validate:"omitempty,email"`
 	Password    string  `json:"password" validate:"required,min=6"`
 	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
 	AvatarURL   *string `json:"avatar_url" validate:"omitempty,url"`
 }
 
-// UpdateUserRequest represents the request payload for updating a user
+// UpdateUserRequest represents the request payload for updating a user. Fields left out
+// of the body are unchanged; display_name and avatar_url can be cleared by sending null.
 type UpdateUserRequest struct {
-	Email       *string `json:"email" validate:"omitempty,email"`
-	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
-	AvatarURL   *string `json:"avatar_url" validate:"omitempty,url"`
+	Username    *string        `json:"username" validate:"omitempty,min=3,max=50,username"`
+	Email       *string        `json:"email" validate:"omitempty,email"`
+	DisplayName NullableString `json:"display_name" validate:"omitempty,max=100"`
+	AvatarURL   NullableString `json:"avatar_url" validate:"omitempty,url"`
 }
 
 // GetUserRequest represents the request payload for getting a user by ID
@@ -56,6 +101,26 @@ type ListUsersRequest struct {
 	Offset int `json:"offset" validate:"omitempty,gte=0" form:"offset"`
 }
 
+// AdminListUsersRequest represents the query parameters for the admin user listing
+type AdminListUsersRequest struct {
+	Role     string `form:"role"`
+	Banned   *bool  `form:"banned"`
+	Verified *bool  `form:"verified"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Limit    int    `form:"limit"`
+	Offset   int    `form:"offset"`
+}
+
+// AdminUserFilter holds the optional filters for the admin user listing
+type AdminUserFilter struct {
+	Role          *string
+	Banned        *bool
+	EmailVerified *bool
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+}
+
 // UserResponse represents the response payload for user data
 type UserResponse struct {
 	ID          uuid.UUID `json:"id"`
@@ -79,3 +144,36 @@ func (u *User) ToResponse() UserResponse {
 		UpdatedAt:   u.UpdatedAt,
 	}
 }
+
+// ProfileResponse represents the authenticated user's own profile, which includes
+// their role; UserResponse omits it because it is shown to other users
+type ProfileResponse struct {
+	UserResponse
+	Role string `json:"role"`
+}
+
+// ToProfileResponse converts User model to ProfileResponse
+func (u *User) ToProfileResponse() ProfileResponse {
+	return ProfileResponse{
+		UserResponse: u.ToResponse(),
+		Role:         u.Role,
+	}
+}
+
+// AdminUserResponse represents the response payload for user data in admin context
+type AdminUserResponse struct {
+	UserResponse
+	Role          string `json:"role"`
+	Banned        bool   `json:"banned"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// ToAdminResponse converts User model to AdminUserResponse
+func (u *User) ToAdminResponse() AdminUserResponse {
+	return AdminUserResponse{
+		UserResponse:  u.ToResponse(),
+		Role:          u.Role,
+		Banned:        u.Banned,
+		EmailVerified: u.EmailVerified,
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -15,23 +16,78 @@ import (
 // CommentRepository interface defines comment data access methods
 type CommentRepository interface {
 	Create(comment *models.Comment) error
+	CreateGuestIfAllowed(comment *models.Comment, since time.Time, allow func(count int) bool) (bool, error)
+	CreateBatch(comments []models.Comment) error
 	GetByID(id uuid.UUID) (*models.Comment, error)
 	GetByIDWithAuthor(id uuid.UUID) (*models.Comment, error)
+	GetSource(id uuid.UUID) (*models.CommentSource, error)
 	Update(id uuid.UUID, updates *models.UpdateCommentRequest) (*models.Comment, error)
 	Delete(id uuid.UUID) error
-	ListByPost(postID uuid.UUID, limit, offset int) ([]models.Comment, error)
-	GetReplies(parentID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	DeleteAsModerator(id, moderatorID uuid.UUID, authorID *uuid.UUID) error
+	Restore(id uuid.UUID) error
+	ListByPost(ctx context.Context, postID uuid.UUID, sort string, limit, offset int) ([]models.Comment, error)
+	ListThreads(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.CommentThread, error)
+	GetReplies(ctx context.Context, parentID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	GetByIDs(ids []uuid.UUID) ([]models.Comment, error)
+	GetLatestByUserAndPost(userID, postID uuid.UUID) (*models.Comment, error)
+	GetLatestGuestByIPAndPost(ip string, postID uuid.UUID) (*models.Comment, error)
+	CountByPost(ctx context.Context, postID uuid.UUID) (int, error)
+	CountReplies(ctx context.Context, parentID uuid.UUID) (int, error)
+	GetDescendants(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	CountDescendants(ctx context.Context, commentID uuid.UUID) (int, error)
+	ListIDsAfter(afterID uuid.UUID, limit int) ([]uuid.UUID, error)
+	RecomputeRepliesCount(commentID uuid.UUID) (bool, error)
+	RecomputeRepliesCounts(commentIDs []uuid.UUID) (int, error)
+	GetFullTree(ctx context.Context, postID uuid.UUID, maxDepth, limit int) ([]models.Comment, bool, error)
+	PurgeDeletedBefore(cutoff time.Time, limit int) (int, error)
+	AddMentions(commentID uuid.UUID, userIDs []uuid.UUID) error
+	ListMentioningUser(userID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.UserComment, error)
+	CountByUser(ctx context.Context, userID uuid.UUID) (int, error)
 	IncrementRepliesCount(commentID uuid.UUID) error
+	DecrementRepliesCount(commentID uuid.UUID) error
+	SearchGlobal(query string, limit, offset int) ([]models.AdminCommentSearchResult, int, error)
+	ParticipantStats(postID uuid.UUID) ([]models.ParticipantStats, error)
+	Bump(ids []uuid.UUID, at time.Time) error
+	Reparent(commentID, newParentID uuid.UUID, maxDepth int) error
+	WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error
+}
+
+// commentListOrderBy maps comment list orderings to their ORDER BY clauses
+var commentListOrderBy = map[string]string{
+	models.CommentSortNewest:   "c.created_at DESC",
+	models.CommentSortOldest:   "c.created_at ASC",
+	models.CommentSortActivity: "c.bumped_at DESC, c.created_at DESC",
 }
 
 // commentRepository implements CommentRepository interface
 type commentRepository struct {
 	db *sql.DB
+
+	// countReplies is set when parents' replies_count is maintained here instead of by
+	// the triggers from migration 002
+	countReplies bool
+}
+
+// NewCommentRepository creates a new comment repository instance. repliesCountMode is
+// models.RepliesCountModeApp when the replies_count triggers are not installed, so
+// creating and deleting replies updates the parent's count in the same transaction.
+func NewCommentRepository(db *sql.DB, repliesCountMode string) CommentRepository {
+	return &commentRepository{
+		db:           db,
+		countReplies: repliesCountMode == models.RepliesCountModeApp,
+	}
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
-// NewCommentRepository creates a new comment repository instance
-func NewCommentRepository(db *sql.DB) CommentRepository {
-	return &commentRepository{db: db}
+// WithTx runs fn inside a transaction that is rolled back if fn returns an error.
+// opts selects the isolation level; nil uses the database default.
+func (r *commentRepository) WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return withTx(r.db, opts, fn)
 }
 
 // convertUUIDSliceToStringArray converts []uuid.UUID to pq.StringArray
@@ -54,15 +110,86 @@ func convertStringArrayToUUIDSlice(strings pq.StringArray) []uuid.UUID {
 	return uuids
 }
 
-// Create creates a new comment in the database
+// Create creates a new comment in the database. When replies are counted in code, a
+// reply and the increment of its parent's replies_count are written in one serializable
+// transaction.
 func (r *commentRepository) Create(comment *models.Comment) error {
+	defer utils.ObserveDBQuery("comment.create", time.Now())
+
+	if !r.countReplies || comment.ParentID == nil {
+		return insertComment(r.db, comment)
+	}
+
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		if err := insertComment(tx, comment); err != nil {
+			return err
+		}
+		return incrementRepliesCount(tx, *comment.ParentID)
+	})
+}
+
+// CreateGuestIfAllowed stores a guest comment if allow approves the number of guest
+// comments posted from its IP address since the given time, deleted ones included, and
+// reports whether it did. Comments from the same IP are serialized with an advisory lock,
+// so concurrent requests cannot all pass the check before any of them is stored.
+func (r *commentRepository) CreateGuestIfAllowed(comment *models.Comment, since time.Time, allow func(count int) bool) (bool, error) {
+	defer utils.ObserveDBQuery("comment.create_guest", time.Now())
+
+	var opts *sql.TxOptions
+	countReplies := r.countReplies && comment.ParentID != nil
+	if countReplies {
+		opts = serializableTx
+	}
+
+	created := false
+	err := r.WithTx(opts, func(tx *sql.Tx) error {
+		created = false
+
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('guest_comment:ip:' || $1::text))`, comment.GuestIP); err != nil {
+			return utils.WrapError(err, "failed to lock guest comment IP")
+		}
+
+		query := `
+			SELECT COUNT(*)
+			FROM comments
+			WHERE guest_ip = $1 AND created_at >= $2`
+		var count int
+		if err := tx.QueryRow(query, comment.GuestIP, since).Scan(&count); err != nil {
+			return utils.WrapError(err, "failed to count guest comments by IP")
+		}
+
+		if !allow(count) {
+			return nil
+		}
+
+		if err := insertComment(tx, comment); err != nil {
+			return err
+		}
+		if countReplies {
+			if err := incrementRepliesCount(tx, *comment.ParentID); err != nil {
+				return err
+			}
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// insertComment inserts a single comment row
+func insertComment(db execer, comment *models.Comment) error {
 	query := `
-		INSERT INTO comments (id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		INSERT INTO comments (id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count, bumped_at, content_raw, attachments, guest_name, guest_ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $8, $11, $12, $13, $14)`
 
 	pathArray := convertUUIDSliceToStringArray(comment.Path)
 
-	_, err := r.db.Exec(query,
+	_, err := db.Exec(query,
 		comment.ID,
 		comment.Content,
 		comment.PostID,
@@ -73,6 +200,10 @@ func (r *commentRepository) Create(comment *models.Comment) error {
 		comment.CreatedAt,
 		comment.UpdatedAt,
 		comment.RepliesCount,
+		comment.ContentRaw,
+		comment.Attachments,
+		comment.GuestName,
+		comment.GuestIP,
 	)
 
 	if err != nil {
@@ -82,10 +213,51 @@ func (r *commentRepository) Create(comment *models.Comment) error {
 	return nil
 }
 
+// CreateBatch inserts comments in a single serializable transaction, in the order given.
+// Parents must come before their replies so the path trigger and replies_count updates see them.
+func (r *commentRepository) CreateBatch(comments []models.Comment) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO comments (id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count, bumped_at, content_raw)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $8, $11)`)
+		if err != nil {
+			return utils.WrapError(err, "failed to prepare comment insert")
+		}
+		defer stmt.Close()
+
+		for _, comment := range comments {
+			_, err := stmt.Exec(
+				comment.ID,
+				comment.Content,
+				comment.PostID,
+				comment.ParentID,
+				convertUUIDSliceToStringArray(comment.Path),
+				comment.ThreadID,
+				comment.CreatedBy,
+				comment.CreatedAt,
+				comment.UpdatedAt,
+				comment.RepliesCount,
+				comment.ContentRaw,
+			)
+			if err != nil {
+				return utils.WrapError(err, "failed to create comment")
+			}
+
+			if r.countReplies && comment.ParentID != nil {
+				if err := incrementRepliesCount(tx, *comment.ParentID); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
 // GetByID retrieves a comment by ID
 func (r *commentRepository) GetByID(id uuid.UUID) (*models.Comment, error) {
 	query := `
-		SELECT id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count
+		SELECT id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count, version, attachments, guest_name
 		FROM comments 
 		WHERE id = $1 AND deleted_at IS NULL`
 
@@ -103,6 +275,9 @@ func (r *commentRepository) GetByID(id uuid.UUID) (*models.Comment, error) {
 		&comment.CreatedAt,
 		&comment.UpdatedAt,
 		&comment.RepliesCount,
+		&comment.Version,
+		&comment.Attachments,
+		&comment.GuestName,
 	)
 
 	if err != nil {
@@ -116,39 +291,44 @@ func (r *commentRepository) GetByID(id uuid.UUID) (*models.Comment, error) {
 	return &comment, nil
 }
 
-// GetByIDWithAuthor retrieves a comment by ID with author information
-func (r *commentRepository) GetByIDWithAuthor(id uuid.UUID) (*models.Comment, error) {
+// GetSource retrieves the content a comment was written with. Comments stored before
+// the raw content was kept fall back to their sanitized content.
+func (r *commentRepository) GetSource(id uuid.UUID) (*models.CommentSource, error) {
 	query := `
-		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count,
-		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
-		FROM comments c
-		JOIN users u ON c.created_by = u.id
-		WHERE c.id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL`
-
-	var comment models.Comment
-	var author models.User
-	var pathArray pq.StringArray
+		SELECT id, COALESCE(content_raw, content), created_by, updated_at
+		FROM comments
+		WHERE id = $1 AND deleted_at IS NULL`
 
+	source := models.CommentSource{ContentFormat: models.CommentContentFormatText}
 	err := r.db.QueryRow(query, id).Scan(
-		&comment.ID,
-		&comment.Content,
-		&comment.PostID,
-		&comment.ParentID,
-		&pathArray,
-		&comment.ThreadID,
-		&comment.CreatedBy,
-		&comment.CreatedAt,
-		&comment.UpdatedAt,
-		&comment.RepliesCount,
-		&author.ID,
-		&author.Username,
-		&author.Email,
-		&author.DisplayName,
-		&author.AvatarURL,
-		&author.CreatedAt,
-		&author.UpdatedAt,
+		&source.ID,
+		&source.ContentRaw,
+		&source.CreatedBy,
+		&source.UpdatedAt,
 	)
 
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrCommentNotFound
+		}
+		return nil, utils.WrapError(err, "failed to get comment source")
+	}
+
+	return &source, nil
+}
+
+// GetByIDWithAuthor retrieves a comment by ID with author information. A comment whose
+// author account was deleted keeps its content and gets the deleted-user placeholder as
+// its author; guest comments have no author.
+func (r *commentRepository) GetByIDWithAuthor(id uuid.UUID) (*models.Comment, error) {
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		WHERE c.id = $1 AND c.deleted_at IS NULL`
+
+	comment, err := scanCommentWithAuthor(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, utils.ErrCommentNotFound
@@ -156,9 +336,11 @@ func (r *commentRepository) GetByIDWithAuthor(id uuid.UUID) (*models.Comment, er
 		return nil, utils.WrapError(err, "failed to get comment with author")
 	}
 
-	comment.Path = convertStringArrayToUUIDSlice(pathArray)
-	comment.Author = &author
-	return &comment, nil
+	if comment.Author == nil && comment.GuestName == nil {
+		comment.Author = models.DeletedUserPlaceholder(comment.CreatedBy)
+	}
+
+	return comment, nil
 }
 
 // Update updates a comment's information
@@ -174,6 +356,12 @@ func (r *commentRepository) Update(id uuid.UUID, updates *models.UpdateCommentRe
 		argIndex++
 	}
 
+	if updates.ContentRaw != nil {
+		setParts = append(setParts, fmt.Sprintf("content_raw = $%d", argIndex))
+		args = append(args, updates.ContentRaw)
+		argIndex++
+	}
+
 	// Always update updated_at when any field is updated
 	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
 	args = append(args, time.Now())
@@ -184,15 +372,24 @@ func (r *commentRepository) Update(id uuid.UUID, updates *models.UpdateCommentRe
 		return r.GetByIDWithAuthor(id)
 	}
 
+	setParts = append(setParts, "version = version + 1")
+
 	// Add WHERE clause
 	args = append(args, id)
+	where := fmt.Sprintf("id = $%d AND deleted_at IS NULL", argIndex)
+	argIndex++
+
+	if updates.ExpectedVersion != nil {
+		where += fmt.Sprintf(" AND version = $%d", argIndex)
+		args = append(args, *updates.ExpectedVersion)
+	}
 
 	query := fmt.Sprintf(`
 		UPDATE comments 
 		SET %s
-		WHERE id = $%d AND deleted_at IS NULL`,
+		WHERE %s`,
 		strings.Join(setParts, ", "),
-		argIndex,
+		where,
 	)
 
 	result, err := r.db.Exec(query, args...)
@@ -206,49 +403,127 @@ func (r *commentRepository) Update(id uuid.UUID, updates *models.UpdateCommentRe
 	}
 
 	if rowsAffected == 0 {
-		return nil, utils.ErrCommentNotFound
+		return nil, missingOrStale(r.db, "comments", id, utils.ErrCommentNotFound)
 	}
 
 	// Return updated comment with author
 	return r.GetByIDWithAuthor(id)
 }
 
-// Delete soft deletes a comment
+// Delete soft deletes a comment. It runs serializable since the parent's replies_count
+// changes with it.
 func (r *commentRepository) Delete(id uuid.UUID) error {
-	query := `
-		UPDATE comments 
-		SET deleted_at = $1 
-		WHERE id = $2 AND deleted_at IS NULL`
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		return r.softDelete(tx, id, time.Now())
+	})
+}
 
-	result, err := r.db.Exec(query, time.Now(), id)
-	if err != nil {
-		return utils.WrapError(err, "failed to delete comment")
+// softDelete marks a comment deleted and, when replies are counted in code, takes it
+// off its parent's replies_count
+func (r *commentRepository) softDelete(tx *sql.Tx, id uuid.UUID, now time.Time) error {
+	var parentID *uuid.UUID
+	err := tx.QueryRow(`
+		UPDATE comments
+		SET deleted_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING parent_id`,
+		now, id,
+	).Scan(&parentID)
+	if err == sql.ErrNoRows {
+		return utils.ErrCommentNotFound
 	}
-
-	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return utils.WrapError(err, "failed to get rows affected")
+		return utils.WrapError(err, "failed to delete comment")
 	}
 
-	if rowsAffected == 0 {
-		return utils.ErrCommentNotFound
+	if r.countReplies && parentID != nil {
+		return decrementRepliesCount(tx, *parentID)
 	}
 
 	return nil
 }
 
-// ListByPost retrieves a paginated list of comments for a specific post
-func (r *commentRepository) ListByPost(postID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+// DeleteAsModerator soft deletes another user's comment and records the action in
+// moderation_log within a single transaction
+func (r *commentRepository) DeleteAsModerator(id, moderatorID uuid.UUID, authorID *uuid.UUID) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		if err := r.softDelete(tx, id, now); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO moderation_log (moderator_id, action, target_type, target_id, target_author_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			moderatorID, models.ModerationActionDeleteComment, models.ModerationTargetComment, id, authorID, now,
+		)
+		if err != nil {
+			return utils.WrapError(err, "failed to record moderation action")
+		}
+
+		return nil
+	})
+}
+
+// Restore clears deleted_at on a soft-deleted comment and gives the reply back to its
+// parent's replies_count. Returns ErrNotDeleted if the comment exists but is not deleted.
+func (r *commentRepository) Restore(id uuid.UUID) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		var parentID *uuid.UUID
+		err := tx.QueryRow(`
+			UPDATE comments
+			SET deleted_at = NULL
+			WHERE id = $1 AND deleted_at IS NOT NULL
+			RETURNING parent_id`,
+			id,
+		).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return utils.WrapError(err, "failed to check comment existence")
+			}
+			if exists {
+				return utils.ErrNotDeleted
+			}
+			return utils.ErrCommentNotFound
+		}
+		if err != nil {
+			return utils.WrapError(err, "failed to restore comment")
+		}
+
+		// The delete decremented the parent's count, by trigger or in softDelete;
+		// nothing reverses that for restores
+		if parentID != nil {
+			if _, err := tx.Exec(`UPDATE comments SET replies_count = replies_count + 1 WHERE id = $1`, *parentID); err != nil {
+				return utils.WrapError(err, "failed to update parent replies count")
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListByPost retrieves a paginated list of top-level comments for a specific post in the
+// given order; unknown orderings fall back to newest first
+func (r *commentRepository) ListByPost(ctx context.Context, postID uuid.UUID, sort string, limit, offset int) ([]models.Comment, error) {
+	defer utils.ObserveDBQuery("comment.list_by_post", time.Now())
+
+	orderBy, ok := commentListOrderBy[sort]
+	if !ok {
+		orderBy = commentListOrderBy[models.CommentSortNewest]
+	}
+
 	query := `
-		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count,
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
 		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
 		FROM comments c
 		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
 		WHERE c.post_id = $1 AND c.deleted_at IS NULL AND c.parent_id IS NULL
-		ORDER BY c.created_at DESC
+		ORDER BY ` + orderBy + `
 		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(query, postID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, postID, limit, offset)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to list comments by post")
 	}
@@ -278,6 +553,9 @@ func (r *commentRepository) ListByPost(postID uuid.UUID, limit, offset int) ([]m
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 			&comment.RepliesCount,
+			&comment.Version,
+			&comment.Attachments,
+			&comment.GuestName,
 			&authorID,
 			&authorUsername,
 			&authorEmail,
@@ -322,10 +600,77 @@ func (r *commentRepository) ListByPost(postID uuid.UUID, limit, offset int) ([]m
 	return comments, nil
 }
 
+// ListThreads retrieves a page of a post's top-level comments in activity order, each with
+// its most recent reply (at any depth) and its total reply count, in a single query
+func (r *commentRepository) ListThreads(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.CommentThread, error) {
+	query := `
+		WITH roots AS (
+			SELECT id
+			FROM comments
+			WHERE post_id = $1 AND deleted_at IS NULL AND parent_id IS NULL
+			ORDER BY bumped_at DESC, created_at DESC
+			LIMIT $2 OFFSET $3
+		),
+		replies AS (
+			SELECT r.id, r.thread_id,
+			       ROW_NUMBER() OVER (PARTITION BY r.thread_id ORDER BY r.created_at DESC, r.id DESC) AS rn,
+			       COUNT(*) OVER (PARTITION BY r.thread_id) AS total
+			FROM comments r
+			JOIN roots ON r.thread_id = roots.id
+			WHERE r.deleted_at IS NULL AND r.id <> r.thread_id
+		)
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       COALESCE(latest.total, 0)
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		LEFT JOIN replies latest ON latest.thread_id = c.id AND latest.rn = 1
+		WHERE c.id IN (SELECT id FROM roots)
+		   OR c.id IN (SELECT id FROM replies WHERE rn = 1)
+		ORDER BY c.parent_id IS NOT NULL,
+		         CASE WHEN c.parent_id IS NULL THEN c.bumped_at END DESC,
+		         CASE WHEN c.parent_id IS NULL THEN c.created_at END DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, postID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list comment threads")
+	}
+	defer rows.Close()
+
+	// Roots come first in activity order, followed by the latest reply of each thread
+	threads := []models.CommentThread{}
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var total int
+		comment, err := scanCommentWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &total)...)
+		}))
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment thread row")
+		}
+
+		if comment.ParentID == nil {
+			index[comment.ID] = len(threads)
+			threads = append(threads, models.CommentThread{Root: *comment, TotalReplies: total})
+			continue
+		}
+
+		if i, ok := index[comment.ThreadID]; ok {
+			threads[i].LatestReply = comment
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment thread rows")
+	}
+
+	return threads, nil
+}
+
 // GetReplies retrieves replies to a specific comment
-func (r *commentRepository) GetReplies(parentID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+func (r *commentRepository) GetReplies(ctx context.Context, parentID uuid.UUID, limit, offset int) ([]models.Comment, error) {
 	query := `
-		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count,
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
 		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
 		FROM comments c
 		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
@@ -333,7 +678,7 @@ func (r *commentRepository) GetReplies(parentID uuid.UUID, limit, offset int) ([
 		ORDER BY c.created_at ASC
 		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(query, parentID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, parentID, limit, offset)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to get comment replies")
 	}
@@ -363,6 +708,9 @@ func (r *commentRepository) GetReplies(parentID uuid.UUID, limit, offset int) ([
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 			&comment.RepliesCount,
+			&comment.Version,
+			&comment.Attachments,
+			&comment.GuestName,
 			&authorID,
 			&authorUsername,
 			&authorEmail,
@@ -407,18 +755,112 @@ func (r *commentRepository) GetReplies(parentID uuid.UUID, limit, offset int) ([
 	return comments, nil
 }
 
-// IncrementRepliesCount increments the replies count for a comment
-// NOTE: This should only be used for manual corrections or data migration.
-// Normal reply creation automatically increments the count via database trigger.
+// commentSortPathJoin orders comments (aliased c) in tree order through "ORDER BY
+// sort.path". It maps each comment's path to the creation time and id of every ancestor,
+// so a parent sorts directly before its replies and siblings sort oldest first rather
+// than by their random ids. The timestamps are formatted at fixed width so they compare
+// correctly as text.
+const commentSortPathJoin = `
+		CROSS JOIN LATERAL (
+			SELECT array_agg(to_char(a.created_at, 'YYYYMMDDHH24MISSUS') || a.id::text ORDER BY p.ord) AS path
+			FROM unnest(c.path) WITH ORDINALITY AS p(id, ord)
+			JOIN comments a ON a.id = p.id
+		) sort`
+
+// GetDescendants retrieves a page of every non-deleted comment below the given one, at
+// any depth, in tree order (each comment directly before its own replies, siblings
+// oldest first). The match is
+// written as path containment rather than "= ANY(path)" so it can use idx_comments_path_gin.
+func (r *commentRepository) GetDescendants(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL` + commentSortPathJoin + `
+		WHERE c.path @> ARRAY[$1]::uuid[] AND c.id <> $1 AND c.deleted_at IS NULL
+		ORDER BY sort.path
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, commentID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get comment descendants")
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanCommentWithAuthor(rows)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment row")
+		}
+		comments = append(comments, *comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment rows")
+	}
+
+	return comments, nil
+}
+
+// CountDescendants counts the non-deleted comments below the given one, at any depth
+func (r *commentRepository) CountDescendants(ctx context.Context, commentID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM comments
+		WHERE path @> ARRAY[$1]::uuid[] AND id <> $1 AND deleted_at IS NULL`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, commentID).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count comment descendants")
+	}
+
+	return total, nil
+}
+
+// IncrementRepliesCount increments the replies count for a comment. Create already does
+// this when replies are counted in code, so call it directly only for manual corrections.
 func (r *commentRepository) IncrementRepliesCount(commentID uuid.UUID) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		return incrementRepliesCount(tx, commentID)
+	})
+}
+
+// DecrementRepliesCount decrements the replies count for a comment, never below zero.
+// Delete already does this when replies are counted in code.
+func (r *commentRepository) DecrementRepliesCount(commentID uuid.UUID) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		return decrementRepliesCount(tx, commentID)
+	})
+}
+
+// incrementRepliesCount adds one to a non-deleted comment's replies_count
+func incrementRepliesCount(db execer, commentID uuid.UUID) error {
 	query := `
-		UPDATE comments 
-		SET replies_count = replies_count + 1, updated_at = $1 
-		WHERE id = $2 AND deleted_at IS NULL`
+		UPDATE comments
+		SET replies_count = replies_count + 1
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	return updateRepliesCount(db, query, commentID, "failed to increment replies count")
+}
+
+// decrementRepliesCount takes one off a comment's replies_count. The parent may itself
+// be soft-deleted, so deleted rows are updated too.
+func decrementRepliesCount(db execer, commentID uuid.UUID) error {
+	query := `
+		UPDATE comments
+		SET replies_count = GREATEST(replies_count - 1, 0)
+		WHERE id = $1`
 
-	result, err := r.db.Exec(query, time.Now(), commentID)
+	return updateRepliesCount(db, query, commentID, "failed to decrement replies count")
+}
+
+// updateRepliesCount runs a replies_count update on one comment, reporting
+// ErrCommentNotFound when no row matched
+func updateRepliesCount(db execer, query string, commentID uuid.UUID, context string) error {
+	result, err := db.Exec(query, commentID)
 	if err != nil {
-		return utils.WrapError(err, "failed to increment replies count")
+		return utils.WrapError(err, context)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -432,3 +874,808 @@ func (r *commentRepository) IncrementRepliesCount(commentID uuid.UUID) error {
 
 	return nil
 }
+
+// GetByIDs retrieves non-deleted comments with author information for the given IDs.
+// Missing or deleted IDs are skipped; the result order is not guaranteed.
+func (r *commentRepository) GetByIDs(ids []uuid.UUID) ([]models.Comment, error) {
+	if len(ids) == 0 {
+		return []models.Comment{}, nil
+	}
+
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		WHERE c.id = ANY($1) AND c.deleted_at IS NULL`
+
+	rows, err := r.db.Query(query, pq.Array(convertUUIDSliceToStringArray(ids)))
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get comments by IDs")
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		comment, err := scanCommentWithAuthor(rows)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment row")
+		}
+		comments = append(comments, *comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment rows")
+	}
+
+	return comments, nil
+}
+
+// GetLatestByUserAndPost retrieves the most recent non-deleted comment a user made on a post
+func (r *commentRepository) GetLatestByUserAndPost(userID, postID uuid.UUID) (*models.Comment, error) {
+	return r.getLatestOnPost("created_by = $1", userID, postID, "failed to get latest comment by user and post")
+}
+
+// GetLatestGuestByIPAndPost retrieves the most recent non-deleted guest comment posted
+// from an IP address on a post
+func (r *commentRepository) GetLatestGuestByIPAndPost(ip string, postID uuid.UUID) (*models.Comment, error) {
+	return r.getLatestOnPost("created_by IS NULL AND guest_ip = $1", ip, postID, "failed to get latest guest comment by IP and post")
+}
+
+// getLatestOnPost retrieves the most recent non-deleted comment on postID matching
+// condition, whose only parameter is $1. Errors other than not found are wrapped with errMsg.
+func (r *commentRepository) getLatestOnPost(condition string, author interface{}, postID uuid.UUID, errMsg string) (*models.Comment, error) {
+	query := `
+		SELECT id, content, post_id, parent_id, path, thread_id, created_by, created_at, updated_at, replies_count, version, attachments, guest_name
+		FROM comments
+		WHERE ` + condition + ` AND post_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var comment models.Comment
+	var pathArray pq.StringArray
+
+	err := r.db.QueryRow(query, author, postID).Scan(
+		&comment.ID,
+		&comment.Content,
+		&comment.PostID,
+		&comment.ParentID,
+		&pathArray,
+		&comment.ThreadID,
+		&comment.CreatedBy,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.RepliesCount,
+		&comment.Version,
+		&comment.Attachments,
+		&comment.GuestName,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrCommentNotFound
+		}
+		return nil, utils.WrapError(err, errMsg)
+	}
+
+	comment.Path = convertStringArrayToUUIDSlice(pathArray)
+	return &comment, nil
+}
+
+// Bump moves the given comments forward in activity ordering. bumped_at never moves
+// backwards, so out-of-order bumps from concurrent replies are harmless.
+func (r *commentRepository) Bump(ids []uuid.UUID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE comments
+		SET bumped_at = GREATEST(bumped_at, $2)
+		WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	if _, err := r.db.Exec(query, pq.Array(convertUUIDSliceToStringArray(ids)), at); err != nil {
+		return utils.WrapError(err, "failed to bump comments")
+	}
+
+	return nil
+}
+
+// Reparent moves a comment and its whole subtree under a new parent on the same post.
+// The comment's parent_id changes, and every row whose path contains the comment gets the
+// old path prefix replaced by the new parent's path and the new parent's thread_id.
+// Moving a comment under itself or one of its descendants is rejected, as is a move that
+// would leave any comment in the subtree deeper than maxDepth (0 for no limit).
+func (r *commentRepository) Reparent(commentID, newParentID uuid.UUID, maxDepth int) error {
+	return r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		var postID uuid.UUID
+		var oldParentID *uuid.UUID
+		var pathArray pq.StringArray
+		err := tx.QueryRow(`
+			SELECT post_id, parent_id, path
+			FROM comments
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE`, commentID).Scan(&postID, &oldParentID, &pathArray)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrCommentNotFound
+			}
+			return utils.WrapError(err, "failed to get comment to reparent")
+		}
+
+		var parentPostID, parentThreadID uuid.UUID
+		var parentPathArray pq.StringArray
+		err = tx.QueryRow(`
+			SELECT post_id, thread_id, path
+			FROM comments
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE`, newParentID).Scan(&parentPostID, &parentThreadID, &parentPathArray)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return utils.WrapError(utils.ErrCommentNotFound, "new parent comment not found")
+			}
+			return utils.WrapError(err, "failed to get new parent comment")
+		}
+
+		if parentPostID != postID {
+			return utils.ErrParentMismatch
+		}
+
+		// The new parent's path contains the comment iff it is the comment or one of its descendants
+		for _, id := range parentPathArray {
+			if id == commentID.String() {
+				return utils.WrapError(utils.ErrInvalidInput, "cannot move a comment under itself or one of its replies")
+			}
+		}
+
+		if maxDepth > 0 {
+			// The deepest comment in the subtree, deleted ones included since they can be
+			// restored, must still be within maxDepth once the subtree hangs off the new parent
+			var deepest int
+			err := tx.QueryRow(`
+				SELECT COALESCE(MAX(array_length(path, 1)), 0)
+				FROM comments
+				WHERE path @> ARRAY[$1]::uuid[]`, commentID).Scan(&deepest)
+			if err != nil {
+				return utils.WrapError(err, "failed to measure comment subtree")
+			}
+			if len(parentPathArray)+deepest-len(pathArray)+1 > maxDepth {
+				return utils.WrapError(utils.ErrInvalidInput, fmt.Sprintf("moving the comment would nest replies more than %d levels deep", maxDepth))
+			}
+		}
+
+		if oldParentID != nil && *oldParentID == newParentID {
+			return nil
+		}
+
+		newPrefix := append(parentPathArray, commentID.String())
+		_, err = tx.Exec(`
+			UPDATE comments
+			SET path = $1::uuid[] || path[$2 + 1:], thread_id = $3
+			WHERE path @> ARRAY[$4]::uuid[]`,
+			newPrefix, len(pathArray), parentThreadID, commentID)
+		if err != nil {
+			return utils.WrapError(err, "failed to rewrite comment subtree paths")
+		}
+
+		if _, err := tx.Exec(`UPDATE comments SET parent_id = $2 WHERE id = $1`, commentID, newParentID); err != nil {
+			return utils.WrapError(err, "failed to update comment parent")
+		}
+
+		// replies_count triggers only cover inserts and soft deletes, so fix both parents here
+		parents := []uuid.UUID{newParentID}
+		if oldParentID != nil {
+			parents = append(parents, *oldParentID)
+		}
+		for _, parentID := range parents {
+			_, err := tx.Exec(`
+				UPDATE comments
+				SET replies_count = (
+					SELECT COUNT(*) FROM comments child
+					WHERE child.parent_id = $1 AND child.deleted_at IS NULL
+				)
+				WHERE id = $1`, parentID)
+			if err != nil {
+				return utils.WrapError(err, "failed to recompute replies count")
+			}
+		}
+
+		return nil
+	})
+}
+
+// CountByPost counts the non-deleted top-level comments of a post
+func (r *commentRepository) CountByPost(ctx context.Context, postID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM comments
+		WHERE post_id = $1 AND deleted_at IS NULL AND parent_id IS NULL`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, postID).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count comments by post")
+	}
+
+	return total, nil
+}
+
+// CountReplies counts the non-deleted direct replies to a comment
+func (r *commentRepository) CountReplies(ctx context.Context, parentID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM comments
+		WHERE parent_id = $1 AND deleted_at IS NULL`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, parentID).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count comment replies")
+	}
+
+	return total, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCommentWithAuthor scans a comment row joined (LEFT JOIN) with its author columns
+func scanCommentWithAuthor(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	var pathArray pq.StringArray
+	var authorID sql.NullString
+	var authorUsername sql.NullString
+	var authorEmail sql.NullString
+	var authorDisplayName sql.NullString
+	var authorAvatarURL sql.NullString
+	var authorCreatedAt sql.NullTime
+	var authorUpdatedAt sql.NullTime
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.Content,
+		&comment.PostID,
+		&comment.ParentID,
+		&pathArray,
+		&comment.ThreadID,
+		&comment.CreatedBy,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.RepliesCount,
+		&comment.Version,
+		&comment.Attachments,
+		&comment.GuestName,
+		&authorID,
+		&authorUsername,
+		&authorEmail,
+		&authorDisplayName,
+		&authorAvatarURL,
+		&authorCreatedAt,
+		&authorUpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Path = convertStringArrayToUUIDSlice(pathArray)
+
+	if authorID.Valid {
+		var author models.User
+		authorUUID, _ := uuid.Parse(authorID.String)
+		author.ID = authorUUID
+		author.Username = authorUsername.String
+		if authorEmail.Valid {
+			author.Email = &authorEmail.String
+		}
+		if authorDisplayName.Valid {
+			author.DisplayName = &authorDisplayName.String
+		}
+		if authorAvatarURL.Valid {
+			author.AvatarURL = &authorAvatarURL.String
+		}
+		author.CreatedAt = authorCreatedAt.Time
+		author.UpdatedAt = authorUpdatedAt.Time
+		comment.Author = &author
+	}
+
+	return &comment, nil
+}
+
+// ListIDsAfter retrieves a page of non-deleted comment IDs ordered by ID, starting
+// after the given ID (keyset pagination; pass uuid.Nil for the first page)
+func (r *commentRepository) ListIDsAfter(afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM comments
+		WHERE id > $1 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list comment IDs")
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment ID")
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment ID rows")
+	}
+
+	return ids, nil
+}
+
+// RecomputeRepliesCount recalculates a comment's replies count from its non-deleted
+// direct replies. It reports whether the stored count was wrong and has been corrected.
+func (r *commentRepository) RecomputeRepliesCount(commentID uuid.UUID) (bool, error) {
+	corrected, err := r.RecomputeRepliesCounts([]uuid.UUID{commentID})
+	if err != nil {
+		return false, err
+	}
+	return corrected > 0, nil
+}
+
+// RecomputeRepliesCounts recalculates the replies count of every given comment from its
+// non-deleted direct replies in one set-based statement, and returns how many stored
+// counts were wrong and have been corrected
+func (r *commentRepository) RecomputeRepliesCounts(commentIDs []uuid.UUID) (int, error) {
+	if len(commentIDs) == 0 {
+		return 0, nil
+	}
+
+	// The LEFT JOIN keeps comments with no live replies, so their count is reset to 0
+	query := `
+		UPDATE comments c
+		SET replies_count = counts.actual
+		FROM (
+			SELECT batch.id AS parent_id, COUNT(child.id) AS actual
+			FROM unnest($1::uuid[]) AS batch(id)
+			LEFT JOIN comments child ON child.parent_id = batch.id AND child.deleted_at IS NULL
+			GROUP BY batch.id
+		) counts
+		WHERE c.id = counts.parent_id AND c.replies_count IS DISTINCT FROM counts.actual`
+
+	// Serializable so a reply added or deleted while counting cannot be overwritten by a
+	// count taken before it
+	var corrected int
+	err := r.WithTx(serializableTx, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, pq.Array(convertUUIDSliceToStringArray(commentIDs)))
+		if err != nil {
+			return utils.WrapError(err, "failed to recompute replies counts")
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
+		corrected = int(rowsAffected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return corrected, nil
+}
+
+// AddMentions records the users mentioned in a comment; repeated mentions are ignored
+func (r *commentRepository) AddMentions(commentID uuid.UUID, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO comment_mentions (comment_id, mentioned_user_id, created_at)
+		SELECT $1, unnest($2::uuid[]), $3
+		ON CONFLICT (comment_id, mentioned_user_id) DO NOTHING`
+
+	_, err := r.db.Exec(query, commentID, pq.Array(convertUUIDSliceToStringArray(userIDs)), time.Now())
+	if err != nil {
+		return utils.WrapError(err, "failed to add comment mentions")
+	}
+
+	return nil
+}
+
+// ListMentioningUser retrieves non-deleted comments that mention a user, newest first
+func (r *commentRepository) ListMentioningUser(userID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		FROM comment_mentions m
+		JOIN comments c ON c.id = m.comment_id
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		WHERE m.mentioned_user_id = $1 AND c.deleted_at IS NULL
+		ORDER BY c.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list comments mentioning user")
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		comment, err := scanCommentWithAuthor(rows)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment row")
+		}
+		comments = append(comments, *comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment rows")
+	}
+
+	return comments, nil
+}
+
+// ListByUser retrieves a user's non-deleted comments on non-deleted posts, newest first,
+// each with the title of its post
+func (r *commentRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.UserComment, error) {
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       p.title
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id AND p.deleted_at IS NULL
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		WHERE c.created_by = $1 AND c.deleted_at IS NULL
+		ORDER BY c.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list comments by user")
+	}
+	defer rows.Close()
+
+	var comments []models.UserComment
+	for rows.Next() {
+		var userComment models.UserComment
+
+		comment, err := scanCommentWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &userComment.PostTitle)...)
+		}))
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment row")
+		}
+
+		userComment.Comment = *comment
+		comments = append(comments, userComment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment rows")
+	}
+
+	return comments, nil
+}
+
+// CountByUser counts the comments ListByUser pages through
+func (r *commentRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id AND p.deleted_at IS NULL
+		WHERE c.created_by = $1 AND c.deleted_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, utils.WrapError(err, "failed to count comments by user")
+	}
+
+	return count, nil
+}
+
+// PurgeDeletedBefore hard-deletes up to limit comments that were soft-deleted before
+// the cutoff and have no replies left. Tombstones with replies are kept so threads stay
+// intact; once their replies are purged they become leaves and go in a later batch.
+func (r *commentRepository) PurgeDeletedBefore(cutoff time.Time, limit int) (int, error) {
+	query := `
+		DELETE FROM comments
+		WHERE id IN (
+			SELECT c.id
+			FROM comments c
+			WHERE c.deleted_at IS NOT NULL AND c.deleted_at < $1
+			  AND NOT EXISTS (SELECT 1 FROM comments child WHERE child.parent_id = c.id)
+			LIMIT $2
+		)`
+
+	result, err := r.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to purge deleted comments")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetFullTree retrieves a post's non-deleted comments up to maxDepth levels as a nested
+// tree using two queries: one for the comments, ordered so parents precede their replies
+// and siblings are oldest first, and one for their authors. At most limit comments are loaded; the returned bool reports
+// whether more existed. Replies whose parent was deleted or cut off are left out.
+func (r *commentRepository) GetFullTree(ctx context.Context, postID uuid.UUID, maxDepth, limit int) ([]models.Comment, bool, error) {
+	defer utils.ObserveDBQuery("comment.get_full_tree", time.Now())
+
+	query := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name
+		FROM comments c` + commentSortPathJoin + `
+		WHERE c.post_id = $1 AND c.deleted_at IS NULL AND array_length(c.path, 1) <= $2
+		ORDER BY sort.path
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, postID, maxDepth, limit+1)
+	if err != nil {
+		return nil, false, utils.WrapError(err, "failed to get comment tree")
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var comment models.Comment
+		var pathArray pq.StringArray
+
+		err := rows.Scan(
+			&comment.ID,
+			&comment.Content,
+			&comment.PostID,
+			&comment.ParentID,
+			&pathArray,
+			&comment.ThreadID,
+			&comment.CreatedBy,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.RepliesCount,
+			&comment.Version,
+			&comment.Attachments,
+			&comment.GuestName,
+		)
+		if err != nil {
+			return nil, false, utils.WrapError(err, "failed to scan comment row")
+		}
+
+		comment.Path = convertStringArrayToUUIDSlice(pathArray)
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, false, utils.WrapError(err, "error iterating comment rows")
+	}
+
+	truncated := len(comments) > limit
+	if truncated {
+		comments = comments[:limit]
+	}
+
+	if err := r.loadAuthors(ctx, comments); err != nil {
+		return nil, false, err
+	}
+
+	return buildCommentTree(comments), truncated, nil
+}
+
+// loadAuthors fills in the authors of the given comments with a single batched query
+func (r *commentRepository) loadAuthors(ctx context.Context, comments []models.Comment) error {
+	seen := make(map[uuid.UUID]bool)
+	var authorIDs []uuid.UUID
+	for _, comment := range comments {
+		if comment.CreatedBy != nil && !seen[*comment.CreatedBy] {
+			seen[*comment.CreatedBy] = true
+			authorIDs = append(authorIDs, *comment.CreatedBy)
+		}
+	}
+
+	if len(authorIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT id, username, email, display_name, avatar_url, created_at, updated_at
+		FROM users
+		WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(convertUUIDSliceToStringArray(authorIDs)))
+	if err != nil {
+		return utils.WrapError(err, "failed to load comment authors")
+	}
+	defer rows.Close()
+
+	authors := make(map[uuid.UUID]*models.User, len(authorIDs))
+	for rows.Next() {
+		var author models.User
+		err := rows.Scan(
+			&author.ID,
+			&author.Username,
+			&author.Email,
+			&author.DisplayName,
+			&author.AvatarURL,
+			&author.CreatedAt,
+			&author.UpdatedAt,
+		)
+		if err != nil {
+			return utils.WrapError(err, "failed to scan comment author row")
+		}
+		authors[author.ID] = &author
+	}
+
+	if err = rows.Err(); err != nil {
+		return utils.WrapError(err, "error iterating comment author rows")
+	}
+
+	for i := range comments {
+		if comments[i].CreatedBy != nil {
+			comments[i].Author = authors[*comments[i].CreatedBy]
+		}
+	}
+
+	return nil
+}
+
+// buildCommentTree nests comments under their parents. Comments must be ordered so
+// that every parent precedes its replies (as ordering by path guarantees).
+func buildCommentTree(comments []models.Comment) []models.Comment {
+	index := make(map[uuid.UUID]int, len(comments))
+	childIndexes := make(map[uuid.UUID][]int, len(comments))
+	var rootIndexes []int
+
+	for i, comment := range comments {
+		index[comment.ID] = i
+		if comment.ParentID == nil {
+			rootIndexes = append(rootIndexes, i)
+			continue
+		}
+		if _, ok := index[*comment.ParentID]; ok {
+			childIndexes[*comment.ParentID] = append(childIndexes[*comment.ParentID], i)
+		}
+	}
+
+	var build func(i int) models.Comment
+	build = func(i int) models.Comment {
+		comment := comments[i]
+		for _, childIndex := range childIndexes[comment.ID] {
+			comment.Children = append(comment.Children, build(childIndex))
+		}
+		return comment
+	}
+
+	roots := make([]models.Comment, 0, len(rootIndexes))
+	for _, i := range rootIndexes {
+		roots = append(roots, build(i))
+	}
+
+	return roots
+}
+
+// SearchGlobal full-text searches comments across all posts for moderation, including
+// soft-deleted comments and comments on deleted posts. Returns the page and the total match count.
+func (r *commentRepository) SearchGlobal(query string, limit, offset int) ([]models.AdminCommentSearchResult, int, error) {
+	sqlQuery := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       c.deleted_at, p.title,
+		       ts_rank(c.search_vector, q) AS rank,
+		       ts_headline('english', ` + headlineSource("c.content") + `, q, $4) AS snippet
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id
+		JOIN posts p ON c.post_id = p.id,
+		     plainto_tsquery('english', $1) q
+		WHERE c.search_vector @@ q
+		ORDER BY rank DESC, c.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(sqlQuery, query, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to search comments")
+	}
+	defer rows.Close()
+
+	var results []models.AdminCommentSearchResult
+	for rows.Next() {
+		var result models.AdminCommentSearchResult
+		var deletedAt sql.NullTime
+
+		comment, err := scanCommentWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &deletedAt, &result.PostTitle, &result.Rank, &result.Snippet)...)
+		}))
+		if err != nil {
+			return nil, 0, utils.WrapError(err, "failed to scan comment search row")
+		}
+
+		if deletedAt.Valid {
+			comment.DeletedAt = &deletedAt.Time
+		}
+		result.Comment = *comment
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, utils.WrapError(err, "error iterating comment search rows")
+	}
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM comments c
+		JOIN posts p ON c.post_id = p.id
+		WHERE c.search_vector @@ plainto_tsquery('english', $1)`
+
+	var total int
+	if err := r.db.QueryRow(countQuery, query).Scan(&total); err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comment search results")
+	}
+
+	return results, total, nil
+}
+
+// ParticipantStats aggregates the non-deleted comments on a post per author, ordered by
+// comment count. Comments by soft-deleted users are still counted and flagged. Guest
+// comments have no author, so each guest_name gets its own row.
+func (r *commentRepository) ParticipantStats(postID uuid.UUID) ([]models.ParticipantStats, error) {
+	query := `
+		SELECT c.created_by, u.username, u.display_name, c.guest_name,
+		       (c.guest_name IS NULL AND (u.id IS NULL OR u.deleted_at IS NOT NULL)) AS author_deleted,
+		       COUNT(*) AS comment_count,
+		       COUNT(*) FILTER (WHERE c.parent_id IS NOT NULL) AS reply_count,
+		       MIN(c.created_at) AS first_comment_at,
+		       MAX(c.created_at) AS last_comment_at
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id
+		WHERE c.post_id = $1 AND c.deleted_at IS NULL
+		GROUP BY c.created_by, c.guest_name, u.id, u.username, u.display_name, u.deleted_at
+		ORDER BY comment_count DESC, last_comment_at DESC`
+
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get participant stats")
+	}
+	defer rows.Close()
+
+	var stats []models.ParticipantStats
+	for rows.Next() {
+		var stat models.ParticipantStats
+		var username sql.NullString
+		var displayName sql.NullString
+
+		err := rows.Scan(
+			&stat.UserID,
+			&username,
+			&displayName,
+			&stat.GuestName,
+			&stat.AuthorDeleted,
+			&stat.CommentCount,
+			&stat.ReplyCount,
+			&stat.FirstCommentAt,
+			&stat.LastCommentAt,
+		)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan participant stats row")
+		}
+
+		if username.Valid {
+			stat.Username = &username.String
+		}
+		if displayName.Valid {
+			stat.DisplayName = &displayName.String
+		}
+		stats = append(stats, stat)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating participant stats rows")
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,670 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+var treeColumns = []string{"id", "content", "post_id", "parent_id", "path", "thread_id", "created_by", "created_at", "updated_at", "replies_count", "version", "attachments", "guest_name"}
+
+// treeRow builds a GetFullTree result row for a comment under the given ancestors
+func treeRow(id, postID, authorID uuid.UUID, createdAt time.Time, ancestors ...uuid.UUID) []driver.Value {
+	path := append(append([]uuid.UUID{}, ancestors...), id)
+	var parentID driver.Value
+	if len(ancestors) > 0 {
+		parentID = ancestors[len(ancestors)-1].String()
+	}
+	return []driver.Value{
+		id.String(), "content " + id.String()[:8], postID.String(), parentID,
+		"{" + strings.Join(convertUUIDSliceToStringArray(path), ",") + "}", path[0].String(),
+		authorID.String(), createdAt, createdAt, 0, 1, []byte("[]"), nil,
+	}
+}
+
+func TestGetFullTree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	postID, authorID := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+	reply, nestedReply, laterReply := uuid.New(), uuid.New(), uuid.New()
+	orphan, missingParent := uuid.New(), uuid.New()
+	t0 := time.Now().Add(-time.Hour)
+
+	// Rows arrive in sort-path order: each parent before its replies, siblings oldest first
+	rows := sqlmock.NewRows(treeColumns).
+		AddRow(treeRow(first, postID, authorID, t0)...).
+		AddRow(treeRow(reply, postID, authorID, t0.Add(2*time.Minute), first)...).
+		AddRow(treeRow(nestedReply, postID, authorID, t0.Add(3*time.Minute), first, reply)...).
+		AddRow(treeRow(laterReply, postID, authorID, t0.Add(4*time.Minute), first)...).
+		AddRow(treeRow(orphan, postID, authorID, t0.Add(5*time.Minute), missingParent)...).
+		AddRow(treeRow(second, postID, authorID, t0.Add(time.Minute))...)
+	mock.ExpectQuery(`FROM comments c\s+CROSS JOIN LATERAL .+ORDER BY sort\.path\s+LIMIT \$3`).
+		WithArgs(postID, 10, 101).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`FROM users\s+WHERE id = ANY\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "display_name", "avatar_url", "created_at", "updated_at"}).
+			AddRow(authorID.String(), "author", nil, nil, nil, t0, t0))
+
+	tree, truncated, err := NewCommentRepository(db, models.RepliesCountModeTrigger).GetFullTree(context.Background(), postID, 10, 100)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// Every statement must have been expected above, so this also bounds the tree to two queries
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if truncated {
+		t.Error("tree reported as truncated")
+	}
+
+	if len(tree) != 2 || tree[0].ID != first || tree[1].ID != second {
+		t.Fatalf("roots = %v, want [%s %s]", commentIDs(tree), first, second)
+	}
+	if got := tree[0].Children; len(got) != 2 || got[0].ID != reply || got[1].ID != laterReply {
+		t.Fatalf("replies of first root = %v, want [%s %s]", commentIDs(got), reply, laterReply)
+	}
+	if got := tree[0].Children[0].Children; len(got) != 1 || got[0].ID != nestedReply {
+		t.Fatalf("nested replies = %v, want [%s]", commentIDs(got), nestedReply)
+	}
+	if len(tree[1].Children) != 0 {
+		t.Errorf("second root has %d replies, want 0", len(tree[1].Children))
+	}
+	if tree[0].Author == nil || tree[0].Children[0].Children[0].Author == nil {
+		t.Error("authors were not attached")
+	}
+}
+
+func TestGetFullTreeTruncated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	postID := uuid.New()
+	rows := sqlmock.NewRows(treeColumns)
+	for i := 0; i < 3; i++ {
+		rows.AddRow(treeRow(uuid.New(), postID, uuid.New(), time.Now())...)
+	}
+	mock.ExpectQuery(`ORDER BY sort\.path`).WithArgs(postID, 5, 3).WillReturnRows(rows)
+	mock.ExpectQuery(`FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "display_name", "avatar_url", "created_at", "updated_at"}))
+
+	tree, truncated, err := NewCommentRepository(db, models.RepliesCountModeTrigger).GetFullTree(context.Background(), postID, 5, 2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !truncated || len(tree) != 2 {
+		t.Errorf("got %d roots, truncated %v; want 2 roots, truncated", len(tree), truncated)
+	}
+}
+
+func commentIDs(comments []models.Comment) []uuid.UUID {
+	ids := make([]uuid.UUID, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestParticipantStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	postID := uuid.New()
+	alice, removed := uuid.New(), uuid.New()
+	at := time.Now()
+
+	mock.ExpectQuery(`GROUP BY c\.created_by, c\.guest_name`).
+		WithArgs(postID).
+		WillReturnRows(sqlmock.NewRows([]string{"created_by", "username", "display_name", "guest_name", "author_deleted", "comment_count", "reply_count", "first_comment_at", "last_comment_at"}).
+			AddRow(alice.String(), "alice", "Alice", nil, false, 5, 3, at, at).
+			AddRow(nil, nil, nil, "Visitor", false, 3, 1, at, at).
+			AddRow(removed.String(), nil, nil, nil, true, 2, 0, at, at).
+			AddRow(nil, nil, nil, "Another visitor", false, 1, 0, at, at))
+
+	stats, err := NewCommentRepository(db, models.RepliesCountModeTrigger).ParticipantStats(postID)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		userID        *uuid.UUID
+		username      string
+		guestName     string
+		authorDeleted bool
+		comments      int
+		replies       int
+	}{
+		{"registered author", &alice, "alice", "", false, 5, 3},
+		{"guest", nil, "", "Visitor", false, 3, 1},
+		{"deleted author", &removed, "", "", true, 2, 0},
+		{"second guest", nil, "", "Another visitor", false, 1, 0},
+	}
+	if len(stats) != len(tests) {
+		t.Fatalf("got %d participants, want %d", len(stats), len(tests))
+	}
+	for i, tt := range tests {
+		got := stats[i]
+		if (got.UserID == nil) != (tt.userID == nil) || (got.UserID != nil && *got.UserID != *tt.userID) {
+			t.Errorf("%s: user_id = %v, want %v", tt.name, got.UserID, tt.userID)
+		}
+		if gotName := derefString(got.Username); gotName != tt.username {
+			t.Errorf("%s: username = %q, want %q", tt.name, gotName, tt.username)
+		}
+		if gotGuest := derefString(got.GuestName); gotGuest != tt.guestName {
+			t.Errorf("%s: guest_name = %q, want %q", tt.name, gotGuest, tt.guestName)
+		}
+		if got.AuthorDeleted != tt.authorDeleted || got.CommentCount != tt.comments || got.ReplyCount != tt.replies {
+			t.Errorf("%s: got deleted=%v comments=%d replies=%d, want deleted=%v comments=%d replies=%d",
+				tt.name, got.AuthorDeleted, got.CommentCount, got.ReplyCount, tt.authorDeleted, tt.comments, tt.replies)
+		}
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// pathLiteral renders a path the way Postgres returns a uuid[] column
+func pathLiteral(ids ...uuid.UUID) string {
+	return "{" + strings.Join(convertUUIDSliceToStringArray(ids), ",") + "}"
+}
+
+func TestReparent(t *testing.T) {
+	postID, otherPostID := uuid.New(), uuid.New()
+	root, oldParent, comment, reply := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	newRoot, newParent := uuid.New(), uuid.New()
+
+	// The comment's deepest reply is at depth 4, {root,oldParent,comment,reply}, so the
+	// subtree is two levels tall
+	const maxDepth = 5
+
+	tests := []struct {
+		name           string
+		parentPostID   uuid.UUID
+		parentPath     []uuid.UUID
+		wantDepthCheck bool
+		wantErr        error
+	}{
+		{"moves the subtree under another thread", postID, []uuid.UUID{newRoot, newParent}, true, nil},
+		{"moves the subtree up to a top-level comment", postID, []uuid.UUID{root}, true, nil},
+		{"moves the subtree to exactly the depth limit", postID, []uuid.UUID{newRoot, uuid.New(), newParent}, true, nil},
+		{"rejects a move that nests replies too deep", postID, []uuid.UUID{newRoot, uuid.New(), uuid.New(), newParent}, true, utils.ErrInvalidInput},
+		{"rejects a move under one of its replies", postID, []uuid.UUID{root, oldParent, comment, reply}, false, utils.ErrInvalidInput},
+		{"rejects a move under itself", postID, []uuid.UUID{root, oldParent, comment}, false, utils.ErrInvalidInput},
+		{"rejects a parent on another post", otherPostID, []uuid.UUID{newRoot, newParent}, false, utils.ErrParentMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			parentID := tt.parentPath[len(tt.parentPath)-1]
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT post_id, parent_id, path\s+FROM comments`).
+				WithArgs(comment).
+				WillReturnRows(sqlmock.NewRows([]string{"post_id", "parent_id", "path"}).
+					AddRow(postID.String(), oldParent.String(), pathLiteral(root, oldParent, comment)))
+			mock.ExpectQuery(`SELECT post_id, thread_id, path\s+FROM comments`).
+				WithArgs(parentID).
+				WillReturnRows(sqlmock.NewRows([]string{"post_id", "thread_id", "path"}).
+					AddRow(tt.parentPostID.String(), tt.parentPath[0].String(), pathLiteral(tt.parentPath...)))
+
+			if tt.wantDepthCheck {
+				mock.ExpectQuery(`SELECT COALESCE\(MAX\(array_length\(path, 1\)\), 0\)`).
+					WithArgs(comment).
+					WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(4))
+			}
+
+			if tt.wantErr == nil {
+				// Every row under the comment swaps the comment's old ancestors (the first
+				// three path elements, itself included) for the new parent's path plus the
+				// comment: a reply at {root,oldParent,comment,reply} ends up at
+				// {<new parent path>,comment,reply}.
+				newPrefix := convertUUIDSliceToStringArray(append(append([]uuid.UUID{}, tt.parentPath...), comment))
+				mock.ExpectExec(`SET path = \$1::uuid\[\] \|\| path\[\$2 \+ 1:\], thread_id = \$3\s+WHERE path @> ARRAY\[\$4\]`).
+					WithArgs(newPrefix, 3, tt.parentPath[0], comment).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`UPDATE comments SET parent_id = \$2 WHERE id = \$1`).
+					WithArgs(comment, parentID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				for _, id := range []uuid.UUID{parentID, oldParent} {
+					mock.ExpectExec(`SET replies_count = \(`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewCommentRepository(db, models.RepliesCountModeTrigger).Reparent(comment, parentID, maxDepth)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestGetSourceIsPlainText(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	id, authorID := uuid.New(), uuid.New()
+	raw := `<img src=x onerror="alert(1)"> and <b>bold</b>`
+	mock.ExpectQuery(`SELECT id, COALESCE\(content_raw, content\), created_by, updated_at`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_raw", "created_by", "updated_at"}).
+			AddRow(id.String(), raw, authorID.String(), time.Now()))
+
+	source, err := NewCommentRepository(db, models.RepliesCountModeTrigger).GetSource(id)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if source.ContentFormat != models.CommentContentFormatText {
+		t.Errorf("got content_format %q, want %q", source.ContentFormat, models.CommentContentFormatText)
+	}
+	if source.ContentRaw != raw {
+		t.Errorf("got content_raw %q, want the submitted text unchanged", source.ContentRaw)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListThreads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	postID, authorID := uuid.New(), uuid.New()
+	busy, quiet := uuid.New(), uuid.New()
+	firstReply, latestReply := uuid.New(), uuid.New()
+	at := time.Now()
+
+	columns := append(append([]string{}, treeColumns...),
+		"author_id", "username", "email", "display_name", "avatar_url", "author_created_at", "author_updated_at", "total")
+	withAuthor := func(row []driver.Value, total int) []driver.Value {
+		return append(row, authorID.String(), "author", nil, nil, nil, at, at, total)
+	}
+
+	// The busy thread has four live replies; the latest is a reply to its first reply
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(PARTITION BY r.thread_id ORDER BY r.created_at DESC, r.id DESC\)`).
+		WithArgs(postID, 20, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(withAuthor(treeRow(busy, postID, authorID, at), 4)...).
+			AddRow(withAuthor(treeRow(quiet, postID, authorID, at.Add(-time.Hour)), 0)...).
+			AddRow(withAuthor(treeRow(latestReply, postID, authorID, at, busy, firstReply), 0)...))
+
+	threads, err := NewCommentRepository(db, models.RepliesCountModeTrigger).ListThreads(context.Background(), postID, 20, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(threads) != 2 || threads[0].Root.ID != busy || threads[1].Root.ID != quiet {
+		t.Fatalf("got %d threads, want busy then quiet", len(threads))
+	}
+
+	if threads[0].TotalReplies != 4 {
+		t.Errorf("busy thread has %d replies, want 4", threads[0].TotalReplies)
+	}
+	if threads[0].LatestReply == nil || threads[0].LatestReply.ID != latestReply {
+		t.Errorf("busy thread latest reply = %v, want %v", threads[0].LatestReply, latestReply)
+	} else if threads[0].LatestReply.Author == nil || threads[0].LatestReply.Author.Username != "author" {
+		t.Errorf("latest reply is missing its author")
+	}
+
+	if threads[1].TotalReplies != 0 || threads[1].LatestReply != nil {
+		t.Errorf("quiet thread = %d replies, latest %v; want none", threads[1].TotalReplies, threads[1].LatestReply)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetByIDWithAuthorPlaceholder(t *testing.T) {
+	postID := uuid.New()
+	at := time.Now()
+	columns := append(append([]string{}, treeColumns...),
+		"author_id", "username", "email", "display_name", "avatar_url", "author_created_at", "author_updated_at")
+	guestName := "visitor"
+
+	tests := []struct {
+		name         string
+		createdBy    driver.Value
+		guestName    driver.Value
+		wantUsername string
+	}{
+		{"author account deleted", nil, nil, models.DeletedUsername},
+		{"guest comment", nil, guestName, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			id := uuid.New()
+			row := treeRow(id, postID, uuid.New(), at)
+			row[6], row[12] = tt.createdBy, tt.guestName
+			row = append(row, nil, nil, nil, nil, nil, nil, nil)
+			mock.ExpectQuery(`WHERE c.id = \$1 AND c.deleted_at IS NULL`).
+				WithArgs(id).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(row...))
+
+			comment, err := NewCommentRepository(db, models.RepliesCountModeTrigger).GetByIDWithAuthor(id)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.wantUsername == "" {
+				if comment.Author != nil {
+					t.Errorf("guest comment got author %+v, want none", comment.Author)
+				}
+			} else if comment.Author == nil || comment.Author.Username != tt.wantUsername || comment.Author.ID != uuid.Nil {
+				t.Errorf("got author %+v, want the deleted-user placeholder", comment.Author)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRepliesCountByMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		isReply   bool
+		wantCount bool
+	}{
+		{"app mode counts a reply", models.RepliesCountModeApp, true, true},
+		{"app mode skips a top-level comment", models.RepliesCountModeApp, false, false},
+		{"trigger mode leaves it to the database", models.RepliesCountModeTrigger, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+			repo := NewCommentRepository(db, tt.mode)
+
+			comment := &models.Comment{ID: uuid.New(), PostID: uuid.New(), CreatedAt: time.Now()}
+			comment.ThreadID = comment.ID
+			comment.Path = []uuid.UUID{comment.ID}
+			var parentRow driver.Value
+			if tt.isReply {
+				parentID := uuid.New()
+				comment.ParentID = &parentID
+				comment.ThreadID = parentID
+				comment.Path = []uuid.UUID{parentID, comment.ID}
+				parentRow = parentID.String()
+			}
+
+			if tt.wantCount {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO comments`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`SET replies_count = replies_count \+ 1`).WithArgs(*comment.ParentID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectExec(`INSERT INTO comments`).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if err := repo.Create(comment); err != nil {
+				t.Fatalf("create: unexpected error %v", err)
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SET deleted_at = \$1`).
+				WithArgs(sqlmock.AnyArg(), comment.ID).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(parentRow))
+			if tt.wantCount {
+				mock.ExpectExec(`SET replies_count = GREATEST\(replies_count - 1, 0\)`).WithArgs(*comment.ParentID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+			if err := repo.Delete(comment.ID); err != nil {
+				t.Fatalf("delete: unexpected error %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCreateGuestIfAllowed(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	ip := "192.0.2.10"
+
+	tests := []struct {
+		name      string
+		mode      string
+		reply     bool
+		allow     bool
+		wantCount bool
+	}{
+		{"allowed comment is stored", models.RepliesCountModeTrigger, false, true, false},
+		{"refused comment is not stored", models.RepliesCountModeTrigger, false, false, false},
+		{"allowed reply counts the parent in app mode", models.RepliesCountModeApp, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			comment := &models.Comment{ID: uuid.New(), PostID: uuid.New(), CreatedAt: time.Now(), GuestIP: &ip}
+			if tt.reply {
+				parentID := uuid.New()
+				comment.ParentID = &parentID
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`pg_advisory_xact_lock\(hashtext\('guest_comment:ip:'`).WithArgs(ip).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`WHERE guest_ip = \$1 AND created_at >= \$2`).WithArgs(ip, since).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+			if tt.allow {
+				mock.ExpectExec(`INSERT INTO comments`).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.wantCount {
+				mock.ExpectExec(`SET replies_count = replies_count \+ 1`).WithArgs(*comment.ParentID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			var got int
+			created, err := NewCommentRepository(db, tt.mode).CreateGuestIfAllowed(comment, since, func(count int) bool {
+				got = count
+				return tt.allow
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if created != tt.allow {
+				t.Errorf("created = %v, want %v", created, tt.allow)
+			}
+			if got != 4 {
+				t.Errorf("allow saw count %d, want 4", got)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSearchGlobalIncludesDeletedCommentsAcrossPosts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	firstPost, secondPost, authorID := uuid.New(), uuid.New(), uuid.New()
+	live, hidden := uuid.New(), uuid.New()
+	now := time.Now()
+	deletedAt := now.Add(-time.Hour)
+
+	columns := append(append([]string{}, treeColumns...),
+		"u_id", "username", "email", "display_name", "avatar_url", "u_created_at", "u_updated_at",
+		"deleted_at", "title", "rank", "snippet")
+	author := []driver.Value{authorID.String(), "alice", nil, nil, nil, now, now}
+	rows := sqlmock.NewRows(columns).
+		AddRow(append(append(treeRow(live, firstPost, authorID, now), author...), nil, "First post", 0.9, "a <mark>spam</mark> link")...).
+		AddRow(append(append(treeRow(hidden, secondPost, authorID, now), author...), deletedAt, "Second post", 0.5, "more <mark>spam</mark>")...)
+
+	mock.ExpectQuery(`LEFT JOIN users u ON c.created_by = u.id\s+JOIN posts p ON c.post_id = p.id,\s+plainto_tsquery\('english', \$1\) q\s+WHERE c.search_vector @@ q\s+ORDER BY`).
+		WithArgs("spam", 20, 0, headlineOptions).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COUNT\(\*\)\s+FROM comments c\s+JOIN posts p ON c.post_id = p.id\s+WHERE c.search_vector @@ plainto_tsquery\('english', \$1\)$`).
+		WithArgs("spam").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	results, total, err := NewCommentRepository(db, models.RepliesCountModeTrigger).SearchGlobal("spam", 20, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if total != 2 {
+		t.Errorf("got total %d, want 2", total)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	want := []struct {
+		id     uuid.UUID
+		postID uuid.UUID
+		title  string
+		status string
+	}{
+		{live, firstPost, "First post", models.CommentStatusActive},
+		{hidden, secondPost, "Second post", models.CommentStatusDeleted},
+	}
+	for i, w := range want {
+		got := results[i].ToResponse()
+		if got.ID != w.id || got.PostID != w.postID || got.PostTitle != w.title {
+			t.Errorf("result %d: got comment %s on post %s %q, want %s on %s %q", i, got.ID, got.PostID, got.PostTitle, w.id, w.postID, w.title)
+		}
+		if got.Status != w.status {
+			t.Errorf("result %d: got status %q, want %q", i, got.Status, w.status)
+		}
+		if got.Author == nil || got.Author.Username != "alice" {
+			t.Errorf("result %d: got author %+v, want alice", i, got.Author)
+		}
+	}
+	if d := results[1].ToResponse().DeletedAt; d == nil || !d.Equal(deletedAt) {
+		t.Errorf("hidden comment deleted_at = %v, want %v", d, deletedAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPurgeDeletedBeforeKeepsTombstones pins the clause that makes the purge leaf-only: a
+// deleted comment that still has replies, deleted or not, is skipped so the thread keeps
+// its shape
+func TestPurgeDeletedBeforeKeepsTombstones(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectExec(`DELETE FROM comments\s+WHERE id IN \(\s+SELECT c.id\s+FROM comments c\s+`+
+		`WHERE c.deleted_at IS NOT NULL AND c.deleted_at < \$1\s+`+
+		`AND NOT EXISTS \(SELECT 1 FROM comments child WHERE child.parent_id = c.id\)\s+`+
+		`LIMIT \$2\s+\)`).
+		WithArgs(cutoff, 500).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := NewCommentRepository(db, models.RepliesCountModeTrigger).PurgeDeletedBefore(cutoff, 500)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if purged != 3 {
+		t.Errorf("got %d purged, want 3", purged)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRecomputeRepliesCountsRepairsCorruptedCount pins the set-based repair. The
+// corrupted parent claims five replies but only two are live; the database reports the
+// single row the UPDATE rewrote, and a batch with nothing to fix skips the query entirely.
+func TestRecomputeRepliesCountsRepairsCorruptedCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	corrupted, healthy := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE comments c\s+SET replies_count = counts.actual\s+FROM \(\s+` +
+		`SELECT batch.id AS parent_id, COUNT\(child.id\) AS actual\s+` +
+		`FROM unnest\(\$1::uuid\[\]\) AS batch\(id\)\s+` +
+		`LEFT JOIN comments child ON child.parent_id = batch.id AND child.deleted_at IS NULL\s+` +
+		`GROUP BY batch.id\s+\) counts\s+` +
+		`WHERE c.id = counts.parent_id AND c.replies_count IS DISTINCT FROM counts.actual`).
+		WithArgs(`{"` + corrupted.String() + `","` + healthy.String() + `"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewCommentRepository(db, models.RepliesCountModeTrigger)
+	corrected, err := repo.RecomputeRepliesCounts([]uuid.UUID{corrupted, healthy})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if corrected != 1 {
+		t.Errorf("got %d corrected, want 1", corrected)
+	}
+
+	if corrected, err := repo.RecomputeRepliesCounts(nil); err != nil || corrected != 0 {
+		t.Errorf("empty batch: got %d, %v, want 0 and no query", corrected, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// IdempotencyRepository interface defines idempotency key data access methods
+type IdempotencyRepository interface {
+	Reserve(record *models.IdempotencyKey, expiredBefore time.Time) (*models.IdempotencyKey, error)
+	Complete(userID uuid.UUID, key string, resourceID uuid.UUID, statusCode int) error
+	Release(userID uuid.UUID, key string) error
+	DeleteBefore(cutoff time.Time) (int, error)
+}
+
+// idempotencyRepository implements IdempotencyRepository interface
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository instance
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a new request. If the key is free, or only held by a record
+// created before expiredBefore, it is claimed and nil is returned. Otherwise the record
+// already holding the key is returned and nothing is written.
+func (r *idempotencyRepository) Reserve(record *models.IdempotencyKey, expiredBefore time.Time) (*models.IdempotencyKey, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, resource_type, request_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET resource_type = EXCLUDED.resource_type,
+			request_hash = EXCLUDED.request_hash,
+			resource_id = NULL,
+			status_code = NULL,
+			created_at = EXCLUDED.created_at
+		WHERE idempotency_keys.created_at < $6`
+
+	result, err := r.db.Exec(query, record.UserID, record.Key, record.ResourceType, record.RequestHash, record.CreatedAt, expiredBefore)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to reserve idempotency key")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get rows affected")
+	}
+	if rowsAffected == 1 {
+		return nil, nil
+	}
+
+	selectQuery := `
+		SELECT user_id, idempotency_key, resource_type, request_hash, resource_id, status_code, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	var existing models.IdempotencyKey
+	err = r.db.QueryRow(selectQuery, record.UserID, record.Key).Scan(
+		&existing.UserID,
+		&existing.Key,
+		&existing.ResourceType,
+		&existing.RequestHash,
+		&existing.ResourceID,
+		&existing.StatusCode,
+		&existing.CreatedAt,
+	)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get idempotency key")
+	}
+
+	return &existing, nil
+}
+
+// Complete records the resource created by the request holding the key
+func (r *idempotencyRepository) Complete(userID uuid.UUID, key string, resourceID uuid.UUID, statusCode int) error {
+	query := `
+		UPDATE idempotency_keys
+		SET resource_id = $3, status_code = $4
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	if _, err := r.db.Exec(query, userID, key, resourceID, statusCode); err != nil {
+		return utils.WrapError(err, "failed to complete idempotency key")
+	}
+
+	return nil
+}
+
+// Release frees a key whose request failed, so the client can retry with it.
+// Keys that already point at a created resource are left alone.
+func (r *idempotencyRepository) Release(userID uuid.UUID, key string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND resource_id IS NULL`
+
+	if _, err := r.db.Exec(query, userID, key); err != nil {
+		return utils.WrapError(err, "failed to release idempotency key")
+	}
+
+	return nil
+}
+
+// DeleteBefore removes keys created before cutoff
+func (r *idempotencyRepository) DeleteBefore(cutoff time.Time) (int, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	result, err := r.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to delete expired idempotency keys")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
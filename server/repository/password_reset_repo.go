@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// PasswordResetRepository interface defines password reset token data access methods
+type PasswordResetRepository interface {
+	CreateIfAllowed(token *models.PasswordResetToken, since time.Time, allow func(history models.PasswordResetSendHistory) bool) (bool, error)
+	GetByTokenHash(tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsed(id uuid.UUID) (bool, error)
+	InvalidateForUser(userID uuid.UUID) error
+}
+
+// passwordResetRepository implements PasswordResetRepository interface
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository instance
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// CreateIfAllowed stores a new password reset token if allow approves the send history
+// since the given time, and reports whether it did. Requests for the same account and
+// from the same IP are serialized with advisory locks, so concurrent requests cannot all
+// pass the check before any of them is recorded. A token with a nil UserID is stored
+// without an account and only counts against its IP.
+func (r *passwordResetRepository) CreateIfAllowed(token *models.PasswordResetToken, since time.Time, allow func(history models.PasswordResetSendHistory) bool) (bool, error) {
+	created := false
+	err := withTx(r.db, nil, func(tx *sql.Tx) error {
+		var history models.PasswordResetSendHistory
+		var userID interface{}
+
+		// Locks are always taken account first, then IP, so two requests cannot deadlock
+		if token.UserID != uuid.Nil {
+			userID = token.UserID
+			if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('password_reset:user:' || $1::text))`, token.UserID.String()); err != nil {
+				return utils.WrapError(err, "failed to lock password reset account")
+			}
+
+			var latest sql.NullTime
+			query := `
+				SELECT COUNT(*), MAX(created_at)
+				FROM password_reset_tokens
+				WHERE user_id = $1 AND created_at >= $2`
+			if err := tx.QueryRow(query, token.UserID, since).Scan(&history.SentToUser, &latest); err != nil {
+				return utils.WrapError(err, "failed to count recent password reset tokens")
+			}
+			if latest.Valid {
+				history.LastSentAt = &latest.Time
+			}
+		}
+
+		if token.RequestedIP != "" {
+			if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('password_reset:ip:' || $1::text))`, token.RequestedIP); err != nil {
+				return utils.WrapError(err, "failed to lock password reset IP")
+			}
+
+			query := `
+				SELECT COUNT(*)
+				FROM password_reset_tokens
+				WHERE requested_ip = $1 AND created_at >= $2`
+			if err := tx.QueryRow(query, token.RequestedIP, since).Scan(&history.SentFromIP); err != nil {
+				return utils.WrapError(err, "failed to count recent password reset requests")
+			}
+		}
+
+		if !allow(history) {
+			return nil
+		}
+
+		query := `
+			INSERT INTO password_reset_tokens (id, user_id, token_hash, requested_ip, expires_at, used_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		if _, err := tx.Exec(query, token.ID, userID, token.TokenHash, token.RequestedIP, token.ExpiresAt, token.UsedAt, token.CreatedAt); err != nil {
+			return utils.WrapError(err, "failed to create password reset token")
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// GetByTokenHash retrieves a password reset token by the hash of its value
+func (r *passwordResetRepository) GetByTokenHash(tokenHash string) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, requested_ip, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1`
+
+	var token models.PasswordResetToken
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.RequestedIP,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "invalid or expired reset token")
+		}
+		return nil, utils.WrapError(err, "failed to get password reset token")
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes a token. It reports false if the token was already used,
+// so concurrent resets with the same token cannot both succeed.
+func (r *passwordResetRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, id, time.Now())
+	if err != nil {
+		return false, utils.WrapError(err, "failed to mark password reset token as used")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.WrapError(err, "failed to get rows affected")
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateForUser marks every outstanding reset token for a user as used
+func (r *passwordResetRepository) InvalidateForUser(userID uuid.UUID) error {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE user_id = $1 AND used_at IS NULL`
+
+	if _, err := r.db.Exec(query, userID, time.Now()); err != nil {
+		return utils.WrapError(err, "failed to invalidate password reset tokens")
+	}
+
+	return nil
+}
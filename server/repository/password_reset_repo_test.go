@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/google/uuid"
+)
+
+func TestCreateIfAllowed(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		ip          string
+		allow       bool
+		wantHistory models.PasswordResetSendHistory
+	}{
+		{"known account is recorded", uuid.New(), "198.51.100.1", true, models.PasswordResetSendHistory{SentToUser: 2, LastSentAt: &now, SentFromIP: 4}},
+		{"refused send is not recorded", uuid.New(), "198.51.100.1", false, models.PasswordResetSendHistory{SentToUser: 2, LastSentAt: &now, SentFromIP: 4}},
+		{"unknown email only locks and counts the IP", uuid.Nil, "198.51.100.1", true, models.PasswordResetSendHistory{SentFromIP: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			token := &models.PasswordResetToken{ID: uuid.New(), UserID: tt.userID, TokenHash: "hash", RequestedIP: tt.ip, ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+			var userArg interface{}
+			if tt.userID != uuid.Nil {
+				userArg = tt.userID
+			}
+
+			mock.ExpectBegin()
+			if tt.userID != uuid.Nil {
+				mock.ExpectExec(`pg_advisory_xact_lock\(hashtext\('password_reset:user:'`).WithArgs(tt.userID.String()).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`WHERE user_id = \$1 AND created_at >= \$2`).WithArgs(tt.userID, since).
+					WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(2, now))
+			}
+			mock.ExpectExec(`pg_advisory_xact_lock\(hashtext\('password_reset:ip:'`).WithArgs(tt.ip).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`WHERE requested_ip = \$1 AND created_at >= \$2`).WithArgs(tt.ip, since).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+			if tt.allow {
+				mock.ExpectExec(`INSERT INTO password_reset_tokens`).
+					WithArgs(token.ID, userArg, token.TokenHash, token.RequestedIP, token.ExpiresAt, nil, token.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			var got models.PasswordResetSendHistory
+			created, err := NewPasswordResetRepository(db).CreateIfAllowed(token, since, func(history models.PasswordResetSendHistory) bool {
+				got = history
+				return tt.allow
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if created != tt.allow {
+				t.Errorf("created = %v, want %v", created, tt.allow)
+			}
+			if got.SentToUser != tt.wantHistory.SentToUser || got.SentFromIP != tt.wantHistory.SentFromIP || (got.LastSentAt == nil) != (tt.wantHistory.LastSentAt == nil) {
+				t.Errorf("got history %+v, want %+v", got, tt.wantHistory)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
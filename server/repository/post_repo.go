@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // PostRepository interface defines post data access methods
@@ -16,13 +18,35 @@ type PostRepository interface {
 	Create(post *models.Post) error
 	GetByID(id uuid.UUID) (*models.Post, error)
 	GetByIDWithAuthor(id uuid.UUID) (*models.Post, error)
-	GetByIDWithComments(id uuid.UUID) (*models.Post, error)
+	GetAnyByID(id uuid.UUID) (*models.Post, error)
+	GetAnyByIDWithAuthor(id uuid.UUID) (*models.Post, error)
+	GetByIDWithComments(ctx context.Context, id uuid.UUID, limit, offset, repliesPerComment int) (*models.Post, error)
 	Update(id uuid.UUID, updates *models.UpdatePostRequest) (*models.Post, error)
 	Delete(id uuid.UUID) error
-	List(limit, offset int) ([]models.Post, error)
-	ListByUser(userID uuid.UUID, limit, offset int) ([]models.Post, error)
+	Restore(id uuid.UUID) error
+	SetCommentsLocked(id uuid.UUID, locked bool) error
+	GetCommentsStamp(ctx context.Context, id uuid.UUID) (*models.PostCommentsStamp, error)
+	List(ctx context.Context, limit, offset int) ([]models.Post, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error)
+	ListScheduledByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error)
+	CountScheduledByUser(ctx context.Context, userID uuid.UUID) (int, error)
+	ListByTag(ctx context.Context, tag string, limit, offset int) ([]models.Post, error)
+	ListTrending(ctx context.Context, since time.Time, limit, offset int) ([]models.Post, error)
+	CountTrending(ctx context.Context, since time.Time) (int, error)
+	Count(ctx context.Context) (int, error)
+	DeleteImpact(id uuid.UUID) (*models.PostDeleteImpact, error)
+	WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error
 }
 
+// publishedPost restricts a query on posts p to those already published: posts with no
+// publish_at, or whose publish_at has passed. Scheduled posts become visible on their own
+// once the time comes, with nothing to update.
+const publishedPost = "(p.publish_at IS NULL OR p.publish_at <= NOW())"
+
+// postTagsColumn selects the tag names of post p, sorted, as one array column, so list
+// queries return tags with each row instead of needing a second query
+const postTagsColumn = "ARRAY(SELECT t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id ORDER BY t.name)"
+
 // postRepository implements PostRepository interface
 type postRepository struct {
 	db *sql.DB
@@ -33,34 +57,60 @@ func NewPostRepository(db *sql.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
-// Create creates a new post in the database
+// WithTx runs fn inside a transaction that is rolled back if fn returns an error.
+// opts selects the isolation level; nil uses the database default.
+func (r *postRepository) WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return withTx(r.db, opts, fn)
+}
+
+// Create creates a new post and its tag links in a single transaction
 func (r *postRepository) Create(post *models.Post) error {
-	query := `
-		INSERT INTO posts (id, title, content, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
-
-	_, err := r.db.Exec(query,
-		post.ID,
-		post.Title,
-		post.Content,
-		post.CreatedBy,
-		post.CreatedAt,
-		post.UpdatedAt,
-	)
+	defer utils.ObserveDBQuery("post.create", time.Now())
+
+	return r.WithTx(nil, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO posts (id, title, content, created_by, created_at, updated_at, allow_anonymous_comments, publish_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+		_, err := tx.Exec(query,
+			post.ID,
+			post.Title,
+			post.Content,
+			post.CreatedBy,
+			post.CreatedAt,
+			post.UpdatedAt,
+			post.AllowAnonymousComments,
+			post.PublishAt,
+		)
 
-	if err != nil {
-		return utils.WrapError(err, "failed to create post")
-	}
+		if err != nil {
+			return utils.WrapError(err, "failed to create post")
+		}
 
-	return nil
+		return replacePostTags(tx, post.ID, post.Tags)
+	})
 }
 
-// GetByID retrieves a post by ID
+// GetByID retrieves a published post by ID
 func (r *postRepository) GetByID(id uuid.UUID) (*models.Post, error) {
+	return r.getByID(id, false)
+}
+
+// GetAnyByID retrieves a post by ID whether or not it is published yet. It is for the
+// author's own views and for ownership checks; readers should go through GetByID.
+func (r *postRepository) GetAnyByID(id uuid.UUID) (*models.Post, error) {
+	return r.getByID(id, true)
+}
+
+// getByID retrieves a post by ID, leaving out scheduled posts unless includeScheduled is set
+func (r *postRepository) getByID(id uuid.UUID, includeScheduled bool) (*models.Post, error) {
 	query := `
-		SELECT id, title, content, created_by, created_at, updated_at
-		FROM posts 
-		WHERE id = $1 AND deleted_at IS NULL`
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at
+		FROM posts p
+		WHERE p.id = $1 AND p.deleted_at IS NULL`
+	if !includeScheduled {
+		query += " AND " + publishedPost
+	}
 
 	var post models.Post
 	err := r.db.QueryRow(query, id).Scan(
@@ -70,6 +120,10 @@ func (r *postRepository) GetByID(id uuid.UUID) (*models.Post, error) {
 		&post.CreatedBy,
 		&post.CreatedAt,
 		&post.UpdatedAt,
+		&post.Version,
+		&post.CommentsLocked,
+		&post.AllowAnonymousComments,
+		&post.PublishAt,
 	)
 
 	if err != nil {
@@ -82,34 +136,31 @@ func (r *postRepository) GetByID(id uuid.UUID) (*models.Post, error) {
 	return &post, nil
 }
 
-// GetByIDWithAuthor retrieves a post by ID with author information
+// GetByIDWithAuthor retrieves a published post by ID with author information
 func (r *postRepository) GetByIDWithAuthor(id uuid.UUID) (*models.Post, error) {
+	return r.getByIDWithAuthor(id, false)
+}
+
+// GetAnyByIDWithAuthor retrieves a post by ID with author information whether or not it
+// is published yet
+func (r *postRepository) GetAnyByIDWithAuthor(id uuid.UUID) (*models.Post, error) {
+	return r.getByIDWithAuthor(id, true)
+}
+
+// getByIDWithAuthor retrieves a post by ID with author information, leaving out
+// scheduled posts unless includeScheduled is set
+func (r *postRepository) getByIDWithAuthor(id uuid.UUID, includeScheduled bool) (*models.Post, error) {
 	query := `
-		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at,
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
 		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
 		FROM posts p
-		JOIN users u ON p.created_by = u.id
-		WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL`
-
-	var post models.Post
-	var author models.User
-
-	err := r.db.QueryRow(query, id).Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.CreatedBy,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-		&author.ID,
-		&author.Username,
-		&author.Email,
-		&author.DisplayName,
-		&author.AvatarURL,
-		&author.CreatedAt,
-		&author.UpdatedAt,
-	)
+		LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+		WHERE p.id = $1 AND p.deleted_at IS NULL`
+	if !includeScheduled {
+		query += " AND " + publishedPost
+	}
 
+	post, err := scanPostWithAuthor(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, utils.ErrPostNotFound
@@ -117,92 +168,139 @@ func (r *postRepository) GetByIDWithAuthor(id uuid.UUID) (*models.Post, error) {
 		return nil, utils.WrapError(err, "failed to get post with author")
 	}
 
-	post.Author = &author
-	return &post, nil
-}
-
-// GetByIDWithComments retrieves a post by ID with comments and authors
-func (r *postRepository) GetByIDWithComments(id uuid.UUID) (*models.Post, error) {
-	post, err := r.GetByIDWithAuthor(id)
-	if err != nil {
+	posts := []models.Post{*post}
+	if err := r.loadTags(context.Background(), posts); err != nil {
 		return nil, err
 	}
 
-	commentsQuery := `
-		SELECT c.id, c.content, c.post_id, c.parent_id, c.thread_id, c.created_by, c.created_at,
-		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
-		FROM comments c
-		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
-		WHERE c.post_id = $1 AND c.deleted_at IS NULL AND c.parent_id IS NULL
-		ORDER BY c.created_at DESC`
+	return &posts[0], nil
+}
 
-	rows, err := r.db.Query(commentsQuery, id)
+// GetByIDWithComments retrieves a post by ID with a page of its top-level comments, each
+// with up to repliesPerComment of its earliest direct replies nested under Children.
+// The post, its author and tags, the comments, the replies and their authors are all
+// loaded by a single statement: every row carries the post columns followed by one
+// comment, and a post without comments comes back as one row with NULL comment columns.
+func (r *postRepository) GetByIDWithComments(ctx context.Context, id uuid.UUID, limit, offset, repliesPerComment int) (*models.Post, error) {
+	defer utils.ObserveDBQuery("post.get_with_comments", time.Now())
+
+	query := `
+		WITH post AS (
+			SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+			       u.id AS author_id, u.username, u.email, u.display_name, u.avatar_url, u.created_at AS author_created_at, u.updated_at AS author_updated_at,
+			       ARRAY(
+			           SELECT t.name
+			           FROM post_tags pt
+			           JOIN tags t ON t.id = pt.tag_id
+			           WHERE pt.post_id = p.id
+			           ORDER BY t.name
+			       ) AS tags
+			FROM posts p
+			LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+			WHERE p.id = $1 AND p.deleted_at IS NULL AND ` + publishedPost + `
+		),
+		top AS (
+			SELECT c.id
+			FROM comments c
+			JOIN post ON c.post_id = post.id
+			WHERE c.deleted_at IS NULL AND c.parent_id IS NULL
+			ORDER BY c.created_at DESC
+			LIMIT $2 OFFSET $3
+		),
+		ranked_replies AS (
+			SELECT r.id, ROW_NUMBER() OVER (PARTITION BY r.parent_id ORDER BY r.created_at ASC) AS rn
+			FROM comments r
+			JOIN top ON r.parent_id = top.id
+			WHERE r.deleted_at IS NULL
+		),
+		page AS (
+			SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+			       u.id AS author_id, u.username, u.email, u.display_name, u.avatar_url, u.created_at AS author_created_at, u.updated_at AS author_updated_at
+			FROM comments c
+			LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+			WHERE c.id IN (SELECT id FROM top)
+			   OR c.id IN (SELECT id FROM ranked_replies WHERE rn <= $4)
+		)
+		SELECT post.*, page.*
+		FROM post
+		LEFT JOIN page ON true
+		ORDER BY page.parent_id IS NOT NULL,
+		         CASE WHEN page.parent_id IS NULL THEN page.created_at END DESC,
+		         page.created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, id, limit, offset, repliesPerComment)
 	if err != nil {
-		return nil, utils.WrapError(err, "failed to get comments for post")
+		return nil, utils.WrapError(err, "failed to get post with comments")
 	}
 	defer rows.Close()
 
-	var comments []models.Comment
+	// Each row is scanned in windows: the post and its author, the tags, then the comment
+	const postColumns, tagColumns, commentColumns = 17, 1, 20
+
+	// Top-level comments come first (newest first), followed by replies (oldest first)
+	var post *models.Post
+	comments := []models.Comment{}
+	index := make(map[uuid.UUID]int)
 	for rows.Next() {
-		var comment models.Comment
-		var author models.User
-		var authorID sql.NullString
-		var authorUsername sql.NullString
-		var authorEmail sql.NullString
-		var authorDisplayName sql.NullString
-		var authorAvatarURL sql.NullString
-		var authorCreatedAt sql.NullTime
-		var authorUpdatedAt sql.NullTime
+		if post == nil {
+			var tags pq.StringArray
+			post, err = scanPostWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+				return rows.Scan(append(append(dest, &tags), discardColumns(commentColumns)...)...)
+			}))
+			if err != nil {
+				return nil, utils.WrapError(err, "failed to scan post row")
+			}
+			post.Tags = append([]string{}, tags...)
+		}
 
-		err := rows.Scan(
-			&comment.ID,
-			&comment.Content,
-			&comment.PostID,
-			&comment.ParentID,
-			&comment.ThreadID,
-			&comment.CreatedBy,
-			&comment.CreatedAt,
-			&authorID,
-			&authorUsername,
-			&authorEmail,
-			&authorDisplayName,
-			&authorAvatarURL,
-			&authorCreatedAt,
-			&authorUpdatedAt,
-		)
+		var commentID uuid.NullUUID
+		if err := rows.Scan(append(append(discardColumns(postColumns+tagColumns), &commentID), discardColumns(commentColumns-1)...)...); err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment row")
+		}
+		if !commentID.Valid {
+			continue
+		}
+
+		comment, err := scanCommentWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(discardColumns(postColumns+tagColumns), dest...)...)
+		}))
 		if err != nil {
 			return nil, utils.WrapError(err, "failed to scan comment row")
 		}
 
-		if authorID.Valid {
-			authorUUID, _ := uuid.Parse(authorID.String)
-			author.ID = authorUUID
-			author.Username = authorUsername.String
-			if authorEmail.Valid {
-				author.Email = &authorEmail.String
-			}
-			if authorDisplayName.Valid {
-				author.DisplayName = &authorDisplayName.String
-			}
-			if authorAvatarURL.Valid {
-				author.AvatarURL = &authorAvatarURL.String
-			}
-			author.CreatedAt = authorCreatedAt.Time
-			author.UpdatedAt = authorUpdatedAt.Time
-			comment.Author = &author
+		if comment.ParentID == nil {
+			index[comment.ID] = len(comments)
+			comments = append(comments, *comment)
+			continue
 		}
 
-		comments = append(comments, comment)
+		if i, ok := index[*comment.ParentID]; ok {
+			comments[i].Children = append(comments[i].Children, *comment)
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, utils.WrapError(err, "error iterating comment rows")
+		return nil, utils.WrapError(err, "error iterating post with comments rows")
+	}
+
+	if post == nil {
+		return nil, utils.ErrPostNotFound
 	}
 
 	post.Comments = comments
 	return post, nil
 }
 
+// discardColumns returns n scan destinations whose values are thrown away, so a shared
+// scan helper can read one window of a wider row
+func discardColumns(n int) []interface{} {
+	dest := make([]interface{}, n)
+	for i := range dest {
+		dest[i] = new(interface{})
+	}
+	return dest
+}
+
 // Update updates a post's information
 func (r *postRepository) Update(id uuid.UUID, updates *models.UpdatePostRequest) (*models.Post, error) {
 	setParts := []string{}
@@ -221,39 +319,65 @@ func (r *postRepository) Update(id uuid.UUID, updates *models.UpdatePostRequest)
 		argIndex++
 	}
 
-	if len(setParts) == 0 {
-		return r.GetByIDWithAuthor(id)
+	if updates.AllowAnonymousComments != nil {
+		setParts = append(setParts, fmt.Sprintf("allow_anonymous_comments = $%d", argIndex))
+		args = append(args, *updates.AllowAnonymousComments)
+		argIndex++
+	}
+
+	if len(setParts) == 0 && updates.Tags == nil {
+		return r.GetAnyByIDWithAuthor(id)
 	}
 
 	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
 	args = append(args, time.Now())
 	argIndex++
 
+	setParts = append(setParts, "version = version + 1")
+
 	args = append(args, id)
+	where := fmt.Sprintf("id = $%d AND deleted_at IS NULL", argIndex)
+	argIndex++
+
+	if updates.ExpectedVersion != nil {
+		where += fmt.Sprintf(" AND version = $%d", argIndex)
+		args = append(args, *updates.ExpectedVersion)
+	}
 
 	query := fmt.Sprintf(`
 		UPDATE posts 
 		SET %s
-		WHERE id = $%d AND deleted_at IS NULL`,
+		WHERE %s`,
 		strings.Join(setParts, ", "),
-		argIndex,
+		where,
 	)
 
-	result, err := r.db.Exec(query, args...)
-	if err != nil {
-		return nil, utils.WrapError(err, "failed to update post")
-	}
+	err := r.WithTx(nil, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return utils.WrapError(err, "failed to update post")
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, utils.WrapError(err, "failed to get rows affected")
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
 
-	if rowsAffected == 0 {
-		return nil, utils.ErrPostNotFound
+		if rowsAffected == 0 {
+			return missingOrStale(tx, "posts", id, utils.ErrPostNotFound)
+		}
+
+		if updates.Tags != nil {
+			return replacePostTags(tx, id, *updates.Tags)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return r.GetByIDWithAuthor(id)
+	return r.GetAnyByIDWithAuthor(id)
 }
 
 // Delete soft deletes a post
@@ -280,18 +404,55 @@ func (r *postRepository) Delete(id uuid.UUID) error {
 	return nil
 }
 
+// Restore clears deleted_at on a soft-deleted post. Returns ErrNotDeleted if the post
+// exists but is not deleted.
+func (r *postRepository) Restore(id uuid.UUID) error {
+	query := `
+		UPDATE posts
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return utils.WrapError(err, "failed to restore post")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.WrapError(err, "failed to get rows affected")
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return utils.WrapError(err, "failed to check post existence")
+	}
+	if exists {
+		return utils.ErrNotDeleted
+	}
+
+	return utils.ErrPostNotFound
+}
+
 // List retrieves a paginated list of posts with authors
-func (r *postRepository) List(limit, offset int) ([]models.Post, error) {
+func (r *postRepository) List(ctx context.Context, limit, offset int) ([]models.Post, error) {
+	defer utils.ObserveDBQuery("post.list", time.Now())
+
 	query := `
-		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at,
-		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+		       ` + postTagsColumn + `
 		FROM posts p
-		JOIN users u ON p.created_by = u.id
-		WHERE p.deleted_at IS NULL AND u.deleted_at IS NULL
+		LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+		WHERE p.deleted_at IS NULL AND ` + publishedPost + `
 		ORDER BY p.created_at DESC
 		LIMIT $1 OFFSET $2`
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to list posts")
 	}
@@ -299,9 +460,44 @@ func (r *postRepository) List(limit, offset int) ([]models.Post, error) {
 
 	var posts []models.Post
 	for rows.Next() {
-		var post models.Post
-		var author models.User
+		post, err := scanListedPost(rows)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan post row")
+		}
+
+		posts = append(posts, *post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating post rows")
+	}
 
+	return posts, nil
+}
+
+// ListByUser retrieves a paginated list of posts by a specific user. Author is left nil:
+// every post shares the same author, which the caller loads once instead of per row.
+func (r *postRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+		       ` + postTagsColumn + `
+		FROM posts p
+		WHERE p.created_by = $1 AND p.deleted_at IS NULL AND ` + publishedPost + `
+		ORDER BY p.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list posts by user")
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var commentCount int
+		var tags pq.StringArray
 		err := rows.Scan(
 			&post.ID,
 			&post.Title,
@@ -309,19 +505,19 @@ func (r *postRepository) List(limit, offset int) ([]models.Post, error) {
 			&post.CreatedBy,
 			&post.CreatedAt,
 			&post.UpdatedAt,
-			&author.ID,
-			&author.Username,
-			&author.Email,
-			&author.DisplayName,
-			&author.AvatarURL,
-			&author.CreatedAt,
-			&author.UpdatedAt,
+			&post.Version,
+			&post.CommentsLocked,
+			&post.AllowAnonymousComments,
+			&post.PublishAt,
+			&commentCount,
+			&tags,
 		)
 		if err != nil {
 			return nil, utils.WrapError(err, "failed to scan post row")
 		}
 
-		post.Author = &author
+		post.CommentCount = &commentCount
+		post.Tags = tagsOrEmpty(tags)
 		posts = append(posts, post)
 	}
 
@@ -332,28 +528,25 @@ func (r *postRepository) List(limit, offset int) ([]models.Post, error) {
 	return posts, nil
 }
 
-// ListByUser retrieves a paginated list of posts by a specific user
-func (r *postRepository) ListByUser(userID uuid.UUID, limit, offset int) ([]models.Post, error) {
+// ListScheduledByUser retrieves a user's posts that are scheduled for later, soonest
+// first. Author is left nil as in ListByUser.
+func (r *postRepository) ListScheduledByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error) {
 	query := `
-		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at,
-		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at
 		FROM posts p
-		JOIN users u ON p.created_by = u.id
-		WHERE p.created_by = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
-		ORDER BY p.created_at DESC
+		WHERE p.created_by = $1 AND p.deleted_at IS NULL AND p.publish_at > NOW()
+		ORDER BY p.publish_at ASC
 		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(query, userID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
-		return nil, utils.WrapError(err, "failed to list posts by user")
+		return nil, utils.WrapError(err, "failed to list scheduled posts")
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	posts := []models.Post{}
 	for rows.Next() {
 		var post models.Post
-		var author models.User
-
 		err := rows.Scan(
 			&post.ID,
 			&post.Title,
@@ -361,25 +554,419 @@ func (r *postRepository) ListByUser(userID uuid.UUID, limit, offset int) ([]mode
 			&post.CreatedBy,
 			&post.CreatedAt,
 			&post.UpdatedAt,
-			&author.ID,
-			&author.Username,
-			&author.Email,
-			&author.DisplayName,
-			&author.AvatarURL,
-			&author.CreatedAt,
-			&author.UpdatedAt,
+			&post.Version,
+			&post.CommentsLocked,
+			&post.AllowAnonymousComments,
+			&post.PublishAt,
 		)
 		if err != nil {
-			return nil, utils.WrapError(err, "failed to scan post row")
+			return nil, utils.WrapError(err, "failed to scan scheduled post row")
 		}
 
-		post.Author = &author
 		posts = append(posts, post)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating scheduled post rows")
+	}
+
+	if err := r.loadTags(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// CountScheduledByUser counts the posts ListScheduledByUser pages through
+func (r *postRepository) CountScheduledByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM posts p
+		WHERE p.created_by = $1 AND p.deleted_at IS NULL AND p.publish_at > NOW()`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count scheduled posts")
+	}
+
+	return total, nil
+}
+
+// Count counts the non-deleted posts visible in List
+func (r *postRepository) Count(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM posts p
+		WHERE p.deleted_at IS NULL AND ` + publishedPost
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count posts")
+	}
+
+	return total, nil
+}
+
+// DeleteImpact counts the non-deleted comments on a post and their distinct authors,
+// i.e. what deleting the post would hide
+func (r *postRepository) DeleteImpact(id uuid.UUID) (*models.PostDeleteImpact, error) {
+	// Guests have no account, so each guest name counts as one author, as in
+	// ParticipantStats. Comments whose account was removed have no author to count.
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE parent_id IS NULL),
+			COUNT(DISTINCT created_by) + COUNT(DISTINCT guest_name) FILTER (WHERE created_by IS NULL)
+		FROM comments
+		WHERE post_id = $1 AND deleted_at IS NULL`
+
+	impact := &models.PostDeleteImpact{PostID: id}
+	if err := r.db.QueryRow(query, id).Scan(&impact.Comments, &impact.TopLevel, &impact.AffectedAuthors); err != nil {
+		return nil, utils.WrapError(err, "failed to count post delete impact")
+	}
+	impact.Replies = impact.Comments - impact.TopLevel
+
+	return impact, nil
+}
+
+// ListByTag retrieves a paginated list of posts carrying the given (normalized) tag
+func (r *postRepository) ListByTag(ctx context.Context, tag string, limit, offset int) ([]models.Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+		       ` + postTagsColumn + `
+		FROM posts p
+		LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+		JOIN post_tags pt ON pt.post_id = p.id
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE t.name = $1 AND p.deleted_at IS NULL AND ` + publishedPost + `
+		ORDER BY p.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, tag, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list posts by tag")
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		post, err := scanListedPost(rows)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan post row")
+		}
+
+		posts = append(posts, *post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating post rows")
+	}
+
+	return posts, nil
+}
+
+// ListTrending retrieves posts ranked by the number of non-deleted comments made after
+// since. Posts with no comments in that time are not included.
+func (r *postRepository) ListTrending(ctx context.Context, since time.Time, limit, offset int) ([]models.Post, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+		       recent.comment_count
+		FROM (
+			SELECT c.post_id, COUNT(*) AS comment_count
+			FROM comments c
+			WHERE c.created_at > $1 AND c.deleted_at IS NULL
+			GROUP BY c.post_id
+		) recent
+		JOIN posts p ON p.id = recent.post_id AND p.deleted_at IS NULL AND ` + publishedPost + `
+		LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+		ORDER BY recent.comment_count DESC, p.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, since, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list trending posts")
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var commentCount, recentCount int
+		post, err := scanPostWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &commentCount, &recentCount)...)
+		}))
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan post row")
+		}
+
+		post.CommentCount = &commentCount
+		post.RecentCommentCount = &recentCount
+		posts = append(posts, *post)
+	}
+
 	if err = rows.Err(); err != nil {
 		return nil, utils.WrapError(err, "error iterating post rows")
 	}
 
+	if err := r.loadTags(ctx, posts); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
+
+// CountTrending counts the posts ListTrending pages through
+func (r *postRepository) CountTrending(ctx context.Context, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT p.id)
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND ` + publishedPost + `
+		WHERE c.created_at > $1 AND c.deleted_at IS NULL`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, since).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count trending posts")
+	}
+
+	return total, nil
+}
+
+// GetCommentsStamp loads what identifies the current version of a post and its
+// comments for cache validation. Deleted comments are included, so deleting one moves the
+// latest change time and purging one changes the count. The authors' latest change
+// covers the post author and every commenter, since their profiles are embedded.
+func (r *postRepository) GetCommentsStamp(ctx context.Context, id uuid.UUID) (*models.PostCommentsStamp, error) {
+	query := `
+		SELECT p.id, p.version, p.updated_at, p.comments_locked,
+			COUNT(c.id), MAX(GREATEST(c.created_at, c.updated_at, c.deleted_at)),
+			(
+				SELECT MAX(GREATEST(u.updated_at, u.deleted_at))
+				FROM users u
+				WHERE u.id = p.created_by
+					OR u.id IN (SELECT author.created_by FROM comments author WHERE author.post_id = p.id)
+			)
+		FROM posts p
+		LEFT JOIN comments c ON c.post_id = p.id
+		WHERE p.id = $1 AND p.deleted_at IS NULL AND ` + publishedPost + `
+		GROUP BY p.id`
+
+	stamp := &models.PostCommentsStamp{}
+	var commentsUpdatedAt, authorsUpdatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&stamp.PostID,
+		&stamp.PostVersion,
+		&stamp.PostUpdatedAt,
+		&stamp.CommentsLocked,
+		&stamp.CommentCount,
+		&commentsUpdatedAt,
+		&authorsUpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrPostNotFound
+		}
+		return nil, utils.WrapError(err, "failed to get post comments stamp")
+	}
+
+	if commentsUpdatedAt.Valid {
+		stamp.CommentsUpdatedAt = &commentsUpdatedAt.Time
+	}
+	if authorsUpdatedAt.Valid {
+		stamp.AuthorsUpdatedAt = &authorsUpdatedAt.Time
+	}
+
+	return stamp, nil
+}
+
+// SetCommentsLocked opens or closes a post to new comments. It is not a content edit, so
+// updated_at and version are left alone.
+func (r *postRepository) SetCommentsLocked(id uuid.UUID, locked bool) error {
+	query := `
+		UPDATE posts
+		SET comments_locked = $1
+		WHERE id = $2 AND deleted_at IS NULL`
+
+	result, err := r.db.Exec(query, locked, id)
+	if err != nil {
+		return utils.WrapError(err, "failed to update post comment lock")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.WrapError(err, "failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return utils.ErrPostNotFound
+	}
+
+	return nil
+}
+
+// scanListedPost scans a post row from the list queries, which follow the author columns
+// with a correlated subquery counting the post's non-deleted comments, replies included,
+// and the post's tags. Both come back in the same query, so listing posts is one query.
+func scanListedPost(rows *sql.Rows) (*models.Post, error) {
+	var commentCount int
+	var tags pq.StringArray
+	post, err := scanPostWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+		return rows.Scan(append(dest, &commentCount, &tags)...)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	post.CommentCount = &commentCount
+	post.Tags = tagsOrEmpty(tags)
+	return post, nil
+}
+
+// tagsOrEmpty converts a scanned tag array, using an empty list rather than nil
+func tagsOrEmpty(tags pq.StringArray) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// scanPostWithAuthor scans a post row joined (LEFT JOIN) with its author columns. Posts
+// whose author was deleted stay visible with a placeholder author.
+func scanPostWithAuthor(row rowScanner) (*models.Post, error) {
+	var post models.Post
+	var authorID sql.NullString
+	var authorUsername sql.NullString
+	var authorEmail sql.NullString
+	var authorDisplayName sql.NullString
+	var authorAvatarURL sql.NullString
+	var authorCreatedAt sql.NullTime
+	var authorUpdatedAt sql.NullTime
+
+	err := row.Scan(
+		&post.ID,
+		&post.Title,
+		&post.Content,
+		&post.CreatedBy,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.Version,
+		&post.CommentsLocked,
+		&post.AllowAnonymousComments,
+		&post.PublishAt,
+		&authorID,
+		&authorUsername,
+		&authorEmail,
+		&authorDisplayName,
+		&authorAvatarURL,
+		&authorCreatedAt,
+		&authorUpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if !authorID.Valid {
+		post.Author = models.DeletedUserPlaceholder(post.CreatedBy)
+		return &post, nil
+	}
+
+	var author models.User
+	author.ID, _ = uuid.Parse(authorID.String)
+	author.Username = authorUsername.String
+	if authorEmail.Valid {
+		author.Email = &authorEmail.String
+	}
+	if authorDisplayName.Valid {
+		author.DisplayName = &authorDisplayName.String
+	}
+	if authorAvatarURL.Valid {
+		author.AvatarURL = &authorAvatarURL.String
+	}
+	author.CreatedAt = authorCreatedAt.Time
+	author.UpdatedAt = authorUpdatedAt.Time
+	post.Author = &author
+
+	return &post, nil
+}
+
+// loadTags fills in the tags of each post with a single query
+func (r *postRepository) loadTags(ctx context.Context, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(posts))
+	index := make(map[uuid.UUID]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID.String()
+		index[posts[i].ID] = i
+		posts[i].Tags = []string{}
+	}
+
+	query := `
+		SELECT pt.post_id, t.name
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id = ANY($1)
+		ORDER BY t.name`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return utils.WrapError(err, "failed to load post tags")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID uuid.UUID
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return utils.WrapError(err, "failed to scan post tag row")
+		}
+		if i, ok := index[postID]; ok {
+			posts[i].Tags = append(posts[i].Tags, name)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return utils.WrapError(err, "error iterating post tag rows")
+	}
+
+	return nil
+}
+
+// replacePostTags replaces a post's tag links with the given normalized tag names,
+// creating any tags that don't exist yet
+func replacePostTags(tx *sql.Tx, postID uuid.UUID, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_id = $1`, postID); err != nil {
+		return utils.WrapError(err, "failed to clear post tags")
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING`,
+		pq.Array(tags),
+	)
+	if err != nil {
+		return utils.WrapError(err, "failed to upsert tags")
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO post_tags (post_id, tag_id, created_at)
+		SELECT $1, id, $3
+		FROM tags
+		WHERE name = ANY($2)`,
+		postID, pq.Array(tags), time.Now(),
+	)
+	if err != nil {
+		return utils.WrapError(err, "failed to link post tags")
+	}
+
+	return nil
+}
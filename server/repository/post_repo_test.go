@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+var postWithCommentsColumns = []string{
+	"id", "title", "content", "created_by", "created_at", "updated_at", "version", "comments_locked", "allow_anonymous_comments", "publish_at",
+	"author_id", "username", "email", "display_name", "avatar_url", "author_created_at", "author_updated_at",
+	"tags",
+	"id", "content", "post_id", "parent_id", "path", "thread_id", "created_by", "created_at", "updated_at", "replies_count", "version", "attachments", "guest_name",
+	"author_id", "username", "email", "display_name", "avatar_url", "author_created_at", "author_updated_at",
+}
+
+// postWithCommentsRow builds one GetByIDWithComments result row; a nil commentID leaves
+// the comment columns NULL as the LEFT JOIN does for a post without comments
+func postWithCommentsRow(postID, authorID uuid.UUID, commentID, parentID *uuid.UUID, at time.Time) []driver.Value {
+	row := []driver.Value{
+		postID.String(), "Title", "Body", authorID.String(), at, at, 3, false, false, nil,
+		authorID.String(), "author", nil, nil, nil, at, at,
+		[]byte("{go,testing}"),
+	}
+	if commentID == nil {
+		return append(row, make([]driver.Value, 20)...)
+	}
+
+	path := "{" + commentID.String() + "}"
+	threadID := commentID.String()
+	var parent driver.Value
+	if parentID != nil {
+		parent = parentID.String()
+		path = "{" + parentID.String() + "," + commentID.String() + "}"
+		threadID = parentID.String()
+	}
+	return append(row,
+		commentID.String(), "comment", postID.String(), parent, []byte(path), threadID, authorID.String(), at, at, 0, 1, []byte("[]"), nil,
+		authorID.String(), "author", nil, nil, nil, at, at,
+	)
+}
+
+func TestGetByIDWithComments(t *testing.T) {
+	postID, authorID := uuid.New(), uuid.New()
+	newer, older, reply := uuid.New(), uuid.New(), uuid.New()
+	at := time.Now()
+
+	tests := []struct {
+		name         string
+		rows         [][]driver.Value
+		wantErr      error
+		wantComments []uuid.UUID
+		wantReplies  int
+	}{
+		{
+			name: "comments with a reply preview",
+			rows: [][]driver.Value{
+				postWithCommentsRow(postID, authorID, &newer, nil, at),
+				postWithCommentsRow(postID, authorID, &older, nil, at.Add(-time.Hour)),
+				postWithCommentsRow(postID, authorID, &reply, &older, at),
+			},
+			wantComments: []uuid.UUID{newer, older},
+			wantReplies:  1,
+		},
+		{
+			name:         "post without comments",
+			rows:         [][]driver.Value{postWithCommentsRow(postID, authorID, nil, nil, at)},
+			wantComments: []uuid.UUID{},
+		},
+		{
+			name:    "missing post",
+			wantErr: utils.ErrPostNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			rows := sqlmock.NewRows(postWithCommentsColumns)
+			for _, row := range tt.rows {
+				rows.AddRow(row...)
+			}
+			mock.ExpectQuery(`WITH post AS .+SELECT post\.\*, page\.\*`).WithArgs(postID, 50, 0, 3).WillReturnRows(rows)
+
+			post, err := NewPostRepository(db).GetByIDWithComments(context.Background(), postID, 50, 0, 3)
+			// Only the one statement was expected, so any further query fails the test
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if post.ID != postID || post.Author == nil || post.Author.Username != "author" {
+				t.Errorf("post or author not populated: %+v", post)
+			}
+			if len(post.Tags) != 2 || post.Tags[0] != "go" || post.Tags[1] != "testing" {
+				t.Errorf("tags = %v, want [go testing]", post.Tags)
+			}
+			if got := commentIDs(post.Comments); len(got) != len(tt.wantComments) {
+				t.Fatalf("comments = %v, want %v", got, tt.wantComments)
+			}
+			replies := 0
+			for i, comment := range post.Comments {
+				if comment.ID != tt.wantComments[i] {
+					t.Errorf("comment %d = %s, want %s", i, comment.ID, tt.wantComments[i])
+				}
+				replies += len(comment.Children)
+			}
+			if replies != tt.wantReplies {
+				t.Errorf("got %d nested replies, want %d", replies, tt.wantReplies)
+			}
+		})
+	}
+}
+
+func TestRestorePost(t *testing.T) {
+	tests := []struct {
+		name     string
+		restored int64
+		exists   bool
+		wantErr  error
+	}{
+		{"restores a deleted post", 1, true, nil},
+		{"post that is not deleted", 0, true, utils.ErrNotDeleted},
+		{"missing post", 0, false, utils.ErrPostNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			id := uuid.New()
+			mock.ExpectExec(`UPDATE posts\s+SET deleted_at = NULL\s+WHERE id = \$1 AND deleted_at IS NOT NULL`).
+				WithArgs(id).
+				WillReturnResult(sqlmock.NewResult(0, tt.restored))
+			if tt.restored == 0 {
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM posts WHERE id = \$1\)`).
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.exists))
+			}
+
+			err = NewPostRepository(db).Restore(id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDeleteImpact(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	postID := uuid.New()
+	// Seven live comments on the post: three top-level and four replies, written by two
+	// accounts and two guests
+	mock.ExpectQuery(`COUNT\(DISTINCT created_by\) \+ COUNT\(DISTINCT guest_name\) FILTER \(WHERE created_by IS NULL\)`).
+		WithArgs(postID).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "top_level", "authors"}).AddRow(7, 3, 4))
+
+	impact, err := NewPostRepository(db).DeleteImpact(postID)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if impact.PostID != postID || impact.Comments != 7 || impact.TopLevel != 3 || impact.Replies != 4 || impact.AffectedAuthors != 4 {
+		t.Errorf("got impact %+v, want 7 comments, 3 top-level, 4 replies and 4 authors", impact)
+	}
+	if impact.TopLevel+impact.Replies != impact.Comments {
+		t.Errorf("top-level %d and replies %d do not add up to %d comments", impact.TopLevel, impact.Replies, impact.Comments)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadQueriesStopOnCancelledContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewPostRepository(db).List(ctx, 20, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("List: got error %v, want context.Canceled", err)
+	}
+	if _, err := NewCommentRepository(db, models.RepliesCountModeTrigger).ListByPost(ctx, uuid.New(), "newest", 20, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListByPost: got error %v, want context.Canceled", err)
+	}
+
+	// Neither query may have reached the database
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetCommentsStamp(t *testing.T) {
+	postUpdatedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	commentsUpdatedAt := postUpdatedAt.Add(time.Hour)
+	authorsUpdatedAt := postUpdatedAt.Add(2 * time.Hour)
+
+	tests := []struct {
+		name             string
+		comments         driver.Value
+		authors          driver.Value
+		wantLastModified time.Time
+	}{
+		{"no comments and no author", nil, nil, postUpdatedAt},
+		{"comment changed last", commentsUpdatedAt, postUpdatedAt, commentsUpdatedAt},
+		{"author changed last", commentsUpdatedAt, authorsUpdatedAt, authorsUpdatedAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			id := uuid.New()
+			mock.ExpectQuery(`SELECT MAX\(GREATEST\(u.updated_at, u.deleted_at\)\)\s+FROM users u`).
+				WithArgs(id).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "version", "updated_at", "comments_locked", "count", "comments_updated_at", "authors_updated_at"}).
+					AddRow(id.String(), 3, postUpdatedAt, false, 2, tt.comments, tt.authors))
+
+			stamp, err := NewPostRepository(db).GetCommentsStamp(context.Background(), id)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := stamp.LastModified(); !got.Equal(tt.wantLastModified) {
+				t.Errorf("LastModified = %v, want %v", got, tt.wantLastModified)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestListIsOneQuery checks that List returns the comment count and tags of each post from
+// the list query itself: sqlmock fails any query beyond the single expected one
+func TestListIsOneQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	tagged, untagged, authorID := uuid.New(), uuid.New(), uuid.New()
+	columns := []string{
+		"id", "title", "content", "created_by", "created_at", "updated_at", "version", "comments_locked", "allow_anonymous_comments", "publish_at",
+		"author_id", "username", "email", "display_name", "avatar_url", "author_created_at", "author_updated_at",
+		"comment_count", "tags",
+	}
+	mock.ExpectQuery(`\(SELECT COUNT\(\*\) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL\),\s+ARRAY\(SELECT t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id ORDER BY t.name\)\s+FROM posts p`).
+		WithArgs(20, 40).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(tagged.String(), "Tagged", "Body", authorID.String(), at, at, 1, false, false, nil,
+				authorID.String(), "author", nil, nil, nil, at, at,
+				4, []byte("{go,testing}")).
+			AddRow(untagged.String(), "Untagged", "Body", authorID.String(), at, at, 1, false, false, nil,
+				authorID.String(), "author", nil, nil, nil, at, at,
+				0, []byte("{}")))
+
+	posts, err := NewPostRepository(db).List(context.Background(), 20, 40)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+
+	if posts[0].CommentCount == nil || *posts[0].CommentCount != 4 {
+		t.Errorf("tagged post CommentCount = %v, want 4", posts[0].CommentCount)
+	}
+	if got := strings.Join(posts[0].Tags, ","); got != "go,testing" {
+		t.Errorf("tagged post tags = %q, want go,testing", got)
+	}
+	if posts[1].CommentCount == nil || *posts[1].CommentCount != 0 {
+		t.Errorf("untagged post CommentCount = %v, want 0", posts[1].CommentCount)
+	}
+	if posts[1].Tags == nil || len(posts[1].Tags) != 0 {
+		t.Errorf("untagged post tags = %#v, want an empty list", posts[1].Tags)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
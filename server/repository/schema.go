@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/lib/pq"
+)
+
+// ExpectedSchemaVersion is the latest migration this code depends on. Bump it together
+// with every new file in migrations/.
+const ExpectedSchemaVersion = 25
+
+// pqUndefinedTable is the PostgreSQL error code for a missing relation
+const pqUndefinedTable = "42P01"
+
+// SchemaVersion returns the highest migration version recorded in schema_migrations,
+// or 0 when no migration has been recorded (including before the table itself exists).
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUndefinedTable {
+			return 0, nil
+		}
+		return 0, utils.WrapError(err, "failed to read schema version")
+	}
+
+	return version, nil
+}
+
+// repliesCountTriggers are the triggers from migration 002 that maintain replies_count
+var repliesCountTriggers = []string{"update_replies_count_trigger", "decrement_replies_count_trigger"}
+
+// CheckRepliesCountMode fails when replies are counted in code while the replies_count
+// triggers are still installed on comments, since every reply would then be counted twice
+func CheckRepliesCountMode(ctx context.Context, db *sql.DB, repliesCountMode string) error {
+	if repliesCountMode != models.RepliesCountModeApp {
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT tgname FROM pg_trigger
+		WHERE tgrelid = 'comments'::regclass AND NOT tgisinternal AND tgname = ANY($1)
+		ORDER BY tgname`,
+		pq.Array(repliesCountTriggers),
+	)
+	if err != nil {
+		return utils.WrapError(err, "failed to look up replies_count triggers")
+	}
+	defer rows.Close()
+
+	var installed []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return utils.WrapError(err, "failed to scan trigger name")
+		}
+		installed = append(installed, name)
+	}
+	if err := rows.Err(); err != nil {
+		return utils.WrapError(err, "failed to look up replies_count triggers")
+	}
+
+	if len(installed) > 0 {
+		return fmt.Errorf("replies_count is counted in code but triggers %s are installed on comments; drop them or use trigger mode", strings.Join(installed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+)
+
+func TestCheckRepliesCountMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		triggers []string
+		wantErr  string
+	}{
+		{"app mode without triggers", models.RepliesCountModeApp, nil, ""},
+		{"app mode with both triggers", models.RepliesCountModeApp, []string{"decrement_replies_count_trigger", "update_replies_count_trigger"}, "decrement_replies_count_trigger, update_replies_count_trigger"},
+		{"app mode with one trigger", models.RepliesCountModeApp, []string{"update_replies_count_trigger"}, "update_replies_count_trigger"},
+		{"trigger mode is not checked", models.RepliesCountModeTrigger, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.mode == models.RepliesCountModeApp {
+				rows := sqlmock.NewRows([]string{"tgname"})
+				for _, name := range tt.triggers {
+					rows.AddRow(name)
+				}
+				mock.ExpectQuery(`FROM pg_trigger`).WillReturnRows(rows)
+			}
+
+			err = CheckRepliesCountMode(context.Background(), db, tt.mode)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one naming %s", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// headlineOptions configures ts_headline snippets; matches are wrapped in <mark> tags
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+// headlineSource strips the markup from a stored HTML column before ts_headline picks
+// fragments from it, so a snippet never cuts through a tag or highlights a word inside
+// an attribute such as a link's href
+func headlineSource(column string) string {
+	return "regexp_replace(" + column + ", '<[^>]*>', ' ', 'g')"
+}
+
+// SearchRepository interface defines full-text search data access methods
+type SearchRepository interface {
+	SearchPosts(query string, limit, offset int) ([]models.PostSearchResult, error)
+	SearchComments(query string, limit, offset int) ([]models.CommentSearchResult, error)
+}
+
+// searchRepository implements SearchRepository interface
+type searchRepository struct {
+	db *sql.DB
+}
+
+// NewSearchRepository creates a new search repository instance
+func NewSearchRepository(db *sql.DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// SearchPosts retrieves posts matching the query ordered by relevance.
+// The query is passed through plainto_tsquery so operators and punctuation are treated as plain text.
+func (r *searchRepository) SearchPosts(query string, limit, offset int) ([]models.PostSearchResult, error) {
+	sqlQuery := `
+		SELECT p.id, p.title, p.content, p.created_by, p.created_at, p.updated_at, p.version, p.comments_locked, p.allow_anonymous_comments, p.publish_at,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       ts_rank(p.search_vector, q) AS rank,
+		       ts_headline('english', ` + headlineSource("p.content") + `, q, $4) AS snippet
+		FROM posts p
+		LEFT JOIN users u ON p.created_by = u.id AND u.deleted_at IS NULL
+		CROSS JOIN plainto_tsquery('english', $1) q
+		WHERE p.search_vector @@ q AND p.deleted_at IS NULL AND ` + publishedPost + `
+		ORDER BY rank DESC, p.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(sqlQuery, query, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to search posts")
+	}
+	defer rows.Close()
+
+	var results []models.PostSearchResult
+	for rows.Next() {
+		var result models.PostSearchResult
+
+		post, err := scanPostWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &result.Rank, &result.Snippet)...)
+		}))
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan post search row")
+		}
+
+		result.Post = *post
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating post search rows")
+	}
+
+	return results, nil
+}
+
+// SearchComments retrieves comments matching the query ordered by relevance
+func (r *searchRepository) SearchComments(query string, limit, offset int) ([]models.CommentSearchResult, error) {
+	sqlQuery := `
+		SELECT c.id, c.content, c.post_id, c.parent_id, c.path, c.thread_id, c.created_by, c.created_at, c.updated_at, c.replies_count, c.version, c.attachments, c.guest_name,
+		       u.id, u.username, u.email, u.display_name, u.avatar_url, u.created_at, u.updated_at,
+		       ts_rank(c.search_vector, q) AS rank,
+		       ts_headline('english', ` + headlineSource("c.content") + `, q, $4) AS snippet
+		FROM comments c
+		LEFT JOIN users u ON c.created_by = u.id AND u.deleted_at IS NULL
+		JOIN posts p ON c.post_id = p.id AND p.deleted_at IS NULL,
+		     plainto_tsquery('english', $1) q
+		WHERE c.search_vector @@ q AND c.deleted_at IS NULL
+		ORDER BY rank DESC, c.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(sqlQuery, query, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to search comments")
+	}
+	defer rows.Close()
+
+	var results []models.CommentSearchResult
+	for rows.Next() {
+		var rank float64
+		var snippet string
+
+		comment, err := scanCommentWithAuthor(rowScannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &rank, &snippet)...)
+		}))
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to scan comment search row")
+		}
+
+		results = append(results, models.CommentSearchResult{
+			Comment: *comment,
+			Rank:    rank,
+			Snippet: snippet,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating comment search rows")
+	}
+
+	return results, nil
+}
+
+// rowScannerFunc adapts a function to the rowScanner interface so extra
+// trailing columns can be scanned alongside a shared scan helper
+type rowScannerFunc func(dest ...interface{}) error
+
+// Scan implements rowScanner
+func (f rowScannerFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
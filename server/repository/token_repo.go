@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// TokenRepository interface defines revoked token data access methods
+type TokenRepository interface {
+	Revoke(jti, userID uuid.UUID, tokenType string, expiresAt time.Time) (bool, error)
+	IsRevoked(jti uuid.UUID) (bool, error)
+	DeleteExpired() (int, error)
+}
+
+// tokenRepository implements TokenRepository interface
+type tokenRepository struct {
+	db *sql.DB
+}
+
+// NewTokenRepository creates a new token repository instance
+func NewTokenRepository(db *sql.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// Revoke adds a token id to the denylist and reports whether this call revoked it.
+// Revoking an already revoked token is a no-op that reports false, so of two concurrent
+// revocations of the same token exactly one sees true.
+func (r *tokenRepository) Revoke(jti, userID uuid.UUID, tokenType string, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, token_type, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (jti) DO NOTHING`
+
+	result, err := r.db.Exec(query, jti, userID, tokenType, expiresAt, time.Now())
+	if err != nil {
+		return false, utils.WrapError(err, "failed to revoke token")
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.WrapError(err, "failed to get rows affected")
+	}
+
+	return inserted > 0, nil
+}
+
+// IsRevoked reports whether a token id is on the denylist
+func (r *tokenRepository) IsRevoked(jti uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	if err := r.db.QueryRow(query, jti).Scan(&revoked); err != nil {
+		return false, utils.WrapError(err, "failed to check token revocation")
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpired removes denylist entries for tokens that have expired on their own
+func (r *tokenRepository) DeleteExpired() (int, error) {
+	query := `DELETE FROM revoked_tokens WHERE expires_at < $1`
+
+	result, err := r.db.Exec(query, time.Now())
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to delete expired revoked tokens")
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, utils.WrapError(err, "failed to get rows affected")
+	}
+
+	return int(deleted), nil
+}
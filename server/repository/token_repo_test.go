@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        int64
+		wantRevoked bool
+	}{
+		{"inserts a denylist row", 1, true},
+		{"token already revoked", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			jti, userID := uuid.New(), uuid.New()
+			expiresAt := time.Now().Add(time.Hour)
+			mock.ExpectExec(`INSERT INTO revoked_tokens .+ON CONFLICT \(jti\) DO NOTHING`).
+				WithArgs(jti, userID, "refresh", expiresAt, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			revoked, err := NewTokenRepository(db).Revoke(jti, userID, "refresh", expiresAt)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/lib/pq"
+)
+
+// PostgreSQL error codes for transactions that lost a concurrency conflict and can be
+// retried from the start
+const (
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+)
+
+// serializableTxAttempts bounds how many times a conflicting transaction is retried
+const serializableTxAttempts = 3
+
+// serializableTx runs a transaction at SERIALIZABLE. Use it for writes that read and
+// then update counters or paths, so concurrent writers cannot lose each other's updates.
+var serializableTx = &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+// withTx runs fn inside a database transaction. The transaction is committed when fn
+// returns nil and rolled back otherwise (including on panic), so partial writes never persist.
+// Errors returned by fn are passed through unwrapped so sentinel errors keep working.
+// A nil opts uses the database default isolation level (READ COMMITTED). Transactions at
+// a stronger level are retried when PostgreSQL aborts them with a serialization failure
+// or deadlock, so fn must not have side effects outside the transaction.
+func withTx(db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	attempts := 1
+	if opts != nil && opts.Isolation != sql.LevelDefault {
+		attempts = serializableTxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = runTx(db, opts, fn)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+
+		if attempt < attempts {
+			utils.LogWarn("Retrying transaction after concurrency conflict", utils.LogFields{
+				"attempt": attempt,
+			})
+			time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+		}
+	}
+
+	return err
+}
+
+// runTx runs fn in a single transaction attempt
+func runTx(db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return utils.WrapError(err, "failed to begin transaction")
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return utils.WrapError(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// isRetryableTxError reports whether err is a serialization failure or deadlock
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var errSerialization = &pq.Error{Code: pqSerializationFailure}
+
+func TestWithTxRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         *sql.TxOptions
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"default isolation is not retried", nil, 1, 1, true},
+		{"serializable is retried after a conflict", serializableTx, 1, 2, false},
+		{"serializable gives up after the last attempt", serializableTx, serializableTxAttempts, serializableTxAttempts, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			for i := 0; i < tt.wantAttempts; i++ {
+				mock.ExpectBegin()
+				if i < tt.failures {
+					mock.ExpectRollback()
+				} else {
+					mock.ExpectCommit()
+				}
+			}
+
+			attempts := 0
+			err = withTx(db, tt.opts, func(tx *sql.Tx) error {
+				attempts++
+				if attempts <= tt.failures {
+					return errSerialization
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("ran %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestCreateReplyLostUpdate replays what PostgreSQL does when two replies to the same
+// comment are written concurrently with replies counted in code: the second writer's
+// transaction is aborted with a serialization failure instead of committing a count
+// computed from a stale row, and the retry increments the count again on top of the
+// first writer's.
+func TestCreateReplyLostUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	parentID := uuid.New()
+	reply := &models.Comment{ID: uuid.New(), PostID: uuid.New(), ParentID: &parentID, CreatedAt: time.Now()}
+	reply.Path = []uuid.UUID{parentID, reply.ID}
+	reply.ThreadID = parentID
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO comments`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SET replies_count = replies_count \+ 1`).WithArgs(parentID).WillReturnError(errSerialization)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO comments`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SET replies_count = replies_count \+ 1`).WithArgs(parentID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := NewCommentRepository(db, models.RepliesCountModeApp).Create(reply); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteReplyConflictIsNotCommitted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	id, parentID := uuid.New(), uuid.New()
+	for i := 0; i < serializableTxAttempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SET deleted_at = \$1`).
+			WithArgs(sqlmock.AnyArg(), id).
+			WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(parentID.String()))
+		mock.ExpectExec(`SET replies_count = GREATEST\(replies_count - 1, 0\)`).WithArgs(parentID).WillReturnError(errSerialization)
+		mock.ExpectRollback()
+	}
+
+	err = NewCommentRepository(db, models.RepliesCountModeApp).Delete(id)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pqSerializationFailure {
+		t.Fatalf("got error %v, want the serialization failure once retries run out", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,18 +10,32 @@ import (
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// pqUniqueViolation is the PostgreSQL error code for unique constraint violations
+const pqUniqueViolation = "23505"
+
 // UserRepository interface defines user data access methods
 type UserRepository interface {
 	Create(user *models.User) error
 	GetByID(id uuid.UUID) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
+	ListIDsByUsernames(usernames []string) ([]uuid.UUID, error)
 	GetByEmail(email string) (*models.User, error)
+	ExistsByUsername(username string) (bool, error)
+	ExistsByEmail(email string) (bool, error)
 	Update(id uuid.UUID, updates *models.UpdateUserRequest) (*models.User, error)
 	UpdatePassword(id uuid.UUID, hashedPassword string) error
 	Delete(id uuid.UUID) error
+	DeleteWithContent(id uuid.UUID, policy string) (*models.UserDeletionResult, error)
 	List(limit, offset int) ([]models.User, error)
+	AdminList(filter *models.AdminUserFilter, limit, offset int) ([]models.User, int, error)
+	IsUsernameTaken(username string, excludeID uuid.UUID) (bool, error)
+	GetLastUsernameChange(userID uuid.UUID) (*time.Time, error)
+	ChangeUsername(id uuid.UUID, oldUsername, newUsername string) error
+	Count() (int, error)
+	WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error
 }
 
 // userRepository implements UserRepository interface
@@ -36,8 +51,8 @@ func NewUserRepository(db *sql.DB) UserRepository {
 // Create creates a new user in the database
 func (r *userRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (id, username, email, password_hash, display_name, avatar_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		INSERT INTO users (id, username, email, password_hash, display_name, avatar_url, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	_, err := r.db.Exec(query,
 		user.ID,
@@ -46,11 +61,16 @@ func (r *userRepository) Create(user *models.User) error {
 		user.PasswordHash,
 		user.DisplayName,
 		user.AvatarURL,
+		user.Role,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+			return utils.ErrUserExists
+		}
 		return utils.WrapError(err, "failed to create user")
 	}
 
@@ -60,7 +80,7 @@ func (r *userRepository) Create(user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, display_name, avatar_url, created_at, updated_at
+		SELECT id, username, email, password_hash, display_name, avatar_url, role, banned, email_verified, created_at, updated_at
 		FROM users 
 		WHERE id = $1 AND deleted_at IS NULL`
 
@@ -72,6 +92,9 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 		&user.PasswordHash,
 		&user.DisplayName,
 		&user.AvatarURL,
+		&user.Role,
+		&user.Banned,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -86,12 +109,12 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-// GetByUsername retrieves a user by username
+// GetByUsername retrieves a user by username, ignoring case
 func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, display_name, avatar_url, created_at, updated_at
+		SELECT id, username, email, password_hash, display_name, avatar_url, role, banned, email_verified, created_at, updated_at
 		FROM users 
-		WHERE username = $1 AND deleted_at IS NULL`
+		WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL`
 
 	var user models.User
 	err := r.db.QueryRow(query, username).Scan(
@@ -101,6 +124,9 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.DisplayName,
 		&user.AvatarURL,
+		&user.Role,
+		&user.Banned,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -115,10 +141,49 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+// ListIDsByUsernames resolves usernames to the IDs of active users in one query,
+// ignoring case. Unknown usernames are skipped, so fewer IDs than names may be returned.
+func (r *userRepository) ListIDsByUsernames(usernames []string) ([]uuid.UUID, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	lowered := make([]string, len(usernames))
+	for i, username := range usernames {
+		lowered[i] = strings.ToLower(username)
+	}
+
+	query := `
+		SELECT id
+		FROM users
+		WHERE LOWER(username) = ANY($1) AND deleted_at IS NULL`
+
+	rows, err := r.db.Query(query, pq.Array(lowered))
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to resolve usernames")
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.WrapError(err, "failed to scan user ID")
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, utils.WrapError(err, "error iterating user ID rows")
+	}
+
+	return ids, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, display_name, avatar_url, created_at, updated_at
+		SELECT id, username, email, password_hash, display_name, avatar_url, role, banned, email_verified, created_at, updated_at
 		FROM users 
 		WHERE email = $1 AND deleted_at IS NULL`
 
@@ -130,6 +195,9 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.DisplayName,
 		&user.AvatarURL,
+		&user.Role,
+		&user.Banned,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -144,6 +212,32 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+// ExistsByUsername reports whether a non-deleted user has this username, ignoring case.
+// Unlike GetByUsername, a miss is (false, nil) so callers can tell it apart from a failed query.
+func (r *userRepository) ExistsByUsername(username string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := r.db.QueryRow(query, username).Scan(&exists); err != nil {
+		return false, utils.WrapError(err, "failed to check username existence")
+	}
+
+	return exists, nil
+}
+
+// ExistsByEmail reports whether a non-deleted user has exactly this email.
+// Unlike GetByEmail, a miss is (false, nil) so callers can tell it apart from a failed query.
+func (r *userRepository) ExistsByEmail(email string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := r.db.QueryRow(query, email).Scan(&exists); err != nil {
+		return false, utils.WrapError(err, "failed to check email existence")
+	}
+
+	return exists, nil
+}
+
 // Update updates a user's information
 func (r *userRepository) Update(id uuid.UUID, updates *models.UpdateUserRequest) (*models.User, error) {
 	// Build dynamic update query
@@ -157,15 +251,16 @@ func (r *userRepository) Update(id uuid.UUID, updates *models.UpdateUserRequest)
 		argIndex++
 	}
 
-	if updates.DisplayName != nil {
+	// A null display_name or avatar_url clears the column
+	if updates.DisplayName.Set {
 		setParts = append(setParts, fmt.Sprintf("display_name = $%d", argIndex))
-		args = append(args, updates.DisplayName)
+		args = append(args, updates.DisplayName.Ptr())
 		argIndex++
 	}
 
-	if updates.AvatarURL != nil {
+	if updates.AvatarURL.Set {
 		setParts = append(setParts, fmt.Sprintf("avatar_url = $%d", argIndex))
-		args = append(args, updates.AvatarURL)
+		args = append(args, updates.AvatarURL.Ptr())
 		argIndex++
 	}
 
@@ -256,10 +351,86 @@ func (r *userRepository) Delete(id uuid.UUID) error {
 	return nil
 }
 
+// DeleteWithContent soft-deletes a user and applies the deletion policy to their posts and
+// comments in one transaction, so the account is never gone while its content is half-handled.
+// Anonymize clears created_by and keeps the content; cascade delete soft-deletes it.
+func (r *userRepository) DeleteWithContent(id uuid.UUID, policy string) (*models.UserDeletionResult, error) {
+	result := &models.UserDeletionResult{UserID: id, Policy: policy}
+
+	err := r.WithTx(nil, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		res, err := tx.Exec(`
+			UPDATE users
+			SET deleted_at = $1
+			WHERE id = $2 AND deleted_at IS NULL`, now, id)
+		if err != nil {
+			return utils.WrapError(err, "failed to delete user")
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
+		if rowsAffected == 0 {
+			return utils.ErrUserNotFound
+		}
+
+		var postsQuery, commentsQuery string
+		args := []interface{}{id}
+		switch policy {
+		case models.UserDeletePolicyCascadeDelete:
+			postsQuery = `UPDATE posts SET deleted_at = $2 WHERE created_by = $1 AND deleted_at IS NULL`
+			commentsQuery = `UPDATE comments SET deleted_at = $2 WHERE created_by = $1 AND deleted_at IS NULL`
+			args = append(args, now)
+		default:
+			postsQuery = `UPDATE posts SET created_by = NULL WHERE created_by = $1`
+			commentsQuery = `UPDATE comments SET created_by = NULL WHERE created_by = $1`
+		}
+
+		res, err = tx.Exec(postsQuery, args...)
+		if err != nil {
+			return utils.WrapError(err, "failed to update posts of deleted user")
+		}
+		if result.Posts, err = res.RowsAffected(); err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
+
+		res, err = tx.Exec(commentsQuery, args...)
+		if err != nil {
+			return utils.WrapError(err, "failed to update comments of deleted user")
+		}
+		if result.Comments, err = res.RowsAffected(); err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Count counts the non-deleted users visible in List
+func (r *userRepository) Count() (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM users
+		WHERE deleted_at IS NULL`
+
+	var total int
+	if err := r.db.QueryRow(query).Scan(&total); err != nil {
+		return 0, utils.WrapError(err, "failed to count users")
+	}
+
+	return total, nil
+}
+
 // List retrieves a paginated list of users
 func (r *userRepository) List(limit, offset int) ([]models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, display_name, avatar_url, created_at, updated_at
+		SELECT id, username, email, password_hash, display_name, avatar_url, role, banned, email_verified, created_at, updated_at
 		FROM users 
 		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -281,6 +452,9 @@ func (r *userRepository) List(limit, offset int) ([]models.User, error) {
 			&user.PasswordHash,
 			&user.DisplayName,
 			&user.AvatarURL,
+			&user.Role,
+			&user.Banned,
+			&user.EmailVerified,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -296,3 +470,180 @@ func (r *userRepository) List(limit, offset int) ([]models.User, error) {
 
 	return users, nil
 }
+
+// AdminList retrieves a filtered, paginated list of users for administration along
+// with the total number of users matching the filter
+func (r *userRepository) AdminList(filter *models.AdminUserFilter, limit, offset int) ([]models.User, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter != nil {
+		if filter.Role != nil {
+			conditions = append(conditions, fmt.Sprintf("role = $%d", argIndex))
+			args = append(args, *filter.Role)
+			argIndex++
+		}
+		if filter.Banned != nil {
+			conditions = append(conditions, fmt.Sprintf("banned = $%d", argIndex))
+			args = append(args, *filter.Banned)
+			argIndex++
+		}
+		if filter.EmailVerified != nil {
+			conditions = append(conditions, fmt.Sprintf("email_verified = $%d", argIndex))
+			args = append(args, *filter.EmailVerified)
+			argIndex++
+		}
+		if filter.CreatedFrom != nil {
+			conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argIndex))
+			args = append(args, *filter.CreatedFrom)
+			argIndex++
+		}
+		if filter.CreatedTo != nil {
+			conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argIndex))
+			args = append(args, *filter.CreatedTo)
+			argIndex++
+		}
+	}
+
+	whereClause := strings.Join(conditions, " AND ")
+
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM users WHERE %s`, whereClause)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count users")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, username, email, password_hash, display_name, avatar_url, role, banned, email_verified, created_at, updated_at
+		FROM users
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`,
+		whereClause,
+		argIndex,
+		argIndex+1,
+	)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to list users for admin")
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.PasswordHash,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.Role,
+			&user.Banned,
+			&user.EmailVerified,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, utils.WrapError(err, "failed to scan user row")
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, utils.WrapError(err, "error iterating user rows")
+	}
+
+	return users, total, nil
+}
+
+// IsUsernameTaken reports whether a non-deleted user other than excludeID already
+// uses the username, compared case-insensitively
+func (r *userRepository) IsUsernameTaken(username string, excludeID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE LOWER(username) = LOWER($1) AND id <> $2 AND deleted_at IS NULL
+		)`
+
+	var taken bool
+	if err := r.db.QueryRow(query, username, excludeID).Scan(&taken); err != nil {
+		return false, utils.WrapError(err, "failed to check username availability")
+	}
+
+	return taken, nil
+}
+
+// GetLastUsernameChange returns when the user last changed their username, or nil if never
+func (r *userRepository) GetLastUsernameChange(userID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT MAX(changed_at)
+		FROM username_history
+		WHERE user_id = $1`
+
+	var changedAt sql.NullTime
+	if err := r.db.QueryRow(query, userID).Scan(&changedAt); err != nil {
+		return nil, utils.WrapError(err, "failed to get last username change")
+	}
+
+	if !changedAt.Valid {
+		return nil, nil
+	}
+	return &changedAt.Time, nil
+}
+
+// ChangeUsername updates a user's username and records the previous one in
+// username_history within a single transaction. A name that is already taken by
+// another active user is rejected by the case-insensitive unique index. A change in
+// casing only is not recorded, so it does not restart the change cooldown.
+func (r *userRepository) ChangeUsername(id uuid.UUID, oldUsername, newUsername string) error {
+	return r.WithTx(nil, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE users
+			SET username = $1, updated_at = $2
+			WHERE id = $3 AND deleted_at IS NULL`,
+			newUsername, time.Now(), id,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+				return utils.ErrUsernameAlreadyExists
+			}
+			return utils.WrapError(err, "failed to update username")
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return utils.WrapError(err, "failed to get rows affected")
+		}
+
+		if rowsAffected == 0 {
+			return utils.ErrUserNotFound
+		}
+
+		if strings.EqualFold(oldUsername, newUsername) {
+			return nil
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO username_history (user_id, old_username, new_username, changed_at)
+			VALUES ($1, $2, $3, $4)`,
+			id, oldUsername, newUsername, time.Now(),
+		)
+		if err != nil {
+			return utils.WrapError(err, "failed to record username history")
+		}
+
+		return nil
+	})
+}
+
+// WithTx runs fn inside a transaction that is rolled back if fn returns an error.
+// opts selects the isolation level; nil uses the database default.
+func (r *userRepository) WithTx(opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	return withTx(r.db, opts, fn)
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestChangeUsername(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldUsername string
+		newUsername string
+		updateErr   error
+		rows        int64
+		wantHistory bool
+		wantErr     error
+	}{
+		{"records the previous username", "alice", "alice_new", nil, 1, true, nil},
+		{"case-only change is not recorded", "alice", "Alice", nil, 1, false, nil},
+		{"unique index violation is a username conflict", "alice", "bob", &pq.Error{Code: pqUniqueViolation}, 0, false, utils.ErrUsernameAlreadyExists},
+		{"missing user", "alice", "alice_new", nil, 0, false, utils.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			id := uuid.New()
+			mock.ExpectBegin()
+			update := mock.ExpectExec(`UPDATE users\s+SET username = \$1`).WithArgs(tt.newUsername, sqlmock.AnyArg(), id)
+			if tt.updateErr != nil {
+				update.WillReturnError(tt.updateErr)
+			} else {
+				update.WillReturnResult(sqlmock.NewResult(0, tt.rows))
+			}
+			if tt.wantHistory {
+				mock.ExpectExec(`INSERT INTO username_history`).
+					WithArgs(id, tt.oldUsername, tt.newUsername, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.wantErr == nil {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewUserRepository(db).ChangeUsername(id, tt.oldUsername, tt.newUsername)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUpdateNullableFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantSet    string
+		wantValues []driver.Value
+	}{
+		{"null clears avatar_url", `{"avatar_url":null}`, `SET avatar_url = \$1, updated_at = \$2`, []driver.Value{nil}},
+		{"value sets avatar_url", `{"avatar_url":"https://example.com/a.png"}`, `SET avatar_url = \$1, updated_at = \$2`, []driver.Value{"https://example.com/a.png"}},
+		{"absent fields are left alone", `{"display_name":null}`, `SET display_name = \$1, updated_at = \$2`, []driver.Value{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			var updates models.UpdateUserRequest
+			if err := json.Unmarshal([]byte(tt.body), &updates); err != nil {
+				t.Fatal(err)
+			}
+
+			id := uuid.New()
+			args := append(append([]driver.Value{}, tt.wantValues...), sqlmock.AnyArg(), id)
+			mock.ExpectExec(`UPDATE users\s+` + tt.wantSet + `\s+WHERE id = \$3`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(`FROM users`).WithArgs(id).WillReturnRows(sqlmock.NewRows(
+				[]string{"id", "username", "email", "password_hash", "display_name", "avatar_url", "role", "banned", "email_verified", "created_at", "updated_at"},
+			).AddRow(id.String(), "alice", nil, "hash", nil, nil, models.RoleUser, false, false, time.Now(), time.Now()))
+
+			user, err := NewUserRepository(db).Update(id, &updates)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if user.ID != id {
+				t.Errorf("got user %s, want %s", user.ID, id)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAdminListFilters(t *testing.T) {
+	moderator, banned := models.RoleModerator, true
+
+	tests := []struct {
+		name      string
+		filter    *models.AdminUserFilter
+		wantWhere string
+		wantArgs  []driver.Value
+		wantLimit string
+	}{
+		{"no filters", nil, "deleted_at IS NULL", nil, "LIMIT $1 OFFSET $2"},
+		{"role", &models.AdminUserFilter{Role: &moderator}, "deleted_at IS NULL AND role = $1", []driver.Value{moderator}, "LIMIT $2 OFFSET $3"},
+		{"ban status", &models.AdminUserFilter{Banned: &banned}, "deleted_at IS NULL AND banned = $1", []driver.Value{banned}, "LIMIT $2 OFFSET $3"},
+		{"role and ban status", &models.AdminUserFilter{Role: &moderator, Banned: &banned}, "deleted_at IS NULL AND role = $1 AND banned = $2", []driver.Value{moderator, banned}, "LIMIT $3 OFFSET $4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE " + tt.wantWhere)).
+				WithArgs(tt.wantArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+			now := time.Now()
+			rows := sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "display_name", "avatar_url", "role", "banned", "email_verified", "created_at", "updated_at"}).
+				AddRow(uuid.New(), "mod_one", nil, "hash", nil, nil, moderator, banned, false, now, now)
+			mock.ExpectQuery(regexp.QuoteMeta("WHERE "+tt.wantWhere) + `\s+ORDER BY created_at DESC\s+` + regexp.QuoteMeta(tt.wantLimit)).
+				WithArgs(append(tt.wantArgs, 20, 40)...).
+				WillReturnRows(rows)
+
+			users, total, err := NewUserRepository(db).AdminList(tt.filter, 20, 40)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if total != 7 {
+				t.Errorf("got total %d, want 7 from the count query", total)
+			}
+			if len(users) != 1 || users[0].Role != moderator || !users[0].Banned {
+				t.Errorf("got users %+v, want the one scanned row", users)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// rowQueryer is satisfied by both *sql.DB and *sql.Tx
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// missingOrStale explains why a versioned UPDATE on table matched no rows: notFound when
+// the row is gone or deleted, ErrVersionConflict when it exists at a different version.
+// table must be a trusted identifier, never user input.
+func missingOrStale(q rowQueryer, table string, id uuid.UUID, notFound error) error {
+	query := `SELECT EXISTS(SELECT 1 FROM ` + table + ` WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := q.QueryRow(query, id).Scan(&exists); err != nil {
+		return utils.WrapError(err, "failed to check "+table+" existence")
+	}
+
+	if exists {
+		return utils.ErrVersionConflict
+	}
+	return notFound
+}
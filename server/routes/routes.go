@@ -1,12 +1,27 @@
 package routes
 
 import (
+	"github.com/TejasThombare20/post-comments-service/config"
 	"github.com/TejasThombare20/post-comments-service/controllers"
 	"github.com/TejasThombare20/post-comments-service/middleware"
+	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/services"
+	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// multipartOverheadBytes is the room left for multipart boundaries and part headers on
+// top of the file size allowed by an upload route
+const multipartOverheadBytes = 64 << 10
+
+// BodySizeLimits returns the request body limits of routes that override the global
+// MAX_REQUEST_BODY_BYTES, keyed by route template
+func BodySizeLimits() map[string]int64 {
+	return map[string]int64{
+		"/api/v1/users/:userId/avatar": services.MaxAvatarBytes + multipartOverheadBytes,
+	}
+}
+
 // SetupRoutes configures all the routes for the application
 func SetupRoutes(
 	router *gin.Engine,
@@ -14,18 +29,26 @@ func SetupRoutes(
 	postController *controllers.PostController,
 	commentController *controllers.CommentController,
 	authController *controllers.AuthController,
+	searchController *controllers.SearchController,
+	healthController *controllers.HealthController,
+	metaController *controllers.MetaController,
+	adminController *controllers.AdminController,
 	jwtService *services.JWTService,
+	uploads *config.UploadsConfig,
 ) {
-	// Add CORS middleware
-	router.Use(middleware.CORS())
-
 	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Post-Comments Service is running",
-		})
-	})
+	router.GET("/health", healthController.Health)
+	router.GET("/health/schema", healthController.Schema)
+
+	// Liveness and readiness probes
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(utils.MetricsHandler()))
+
+	// Uploaded files such as avatars (no directory listing)
+	router.Static(uploads.URLPrefix, uploads.Dir)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
@@ -36,7 +59,9 @@ func SetupRoutes(
 			auth.POST("/register", authController.Register)                                                     // POST /api/v1/auth/register
 			auth.POST("/login", authController.Login)                                                           // POST /api/v1/auth/login
 			auth.POST("/refresh", authController.RefreshToken)                                                  // POST /api/v1/auth/refresh
-			auth.POST("/logout", authController.Logout)                                                         // POST /api/v1/auth/logout
+			auth.POST("/logout", middleware.AuthMiddleware(jwtService), authController.Logout)                  // POST /api/v1/auth/logout
+			auth.POST("/forgot-password", authController.ForgotPassword)                                        // POST /api/v1/auth/forgot-password
+			auth.POST("/reset-password", authController.ResetPassword)                                          // POST /api/v1/auth/reset-password
 			auth.GET("/profile", middleware.AuthMiddleware(jwtService), authController.GetProfile)              // GET /api/v1/auth/profile
 			auth.POST("/change-password", middleware.AuthMiddleware(jwtService), authController.ChangePassword) // POST /api/v1/auth/change-password
 		}
@@ -44,52 +69,121 @@ func SetupRoutes(
 		// Public user routes (read-only)
 		users := v1.Group("/users")
 		{
-			users.GET("", userController.ListUsers)                            // GET /api/v1/users
-			users.GET("/username/:username", userController.GetUserByUsername) // GET /api/v1/users/username/:username
-			users.GET("/:userId/posts", postController.ListPostsByUser)        // GET /api/v1/users/:userId/posts
-			users.GET("/user/:id", userController.GetUserByID)                 // GET /api/v1/users/:id
+			users.GET("", userController.ListUsers)                              // GET /api/v1/users
+			users.GET("/username/:username", userController.GetUserByUsername)   // GET /api/v1/users/username/:username
+			users.GET("/:userId/posts", postController.ListPostsByUser)          // GET /api/v1/users/:userId/posts
+			users.GET("/:userId/comments", commentController.ListCommentsByUser) // GET /api/v1/users/:userId/comments
+			users.GET("/user/:id", userController.GetUserByID)                   // GET /api/v1/users/:id
 		}
 
 		// Protected user routes (require authentication)
 		protectedUsers := v1.Group("/users")
 		protectedUsers.Use(middleware.AuthMiddleware(jwtService))
 		{
-			protectedUsers.PUT("/user/:id", userController.UpdateUser)    // PUT /api/v1/users/:id
-			protectedUsers.DELETE("/user/:id", userController.DeleteUser) // DELETE /api/v1/users/:id
+			protectedUsers.PUT("/user/:id", userController.UpdateUser)                 // PUT /api/v1/users/:id
+			protectedUsers.PATCH("/user/:id", userController.UpdateUser)               // PATCH /api/v1/users/:id
+			protectedUsers.DELETE("/user/:id", userController.DeleteUser)              // DELETE /api/v1/users/:id
+			protectedUsers.GET("/:userId/mentions", commentController.GetUserMentions) // GET /api/v1/users/:userId/mentions
+			protectedUsers.POST("/:userId/avatar", userController.UploadAvatar)        // POST /api/v1/users/:userId/avatar
 		}
 
-		// Public post routes (read-only)
+		// Public post routes (read-only). GET /posts/:id/comments is the paginated flat
+		// comment list; the post together with its comments is at /posts/:id/with-comments.
 		posts := v1.Group("/posts")
 		{
-			posts.GET("", postController.ListPosts)                                   // GET /api/v1/posts
-			posts.GET("/post/:id", postController.GetPost)                            // GET /api/v1/posts/:id
-			posts.GET("/post/:id/comments", postController.GetPostWithComments)       // GET /api/v1/posts/:id/comments
-			posts.GET("/post-comments/:postId", commentController.ListCommentsByPost) // GET /api/v1/posts/:postId/comments
+			posts.GET("", postController.ListPosts)                                 // GET /api/v1/posts
+			posts.GET("/tag/:tag", postController.ListPostsByTag)                   // GET /api/v1/posts/tag/:tag
+			posts.GET("/trending", postController.ListTrending)                     // GET /api/v1/posts/trending
+			posts.GET("/:id/with-comments", postController.GetPostWithComments)     // GET /api/v1/posts/:id/with-comments
+			posts.GET("/:id/comments", commentController.ListCommentsByPost)        // GET /api/v1/posts/:id/comments
+			posts.GET("/:id/comments/full", commentController.GetCommentTree)       // GET /api/v1/posts/:id/comments/full
+			posts.GET("/:id/comments/embed", commentController.GetEmbedCommentTree) // GET /api/v1/posts/:id/comments/embed
+			posts.GET("/:id/comments/live", commentController.StreamComments)       // GET /api/v1/posts/:id/comments/live (WebSocket)
+			posts.GET("/:id/comments/stream", commentController.StreamCommentsSSE)  // GET /api/v1/posts/:id/comments/stream (Server-Sent Events)
+			posts.GET("/:id/threads", commentController.ListThreads)                // GET /api/v1/posts/:id/threads
+		}
+
+		// Post routes with optional authentication
+		optionalAuthPosts := v1.Group("/posts")
+		optionalAuthPosts.Use(middleware.OptionalAuthMiddleware(jwtService))
+		{
+			optionalAuthPosts.GET("/:id", postController.GetPost)                        // GET /api/v1/posts/:id (authors also see their scheduled posts)
+			optionalAuthPosts.GET("/:id/permissions", postController.GetPostPermissions) // GET /api/v1/posts/:id/permissions
+			optionalAuthPosts.POST("/:id/comments", commentController.CreateComment)     // POST /api/v1/posts/:id/comments (guests allowed where the post permits)
 		}
 
 		// Protected post routes (require authentication)
 		protectedPosts := v1.Group("/posts")
 		protectedPosts.Use(middleware.AuthMiddleware(jwtService))
 		{
-			protectedPosts.POST("", postController.CreatePost)                             // POST /api/v1/posts
-			protectedPosts.PUT("/post/:id", postController.UpdatePost)                     // PUT /api/v1/posts/:id
-			protectedPosts.DELETE("/post/:id", postController.DeletePost)                  // DELETE /api/v1/posts/:id
-			protectedPosts.POST("/post-comments/:postId", commentController.CreateComment) // POST /api/v1/posts/:postId/comments
+			protectedPosts.POST("", postController.CreatePost)                       // POST /api/v1/posts
+			protectedPosts.GET("/scheduled", postController.ListScheduledPosts)      // GET /api/v1/posts/scheduled
+			protectedPosts.PUT("/:id", postController.UpdatePost)                    // PUT /api/v1/posts/:id
+			protectedPosts.DELETE("/:id", postController.DeletePost)                 // DELETE /api/v1/posts/:id
+			protectedPosts.GET("/:id/delete-impact", postController.GetDeleteImpact) // GET /api/v1/posts/:id/delete-impact
+			protectedPosts.PUT("/:id/lock", postController.LockComments)             // PUT /api/v1/posts/:id/lock
+			protectedPosts.PUT("/:id/unlock", postController.UnlockComments)         // PUT /api/v1/posts/:id/unlock
+		}
+
+		// Admin post routes (require admin role)
+		adminPosts := v1.Group("/posts")
+		adminPosts.Use(middleware.AuthMiddleware(jwtService), middleware.RequireRole(models.RoleAdmin))
+		{
+			adminPosts.POST("/:id/comments/import", commentController.ImportComments) // POST /api/v1/posts/:id/comments/import
 		}
 
 		// Public comment routes (read-only)
 		comments := v1.Group("/comments")
 		{
-			comments.GET("/:id", commentController.GetComment)                // GET /api/v1/comments/:id
-			comments.GET("/:id/replies", commentController.GetCommentReplies) // GET /api/v1/comments/:id/replies
+			comments.GET("/:id", commentController.GetComment)                        // GET /api/v1/comments/:id
+			comments.GET("/:id/replies", commentController.GetCommentReplies)         // GET /api/v1/comments/:id/replies
+			comments.GET("/:id/descendants", commentController.GetCommentDescendants) // GET /api/v1/comments/:id/descendants
+			comments.GET("/:id/full", commentController.GetCommentPermalink)          // GET /api/v1/comments/:id/full
+			comments.POST("/batch", commentController.BatchGetComments)               // POST /api/v1/comments/batch
+		}
+
+		// Comment routes with optional authentication
+		optionalAuthComments := v1.Group("/comments")
+		optionalAuthComments.Use(middleware.OptionalAuthMiddleware(jwtService))
+		{
+			optionalAuthComments.GET("/:id/permissions", commentController.GetCommentPermissions) // GET /api/v1/comments/:id/permissions
 		}
 
 		// Protected comment routes (require authentication)
 		protectedComments := v1.Group("/comments")
 		protectedComments.Use(middleware.AuthMiddleware(jwtService))
 		{
-			protectedComments.PUT("/:id", commentController.UpdateComment)    // PUT /api/v1/comments/:id
-			protectedComments.DELETE("/:id", commentController.DeleteComment) // DELETE /api/v1/comments/:id
+			protectedComments.PUT("/:id", commentController.UpdateComment)                                                // PUT /api/v1/comments/:id
+			protectedComments.DELETE("/:id", commentController.DeleteComment)                                             // DELETE /api/v1/comments/:id
+			protectedComments.GET("/:id/source", commentController.GetCommentSource)                                      // GET /api/v1/comments/:id/source
+			protectedComments.PUT("/:id/parent", middleware.RequireRole(models.RoleAdmin), commentController.MoveComment) // PUT /api/v1/comments/:id/parent
+		}
+
+		// Search routes (public)
+		v1.GET("/search", searchController.Search) // GET /api/v1/search
+
+		// Meta routes (public)
+		v1.GET("/meta/constraints", metaController.GetConstraints) // GET /api/v1/meta/constraints
+
+		// Moderation routes (require moderator or admin role)
+		moderation := v1.Group("/admin")
+		moderation.Use(middleware.AuthMiddleware(jwtService), middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+		{
+			moderation.GET("/comments/search", commentController.SearchAllComments)               // GET /api/v1/admin/comments/search
+			moderation.GET("/posts/:id/participant-stats", commentController.GetParticipantStats) // GET /api/v1/admin/posts/:id/participant-stats
+			moderation.PATCH("/comments/:id/reparent", commentController.MoveComment)             // PATCH /api/v1/admin/comments/:id/reparent
+		}
+
+		// Admin routes (require admin role)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(jwtService), middleware.RequireRole(models.RoleAdmin))
+		{
+			admin.GET("/users", userController.AdminListUsers)                                 // GET /api/v1/admin/users
+			admin.GET("/db-stats", adminController.DBStats)                                    // GET /api/v1/admin/db-stats
+			admin.POST("/comments/reconcile-counts", commentController.ReconcileRepliesCounts) // POST /api/v1/admin/comments/reconcile-counts
+			admin.POST("/comments/purge-deleted", commentController.PurgeDeletedComments)      // POST /api/v1/admin/comments/purge-deleted
+			admin.POST("/posts/:id/restore", postController.RestorePost)                       // POST /api/v1/admin/posts/:id/restore
+			admin.POST("/comments/:id/restore", commentController.RestoreComment)              // POST /api/v1/admin/comments/:id/restore
 		}
 	}
 }
@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+// TestSetupRoutesRegistersWithoutConflicts builds the full route table. gin panics on
+// registration when two routes declare conflicting wildcards, so this fails if a new
+// route clashes with an existing one. Handlers are never called, so no services are needed.
+func TestSetupRoutesRegistersWithoutConflicts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("route registration panicked: %v", r)
+			}
+		}()
+		SetupRoutes(router,
+			controllers.NewUserController(nil),
+			controllers.NewPostController(nil, nil),
+			controllers.NewCommentController(nil, nil),
+			controllers.NewAuthController(nil, nil, nil),
+			controllers.NewSearchController(nil),
+			controllers.NewHealthController(nil),
+			controllers.NewMetaController(nil),
+			controllers.NewAdminController(nil),
+			nil,
+			&config.UploadsConfig{Dir: t.TempDir(), URLPrefix: "/uploads"},
+		)
+	}()
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, want := range []string{
+		"GET /api/v1/posts/:id",
+		"GET /api/v1/posts/:id/comments",
+		"GET /api/v1/posts/:id/with-comments",
+		"GET /api/v1/posts/trending",
+		"POST /api/v1/posts",
+		"GET /api/v1/comments/:id",
+		"PUT /api/v1/comments/:id/parent",
+		"PATCH /api/v1/admin/comments/:id/reparent",
+	} {
+		if !registered[want] {
+			t.Errorf("route %s is not registered", want)
+		}
+	}
+}
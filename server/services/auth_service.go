@@ -1,42 +1,62 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/repository"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/TejasThombare20/post-comments-service/validator"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthService interface defines authentication business logic methods
 type AuthService interface {
-	Register(req *models.RegisterRequest) (*models.AuthResponse, error)
-	Login(req *models.LoginRequest) (*models.AuthResponse, error)
-	RefreshToken(refreshToken string) (*models.AuthResponse, error)
-	ChangePassword(userID uuid.UUID, req *models.ChangePasswordRequest) error
+	Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error)
+	Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*models.AuthResponse, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) error
+	Logout(ctx context.Context, accessClaims *models.JWTClaims, refreshToken *string) error
+	RequestPasswordReset(ctx context.Context, email, clientIP string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
 }
 
+// passwordResetTokenTTL is how long a password reset token stays valid
+const passwordResetTokenTTL = 30 * time.Minute
+
 // authService implements AuthService interface
 type authService struct {
-	userRepo    repository.UserRepository
-	jwtService  *JWTService
-	userService UserService
-	validator   *validator.Validator
+	userRepo          repository.UserRepository
+	passwordResetRepo repository.PasswordResetRepository
+	jwtService        *JWTService
+	userService       UserService
+	emailSender       EmailSender
+	passwordHasher    utils.PasswordHasher
+	validator         *validator.Validator
+	resetConfig       *config.PasswordResetConfig
 }
 
 // NewAuthService creates a new authentication service instance
-func NewAuthService(userRepo repository.UserRepository, jwtService *JWTService, userService UserService, validator *validator.Validator) AuthService {
+func NewAuthService(userRepo repository.UserRepository, passwordResetRepo repository.PasswordResetRepository, jwtService *JWTService, userService UserService, emailSender EmailSender, passwordHasher utils.PasswordHasher, validator *validator.Validator, resetConfig *config.PasswordResetConfig) AuthService {
 	return &authService{
-		userRepo:    userRepo,
-		jwtService:  jwtService,
-		userService: userService,
-		validator:   validator,
+		userRepo:          userRepo,
+		passwordResetRepo: passwordResetRepo,
+		jwtService:        jwtService,
+		userService:       userService,
+		emailSender:       emailSender,
+		passwordHasher:    passwordHasher,
+		validator:         validator,
+		resetConfig:       resetConfig,
 	}
 }
 
 // Register creates a new user account and returns authentication tokens
-func (s *authService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+func (s *authService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return nil, err
 	}
@@ -54,7 +74,7 @@ func (s *authService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		return nil, err
 	}
 
-	authResponse, err := s.jwtService.GenerateTokenPair(user)
+	authResponse, err := s.jwtService.GenerateTokenPair(ctx, user)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to generate tokens")
 	}
@@ -63,7 +83,7 @@ func (s *authService) Register(req *models.RegisterRequest) (*models.AuthRespons
 }
 
 // Login authenticates a user and returns authentication tokens
-func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return nil, err
 	}
@@ -77,11 +97,11 @@ func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 		return nil, utils.ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.Password)); err != nil {
+	if err := s.passwordHasher.Compare(*user.PasswordHash, req.Password); err != nil {
 		return nil, utils.ErrInvalidCredentials
 	}
 
-	authResponse, err := s.jwtService.GenerateTokenPair(user)
+	authResponse, err := s.jwtService.GenerateTokenPair(ctx, user)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to generate tokens")
 	}
@@ -90,8 +110,8 @@ func (s *authService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 }
 
 // RefreshToken generates new tokens using a valid refresh token
-func (s *authService) RefreshToken(refreshToken string) (*models.AuthResponse, error) {
-	authResponse, err := s.jwtService.RefreshToken(refreshToken, s.userService)
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	authResponse, err := s.jwtService.RefreshToken(ctx, refreshToken, s.userService)
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +119,169 @@ func (s *authService) RefreshToken(refreshToken string) (*models.AuthResponse, e
 	return authResponse, nil
 }
 
+// Logout revokes the current access token and, when supplied, the caller's refresh token
+func (s *authService) Logout(ctx context.Context, accessClaims *models.JWTClaims, refreshToken *string) error {
+	if refreshToken != nil && *refreshToken != "" {
+		refreshClaims, err := s.jwtService.ValidateToken(ctx, *refreshToken)
+		if err != nil || refreshClaims.Type != "refresh" || refreshClaims.UserID != accessClaims.UserID {
+			return utils.ErrUnauthorized
+		}
+
+		if err := s.jwtService.RevokeToken(ctx, refreshClaims); err != nil {
+			return utils.WrapError(err, "failed to revoke refresh token")
+		}
+	}
+
+	if err := s.jwtService.RevokeToken(ctx, accessClaims); err != nil {
+		return utils.WrapError(err, "failed to revoke access token")
+	}
+
+	return nil
+}
+
+// RequestPasswordReset emails a single-use reset token to the account with the given email.
+// It returns nil when no such account exists, or when the send is throttled, so callers
+// cannot probe for registered emails.
+func (s *authService) RequestPasswordReset(ctx context.Context, email, clientIP string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil && !utils.IsNotFoundError(err) {
+		return err
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		return utils.WrapError(err, "failed to generate reset token")
+	}
+
+	now := time.Now()
+	resetToken := &models.PasswordResetToken{
+		ID:          uuid.New(),
+		TokenHash:   hashResetToken(token),
+		RequestedIP: clientIP,
+		ExpiresAt:   now.Add(passwordResetTokenTTL),
+		CreatedAt:   now,
+	}
+	if user != nil {
+		resetToken.UserID = user.ID
+	} else {
+		// Unknown emails get no email, but the request is still recorded so that it counts
+		// against the requesting IP, like a real send would
+		resetToken.UsedAt = &now
+	}
+
+	var since time.Time
+	if s.resetConfig != nil {
+		since = now.Add(-s.resetConfig.Window)
+	}
+
+	created, err := s.passwordResetRepo.CreateIfAllowed(resetToken, since, func(history models.PasswordResetSendHistory) bool {
+		return s.allowPasswordResetSend(ctx, resetToken.UserID, clientIP, history)
+	})
+	if err != nil || !created || user == nil {
+		return err
+	}
+
+	body := "Use this token to reset your password: " + token + "\n\n" +
+		"It expires in " + passwordResetTokenTTL.String() + ". If you did not request a reset, ignore this email."
+
+	// Delivery failures are logged rather than returned so the response does not reveal the account exists
+	if err := s.emailSender.Send(ctx, email, "Reset your password", body); err != nil {
+		utils.LogErrorContext(ctx, "Failed to send password reset email", err, utils.LogFields{
+			"user_id": user.ID,
+		})
+	}
+
+	return nil
+}
+
+// allowPasswordResetSend reports whether another reset email may be sent, given what was
+// recently issued. Issued tokens double as the send log: a send is refused within the
+// cooldown of the previous one, or once the account or the requesting IP reaches its
+// limit for the window. userID is uuid.Nil for an unknown email, which only the IP limit
+// applies to.
+func (s *authService) allowPasswordResetSend(ctx context.Context, userID uuid.UUID, clientIP string, history models.PasswordResetSendHistory) bool {
+	if s.resetConfig == nil {
+		return true
+	}
+
+	reason := ""
+	switch {
+	case history.LastSentAt != nil && time.Since(*history.LastSentAt) < s.resetConfig.Cooldown:
+		reason = "cooldown"
+	case userID != uuid.Nil && history.SentToUser >= s.resetConfig.MaxPerEmail:
+		reason = "email_limit"
+	case clientIP != "" && history.SentFromIP >= s.resetConfig.MaxPerIP:
+		reason = "ip_limit"
+	}
+
+	if reason != "" {
+		utils.LogWarnContext(ctx, "Password reset email throttled", utils.LogFields{
+			"user_id":   userID,
+			"client_ip": clientIP,
+			"reason":    reason,
+		})
+		return false
+	}
+
+	return true
+}
+
+// ResetPassword sets a new password using a valid, unexpired and unused reset token
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	resetToken, err := s.passwordResetRepo.GetByTokenHash(hashResetToken(token))
+	if err != nil {
+		return err
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return utils.WrapError(utils.ErrInvalidInput, "invalid or expired reset token")
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return utils.WrapError(err, "failed to hash new password")
+	}
+
+	// Consume the token before changing the password so it cannot be replayed concurrently
+	consumed, err := s.passwordResetRepo.MarkUsed(resetToken.ID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return utils.WrapError(utils.ErrInvalidInput, "invalid or expired reset token")
+	}
+
+	if err := s.userService.UpdatePassword(resetToken.UserID, hashedPassword); err != nil {
+		return utils.WrapError(err, "failed to update password")
+	}
+
+	// Any other outstanding reset links for the account are no longer needed
+	if err := s.passwordResetRepo.InvalidateForUser(resetToken.UserID); err != nil {
+		utils.LogErrorContext(ctx, "Failed to invalidate remaining password reset tokens", err, utils.LogFields{
+			"user_id": resetToken.UserID,
+		})
+	}
+
+	return nil
+}
+
+// generateResetToken returns a random URL-safe reset token
+func generateResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashResetToken returns the SHA-256 hash under which a reset token is stored
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // ChangePassword changes a user's password
-func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswordRequest) error {
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) error {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return err
 	}
@@ -114,16 +295,16 @@ func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswor
 		return utils.ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+	if err := s.passwordHasher.Compare(*user.PasswordHash, req.CurrentPassword); err != nil {
 		return utils.ErrInvalidCredentials
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.NewPassword)
 	if err != nil {
 		return utils.WrapError(err, "failed to hash new password")
 	}
 
-	if err := s.userService.UpdatePassword(userID, string(hashedPassword)); err != nil {
+	if err := s.userService.UpdatePassword(userID, hashedPassword); err != nil {
 		return utils.WrapError(err, "failed to update password")
 	}
 
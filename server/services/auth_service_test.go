@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/validator"
+)
+
+func TestRequestPasswordResetThrottling(t *testing.T) {
+	resetConfig := &config.PasswordResetConfig{Cooldown: time.Minute, MaxPerEmail: 5, MaxPerIP: 3, Window: time.Hour}
+
+	tests := []struct {
+		name     string
+		requests []string
+		ip       string
+		wantSent int
+	}{
+		{"repeats within the cooldown send once", []string{"known@example.com", "known@example.com", "known@example.com"}, "198.51.100.1", 1},
+		{"repeats without an IP still hit the cooldown", []string{"known@example.com", "known@example.com"}, "", 1},
+		{"unknown emails count against the IP", []string{"nobody@example.com", "other@example.com", "third@example.com", "known@example.com"}, "198.51.100.2", 0},
+		{"unknown emails under the IP limit do not block a send", []string{"nobody@example.com", "known@example.com"}, "198.51.100.3", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			email := "known@example.com"
+			user.Email = &email
+			emails := &fakeEmailSender{}
+			svc := NewAuthService(newFakeUserRepo(user), &fakePasswordResetRepo{}, nil, nil, emails, nil, validator.NewValidator(), resetConfig)
+
+			for _, email := range tt.requests {
+				if err := svc.RequestPasswordReset(context.Background(), email, tt.ip); err != nil {
+					t.Fatalf("request for %s: unexpected error %v", email, err)
+				}
+			}
+
+			if len(emails.sent) != tt.wantSent {
+				t.Errorf("sent %d emails (%v), want %d", len(emails.sent), emails.sent, tt.wantSent)
+			}
+			for _, to := range emails.sent {
+				if to != *user.Email {
+					t.Errorf("sent an email to %s, which has no account", to)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// BlobStore saves uploaded files and returns the URL they are served from
+type BlobStore interface {
+	// Put stores data under key, replacing any existing object, and returns its URL
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// KeyFromURL returns the key of an object this store served at url, and false for
+	// URLs that do not belong to the store
+	KeyFromURL(url string) (string, bool)
+}
+
+// localBlobStore implements BlobStore on the local filesystem. Objects are written
+// below dir and served by the HTTP server under urlPrefix.
+type localBlobStore struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalBlobStore creates a BlobStore that writes files below dir, creating it if
+// needed, and builds URLs by joining urlPrefix and the object key
+func NewLocalBlobStore(dir, urlPrefix string) (BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, utils.WrapError(err, "failed to create upload directory")
+	}
+
+	return &localBlobStore{
+		dir:       dir,
+		urlPrefix: "/" + strings.Trim(urlPrefix, "/"),
+	}, nil
+}
+
+// Put writes data to a temporary file and renames it into place so readers never see
+// a partially written object
+func (s *localBlobStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", utils.WrapError(err, "failed to create upload directory")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", utils.WrapError(err, "failed to create upload file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", utils.WrapError(err, "failed to write upload file")
+	}
+	if err := tmp.Close(); err != nil {
+		return "", utils.WrapError(err, "failed to write upload file")
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", utils.WrapError(err, "failed to write upload file")
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", utils.WrapError(err, "failed to store upload file")
+	}
+
+	return path.Join(s.urlPrefix, key), nil
+}
+
+// Delete removes the file stored under key
+func (s *localBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return utils.WrapError(err, "failed to delete upload file")
+	}
+
+	return nil
+}
+
+// KeyFromURL strips the store's URL prefix from url
+func (s *localBlobStore) KeyFromURL(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, s.urlPrefix+"/")
+	if !ok || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// path maps key to a file below dir, rejecting keys that would escape it
+func (s *localBlobStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", utils.WrapError(utils.ErrInvalidInput, "invalid blob key")
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
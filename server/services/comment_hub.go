@@ -0,0 +1,68 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/google/uuid"
+)
+
+// commentHubBuffer is how many unread comments a subscriber may fall behind by before
+// further comments are dropped for it
+const commentHubBuffer = 16
+
+// CommentHub is an in-process pub/sub of newly created comments, keyed by post. It only
+// reaches subscribers connected to the same instance.
+type CommentHub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan models.CommentResponse]struct{}
+}
+
+// NewCommentHub creates a new comment hub instance
+func NewCommentHub() *CommentHub {
+	return &CommentHub{
+		subscribers: make(map[uuid.UUID]map[chan models.CommentResponse]struct{}),
+	}
+}
+
+// Subscribe registers for comments created on a post. The returned function removes the
+// subscription and closes the channel; it is safe to call more than once.
+func (h *CommentHub) Subscribe(postID uuid.UUID) (<-chan models.CommentResponse, func()) {
+	ch := make(chan models.CommentResponse, commentHubBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[postID] == nil {
+		h.subscribers[postID] = make(map[chan models.CommentResponse]struct{})
+	}
+	h.subscribers[postID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[postID], ch)
+			if len(h.subscribers[postID]) == 0 {
+				delete(h.subscribers, postID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers a comment to every subscriber of its post without blocking; a
+// subscriber whose buffer is full misses the comment
+func (h *CommentHub) Publish(postID uuid.UUID, comment models.CommentResponse) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[postID] {
+		select {
+		case ch <- comment:
+		default:
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// StartCommentPurgeJob runs PurgeDeletedComments every interval in the background.
+// It returns a function that stops the job; a non-positive interval disables it.
+func StartCommentPurgeJob(commentService CommentService, interval time.Duration) func() {
+	if interval <= 0 {
+		utils.LogInfo("Deleted comment purge job disabled", nil)
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := commentService.PurgeDeletedComments(context.Background()); err != nil {
+					utils.LogError("Deleted comment purge job failed", err, nil)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	utils.LogInfo("Deleted comment purge job started", utils.LogFields{"interval": interval.String()})
+
+	return func() {
+		close(done)
+	}
+}
@@ -1,8 +1,18 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/TejasThombare20/post-comments-service/config"
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/repository"
 	"github.com/TejasThombare20/post-comments-service/utils"
@@ -10,16 +20,50 @@ import (
 	"github.com/google/uuid"
 )
 
+// mentionPattern matches @username tokens that are not part of an email address or word
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_]{3,50})\b`)
+
+// maxMentionsPerComment is the most distinct @usernames resolved in one comment; further
+// mentions are left as plain text so a comment cannot notify the whole user base
+const maxMentionsPerComment = 20
+
 // CommentService interface defines comment business logic methods
 type CommentService interface {
-	CreateComment(userID uuid.UUID, req *models.CreateCommentRequest) (*models.Comment, error)
+	CreateComment(ctx context.Context, userID uuid.UUID, req *models.CreateCommentRequest) (*models.Comment, error)
+	CreateGuestComment(ctx context.Context, guestIP string, req *models.CreateCommentRequest) (*models.Comment, error)
 	GetCommentByID(req *models.GetCommentRequest) (*models.Comment, error)
-	UpdateComment(id uuid.UUID, userID uuid.UUID, req *models.UpdateCommentRequest) (*models.Comment, error)
-	DeleteComment(req *models.DeleteCommentRequest, userID uuid.UUID) error
-	ListCommentsByPost(req *models.ListCommentsRequest) ([]models.Comment, error)
-	GetCommentReplies(commentID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	UpdateComment(ctx context.Context, id uuid.UUID, userID uuid.UUID, req *models.UpdateCommentRequest) (*models.Comment, error)
+	DeleteComment(ctx context.Context, req *models.DeleteCommentRequest, userID uuid.UUID, role string) error
+	ListCommentsByPost(ctx context.Context, req *models.ListCommentsRequest) ([]models.Comment, int, error)
+	GetPostCommentsStamp(ctx context.Context, postID uuid.UUID) (*models.PostCommentsStamp, error)
+	ListThreads(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.CommentThread, int, error)
+	GetCommentReplies(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, int, error)
+	GetCommentDescendants(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, int, error)
+	GetCommentsByIDs(req *models.BatchGetCommentsRequest) ([]models.Comment, error)
+	ReconcileRepliesCounts(ctx context.Context, batchSize int) (*models.ReconcileRepliesCountResult, error)
+	GetMentionsForUser(userID uuid.UUID, limit, offset int) ([]models.Comment, error)
+	ListCommentsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.UserComment, int, error)
+	PurgeDeletedComments(ctx context.Context) (*models.PurgeDeletedCommentsResult, error)
+	GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth int) ([]models.Comment, bool, error)
+	GetEmbedCommentTree(ctx context.Context, postID uuid.UUID, maxDepth int) ([]models.Comment, bool, error)
+	GetCommentPermissions(commentID uuid.UUID, userID uuid.UUID, role string) (*models.CommentPermissions, error)
+	GetCommentSource(commentID uuid.UUID, userID uuid.UUID, role string) (*models.CommentSource, error)
+	SearchAllComments(query string, limit, offset int) ([]models.AdminCommentSearchResult, int, error)
+	GetParticipantStats(postID uuid.UUID) ([]models.ParticipantStats, error)
+	MoveComment(ctx context.Context, commentID, newParentID, callerID uuid.UUID) (*models.Comment, error)
+	RestoreComment(id uuid.UUID) error
+	GetCommentPermalink(commentID uuid.UUID) (*models.CommentPermalinkResponse, error)
+	SubscribeToPost(postID uuid.UUID) (<-chan models.CommentResponse, func(), error)
+	ImportComments(ctx context.Context, postID, userID uuid.UUID, req *models.ImportCommentsRequest) (*models.ImportCommentsResult, error)
 }
 
+// Comment tree limits for GetCommentTree
+const (
+	defaultCommentTreeDepth = 10
+	maxCommentTreeDepth     = 50
+	maxCommentTreeSize      = 1000
+)
+
 // commentService implements CommentService interface
 type commentService struct {
 	commentRepo   repository.CommentRepository
@@ -27,29 +71,45 @@ type commentService struct {
 	userRepo      repository.UserRepository
 	validator     *validator.Validator
 	htmlSanitizer *utils.HTMLSanitizer
+	contentFilter *utils.ContentFilter
+	config        *config.CommentConfig
+	hub           *CommentHub
 }
 
-// NewCommentService creates a new comment service instance
-func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, validator *validator.Validator) CommentService {
+// NewCommentService creates a new comment service instance. A nil contentFilter
+// disables banned word filtering.
+func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, validator *validator.Validator, commentConfig *config.CommentConfig, contentFilter *utils.ContentFilter, hub *CommentHub) CommentService {
+	htmlSanitizer := utils.NewHTMLSanitizer()
+	if commentConfig != nil {
+		htmlSanitizer.SetAutolink(commentConfig.AutolinkEnabled)
+	}
+
 	return &commentService{
 		commentRepo:   commentRepo,
 		postRepo:      postRepo,
 		userRepo:      userRepo,
 		validator:     validator,
-		htmlSanitizer: utils.NewHTMLSanitizer(),
+		htmlSanitizer: htmlSanitizer,
+		contentFilter: contentFilter,
+		config:        commentConfig,
+		hub:           hub,
 	}
 }
 
 // CreateComment creates a new comment or reply
-func (s *commentService) CreateComment(userID uuid.UUID, req *models.CreateCommentRequest) (*models.Comment, error) {
+func (s *commentService) CreateComment(ctx context.Context, userID uuid.UUID, req *models.CreateCommentRequest) (*models.Comment, error) {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return nil, err
 	}
 
-	postID, err := uuid.Parse(*req.PostID)
-	if err != nil {
-		return nil, utils.WrapError(err, "invalid post_id format")
+	if req.Content == nil {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "content is required")
+	}
+
+	if req.PostID == uuid.Nil {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "post_id is required")
 	}
+	postID := req.PostID
 
 	// Get user data (we'll need this for the response)
 	user, err := s.userRepo.GetByID(userID)
@@ -57,9 +117,13 @@ func (s *commentService) CreateComment(userID uuid.UUID, req *models.CreateComme
 		return nil, utils.WrapError(err, "failed to find user")
 	}
 
-	if _, err = s.postRepo.GetByID(postID); err != nil {
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
 		return nil, utils.WrapError(err, "failed to find post")
 	}
+	if post.CommentsLocked {
+		return nil, utils.ErrCommentsLocked
+	}
 
 	if err := s.htmlSanitizer.ValidateHTMLContent(*req.Content); err != nil {
 		return nil, utils.WrapError(utils.ErrInvalidInput, "invalid HTML content: "+err.Error())
@@ -67,33 +131,235 @@ func (s *commentService) CreateComment(userID uuid.UUID, req *models.CreateComme
 
 	sanitizedContent := s.htmlSanitizer.ProcessCommentContent(*req.Content)
 
+	isReply := req.ParentID != nil && *req.ParentID != ""
+	if err := s.checkMinLength(sanitizedContent, isReply); err != nil {
+		return nil, err
+	}
+	if err := s.checkMaxLength(sanitizedContent); err != nil {
+		return nil, err
+	}
+
+	sanitizedContent, rawContent, err := s.filterContent(sanitizedContent, *req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moderators repeat boilerplate notices by design, so they can be left out of the check
+	exempt := models.IsModeratorRole(user.Role) && s.config != nil && s.config.DuplicateCheckExemptModerators
+	if !exempt {
+		if err := s.checkDuplicate(ctx, userID, postID, sanitizedContent); err != nil {
+			return nil, err
+		}
+	}
+
+	attachments, err := sanitizeAttachments(req.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new comment has not been edited, so both timestamps share one reading of the clock
+	now := time.Now()
 	comment := &models.Comment{
 		ID:           uuid.New(),
 		Content:      sanitizedContent,
+		ContentRaw:   &rawContent,
 		PostID:       postID,
 		CreatedBy:    &userID,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 		RepliesCount: 0,
+		Version:      1,
 		Path:         []uuid.UUID{},
+		Attachments:  attachments,
 	}
 
-	if req.ParentID != nil && *req.ParentID != "" {
-		parentID, err := uuid.Parse(*req.ParentID)
+	if err := s.saveNewComment(ctx, comment, req.ParentID); err != nil {
+		return nil, err
+	}
+
+	s.recordMentions(ctx, comment, userID)
+
+	// Set the author data directly instead of making another DB call
+	comment.Author = user
+
+	if s.hub != nil {
+		s.hub.Publish(postID, comment.ToResponse())
+	}
+
+	return comment, nil
+}
+
+// CreateGuestComment creates a comment or reply from a visitor who is not signed in, on a
+// post whose author allows it. The comment has no author and carries the guest_name from
+// the request instead. Guests are held to a per-IP rate limit, their content is always
+// treated as plain text, and they cannot add attachments.
+func (s *commentService) CreateGuestComment(ctx context.Context, guestIP string, req *models.CreateCommentRequest) (*models.Comment, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	if req.Content == nil {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "content is required")
+	}
+
+	if req.PostID == uuid.Nil {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "post_id is required")
+	}
+	postID := req.PostID
+
+	if len(req.Attachments) > 0 {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "guests cannot add attachments")
+	}
+
+	guestName, err := s.normalizeGuestName(req.GuestName)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetByID(postID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to find post")
+	}
+	if !post.AllowAnonymousComments {
+		return nil, utils.WrapError(utils.ErrUnauthorized, "sign in to comment on this post")
+	}
+	if post.CommentsLocked {
+		return nil, utils.ErrCommentsLocked
+	}
+
+	sanitizedContent := s.htmlSanitizer.ProcessGuestCommentContent(*req.Content)
+
+	isReply := req.ParentID != nil && *req.ParentID != ""
+	if err := s.checkMinLength(sanitizedContent, isReply); err != nil {
+		return nil, err
+	}
+	if err := s.checkMaxLength(sanitizedContent); err != nil {
+		return nil, err
+	}
+
+	sanitizedContent, rawContent, err := s.filterContent(sanitizedContent, *req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkGuestDuplicate(ctx, guestIP, postID, sanitizedContent); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	comment := &models.Comment{
+		ID:           uuid.New(),
+		Content:      sanitizedContent,
+		ContentRaw:   &rawContent,
+		PostID:       postID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		RepliesCount: 0,
+		Version:      1,
+		Path:         []uuid.UUID{},
+		Attachments:  models.CommentAttachments{},
+		GuestName:    &guestName,
+		GuestIP:      &guestIP,
+	}
+
+	if err := s.saveNewComment(ctx, comment, req.ParentID); err != nil {
+		return nil, err
+	}
+
+	if s.hub != nil {
+		s.hub.Publish(postID, comment.ToResponse())
+	}
+
+	return comment, nil
+}
+
+// normalizeGuestName strips markup and surrounding whitespace from a guest's display name
+// and refuses names that are empty or belong to a registered user, so a guest cannot pass
+// themselves off as a member
+func (s *commentService) normalizeGuestName(name *string) (string, error) {
+	if name == nil {
+		return "", utils.WrapError(utils.ErrInvalidInput, "guest_name is required to comment without signing in")
+	}
+
+	normalized := strings.TrimSpace(html.UnescapeString(s.htmlSanitizer.StripHTMLTags(*name)))
+	if normalized == "" {
+		return "", utils.WrapError(utils.ErrInvalidInput, "guest_name is required to comment without signing in")
+	}
+
+	taken, err := s.userRepo.IsUsernameTaken(normalized, uuid.Nil)
+	if err != nil {
+		return "", utils.WrapError(err, "failed to check guest name")
+	}
+	if taken {
+		return "", utils.WrapError(utils.ErrInvalidInput, "guest_name belongs to a registered user")
+	}
+
+	return normalized, nil
+}
+
+// createGuestComment stores a guest comment unless its IP address has already posted the
+// configured number of guest comments within the window. The count and the insert happen
+// under one lock on the IP, so parallel requests cannot all slip under the limit.
+func (s *commentService) createGuestComment(ctx context.Context, comment *models.Comment) error {
+	if s.config == nil || s.config.GuestMaxPerIP <= 0 {
+		if err := s.commentRepo.Create(comment); err != nil {
+			return utils.WrapError(err, "failed to create comment")
+		}
+		return nil
+	}
+
+	var count int
+	created, err := s.commentRepo.CreateGuestIfAllowed(comment, comment.CreatedAt.Add(-s.config.GuestWindow), func(n int) bool {
+		count = n
+		return n < s.config.GuestMaxPerIP
+	})
+	if err != nil {
+		return utils.WrapError(err, "failed to create comment")
+	}
+	if !created {
+		utils.LogWarnContext(ctx, "Guest comment rate limit reached", utils.LogFields{
+			"guest_ip": *comment.GuestIP,
+			"count":    count,
+		})
+		return utils.ErrGuestRateLimited
+	}
+
+	return nil
+}
+
+// saveNewComment links a new comment under the parent named by parentID, or starts a new
+// thread when it is empty, then stores it and bumps its ancestors
+func (s *commentService) saveNewComment(ctx context.Context, comment *models.Comment, parentID *string) error {
+	if parentID != nil && *parentID != "" {
+		parentUUID, err := uuid.Parse(*parentID)
 		if err != nil {
-			return nil, utils.WrapError(err, "invalid parent_id format")
+			return utils.WrapError(utils.ErrInvalidInput, "invalid parent_id format")
 		}
 
-		parentComment, err := s.commentRepo.GetByID(parentID)
+		parentComment, err := s.commentRepo.GetByID(parentUUID)
 		if err != nil {
-			return nil, utils.WrapError(err, "failed to find parent comment")
+			return utils.WrapError(err, "failed to find parent comment")
 		}
 
-		if parentComment.PostID != postID {
-			return nil, utils.WrapError(utils.ErrInvalidInput, "parent comment does not belong to the same post")
+		if parentComment.PostID != comment.PostID {
+			return utils.ErrParentMismatch
 		}
 
-		comment.ParentID = &parentID
+		// The reply inherits the parent's path and thread, so refuse to build on a corrupt one
+		if !isWellFormedPath(parentComment) || hasCycle(parentComment.Path, comment.ID) {
+			utils.LogWarnContext(ctx, "Parent comment has a malformed path", utils.LogFields{
+				"comment_id": parentComment.ID,
+				"thread_id":  parentComment.ThreadID,
+				"path":       parentComment.Path,
+			})
+			return utils.WrapError(utils.ErrInvalidInput, "parent comment has a malformed thread path")
+		}
+
+		if s.config != nil && s.config.MaxReplyDepth > 0 && parentComment.Depth() >= s.config.MaxReplyDepth {
+			return utils.WrapError(utils.ErrInvalidInput, fmt.Sprintf("replies cannot be nested more than %d levels deep", s.config.MaxReplyDepth))
+		}
+
+		comment.ParentID = &parentUUID
 		comment.ThreadID = parentComment.ThreadID
 		comment.Path = append(parentComment.Path, comment.ID)
 	} else {
@@ -101,14 +367,240 @@ func (s *commentService) CreateComment(userID uuid.UUID, req *models.CreateComme
 		comment.Path = append(comment.Path, comment.ID)
 	}
 
-	if err := s.commentRepo.Create(comment); err != nil {
-		return nil, utils.WrapError(err, "failed to create comment")
+	if comment.GuestIP != nil {
+		if err := s.createGuestComment(ctx, comment); err != nil {
+			return err
+		}
+	} else if err := s.commentRepo.Create(comment); err != nil {
+		return utils.WrapError(err, "failed to create comment")
 	}
 
-	// Set the author data directly instead of making another DB call
-	comment.Author = user
+	// A new reply bumps every ancestor, so the thread rises in activity ordering
+	if comment.ParentID != nil && s.config != nil && s.config.BumpOnReply {
+		s.bumpComments(ctx, comment.Path[:len(comment.Path)-1], comment.CreatedAt)
+	}
 
-	return comment, nil
+	return nil
+}
+
+// recordMentions resolves @username mentions in a comment and stores them.
+// Unknown usernames and self-mentions are ignored, and only the first
+// maxMentionsPerComment distinct usernames are resolved; failures are logged but do
+// not fail comment creation.
+func (s *commentService) recordMentions(ctx context.Context, comment *models.Comment, authorID uuid.UUID) {
+	plain := html.UnescapeString(s.htmlSanitizer.StripHTMLTags(comment.Content))
+
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(plain, -1) {
+		username := strings.ToLower(match[1])
+		if seen[username] {
+			continue
+		}
+		if len(usernames) == maxMentionsPerComment {
+			break
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	if len(usernames) == 0 {
+		return
+	}
+
+	userIDs, err := s.userRepo.ListIDsByUsernames(usernames)
+	if err != nil {
+		utils.LogErrorContext(ctx, "Failed to resolve mentioned users", err, utils.LogFields{
+			"comment_id": comment.ID,
+			"mentions":   len(usernames),
+		})
+		return
+	}
+
+	mentionedIDs := make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != authorID {
+			mentionedIDs = append(mentionedIDs, id)
+		}
+	}
+
+	if err := s.commentRepo.AddMentions(comment.ID, mentionedIDs); err != nil {
+		utils.LogErrorContext(ctx, "Failed to record comment mentions", err, utils.LogFields{
+			"comment_id": comment.ID,
+			"mentions":   len(mentionedIDs),
+		})
+	}
+}
+
+// bumpComments updates bumped_at for activity ordering. Failures are logged but do not
+// fail the write that triggered them.
+func (s *commentService) bumpComments(ctx context.Context, ids []uuid.UUID, at time.Time) {
+	if err := s.commentRepo.Bump(ids, at); err != nil {
+		utils.LogErrorContext(ctx, "Failed to bump comments", err, utils.LogFields{
+			"comments": len(ids),
+		})
+	}
+}
+
+// GetMentionsForUser retrieves comments that mention the given user
+func (s *commentService) GetMentionsForUser(userID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+
+	comments, err := s.commentRepo.ListMentioningUser(userID, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get mentions for user")
+	}
+
+	return comments, nil
+}
+
+// checkDuplicate rejects a comment whose normalized content matches the user's most
+// recent comment on the same post within the configured window
+func (s *commentService) checkDuplicate(ctx context.Context, userID, postID uuid.UUID, content string) error {
+	if s.config == nil || !s.config.DuplicateCheckEnabled {
+		return nil
+	}
+
+	latest, err := s.commentRepo.GetLatestByUserAndPost(userID, postID)
+	return s.rejectDuplicate(ctx, latest, err, content, utils.LogFields{"user_id": userID, "post_id": postID})
+}
+
+// checkGuestDuplicate applies the duplicate check to a guest comment, comparing it with
+// the latest guest comment from the same IP address on the post, when guests are covered
+func (s *commentService) checkGuestDuplicate(ctx context.Context, guestIP string, postID uuid.UUID, content string) error {
+	if s.config == nil || !s.config.DuplicateCheckEnabled || !s.config.DuplicateCheckGuests {
+		return nil
+	}
+
+	latest, err := s.commentRepo.GetLatestGuestByIPAndPost(guestIP, postID)
+	return s.rejectDuplicate(ctx, latest, err, content, utils.LogFields{"guest_ip": guestIP, "post_id": postID})
+}
+
+// rejectDuplicate returns ErrDuplicateComment when content matches latest, the author's
+// previous comment as looked up with lookupErr, and it was posted within the window
+func (s *commentService) rejectDuplicate(ctx context.Context, latest *models.Comment, lookupErr error, content string, fields utils.LogFields) error {
+	if lookupErr != nil {
+		if utils.IsNotFoundError(lookupErr) {
+			return nil
+		}
+		return utils.WrapError(lookupErr, "failed to check for duplicate comment")
+	}
+
+	if time.Since(latest.CreatedAt) > s.config.DuplicateWindow {
+		return nil
+	}
+
+	if s.contentFingerprint(latest.Content) == s.contentFingerprint(content) {
+		fields["duplicate_of"] = latest.ID
+		fields["duplicate_window_ms"] = s.config.DuplicateWindow.Milliseconds()
+		utils.LogWarnContext(ctx, "Duplicate comment rejected", fields)
+		return utils.ErrDuplicateComment
+	}
+
+	return nil
+}
+
+// checkMinLength rejects sanitized content whose visible text is shorter than the
+// configured minimum, so markup-only or single-character comments are not posted
+func (s *commentService) checkMinLength(content string, isReply bool) error {
+	if s.config == nil || s.config.MinContentLength <= 0 {
+		return nil
+	}
+	if isReply && !s.config.MinContentLengthReplies {
+		return nil
+	}
+
+	plain := strings.TrimSpace(html.UnescapeString(s.htmlSanitizer.StripHTMLTags(content)))
+	if utf8.RuneCountInString(plain) < s.config.MinContentLength {
+		return utils.WrapError(utils.ErrInvalidInput, fmt.Sprintf("comment must contain at least %d characters of text", s.config.MinContentLength))
+	}
+
+	return nil
+}
+
+// checkMaxLength rejects sanitized content longer than the configured maximum. It runs
+// after sanitizing because autolinking and escaping can make content longer than the
+// request validation saw.
+func (s *commentService) checkMaxLength(content string) error {
+	if s.config == nil || s.config.MaxContentLength <= 0 {
+		return nil
+	}
+
+	if utf8.RuneCountInString(content) > s.config.MaxContentLength {
+		return utils.WrapError(utils.ErrInvalidInput, fmt.Sprintf("comment is too long: at most %d characters are allowed, including formatting", s.config.MaxContentLength))
+	}
+
+	return nil
+}
+
+// sanitizeAttachments normalizes the attachments of a new comment. The request has
+// already been validated; the URLs are re-checked here so nothing unsanitized is stored.
+func sanitizeAttachments(reqs []models.CommentAttachmentRequest) (models.CommentAttachments, error) {
+	attachments := make(models.CommentAttachments, 0, len(reqs))
+	for _, req := range reqs {
+		url, err := utils.SanitizeAttachmentURL(req.URL)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrInvalidInput, err.Error())
+		}
+
+		attachmentType := req.Type
+		if attachmentType == "" {
+			attachmentType = models.CommentAttachmentTypeImage
+		}
+
+		attachments = append(attachments, models.CommentAttachment{
+			URL:    url,
+			Type:   attachmentType,
+			Width:  req.Width,
+			Height: req.Height,
+		})
+	}
+
+	return attachments, nil
+}
+
+// filterContent applies the banned word filter to a comment's sanitized and raw content.
+// Only text is checked, not tags or attributes such as link targets. In reject mode a
+// banned word fails the comment with ErrInvalidInput; in mask mode both versions are
+// returned with the words masked, so the source endpoint does not reveal them.
+func (s *commentService) filterContent(sanitized, raw string) (string, string, error) {
+	if s.contentFilter == nil {
+		return sanitized, raw, nil
+	}
+
+	if s.config != nil && s.config.BannedWordsMode == utils.ContentFilterModeMask {
+		maskedRaw := s.contentFilter.Mask(raw)
+		if s.htmlSanitizer.IsHTMLContent(raw) {
+			maskedRaw = s.contentFilter.MaskHTML(raw)
+		}
+		return s.contentFilter.MaskHTML(sanitized), maskedRaw, nil
+	}
+
+	if s.contentFilter.ContainsHTML(sanitized) {
+		return "", "", utils.WrapError(utils.ErrInvalidInput, "comment contains a banned word")
+	}
+
+	return sanitized, raw, nil
+}
+
+// contentFingerprint hashes comment content after stripping markup, case and
+// whitespace differences so trivially different repeats compare equal
+func (s *commentService) contentFingerprint(content string) string {
+	plain := html.UnescapeString(s.htmlSanitizer.StripHTMLTags(content))
+	normalized := strings.ToLower(strings.Join(strings.Fields(plain), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
 }
 
 // GetCommentByID retrieves a comment by ID with author information
@@ -131,7 +623,7 @@ func (s *commentService) GetCommentByID(req *models.GetCommentRequest) (*models.
 }
 
 // UpdateComment updates a comment's content
-func (s *commentService) UpdateComment(id uuid.UUID, userID uuid.UUID, req *models.UpdateCommentRequest) (*models.Comment, error) {
+func (s *commentService) UpdateComment(ctx context.Context, id uuid.UUID, userID uuid.UUID, req *models.UpdateCommentRequest) (*models.Comment, error) {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return nil, err
 	}
@@ -141,7 +633,7 @@ func (s *commentService) UpdateComment(id uuid.UUID, userID uuid.UUID, req *mode
 		return nil, utils.WrapError(err, "failed to find comment for update")
 	}
 
-	if existingComment.CreatedBy == nil || *existingComment.CreatedBy != userID {
+	if !isCommentAuthor(existingComment, userID) {
 		return nil, utils.ErrForbidden
 	}
 
@@ -150,6 +642,17 @@ func (s *commentService) UpdateComment(id uuid.UUID, userID uuid.UUID, req *mode
 			return nil, utils.WrapError(utils.ErrInvalidInput, "invalid HTML content: "+err.Error())
 		}
 		sanitizedContent := s.htmlSanitizer.ProcessCommentContent(*req.Content)
+		if err := s.checkMinLength(sanitizedContent, existingComment.ParentID != nil); err != nil {
+			return nil, err
+		}
+		if err := s.checkMaxLength(sanitizedContent); err != nil {
+			return nil, err
+		}
+		sanitizedContent, rawContent, err := s.filterContent(sanitizedContent, *req.Content)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentRaw = &rawContent
 		req.Content = &sanitizedContent
 	}
 
@@ -158,11 +661,16 @@ func (s *commentService) UpdateComment(id uuid.UUID, userID uuid.UUID, req *mode
 		return nil, utils.WrapError(err, "failed to update comment")
 	}
 
+	if s.config != nil && s.config.BumpOnEdit {
+		s.bumpComments(ctx, updatedComment.Path, updatedComment.UpdatedAt)
+	}
+
 	return updatedComment, nil
 }
 
-// DeleteComment deletes a comment
-func (s *commentService) DeleteComment(req *models.DeleteCommentRequest, userID uuid.UUID) error {
+// DeleteComment deletes a comment. Regular users may only delete their own comments;
+// moderators and admins may delete any comment, which is recorded in the moderation log.
+func (s *commentService) DeleteComment(ctx context.Context, req *models.DeleteCommentRequest, userID uuid.UUID, role string) error {
 	if err := s.validator.ValidateStruct(req); err != nil {
 		return err
 	}
@@ -177,30 +685,72 @@ func (s *commentService) DeleteComment(req *models.DeleteCommentRequest, userID
 		return utils.WrapError(err, "failed to find comment for deletion")
 	}
 
-	if existingComment.CreatedBy == nil || *existingComment.CreatedBy != userID {
+	if isCommentAuthor(existingComment, userID) {
+		if err := s.commentRepo.Delete(commentID); err != nil {
+			return utils.WrapError(err, "failed to delete comment")
+		}
+		return nil
+	}
+
+	if !models.IsModeratorRole(role) {
 		return utils.ErrForbidden
 	}
 
-	if err := s.commentRepo.Delete(commentID); err != nil {
-		return utils.WrapError(err, "failed to delete comment")
+	if err := s.commentRepo.DeleteAsModerator(commentID, userID, existingComment.CreatedBy); err != nil {
+		return utils.WrapError(err, "failed to delete comment as moderator")
+	}
+
+	utils.LogInfoContext(ctx, "Comment deleted by moderator", utils.LogFields{
+		"comment_id":   commentID,
+		"moderator_id": userID,
+		"role":         role,
+	})
+
+	return nil
+}
+
+// RestoreComment undoes a soft delete of a comment (admin only)
+func (s *commentService) RestoreComment(id uuid.UUID) error {
+	if err := s.commentRepo.Restore(id); err != nil {
+		return utils.WrapError(err, "failed to restore comment")
 	}
 
 	return nil
 }
 
-// ListCommentsByPost retrieves comments for a specific post
-func (s *commentService) ListCommentsByPost(req *models.ListCommentsRequest) ([]models.Comment, error) {
+// GetPostCommentsStamp returns what identifies the current version of a post's comments,
+// so conditional requests can be answered without loading them
+func (s *commentService) GetPostCommentsStamp(ctx context.Context, postID uuid.UUID) (*models.PostCommentsStamp, error) {
+	stamp, err := s.postRepo.GetCommentsStamp(ctx, postID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get post comments stamp")
+	}
+	return stamp, nil
+}
+
+// ListCommentsByPost retrieves comments for a specific post along with the total
+// number of top-level comments on the post
+func (s *commentService) ListCommentsByPost(ctx context.Context, req *models.ListCommentsRequest) ([]models.Comment, int, error) {
 	if err := s.validator.ValidateStruct(req); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	postID, err := uuid.Parse(req.PostID)
 	if err != nil {
-		return nil, utils.WrapError(err, "invalid post ID format")
+		return nil, 0, utils.WrapError(err, "invalid post ID format")
+	}
+
+	sort := req.Sort
+	switch sort {
+	case "":
+		sort = models.CommentSortNewest
+	case models.CommentSortNewest, models.CommentSortOldest, models.CommentSortActivity:
+	default:
+		return nil, 0, utils.WrapError(utils.ErrInvalidInput, "sort must be one of: newest, oldest, activity")
 	}
 
 	if _, err = s.postRepo.GetByID(postID); err != nil {
-		return nil, utils.WrapError(err, "failed to find post")
+		return nil, 0, utils.WrapError(err, "failed to find post")
 	}
 
 	limit := 20
@@ -212,22 +762,120 @@ func (s *commentService) ListCommentsByPost(req *models.ListCommentsRequest) ([]
 	if req.Offset >= 0 {
 		offset = req.Offset
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
 	}
 
-	comments, err := s.commentRepo.ListByPost(postID, limit, offset)
+	comments, err := s.commentRepo.ListByPost(ctx, postID, sort, limit, offset)
 	if err != nil {
-		return nil, utils.WrapError(err, "failed to list comments by post")
+		return nil, 0, utils.WrapError(err, "failed to list comments by post")
 	}
 
-	return comments, nil
+	total, err := s.commentRepo.CountByPost(ctx, postID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comments by post")
+	}
+
+	return comments, total, nil
 }
 
-// GetCommentReplies retrieves replies for a specific comment
-func (s *commentService) GetCommentReplies(commentID uuid.UUID, limit, offset int) ([]models.Comment, error) {
+// ListThreads retrieves a page of a post's threads in activity order, each with its latest
+// reply and total reply count. Returns the page and the total number of threads.
+func (s *commentService) ListThreads(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.CommentThread, int, error) {
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return nil, 0, utils.WrapError(err, "failed to find post")
+	}
+
+	threads, err := s.commentRepo.ListThreads(ctx, postID, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to list comment threads")
+	}
+
+	total, err := s.commentRepo.CountByPost(ctx, postID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comment threads")
+	}
+
+	return threads, total, nil
+}
+
+// GetCommentTree retrieves a post's comments as a nested tree, up to maxDepth levels
+// and maxCommentTreeSize comments. The returned bool reports whether the tree was truncated.
+func (s *commentService) GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth int) ([]models.Comment, bool, error) {
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return nil, false, utils.WrapError(err, "failed to find post")
+	}
+
+	if maxDepth <= 0 {
+		maxDepth = defaultCommentTreeDepth
+	}
+	if maxDepth > maxCommentTreeDepth {
+		maxDepth = maxCommentTreeDepth
+	}
+
+	comments, truncated, err := s.commentRepo.GetFullTree(ctx, postID, maxDepth, maxCommentTreeSize)
+	if err != nil {
+		return nil, false, utils.WrapError(err, "failed to get comment tree")
+	}
+
+	return comments, truncated, nil
+}
+
+// GetEmbedCommentTree retrieves a post's comment tree like GetCommentTree, with every
+// comment's content re-sanitized by the stricter embed policy for third-party pages
+func (s *commentService) GetEmbedCommentTree(ctx context.Context, postID uuid.UUID, maxDepth int) ([]models.Comment, bool, error) {
+	comments, truncated, err := s.GetCommentTree(ctx, postID, maxDepth)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.sanitizeEmbedContent(comments)
+
+	return comments, truncated, nil
+}
+
+// sanitizeEmbedContent applies the embed policy to a comment tree in place
+func (s *commentService) sanitizeEmbedContent(comments []models.Comment) {
+	for i := range comments {
+		comments[i].Content = s.htmlSanitizer.SanitizeEmbed(comments[i].Content)
+		s.sanitizeEmbedContent(comments[i].Children)
+	}
+}
+
+// GetCommentReplies retrieves replies for a specific comment along with the total reply count
+func (s *commentService) GetCommentReplies(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
+	if _, err := s.commentRepo.GetByID(commentID); err != nil {
+		return nil, 0, utils.WrapError(err, "failed to find comment")
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+
+	replies, err := s.commentRepo.GetReplies(ctx, commentID, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to get comment replies")
+	}
+
+	total, err := s.commentRepo.CountReplies(ctx, commentID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comment replies")
+	}
+
+	return replies, total, nil
+}
+
+// GetCommentDescendants retrieves a page of a comment's whole subtree (replies, their
+// replies, and so on) in tree order, along with the total size of the subtree
+func (s *commentService) GetCommentDescendants(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
 	if _, err := s.commentRepo.GetByID(commentID); err != nil {
-		return nil, utils.WrapError(err, "failed to find comment")
+		return nil, 0, utils.WrapError(err, "failed to find comment")
 	}
 
 	if limit <= 0 {
@@ -236,21 +884,575 @@ func (s *commentService) GetCommentReplies(commentID uuid.UUID, limit, offset in
 	if offset < 0 {
 		offset = 0
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
 	}
 
-	replies, err := s.commentRepo.GetReplies(commentID, limit, offset)
+	descendants, err := s.commentRepo.GetDescendants(ctx, commentID, limit, offset)
 	if err != nil {
-		return nil, utils.WrapError(err, "failed to get comment replies")
+		return nil, 0, utils.WrapError(err, "failed to get comment descendants")
+	}
+
+	total, err := s.commentRepo.CountDescendants(ctx, commentID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comment descendants")
+	}
+
+	return descendants, total, nil
+}
+
+// ListCommentsByUser retrieves a page of a user's comment history, newest first, along
+// with the total number of comments in it
+func (s *commentService) ListCommentsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.UserComment, int, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, 0, err
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+
+	comments, err := s.commentRepo.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to list comments by user")
+	}
+
+	total, err := s.commentRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count comments by user")
+	}
+
+	return comments, total, nil
+}
+
+// GetCommentsByIDs retrieves comments for the given IDs, preserving the input order.
+// IDs that are missing or deleted are silently skipped.
+func (s *commentService) GetCommentsByIDs(req *models.BatchGetCommentsRequest) ([]models.Comment, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for _, idStr := range req.IDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "invalid comment ID format")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	comments, err := s.commentRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get comments by IDs")
+	}
+
+	byID := make(map[uuid.UUID]models.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	ordered := make([]models.Comment, 0, len(comments))
+	for _, id := range ids {
+		if comment, ok := byID[id]; ok {
+			ordered = append(ordered, comment)
+		}
+	}
+
+	return ordered, nil
+}
+
+// ReconcileRepliesCounts walks every comment in pages of batchSize and repairs any
+// replies_count that has drifted from the actual number of non-deleted replies, with
+// one set-based update per page
+func (s *commentService) ReconcileRepliesCounts(ctx context.Context, batchSize int) (*models.ReconcileRepliesCountResult, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	result := &models.ReconcileRepliesCountResult{}
+	afterID := uuid.Nil
+
+	for {
+		ids, err := s.commentRepo.ListIDsAfter(afterID, batchSize)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to list comments for reconciliation")
+		}
+
+		corrected, err := s.commentRepo.RecomputeRepliesCounts(ids)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to reconcile replies count")
+		}
+		result.Scanned += len(ids)
+		result.Corrected += corrected
+
+		if len(ids) < batchSize {
+			break
+		}
+		afterID = ids[len(ids)-1]
+	}
+
+	utils.LogInfoContext(ctx, "Replies count reconciliation completed", utils.LogFields{
+		"scanned":   result.Scanned,
+		"corrected": result.Corrected,
+	})
+
+	return result, nil
+}
+
+// PurgeDeletedComments hard-deletes comments that have been soft-deleted for longer
+// than the configured retention period, in batches, keeping tombstones that still have replies
+func (s *commentService) PurgeDeletedComments(ctx context.Context) (*models.PurgeDeletedCommentsResult, error) {
+	retention := 30 * 24 * time.Hour
+	batchSize := 500
+	if s.config != nil {
+		retention = s.config.PurgeRetention
+		batchSize = s.config.PurgeBatchSize
+	}
+
+	result := &models.PurgeDeletedCommentsResult{
+		Cutoff: time.Now().Add(-retention),
+	}
+
+	for {
+		purged, err := s.commentRepo.PurgeDeletedBefore(result.Cutoff, batchSize)
+		if err != nil {
+			return nil, utils.WrapError(err, "failed to purge deleted comments")
+		}
+		result.Purged += purged
+
+		if purged == 0 {
+			break
+		}
+	}
+
+	utils.LogInfoContext(ctx, "Deleted comment purge completed", utils.LogFields{
+		"purged": result.Purged,
+		"cutoff": result.Cutoff,
+	})
+
+	return result, nil
+}
+
+// GetCommentPermissions reports what the given user may do with a comment, using the
+// same checks the mutation endpoints enforce. Pass uuid.Nil for anonymous users.
+func (s *commentService) GetCommentPermissions(commentID uuid.UUID, userID uuid.UUID, role string) (*models.CommentPermissions, error) {
+	comment, err := s.commentRepo.GetByID(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetAnyByID(comment.PostID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to find post")
+	}
+
+	canReply := s.canReplyTo(comment, post)
+
+	// Visitors who are not signed in can only reply, and only where guests are allowed
+	if userID == uuid.Nil {
+		return &models.CommentPermissions{
+			CanReply: canReply && post.AllowAnonymousComments,
+		}, nil
+	}
+
+	return &models.CommentPermissions{
+		CanEdit:     isCommentAuthor(comment, userID),
+		CanDelete:   isCommentAuthor(comment, userID) || models.IsModeratorRole(role),
+		CanModerate: models.IsModeratorRole(role),
+		CanReply:    canReply,
+	}, nil
+}
+
+// canReplyTo reports whether saveNewComment would accept a reply under the comment: the
+// post must be published and not locked, the comment's path must be intact and the reply
+// must not exceed the maximum nesting depth
+func (s *commentService) canReplyTo(comment *models.Comment, post *models.Post) bool {
+	if post.CommentsLocked || post.IsScheduled(time.Now()) || !isWellFormedPath(comment) {
+		return false
+	}
+	return s.config == nil || s.config.MaxReplyDepth <= 0 || comment.Depth() < s.config.MaxReplyDepth
+}
+
+// GetCommentSource returns the content a comment was written with, for editing.
+// Only the author and moderators may read it.
+func (s *commentService) GetCommentSource(commentID uuid.UUID, userID uuid.UUID, role string) (*models.CommentSource, error) {
+	source, err := s.commentRepo.GetSource(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	isAuthor := source.CreatedBy != nil && *source.CreatedBy == userID
+	if !isAuthor && !models.IsModeratorRole(role) {
+		return nil, utils.ErrForbidden
 	}
 
-	return replies, nil
+	return source, nil
+}
+
+// SearchAllComments full-text searches every comment, including soft-deleted ones,
+// for moderation. Returns the page of results and the total match count.
+func (s *commentService) SearchAllComments(query string, limit, offset int) ([]models.AdminCommentSearchResult, int, error) {
+	query, err := normalizeSearchQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit, offset = normalizeSearchPage(limit, offset)
+
+	results, total, err := s.commentRepo.SearchGlobal(query, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to search all comments")
+	}
+
+	for i := range results {
+		results[i].Snippet = s.htmlSanitizer.SanitizeSnippet(results[i].Snippet)
+	}
+
+	return results, total, nil
+}
+
+// GetParticipantStats retrieves per-author comment activity for a post
+func (s *commentService) GetParticipantStats(postID uuid.UUID) ([]models.ParticipantStats, error) {
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return nil, utils.WrapError(err, "failed to find post")
+	}
+
+	stats, err := s.commentRepo.ParticipantStats(postID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get participant stats")
+	}
+
+	return stats, nil
+}
+
+// isCommentAuthor reports whether the user wrote the comment
+func isCommentAuthor(comment *models.Comment, userID uuid.UUID) bool {
+	return comment.CreatedBy != nil && *comment.CreatedBy == userID
+}
+
+// isWellFormedPath reports whether the comment's materialized path is consistent with the
+// rest of the row: it starts at the thread root, ends at the comment itself and passes
+// through its parent just before that
+func isWellFormedPath(comment *models.Comment) bool {
+	path := comment.Path
+	if len(path) == 0 || path[0] != comment.ThreadID || path[len(path)-1] != comment.ID {
+		return false
+	}
+
+	if comment.ParentID == nil {
+		return len(path) == 1
+	}
+	return len(path) > 1 && path[len(path)-2] == *comment.ParentID
+}
+
+// hasCycle reports whether placing commentID below a parent whose materialized path is
+// parentPath would make a comment its own ancestor: either parentPath already visits a
+// comment twice, or it passes through commentID, meaning the parent is the comment itself
+// or one of its replies. A newly created comment has a fresh ID and can only trip the
+// first case; moving an existing comment can trip either.
+func hasCycle(parentPath []uuid.UUID, commentID uuid.UUID) bool {
+	seen := make(map[uuid.UUID]bool, len(parentPath))
+	for _, id := range parentPath {
+		if id == commentID || seen[id] {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+// MoveComment moves a comment and all of its replies under another comment on the same
+// post on behalf of callerID. The repository rewrites the parent_id, and the path and
+// thread_id of the whole subtree, in one transaction and repeats the cycle check there
+// under row locks, along with the reply depth limit for the moved subtree; the checks
+// here give clear errors for the common cases up front.
+func (s *commentService) MoveComment(ctx context.Context, commentID, newParentID, callerID uuid.UUID) (*models.Comment, error) {
+	comment, err := s.commentRepo.GetByID(commentID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get comment to move")
+	}
+
+	newParent, err := s.commentRepo.GetByID(newParentID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get new parent comment")
+	}
+
+	if newParent.PostID != comment.PostID {
+		return nil, utils.ErrParentMismatch
+	}
+
+	if !isWellFormedPath(newParent) {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "new parent comment has a malformed thread path")
+	}
+
+	// The new parent must not be the comment itself or one of its replies
+	if hasCycle(newParent.Path, commentID) {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "cannot move a comment under itself or one of its replies")
+	}
+
+	maxDepth := 0
+	if s.config != nil {
+		maxDepth = s.config.MaxReplyDepth
+	}
+	if err := s.commentRepo.Reparent(commentID, newParentID, maxDepth); err != nil {
+		return nil, utils.WrapError(err, "failed to move comment")
+	}
+
+	utils.LogInfoContext(ctx, "Comment moved", utils.LogFields{
+		"comment_id":    commentID,
+		"old_parent_id": comment.ParentID,
+		"new_parent_id": newParentID,
+		"moved_by":      callerID,
+	})
+
+	moved, err := s.commentRepo.GetByIDWithAuthor(commentID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get moved comment")
+	}
+
+	return moved, nil
 }
 
 // incrementRepliesCount increments the replies count for a comment
 // NOTE: This should only be used for manual corrections or data migration.
-// Normal reply creation automatically increments the count via database trigger.
+// Reply creation already increments the count, via trigger or in the repository.
 func (s *commentService) incrementRepliesCount(commentID uuid.UUID) error {
 	return s.commentRepo.IncrementRepliesCount(commentID)
 }
+
+// ImportComments bulk-creates a thread on a post from a batch whose comments reference
+// their parents by temp_id. The batch must form a forest: every parent_temp_id must name
+// another comment in the batch and no chain of parents may loop. All comments are
+// inserted in one transaction, authored by the importing user. Mentions are not
+// recorded and nothing is bumped, so an import does not surface as new activity.
+func (s *commentService) ImportComments(ctx context.Context, postID, userID uuid.UUID, req *models.ImportCommentsRequest) (*models.ImportCommentsResult, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	order, err := orderImportedComments(req.Comments)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return nil, utils.WrapError(err, "failed to find post")
+	}
+
+	for _, item := range req.Comments {
+		if err := s.htmlSanitizer.ValidateHTMLContent(item.Content); err != nil {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "invalid HTML content in comment "+item.TempID+": "+err.Error())
+		}
+	}
+
+	// Stagger created_at by insert position so replies never predate their parent and
+	// siblings keep the order they were sent in
+	now := time.Now()
+	byTempID := make(map[string]*models.Comment, len(req.Comments))
+	comments := make([]models.Comment, 0, len(req.Comments))
+	for pos, i := range order {
+		item := req.Comments[i]
+		createdAt := now.Add(time.Duration(pos) * time.Microsecond)
+
+		content := s.htmlSanitizer.ProcessCommentContent(item.Content)
+		if err := s.checkMaxLength(content); err != nil {
+			return nil, utils.WrapError(err, "comment "+item.TempID)
+		}
+
+		content, rawContent, err := s.filterContent(content, item.Content)
+		if err != nil {
+			return nil, utils.WrapError(err, "comment "+item.TempID)
+		}
+
+		comment := models.Comment{
+			ID:         uuid.New(),
+			Content:    content,
+			ContentRaw: &rawContent,
+			PostID:     postID,
+			CreatedBy:  &userID,
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		}
+
+		if item.ParentTempID != nil && *item.ParentTempID != "" {
+			parent := byTempID[*item.ParentTempID]
+			comment.ParentID = &parent.ID
+			comment.ThreadID = parent.ThreadID
+			comment.Path = append(append([]uuid.UUID{}, parent.Path...), comment.ID)
+		} else {
+			comment.ThreadID = comment.ID
+			comment.Path = []uuid.UUID{comment.ID}
+		}
+
+		comments = append(comments, comment)
+		byTempID[item.TempID] = &comments[len(comments)-1]
+	}
+
+	if err := s.commentRepo.CreateBatch(comments); err != nil {
+		return nil, utils.WrapError(err, "failed to import comments")
+	}
+
+	result := &models.ImportCommentsResult{
+		Created: len(comments),
+		IDs:     make(map[string]uuid.UUID, len(comments)),
+	}
+	for tempID, comment := range byTempID {
+		result.IDs[tempID] = comment.ID
+	}
+
+	utils.LogInfoContext(ctx, "Comments imported", utils.LogFields{
+		"post_id":  postID,
+		"user_id":  userID,
+		"comments": result.Created,
+	})
+
+	return result, nil
+}
+
+// orderImportedComments checks that an import batch forms a forest and returns the
+// indices of its items ordered so every parent precedes its replies. Items otherwise
+// keep their input order.
+func orderImportedComments(items []models.ImportCommentItem) ([]int, error) {
+	indexByTempID := make(map[string]int, len(items))
+	for i, item := range items {
+		if _, exists := indexByTempID[item.TempID]; exists {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "duplicate temp_id: "+item.TempID)
+		}
+		indexByTempID[item.TempID] = i
+	}
+
+	parentIndex := make([]int, len(items))
+	for i, item := range items {
+		parentIndex[i] = -1
+		if item.ParentTempID == nil || *item.ParentTempID == "" {
+			continue
+		}
+		p, ok := indexByTempID[*item.ParentTempID]
+		if !ok {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "unknown parent_temp_id for comment "+item.TempID+": "+*item.ParentTempID)
+		}
+		if p == i {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "comment "+item.TempID+" cannot be its own parent")
+		}
+		parentIndex[i] = p
+	}
+
+	// Walk up from each unplaced item until reaching a placed item or a root, then place
+	// the walked chain root-first. Revisiting an item on the current chain means a cycle.
+	placed := make([]bool, len(items))
+	onChain := make([]bool, len(items))
+	order := make([]int, 0, len(items))
+	for i := range items {
+		var chain []int
+		for j := i; j != -1 && !placed[j]; j = parentIndex[j] {
+			if onChain[j] {
+				return nil, utils.WrapError(utils.ErrInvalidInput, "parent_temp_id references form a cycle at comment "+items[j].TempID)
+			}
+			onChain[j] = true
+			chain = append(chain, j)
+		}
+		for k := len(chain) - 1; k >= 0; k-- {
+			placed[chain[k]] = true
+			onChain[chain[k]] = false
+			order = append(order, chain[k])
+		}
+	}
+
+	return order, nil
+}
+
+// SubscribeToPost streams comments created on a post from now on. The returned function
+// ends the subscription and must be called when the caller stops reading.
+func (s *commentService) SubscribeToPost(postID uuid.UUID) (<-chan models.CommentResponse, func(), error) {
+	if s.hub == nil {
+		return nil, nil, utils.WrapError(utils.ErrInternalServer, "live comments are not enabled")
+	}
+
+	if _, err := s.postRepo.GetByID(postID); err != nil {
+		return nil, nil, utils.WrapError(err, "failed to find post")
+	}
+
+	updates, unsubscribe := s.hub.Subscribe(postID)
+	return updates, unsubscribe, nil
+}
+
+// GetCommentPermalink loads a comment with its author, its post and its ancestor chain.
+// The post and the ancestors are loaded concurrently. Deleted ancestors are left out of
+// the chain; a deleted comment or post is reported as not found.
+func (s *commentService) GetCommentPermalink(commentID uuid.UUID) (*models.CommentPermalinkResponse, error) {
+	comment, err := s.commentRepo.GetByIDWithAuthor(commentID)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get comment")
+	}
+
+	// The path ends with the comment itself
+	var ancestorIDs []uuid.UUID
+	if len(comment.Path) > 1 {
+		ancestorIDs = comment.Path[:len(comment.Path)-1]
+	}
+
+	var (
+		wg           sync.WaitGroup
+		post         *models.Post
+		postErr      error
+		ancestors    []models.Comment
+		ancestorsErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		post, postErr = s.postRepo.GetByIDWithAuthor(comment.PostID)
+	}()
+	go func() {
+		defer wg.Done()
+		ancestors, ancestorsErr = s.commentRepo.GetByIDs(ancestorIDs)
+	}()
+	wg.Wait()
+
+	if postErr != nil {
+		return nil, utils.WrapError(postErr, "failed to get comment's post")
+	}
+	if ancestorsErr != nil {
+		return nil, utils.WrapError(ancestorsErr, "failed to get comment ancestors")
+	}
+
+	// GetByIDs does not preserve order, so put the ancestors back in path order
+	byID := make(map[uuid.UUID]models.Comment, len(ancestors))
+	for _, ancestor := range ancestors {
+		byID[ancestor.ID] = ancestor
+	}
+	ancestorResponses := make([]models.CommentResponse, 0, len(ancestors))
+	for _, id := range ancestorIDs {
+		if ancestor, ok := byID[id]; ok {
+			ancestorResponses = append(ancestorResponses, ancestor.ToResponse())
+		}
+	}
+
+	var author models.UserResponse
+	if post.Author != nil {
+		author = post.Author.ToResponse()
+	}
+
+	return &models.CommentPermalinkResponse{
+		Comment: comment.ToResponse(),
+		Post: models.CommentPostSummary{
+			ID:        post.ID,
+			Title:     post.Title,
+			CreatedBy: post.CreatedBy,
+			Author:    author,
+			CreatedAt: post.CreatedAt,
+		},
+		Ancestors: ancestorResponses,
+	}, nil
+}
@@ -0,0 +1,1007 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+func TestCreateCommentDuplicateCheck(t *testing.T) {
+	tests := []struct {
+		name             string
+		role             string
+		first            string
+		second           string
+		window           time.Duration
+		exemptModerators bool
+		checkGuests      bool
+		wantErr          error
+	}{
+		{"exact repeat is blocked", models.RoleUser, "Great post!", "Great post!", time.Minute, true, false, utils.ErrDuplicateComment},
+		{"repeat with trivial differences is blocked", models.RoleUser, "Great post!", "  great   POST! ", time.Minute, true, false, utils.ErrDuplicateComment},
+		{"different comment is allowed", models.RoleUser, "Great post!", "Thanks for writing this", time.Minute, true, false, nil},
+		{"repeat outside the window is allowed", models.RoleUser, "Great post!", "Great post!", time.Nanosecond, true, false, nil},
+		{"exempt moderator repeat is allowed", models.RoleModerator, "Please keep it civil", "Please keep it civil", time.Minute, true, false, nil},
+		{"exempt admin repeat is allowed", models.RoleAdmin, "Please keep it civil", "Please keep it civil", time.Minute, true, false, nil},
+		{"moderator repeat is blocked without the exemption", models.RoleModerator, "Please keep it civil", "Please keep it civil", time.Minute, false, false, utils.ErrDuplicateComment},
+		{"guest repeat is allowed when guests are not checked", "", "Great post!", "Great post!", time.Minute, true, false, nil},
+		{"guest repeat is blocked when guests are checked", "", "Great post!", " great post! ", time.Minute, true, true, utils.ErrDuplicateComment},
+		{"different guest comment is allowed", "", "Great post!", "Thanks for writing this", time.Minute, true, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			if tt.role != "" {
+				user.Role = tt.role
+			}
+			post := testPost(user.ID)
+			post.AllowAnonymousComments = true
+			cfg := &config.CommentConfig{
+				DuplicateCheckEnabled:          true,
+				DuplicateWindow:                tt.window,
+				DuplicateCheckExemptModerators: tt.exemptModerators,
+				DuplicateCheckGuests:           tt.checkGuests,
+			}
+			svc := newTestCommentService(cfg, newFakeCommentRepo(), newFakePostRepo(post), newFakeUserRepo(user))
+
+			// An empty role posts as a guest from a fixed address
+			create := func(content string) error {
+				req := &models.CreateCommentRequest{PostID: post.ID, Content: &content}
+				if tt.role == "" {
+					name := "Jane"
+					req.GuestName = &name
+					_, err := svc.CreateGuestComment(context.Background(), "192.0.2.10", req)
+					return err
+				}
+				_, err := svc.CreateComment(context.Background(), user.ID, req)
+				return err
+			}
+
+			if err := create(tt.first); err != nil {
+				t.Fatalf("first comment: unexpected error %v", err)
+			}
+			if tt.window == time.Nanosecond {
+				time.Sleep(time.Millisecond)
+			}
+
+			if err := create(tt.second); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("second comment: got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestActivityOrderingBumps(t *testing.T) {
+	tests := []struct {
+		name        string
+		bumpOnEdit  bool
+		action      string
+		wantOldLead bool
+	}{
+		{"content edit does not reorder", false, "edit", false},
+		{"content edit reorders when bump on edit is enabled", true, "edit", true},
+		{"new reply reorders", false, "reply", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			post := testPost(user.ID)
+			older := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &user.ID, Content: "older", CreatedAt: time.Now().Add(-2 * time.Hour)}
+			older.Path, older.ThreadID = []uuid.UUID{older.ID}, older.ID
+			newer := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &user.ID, Content: "newer", CreatedAt: time.Now().Add(-time.Hour)}
+			newer.Path, newer.ThreadID = []uuid.UUID{newer.ID}, newer.ID
+
+			repo := newFakeCommentRepo(older, newer)
+			cfg := &config.CommentConfig{BumpOnReply: true, BumpOnEdit: tt.bumpOnEdit, MaxReplyDepth: 8}
+			svc := newTestCommentService(cfg, repo, newFakePostRepo(post), newFakeUserRepo(user))
+
+			content := "an update to the older comment"
+			switch tt.action {
+			case "edit":
+				if _, err := svc.UpdateComment(context.Background(), older.ID, user.ID, &models.UpdateCommentRequest{Content: &content}); err != nil {
+					t.Fatalf("edit: unexpected error %v", err)
+				}
+			case "reply":
+				parentID := older.ID.String()
+				if _, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, ParentID: &parentID, Content: &content}); err != nil {
+					t.Fatalf("reply: unexpected error %v", err)
+				}
+			}
+
+			order := repo.activityOrder(post.ID)
+			if len(order) != 2 {
+				t.Fatalf("got %d top-level comments, want 2", len(order))
+			}
+			if gotOldLead := order[0] == older.ID; gotOldLead != tt.wantOldLead {
+				t.Errorf("older comment first = %v, want %v", gotOldLead, tt.wantOldLead)
+			}
+		})
+	}
+}
+
+func TestMoveComment(t *testing.T) {
+	admin := testUser(models.RoleAdmin)
+	post, otherPost := testPost(admin.ID), testPost(admin.ID)
+
+	newComment := func(postID uuid.UUID, parent *models.Comment) *models.Comment {
+		c := &models.Comment{ID: uuid.New(), PostID: postID, CreatedBy: &admin.ID, CreatedAt: time.Now()}
+		if parent == nil {
+			c.ThreadID, c.Path = c.ID, []uuid.UUID{c.ID}
+		} else {
+			c.ParentID, c.ThreadID = &parent.ID, parent.ThreadID
+			c.Path = append(append([]uuid.UUID{}, parent.Path...), c.ID)
+		}
+		return c
+	}
+
+	toTarget := func(_, _, target, _ *models.Comment) *models.Comment { return target }
+
+	// Under target (depth 2), the moved subtree's deepest comment lands at depth 5
+	tests := []struct {
+		name      string
+		newParent func(moved, reply *models.Comment, target, elsewhere *models.Comment) *models.Comment
+		maxDepth  int
+		wantErr   error
+	}{
+		{"moves under another thread", toTarget, 0, nil},
+		{"moves to exactly the depth limit", toTarget, 5, nil},
+		{"rejects a move that nests replies too deep", toTarget, 4, utils.ErrInvalidInput},
+		{"rejects a move under itself", func(moved, _, _, _ *models.Comment) *models.Comment { return moved }, 0, utils.ErrInvalidInput},
+		{"rejects a move under one of its replies", func(_, reply, _, _ *models.Comment) *models.Comment { return reply }, 0, utils.ErrInvalidInput},
+		{"rejects a parent on another post", func(_, _, _, elsewhere *models.Comment) *models.Comment { return elsewhere }, 0, utils.ErrParentMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newComment(post.ID, nil)
+			moved := newComment(post.ID, root)
+			reply := newComment(post.ID, moved)
+			nested := newComment(post.ID, reply)
+			target := newComment(post.ID, newComment(post.ID, nil))
+			elsewhere := newComment(otherPost.ID, nil)
+			repo := newFakeCommentRepo(root, moved, reply, nested, target, elsewhere)
+			svc := newTestCommentService(&config.CommentConfig{MaxReplyDepth: tt.maxDepth}, repo, newFakePostRepo(post, otherPost), newFakeUserRepo(admin))
+
+			newParent := tt.newParent(moved, reply, target, elsewhere)
+			_, err := svc.MoveComment(context.Background(), moved.ID, newParent.ID, admin.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if moved.ParentID == nil || *moved.ParentID != root.ID {
+					t.Errorf("rejected move still changed the parent")
+				}
+				return
+			}
+
+			// Each descendant keeps its place below the moved comment, below the new parent
+			wantPaths := map[*models.Comment][]uuid.UUID{
+				moved:  append(append([]uuid.UUID{}, target.Path...), moved.ID),
+				reply:  append(append([]uuid.UUID{}, target.Path...), moved.ID, reply.ID),
+				nested: append(append([]uuid.UUID{}, target.Path...), moved.ID, reply.ID, nested.ID),
+			}
+			for c, want := range wantPaths {
+				if fmt.Sprint(c.Path) != fmt.Sprint(want) {
+					t.Errorf("path = %v, want %v", c.Path, want)
+				}
+				if c.ThreadID != target.ThreadID {
+					t.Errorf("thread_id = %v, want %v", c.ThreadID, target.ThreadID)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileRepliesCounts(t *testing.T) {
+	author := testUser(models.RoleUser)
+	post := testPost(author.ID)
+
+	newComment := func(parent *models.Comment) *models.Comment {
+		c := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &author.ID, CreatedAt: time.Now()}
+		if parent != nil {
+			c.ParentID = &parent.ID
+			parent.RepliesCount++
+		}
+		return c
+	}
+
+	root := newComment(nil)
+	reply := newComment(root)
+	nested := newComment(reply)
+	other := newComment(nil)
+	deletedAt := time.Now()
+	deletedReply := newComment(other)
+	deletedReply.DeletedAt = &deletedAt
+	other.RepliesCount-- // the deleted reply no longer counts
+
+	// Corrupt two counts: one too high, one too low
+	root.RepliesCount = 7
+	reply.RepliesCount = 0
+
+	repo := newFakeCommentRepo(root, reply, nested, other, deletedReply)
+	svc := newTestCommentService(nil, repo, newFakePostRepo(post), newFakeUserRepo(author))
+
+	for _, batchSize := range []int{1, 2, 500} {
+		result, err := svc.ReconcileRepliesCounts(context.Background(), batchSize)
+		if err != nil {
+			t.Fatalf("batch size %d: unexpected error %v", batchSize, err)
+		}
+		if result.Scanned != 4 {
+			t.Errorf("batch size %d: scanned %d comments, want 4", batchSize, result.Scanned)
+		}
+		wantCorrected := 0
+		if batchSize == 1 {
+			wantCorrected = 2
+		}
+		if result.Corrected != wantCorrected {
+			t.Errorf("batch size %d: corrected %d counts, want %d", batchSize, result.Corrected, wantCorrected)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		comment *models.Comment
+		want    int
+	}{
+		{"root", root, 1},
+		{"reply", reply, 1},
+		{"nested", nested, 0},
+		{"other", other, 0},
+	} {
+		if tc.comment.RepliesCount != tc.want {
+			t.Errorf("%s replies_count = %d, want %d", tc.name, tc.comment.RepliesCount, tc.want)
+		}
+	}
+}
+
+func TestCreateCommentRecordsMentions(t *testing.T) {
+	author := testUser(models.RoleUser)
+	author.Username = "author"
+	users := newFakeUserRepo(author)
+	for _, name := range []string{"alice", "bob"} {
+		u := testUser(models.RoleUser)
+		u.Username = name
+		users.users[u.ID] = u
+	}
+	var many []string
+	for i := 0; i < maxMentionsPerComment+5; i++ {
+		u := testUser(models.RoleUser)
+		users.users[u.ID] = u
+		many = append(many, "@"+u.Username)
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		wantMentions int
+		wantLookups  int
+	}{
+		{"two users", "Thanks @alice and @bob", 2, 1},
+		{"same user twice in different casing", "@alice said it, right @ALICE?", 1, 1},
+		{"unknown username is ignored", "Hello @nobody_here and @bob", 1, 1},
+		{"self mention is ignored", "As @author I agree with @alice", 1, 1},
+		{"email address is not a mention", "Write to bob@example.com", 0, 0},
+		{"mentions beyond the cap are ignored", strings.Join(many, " "), maxMentionsPerComment, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := testPost(author.ID)
+			comments := newFakeCommentRepo()
+			users.usernameLookups = 0
+			svc := newTestCommentService(nil, comments, newFakePostRepo(post), users)
+
+			content := tt.content
+			comment, err := svc.CreateComment(context.Background(), author.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := len(comments.mentions[comment.ID]); got != tt.wantMentions {
+				t.Errorf("recorded %d mentions, want %d", got, tt.wantMentions)
+			}
+			if users.usernameLookups != tt.wantLookups {
+				t.Errorf("made %d username lookups, want %d", users.usernameLookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestServiceLogsIncludeRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	previous := utils.Logger.Out
+	utils.Logger.SetOutput(&buf)
+	defer utils.Logger.SetOutput(previous)
+
+	reqCtx := &utils.RequestContext{
+		RequestID: "req-123",
+		UserID:    uuid.NewString(),
+		Method:    "POST",
+		Path:      "/api/v1/admin/comments/reconcile-counts",
+	}
+	ctx := utils.ContextWithRequestContext(context.Background(), reqCtx)
+
+	svc := newTestCommentService(nil, newFakeCommentRepo(), newFakePostRepo(), newFakeUserRepo())
+	if _, err := svc.ReconcileRepliesCounts(ctx, 10); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log output %q is not a single JSON entry: %v", buf.String(), err)
+	}
+	want := map[string]string{
+		"request_id": reqCtx.RequestID,
+		"user_id":    reqCtx.UserID,
+		"method":     reqCtx.Method,
+		"path":       reqCtx.Path,
+	}
+	for field, value := range want {
+		if entry[field] != value {
+			t.Errorf("log field %s = %v, want %q", field, entry[field], value)
+		}
+	}
+}
+
+func TestPurgeDeletedComments(t *testing.T) {
+	author := testUser(models.RoleUser)
+	post := testPost(author.ID)
+	longAgo := time.Now().Add(-48 * time.Hour)
+	recently := time.Now().Add(-time.Minute)
+
+	newComment := func(parent *models.Comment, deletedAt *time.Time) *models.Comment {
+		c := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &author.ID, DeletedAt: deletedAt}
+		if parent != nil {
+			c.ParentID = &parent.ID
+		}
+		return c
+	}
+
+	leaf := newComment(nil, &longAgo)
+	tombstone := newComment(nil, &longAgo)
+	liveReply := newComment(tombstone, nil)
+	recentLeaf := newComment(nil, &recently)
+	// A deleted parent whose only reply is also purgeable goes once the reply is gone
+	deletedParent := newComment(nil, &longAgo)
+	deletedChild := newComment(deletedParent, &longAgo)
+
+	repo := newFakeCommentRepo(leaf, tombstone, liveReply, recentLeaf, deletedParent, deletedChild)
+	cfg := &config.CommentConfig{PurgeRetention: 24 * time.Hour, PurgeBatchSize: 1}
+	svc := newTestCommentService(cfg, repo, newFakePostRepo(post), newFakeUserRepo(author))
+
+	result, err := svc.PurgeDeletedComments(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Purged != 3 {
+		t.Errorf("purged %d comments, want 3", result.Purged)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		comment *models.Comment
+		kept    bool
+	}{
+		{"purgeable leaf", leaf, false},
+		{"tombstone with a live reply", tombstone, true},
+		{"live reply", liveReply, true},
+		{"leaf deleted within the retention", recentLeaf, true},
+		{"deleted parent of a purged reply", deletedParent, false},
+		{"purged reply", deletedChild, false},
+	} {
+		if _, kept := repo.comments[tc.comment.ID]; kept != tc.kept {
+			t.Errorf("%s: kept = %v, want %v", tc.name, kept, tc.kept)
+		}
+	}
+}
+
+func TestGetCommentPermissions(t *testing.T) {
+	owner := testUser(models.RoleUser)
+	stranger := testUser(models.RoleUser)
+	moderator := testUser(models.RoleModerator)
+
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		role        string
+		locked      bool
+		allowGuests bool
+		depth       int
+		maxDepth    int
+		brokenPath  bool
+		want        models.CommentPermissions
+	}{
+		{"owner", owner.ID, owner.Role, false, false, 1, 0, false, models.CommentPermissions{CanEdit: true, CanDelete: true, CanReply: true}},
+		{"moderator", moderator.ID, moderator.Role, false, false, 1, 0, false, models.CommentPermissions{CanDelete: true, CanModerate: true, CanReply: true}},
+		{"stranger", stranger.ID, stranger.Role, false, false, 1, 0, false, models.CommentPermissions{CanReply: true}},
+		{"anonymous on a post without guest comments", uuid.Nil, "", false, false, 1, 0, false, models.CommentPermissions{}},
+		{"anonymous on a post with guest comments", uuid.Nil, "", false, true, 1, 0, false, models.CommentPermissions{CanReply: true}},
+		{"anonymous on a locked post with guest comments", uuid.Nil, "", true, true, 1, 0, false, models.CommentPermissions{}},
+		{"stranger on a locked post", stranger.ID, stranger.Role, true, false, 1, 0, false, models.CommentPermissions{}},
+		{"owner below the depth cap", owner.ID, owner.Role, false, false, 2, 3, false, models.CommentPermissions{CanEdit: true, CanDelete: true, CanReply: true}},
+		{"owner at the depth cap", owner.ID, owner.Role, false, false, 3, 3, false, models.CommentPermissions{CanEdit: true, CanDelete: true}},
+		{"stranger on a comment with a broken path", stranger.ID, stranger.Role, false, false, 2, 0, true, models.CommentPermissions{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := testPost(owner.ID)
+			post.CommentsLocked = tt.locked
+			post.AllowAnonymousComments = tt.allowGuests
+
+			// Build a chain of tt.depth comments owned by owner and ask about the deepest
+			comments := newFakeCommentRepo()
+			var parent *models.Comment
+			for i := 0; i < tt.depth; i++ {
+				c := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &owner.ID}
+				if parent == nil {
+					c.ThreadID = c.ID
+					c.Path = []uuid.UUID{c.ID}
+				} else {
+					c.ParentID = &parent.ID
+					c.ThreadID = parent.ThreadID
+					c.Path = append(append([]uuid.UUID{}, parent.Path...), c.ID)
+				}
+				comments.comments[c.ID] = c
+				parent = c
+			}
+			if tt.brokenPath {
+				parent.Path = []uuid.UUID{parent.ID}
+			}
+
+			cfg := &config.CommentConfig{MaxReplyDepth: tt.maxDepth}
+			svc := newTestCommentService(cfg, comments, newFakePostRepo(post), newFakeUserRepo(owner, stranger, moderator))
+
+			got, err := svc.GetCommentPermissions(parent.ID, tt.userID, tt.role)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func importItem(tempID, parentTempID string) models.ImportCommentItem {
+	item := models.ImportCommentItem{TempID: tempID, Content: "comment " + tempID}
+	if parentTempID != "" {
+		item.ParentTempID = &parentTempID
+	}
+	return item
+}
+
+func TestOrderImportedComments(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []models.ImportCommentItem
+		wantOrder []string
+		wantErr   bool
+	}{
+		{
+			name:      "already ordered forest keeps its order",
+			items:     []models.ImportCommentItem{importItem("a", ""), importItem("b", "a"), importItem("c", "")},
+			wantOrder: []string{"a", "b", "c"},
+		},
+		{
+			name:      "replies before their parents are moved after them",
+			items:     []models.ImportCommentItem{importItem("c", "b"), importItem("b", "a"), importItem("a", ""), importItem("d", "")},
+			wantOrder: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:      "siblings keep their input order",
+			items:     []models.ImportCommentItem{importItem("y", "root"), importItem("x", "root"), importItem("root", "")},
+			wantOrder: []string{"root", "y", "x"},
+		},
+		{name: "duplicate temp_id", items: []models.ImportCommentItem{importItem("a", ""), importItem("a", "")}, wantErr: true},
+		{name: "unknown parent", items: []models.ImportCommentItem{importItem("a", "missing")}, wantErr: true},
+		{name: "own parent", items: []models.ImportCommentItem{importItem("a", "a")}, wantErr: true},
+		{name: "cycle", items: []models.ImportCommentItem{importItem("r", ""), importItem("a", "c"), importItem("b", "a"), importItem("c", "b")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := orderImportedComments(tt.items)
+			if tt.wantErr {
+				if !errors.Is(err, utils.ErrInvalidInput) {
+					t.Fatalf("got error %v, want invalid input", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := make([]string, len(order))
+			for i, idx := range order {
+				got[i] = tt.items[idx].TempID
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantOrder, ",") {
+				t.Errorf("got order %v, want %v", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestImportNestedThread(t *testing.T) {
+	admin := testUser(models.RoleAdmin)
+	post := testPost(admin.ID)
+	repo := newFakeCommentRepo()
+	svc := newTestCommentService(nil, repo, newFakePostRepo(post), newFakeUserRepo(admin))
+
+	// Sent leaf first, so the service has to order parents before replies
+	req := &models.ImportCommentsRequest{Comments: []models.ImportCommentItem{
+		importItem("nested", "reply"),
+		importItem("reply", "root"),
+		importItem("sibling", "root"),
+		importItem("root", ""),
+		importItem("second", ""),
+	}}
+
+	result, err := svc.ImportComments(context.Background(), post.ID, admin.ID, req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Created != 5 || len(result.IDs) != 5 {
+		t.Fatalf("created %d comments with %d ids, want 5", result.Created, len(result.IDs))
+	}
+
+	ids := result.IDs
+	wantPaths := map[string][]uuid.UUID{
+		"root":    {ids["root"]},
+		"reply":   {ids["root"], ids["reply"]},
+		"nested":  {ids["root"], ids["reply"], ids["nested"]},
+		"sibling": {ids["root"], ids["sibling"]},
+		"second":  {ids["second"]},
+	}
+	for tempID, wantPath := range wantPaths {
+		c := repo.comments[ids[tempID]]
+		if c == nil {
+			t.Fatalf("comment %s was not created", tempID)
+		}
+		if fmt.Sprint(c.Path) != fmt.Sprint(wantPath) {
+			t.Errorf("%s: path = %v, want %v", tempID, c.Path, wantPath)
+		}
+		if c.ThreadID != wantPath[0] {
+			t.Errorf("%s: thread_id = %v, want %v", tempID, c.ThreadID, wantPath[0])
+		}
+		if len(wantPath) > 1 && (c.ParentID == nil || *c.ParentID != wantPath[len(wantPath)-2]) {
+			t.Errorf("%s: parent_id = %v, want %v", tempID, c.ParentID, wantPath[len(wantPath)-2])
+		}
+		if len(wantPath) > 1 && !c.CreatedAt.After(repo.comments[*c.ParentID].CreatedAt) {
+			t.Errorf("%s: created_at does not follow its parent's", tempID)
+		}
+	}
+}
+
+func TestDeleteThenRestoreComment(t *testing.T) {
+	author := testUser(models.RoleUser)
+	post := testPost(author.ID)
+	comment := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &author.ID, Content: "hello", CreatedAt: time.Now()}
+	svc := newTestCommentService(nil, newFakeCommentRepo(comment), newFakePostRepo(post), newFakeUserRepo(author))
+	get := &models.GetCommentRequest{ID: comment.ID.String()}
+
+	if err := svc.RestoreComment(comment.ID); !errors.Is(err, utils.ErrNotDeleted) {
+		t.Fatalf("restoring a live comment: got error %v, want ErrNotDeleted", err)
+	}
+
+	if err := svc.DeleteComment(context.Background(), &models.DeleteCommentRequest{ID: comment.ID.String()}, author.ID, author.Role); err != nil {
+		t.Fatalf("delete: unexpected error %v", err)
+	}
+	if _, err := svc.GetCommentByID(get); !errors.Is(err, utils.ErrCommentNotFound) {
+		t.Fatalf("get after delete: got error %v, want ErrCommentNotFound", err)
+	}
+
+	if err := svc.RestoreComment(comment.ID); err != nil {
+		t.Fatalf("restore: unexpected error %v", err)
+	}
+	restored, err := svc.GetCommentByID(get)
+	if err != nil {
+		t.Fatalf("get after restore: unexpected error %v", err)
+	}
+	if restored.ID != comment.ID || restored.Content != "hello" {
+		t.Errorf("got comment %v %q after restore, want %v %q", restored.ID, restored.Content, comment.ID, "hello")
+	}
+
+	if err := svc.RestoreComment(uuid.New()); !errors.Is(err, utils.ErrCommentNotFound) {
+		t.Errorf("restoring a missing comment: got error %v, want ErrCommentNotFound", err)
+	}
+}
+
+func TestGetCommentPermalink(t *testing.T) {
+	author := testUser(models.RoleUser)
+	replier := testUser(models.RoleUser)
+	post := testPost(author.ID)
+	post.Author = author
+
+	top := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &author.ID, Author: author, Content: "top"}
+	top.Path, top.ThreadID = []uuid.UUID{top.ID}, top.ID
+	middle := &models.Comment{ID: uuid.New(), PostID: post.ID, ParentID: &top.ID, CreatedBy: &replier.ID, Author: replier, Content: "middle"}
+	middle.Path, middle.ThreadID = []uuid.UUID{top.ID, middle.ID}, top.ID
+	leaf := &models.Comment{ID: uuid.New(), PostID: post.ID, ParentID: &middle.ID, CreatedBy: &replier.ID, Author: replier, Content: "leaf"}
+	leaf.Path, leaf.ThreadID = []uuid.UUID{top.ID, middle.ID, leaf.ID}, top.ID
+
+	comments := newFakeCommentRepo(top, middle, leaf)
+	posts := newFakePostRepo(post)
+	svc := newTestCommentService(nil, comments, posts, newFakeUserRepo(author, replier))
+
+	permalink, err := svc.GetCommentPermalink(leaf.ID)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if permalink.Comment.ID != leaf.ID || permalink.Comment.Content != "leaf" {
+		t.Errorf("got comment %v %q, want %v %q", permalink.Comment.ID, permalink.Comment.Content, leaf.ID, "leaf")
+	}
+	if permalink.Comment.Author == nil || permalink.Comment.Author.ID != replier.ID {
+		t.Errorf("got comment author %+v, want %v", permalink.Comment.Author, replier.ID)
+	}
+	if permalink.Post.ID != post.ID || permalink.Post.Title != post.Title || permalink.Post.CreatedAt.IsZero() {
+		t.Errorf("got post summary %+v, want post %v %q", permalink.Post, post.ID, post.Title)
+	}
+	if permalink.Post.Author.ID != author.ID || permalink.Post.Author.Username != author.Username {
+		t.Errorf("got post author %+v, want %v", permalink.Post.Author, author.ID)
+	}
+	if len(permalink.Ancestors) != 2 || permalink.Ancestors[0].ID != top.ID || permalink.Ancestors[1].ID != middle.ID {
+		t.Fatalf("got ancestors %+v, want top then middle", permalink.Ancestors)
+	}
+	for _, ancestor := range permalink.Ancestors {
+		if ancestor.Author == nil || ancestor.Content == "" {
+			t.Errorf("ancestor %v is missing its author or content", ancestor.ID)
+		}
+	}
+
+	now := time.Now()
+	post.DeletedAt = &now
+	if _, err := svc.GetCommentPermalink(leaf.ID); !errors.Is(err, utils.ErrPostNotFound) {
+		t.Errorf("comment on a deleted post: got error %v, want ErrPostNotFound", err)
+	}
+	post.DeletedAt = nil
+
+	leaf.DeletedAt = &now
+	if _, err := svc.GetCommentPermalink(leaf.ID); !errors.Is(err, utils.ErrCommentNotFound) {
+		t.Errorf("deleted comment: got error %v, want ErrCommentNotFound", err)
+	}
+}
+
+func TestReplyChainStopsAtMaxDepth(t *testing.T) {
+	author := testUser(models.RoleUser)
+	post := testPost(author.ID)
+	comments := newFakeCommentRepo()
+	svc := newTestCommentService(&config.CommentConfig{MaxReplyDepth: 3}, comments, newFakePostRepo(post), newFakeUserRepo(author))
+
+	var parentID *string
+	var chain []*models.Comment
+	for depth := 1; depth <= 3; depth++ {
+		content := fmt.Sprintf("comment at depth %d", depth)
+		comment, err := svc.CreateComment(context.Background(), author.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content, ParentID: parentID})
+		if err != nil {
+			t.Fatalf("depth %d: unexpected error %v", depth, err)
+		}
+		if comment.Depth() != depth {
+			t.Fatalf("comment at depth %d reports depth %d", depth, comment.Depth())
+		}
+		chain = append(chain, comment)
+		id := comment.ID.String()
+		parentID = &id
+	}
+
+	content := "one level too deep"
+	_, err := svc.CreateComment(context.Background(), author.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content, ParentID: parentID})
+	if !errors.Is(err, utils.ErrInvalidInput) || !strings.Contains(err.Error(), "more than 3 levels deep") {
+		t.Fatalf("reply below the cap: got error %v, want the depth limit", err)
+	}
+	if len(comments.comments) != 3 {
+		t.Errorf("stored %d comments, want the rejected reply not to be stored", len(comments.comments))
+	}
+
+	// A sibling at the last allowed level is still accepted
+	sibling := "another reply at depth 3"
+	parent := chain[1].ID.String()
+	if _, err := svc.CreateComment(context.Background(), author.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &sibling, ParentID: &parent}); err != nil {
+		t.Errorf("reply at the cap: unexpected error %v", err)
+	}
+}
+
+func TestCreateCommentBannedWords(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		content     string
+		wantErr     error
+		wantContent string
+		wantRaw     string
+	}{
+		{
+			name:    "reject mode fails the comment",
+			mode:    utils.ContentFilterModeReject,
+			content: "well darn it",
+			wantErr: utils.ErrInvalidInput,
+		},
+		{
+			name:        "reject mode ignores a word inside a longer one",
+			mode:        utils.ContentFilterModeReject,
+			content:     "darned if I know",
+			wantContent: "<span>darned if I know</span>",
+			wantRaw:     "darned if I know",
+		},
+		{
+			name:        "reject mode ignores a word only in a link target",
+			mode:        utils.ContentFilterModeReject,
+			content:     `<a href="https://example.com/darn">link</a>`,
+			wantContent: `<a href="https://example.com/darn">link</a>`,
+			wantRaw:     `<a href="https://example.com/darn">link</a>`,
+		},
+		{
+			name:        "mask mode masks plain text",
+			mode:        utils.ContentFilterModeMask,
+			content:     "well DARN it",
+			wantContent: "<span>well **** it</span>",
+			wantRaw:     "well **** it",
+		},
+		{
+			name:        "mask mode keeps link targets",
+			mode:        utils.ContentFilterModeMask,
+			content:     `<a href="https://example.com/darn">darn</a>`,
+			wantContent: `<a href="https://example.com/darn">****</a>`,
+			wantRaw:     `<a href="https://example.com/darn">****</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			post := testPost(user.ID)
+			cfg := &config.CommentConfig{BannedWordsMode: tt.mode}
+			svc := newTestCommentService(cfg, newFakeCommentRepo(), newFakePostRepo(post), newFakeUserRepo(user))
+			svc.contentFilter = utils.NewContentFilter([]string{"darn"})
+
+			content := tt.content
+			comment, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if comment.Content != tt.wantContent {
+				t.Errorf("got content %q, want %q", comment.Content, tt.wantContent)
+			}
+			if comment.ContentRaw == nil || *comment.ContentRaw != tt.wantRaw {
+				t.Errorf("got raw content %v, want %q", comment.ContentRaw, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestCommentContentMaxLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"within the limit", "short", ""},
+		{"over the limit", strings.Repeat("a", 30), "comment is too long"},
+		{"over the limit only after escaping", "&&&&", "comment is too long"},
+		{"over the request limit", strings.Repeat("a", 10001), "content must be at most 10000 characters long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			post := testPost(user.ID)
+			comments := newFakeCommentRepo()
+			// "<span>short</span>" is 18 characters once processed
+			cfg := &config.CommentConfig{MaxContentLength: 20}
+			svc := newTestCommentService(cfg, comments, newFakePostRepo(post), newFakeUserRepo(user))
+
+			content := tt.content
+			_, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content})
+			checkLengthError(t, "create", err, tt.wantErr)
+
+			original := "ok"
+			existing, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &original})
+			if err != nil {
+				t.Fatalf("creating the comment to update: %v", err)
+			}
+			_, err = svc.UpdateComment(context.Background(), existing.ID, user.ID, &models.UpdateCommentRequest{Content: &content})
+			checkLengthError(t, "update", err, tt.wantErr)
+		})
+	}
+}
+
+func TestCommentContentMinLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		reply       bool
+		holdReplies bool
+		wantErr     string
+	}{
+		{"enough text", "<b>okay</b>", false, true, ""},
+		{"too short once markup is stripped", "<b>k</b>", false, true, "at least 3 characters of text"},
+		{"only markup and whitespace", "<p>   </p>", false, true, "at least 3 characters of text"},
+		{"reply exempted when replies are not held to the minimum", "<b>k</b>", true, false, ""},
+		{"reply held to the minimum", "<b>k</b>", true, true, "at least 3 characters of text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(models.RoleUser)
+			post := testPost(user.ID)
+			cfg := &config.CommentConfig{MinContentLength: 3, MinContentLengthReplies: tt.holdReplies, MaxReplyDepth: 8}
+			svc := newTestCommentService(cfg, newFakeCommentRepo(), newFakePostRepo(post), newFakeUserRepo(user))
+
+			parentContent := "the parent comment"
+			parent, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &parentContent})
+			if err != nil {
+				t.Fatalf("creating the parent comment: %v", err)
+			}
+			request := func(content string) *models.CreateCommentRequest {
+				req := &models.CreateCommentRequest{PostID: post.ID, Content: &content}
+				if tt.reply {
+					parentID := parent.ID.String()
+					req.ParentID = &parentID
+				}
+				return req
+			}
+
+			_, err = svc.CreateComment(context.Background(), user.ID, request(tt.content))
+			checkLengthError(t, "create", err, tt.wantErr)
+
+			existing, err := svc.CreateComment(context.Background(), user.ID, request("long enough to start with"))
+			if err != nil {
+				t.Fatalf("creating the comment to update: %v", err)
+			}
+			content := tt.content
+			_, err = svc.UpdateComment(context.Background(), existing.ID, user.ID, &models.UpdateCommentRequest{Content: &content})
+			checkLengthError(t, "update", err, tt.wantErr)
+		})
+	}
+}
+
+// checkLengthError fails the test unless err is nil when wantErr is empty, or an
+// invalid input error containing wantErr otherwise
+func checkLengthError(t *testing.T, action string, err error, wantErr string) {
+	t.Helper()
+	if wantErr == "" {
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", action, err)
+		}
+		return
+	}
+	if !errors.Is(err, utils.ErrInvalidInput) || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("%s: got error %v, want an invalid input error containing %q", action, err, wantErr)
+	}
+}
+
+func TestIsWellFormedPath(t *testing.T) {
+	root, mid, leaf, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		comment *models.Comment
+		want    bool
+	}{
+		{"top-level comment", &models.Comment{ID: root, ThreadID: root, Path: []uuid.UUID{root}}, true},
+		{"reply", &models.Comment{ID: leaf, ParentID: &mid, ThreadID: root, Path: []uuid.UUID{root, mid, leaf}}, true},
+		{"empty path", &models.Comment{ID: root, ThreadID: root}, false},
+		{"path does not end at the comment", &models.Comment{ID: leaf, ParentID: &mid, ThreadID: root, Path: []uuid.UUID{root, mid}}, false},
+		{"path does not start at the thread", &models.Comment{ID: leaf, ParentID: &mid, ThreadID: other, Path: []uuid.UUID{root, mid, leaf}}, false},
+		{"parent is not the previous entry", &models.Comment{ID: leaf, ParentID: &other, ThreadID: root, Path: []uuid.UUID{root, mid, leaf}}, false},
+		{"top-level comment with ancestors", &models.Comment{ID: leaf, ThreadID: root, Path: []uuid.UUID{root, leaf}}, false},
+		{"reply without ancestors", &models.Comment{ID: root, ParentID: &mid, ThreadID: root, Path: []uuid.UUID{root}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWellFormedPath(tt.comment); got != tt.want {
+				t.Errorf("isWellFormedPath = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasCycle(t *testing.T) {
+	a, b, c, moved := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		parentPath []uuid.UUID
+		want       bool
+	}{
+		{"fresh comment under a clean path", []uuid.UUID{a, b, c}, false},
+		{"parent is the comment itself", []uuid.UUID{a, moved}, true},
+		{"parent is a reply of the comment", []uuid.UUID{a, moved, b}, true},
+		{"path repeats an ancestor", []uuid.UUID{a, b, a, c}, true},
+		{"path repeats the parent", []uuid.UUID{a, c, c}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasCycle(tt.parentPath, moved); got != tt.want {
+				t.Errorf("hasCycle = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCommentRejectsMalformedParentPath(t *testing.T) {
+	user := testUser(models.RoleUser)
+	post := testPost(user.ID)
+	root, parent := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name string
+		path []uuid.UUID
+	}{
+		{"path loops back to the thread", []uuid.UUID{root, parent, root, parent}},
+		{"path ends at another comment", []uuid.UUID{root, uuid.New()}},
+		{"path repeats the parent", []uuid.UUID{root, parent, parent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments := newFakeCommentRepo()
+			comments.comments[parent] = &models.Comment{ID: parent, PostID: post.ID, ParentID: &root, ThreadID: root, Path: tt.path, CreatedAt: time.Now()}
+			svc := newTestCommentService(nil, comments, newFakePostRepo(post), newFakeUserRepo(user))
+
+			content, parentID := "reply", parent.String()
+			_, err := svc.CreateComment(context.Background(), user.ID, &models.CreateCommentRequest{PostID: post.ID, ParentID: &parentID, Content: &content})
+			if !errors.Is(err, utils.ErrInvalidInput) || !strings.Contains(err.Error(), "malformed thread path") {
+				t.Fatalf("got error %v, want a malformed path error", err)
+			}
+			if len(comments.comments) != 1 {
+				t.Errorf("stored %d comments, want only the parent", len(comments.comments))
+			}
+		})
+	}
+}
+
+func TestCreateGuestCommentRateLimit(t *testing.T) {
+	author := testUser(models.RoleUser)
+	post := testPost(author.ID)
+	post.AllowAnonymousComments = true
+
+	// An earlier comment from the same address, outside the window
+	oldIP := "192.0.2.10"
+	old := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedAt: time.Now().Add(-2 * time.Hour), GuestIP: &oldIP}
+	comments := newFakeCommentRepo(old)
+
+	cfg := &config.CommentConfig{GuestMaxPerIP: 2, GuestWindow: time.Hour}
+	svc := newTestCommentService(cfg, comments, newFakePostRepo(post), newFakeUserRepo(author))
+
+	create := func(ip string) error {
+		content, name := "hello there", "Jane"
+		_, err := svc.CreateGuestComment(context.Background(), ip, &models.CreateCommentRequest{PostID: post.ID, Content: &content, GuestName: &name})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := create("192.0.2.10"); err != nil {
+			t.Fatalf("comment %d: unexpected error %v", i+1, err)
+		}
+	}
+	if err := create("192.0.2.10"); !errors.Is(err, utils.ErrGuestRateLimited) {
+		t.Fatalf("comment over the limit: got error %v, want %v", err, utils.ErrGuestRateLimited)
+	}
+	if len(comments.comments) != 3 {
+		t.Errorf("stored %d comments, want 3 with the refused one left out", len(comments.comments))
+	}
+	if err := create("192.0.2.11"); err != nil {
+		t.Errorf("another address: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// EmailSender delivers transactional email such as password reset messages
+type EmailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// logEmailSender implements EmailSender by writing messages to the application log.
+// The body is only logged at debug level since it may contain secrets such as reset tokens.
+type logEmailSender struct{}
+
+// NewLogEmailSender creates an EmailSender that logs messages instead of sending them,
+// for development and for deployments without a mail provider configured
+func NewLogEmailSender() EmailSender {
+	return &logEmailSender{}
+}
+
+// Send logs the message
+func (s *logEmailSender) Send(ctx context.Context, to, subject, body string) error {
+	utils.LogInfoContext(ctx, "Email queued", utils.LogFields{
+		"to":      to,
+		"subject": subject,
+	})
+	utils.LogDebugContext(ctx, "Email body", utils.LogFields{
+		"to":   to,
+		"body": body,
+	})
+
+	return nil
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/TejasThombare20/post-comments-service/validator"
+	"github.com/google/uuid"
+)
+
+// The fakes embed the repository interfaces so each one only implements the methods the
+// tests in this package exercise; calling anything else panics on the nil embedded value.
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users           map[uuid.UUID]*models.User
+	usernameChanges map[uuid.UUID][]time.Time
+	usernameLookups int
+}
+
+func newFakeUserRepo(users ...*models.User) *fakeUserRepo {
+	r := &fakeUserRepo{
+		users:           make(map[uuid.UUID]*models.User),
+		usernameChanges: make(map[uuid.UUID][]time.Time),
+	}
+	for _, u := range users {
+		r.users[u.ID] = u
+	}
+	return r
+}
+
+func (r *fakeUserRepo) GetByID(id uuid.UUID) (*models.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetByEmail(email string) (*models.User, error) {
+	for _, u := range r.users {
+		if u.Email != nil && strings.EqualFold(*u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetByUsername(username string) (*models.User, error) {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, username) {
+			return u, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) IsUsernameTaken(username string, excludeID uuid.UUID) (bool, error) {
+	for _, u := range r.users {
+		if u.ID != excludeID && u.DeletedAt == nil && strings.EqualFold(u.Username, username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeUserRepo) ListIDsByUsernames(usernames []string) ([]uuid.UUID, error) {
+	r.usernameLookups++
+	var ids []uuid.UUID
+	for _, username := range usernames {
+		if u, err := r.GetByUsername(username); err == nil {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeUserRepo) GetLastUsernameChange(userID uuid.UUID) (*time.Time, error) {
+	changes := r.usernameChanges[userID]
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	last := changes[len(changes)-1]
+	return &last, nil
+}
+
+// ChangeUsername mirrors the repository: the unique index rejects a name held by another
+// active user, and a change in casing only is not recorded in history
+func (r *fakeUserRepo) ChangeUsername(id uuid.UUID, oldUsername, newUsername string) error {
+	for _, u := range r.users {
+		if u.ID != id && u.DeletedAt == nil && strings.EqualFold(u.Username, newUsername) {
+			return utils.ErrUsernameAlreadyExists
+		}
+	}
+	user, ok := r.users[id]
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	user.Username = newUsername
+	if !strings.EqualFold(oldUsername, newUsername) {
+		r.usernameChanges[id] = append(r.usernameChanges[id], time.Now())
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) Update(id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error) {
+	return r.GetByID(id)
+}
+
+type fakePostRepo struct {
+	repository.PostRepository
+	posts    map[uuid.UUID]*models.Post
+	comments []*models.Comment
+
+	// trendingSince records the cutoff each trending query was given
+	trendingSince []time.Time
+}
+
+func newFakePostRepo(posts ...*models.Post) *fakePostRepo {
+	r := &fakePostRepo{posts: make(map[uuid.UUID]*models.Post)}
+	for _, p := range posts {
+		r.posts[p.ID] = p
+	}
+	return r
+}
+
+func (r *fakePostRepo) Create(post *models.Post) error {
+	stored := *post
+	r.posts[post.ID] = &stored
+	return nil
+}
+
+func (r *fakePostRepo) GetByID(id uuid.UUID) (*models.Post, error) {
+	if p, ok := r.posts[id]; ok {
+		return p, nil
+	}
+	return nil, utils.ErrPostNotFound
+}
+
+func (r *fakePostRepo) GetAnyByID(id uuid.UUID) (*models.Post, error) {
+	return r.GetByID(id)
+}
+
+func (r *fakePostRepo) GetByIDWithAuthor(id uuid.UUID) (*models.Post, error) {
+	if p, ok := r.posts[id]; ok && p.DeletedAt == nil {
+		return p, nil
+	}
+	return nil, utils.ErrPostNotFound
+}
+
+// ListTrending mirrors the repository: posts with a live comment after since, most
+// such comments first
+func (r *fakePostRepo) ListTrending(ctx context.Context, since time.Time, limit, offset int) ([]models.Post, error) {
+	r.trendingSince = append(r.trendingSince, since)
+	recent := make(map[uuid.UUID]int)
+	for _, c := range r.comments {
+		if c.DeletedAt == nil && c.CreatedAt.After(since) {
+			recent[c.PostID]++
+		}
+	}
+	var posts []models.Post
+	for id, count := range recent {
+		post := *r.posts[id]
+		count := count
+		post.RecentCommentCount = &count
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool { return *posts[i].RecentCommentCount > *posts[j].RecentCommentCount })
+	if offset > len(posts) {
+		offset = len(posts)
+	}
+	posts = posts[offset:]
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+func (r *fakePostRepo) CountTrending(ctx context.Context, since time.Time) (int, error) {
+	r.trendingSince = append(r.trendingSince, since)
+	recent := make(map[uuid.UUID]bool)
+	for _, c := range r.comments {
+		if c.DeletedAt == nil && c.CreatedAt.After(since) {
+			recent[c.PostID] = true
+		}
+	}
+	return len(recent), nil
+}
+
+// scheduledByUser returns the user's live posts with a future publish_at, soonest first
+func (r *fakePostRepo) scheduledByUser(userID uuid.UUID) []models.Post {
+	now := time.Now()
+	var posts []models.Post
+	for _, p := range r.posts {
+		if p.CreatedBy != nil && *p.CreatedBy == userID && p.DeletedAt == nil && p.IsScheduled(now) {
+			posts = append(posts, *p)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].PublishAt.Before(*posts[j].PublishAt) })
+	return posts
+}
+
+func (r *fakePostRepo) ListScheduledByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error) {
+	posts := r.scheduledByUser(userID)
+	if offset > len(posts) {
+		offset = len(posts)
+	}
+	if end := offset + limit; end < len(posts) {
+		posts = posts[:end]
+	}
+	return posts[offset:], nil
+}
+
+func (r *fakePostRepo) CountScheduledByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	return len(r.scheduledByUser(userID)), nil
+}
+
+// DeleteImpact mirrors the repository's counts over the post's live comments in
+// r.comments, counting each guest name as one author
+func (r *fakePostRepo) DeleteImpact(id uuid.UUID) (*models.PostDeleteImpact, error) {
+	impact := &models.PostDeleteImpact{PostID: id}
+	authors := make(map[string]bool)
+	for _, c := range r.comments {
+		if c.PostID != id || c.DeletedAt != nil {
+			continue
+		}
+		impact.Comments++
+		if c.ParentID == nil {
+			impact.TopLevel++
+		}
+		if c.CreatedBy != nil {
+			authors["user:"+c.CreatedBy.String()] = true
+		} else if c.GuestName != nil {
+			authors["guest:"+*c.GuestName] = true
+		}
+	}
+	impact.Replies = impact.Comments - impact.TopLevel
+	impact.AffectedAuthors = len(authors)
+	return impact, nil
+}
+
+type fakeCommentRepo struct {
+	repository.CommentRepository
+	comments map[uuid.UUID]*models.Comment
+	mentions map[uuid.UUID][]uuid.UUID
+	bumpedAt map[uuid.UUID]time.Time
+}
+
+func newFakeCommentRepo(comments ...*models.Comment) *fakeCommentRepo {
+	r := &fakeCommentRepo{
+		comments: make(map[uuid.UUID]*models.Comment),
+		mentions: make(map[uuid.UUID][]uuid.UUID),
+		bumpedAt: make(map[uuid.UUID]time.Time),
+	}
+	for _, c := range comments {
+		r.comments[c.ID] = c
+		r.bumpedAt[c.ID] = c.CreatedAt
+	}
+	return r
+}
+
+func (r *fakeCommentRepo) Create(comment *models.Comment) error {
+	r.comments[comment.ID] = comment
+	r.bumpedAt[comment.ID] = comment.CreatedAt
+	return nil
+}
+
+func (r *fakeCommentRepo) CreateGuestIfAllowed(comment *models.Comment, since time.Time, allow func(count int) bool) (bool, error) {
+	count := 0
+	for _, c := range r.comments {
+		if c.GuestIP != nil && *c.GuestIP == *comment.GuestIP && !c.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	if !allow(count) {
+		return false, nil
+	}
+	return true, r.Create(comment)
+}
+
+// CreateBatch mirrors the repository: comments are inserted in the order given, so a
+// parent must already be present when its reply is inserted
+func (r *fakeCommentRepo) CreateBatch(comments []models.Comment) error {
+	for i := range comments {
+		c := comments[i]
+		if c.ParentID != nil && r.comments[*c.ParentID] == nil {
+			return errors.New("parent inserted after its reply")
+		}
+		r.comments[c.ID] = &c
+	}
+	return nil
+}
+
+func (r *fakeCommentRepo) Update(id uuid.UUID, updates *models.UpdateCommentRequest) (*models.Comment, error) {
+	c, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Content != nil {
+		c.Content = *updates.Content
+	}
+	c.UpdatedAt = time.Now()
+	c.Version++
+	return c, nil
+}
+
+func (r *fakeCommentRepo) GetByID(id uuid.UUID) (*models.Comment, error) {
+	if c, ok := r.comments[id]; ok && c.DeletedAt == nil {
+		return c, nil
+	}
+	return nil, utils.ErrCommentNotFound
+}
+
+func (r *fakeCommentRepo) Delete(id uuid.UUID) error {
+	c, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	c.DeletedAt = &now
+	return nil
+}
+
+// Restore mirrors the repository: missing comments are not found, and comments that are
+// not deleted cannot be restored
+func (r *fakeCommentRepo) Restore(id uuid.UUID) error {
+	c, ok := r.comments[id]
+	if !ok {
+		return utils.ErrCommentNotFound
+	}
+	if c.DeletedAt == nil {
+		return utils.ErrNotDeleted
+	}
+	c.DeletedAt = nil
+	return nil
+}
+
+func (r *fakeCommentRepo) GetByIDWithAuthor(id uuid.UUID) (*models.Comment, error) {
+	return r.GetByID(id)
+}
+
+// GetByIDs mirrors the repository: deleted comments are left out and order is not kept
+func (r *fakeCommentRepo) GetByIDs(ids []uuid.UUID) ([]models.Comment, error) {
+	var comments []models.Comment
+	for _, id := range ids {
+		if c, err := r.GetByID(id); err == nil {
+			comments = append(comments, *c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID.String() < comments[j].ID.String() })
+	return comments, nil
+}
+
+// Reparent mirrors the repository's depth check and path rewrite: every comment whose
+// path contains commentID has the prefix up to commentID replaced by the new parent's path
+func (r *fakeCommentRepo) Reparent(commentID, newParentID uuid.UUID, maxDepth int) error {
+	comment, parent := r.comments[commentID], r.comments[newParentID]
+	oldPrefix := len(comment.Path)
+	newPrefix := append(append([]uuid.UUID{}, parent.Path...), commentID)
+	for _, c := range r.comments {
+		if maxDepth > 0 && len(c.Path) >= oldPrefix && c.Path[oldPrefix-1] == commentID && len(newPrefix)+len(c.Path)-oldPrefix > maxDepth {
+			return utils.WrapError(utils.ErrInvalidInput, fmt.Sprintf("moving the comment would nest replies more than %d levels deep", maxDepth))
+		}
+	}
+	for _, c := range r.comments {
+		if len(c.Path) >= oldPrefix && c.Path[oldPrefix-1] == commentID {
+			c.Path = append(append([]uuid.UUID{}, newPrefix...), c.Path[oldPrefix:]...)
+			c.ThreadID = parent.ThreadID
+		}
+	}
+	comment.ParentID = &newParentID
+	return nil
+}
+
+func (r *fakeCommentRepo) GetLatestByUserAndPost(userID, postID uuid.UUID) (*models.Comment, error) {
+	var latest *models.Comment
+	for _, c := range r.comments {
+		if c.CreatedBy == nil || *c.CreatedBy != userID || c.PostID != postID || c.DeletedAt != nil {
+			continue
+		}
+		if latest == nil || c.CreatedAt.After(latest.CreatedAt) {
+			latest = c
+		}
+	}
+	if latest == nil {
+		return nil, utils.ErrCommentNotFound
+	}
+	return latest, nil
+}
+
+func (r *fakeCommentRepo) GetLatestGuestByIPAndPost(ip string, postID uuid.UUID) (*models.Comment, error) {
+	var latest *models.Comment
+	for _, c := range r.comments {
+		if c.CreatedBy != nil || c.GuestIP == nil || *c.GuestIP != ip || c.PostID != postID || c.DeletedAt != nil {
+			continue
+		}
+		if latest == nil || c.CreatedAt.After(latest.CreatedAt) {
+			latest = c
+		}
+	}
+	if latest == nil {
+		return nil, utils.ErrCommentNotFound
+	}
+	return latest, nil
+}
+
+func (r *fakeCommentRepo) AddMentions(commentID uuid.UUID, userIDs []uuid.UUID) error {
+	r.mentions[commentID] = userIDs
+	return nil
+}
+
+func (r *fakeCommentRepo) ListIDsAfter(afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, c := range r.comments {
+		if c.DeletedAt == nil && id.String() > afterID.String() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *fakeCommentRepo) RecomputeRepliesCounts(commentIDs []uuid.UUID) (int, error) {
+	corrected := 0
+	for _, id := range commentIDs {
+		actual := 0
+		for _, child := range r.comments {
+			if child.ParentID != nil && *child.ParentID == id && child.DeletedAt == nil {
+				actual++
+			}
+		}
+		if c := r.comments[id]; c != nil && c.RepliesCount != actual {
+			c.RepliesCount = actual
+			corrected++
+		}
+	}
+	return corrected, nil
+}
+
+// PurgeDeletedBefore mirrors the repository: comments soft-deleted before cutoff are
+// removed, except those that still have replies of any kind
+func (r *fakeCommentRepo) PurgeDeletedBefore(cutoff time.Time, limit int) (int, error) {
+	var purgeable []uuid.UUID
+	for id, c := range r.comments {
+		if c.DeletedAt == nil || !c.DeletedAt.Before(cutoff) || len(purgeable) == limit {
+			continue
+		}
+		hasReplies := false
+		for _, child := range r.comments {
+			if child.ParentID != nil && *child.ParentID == id {
+				hasReplies = true
+				break
+			}
+		}
+		if !hasReplies {
+			purgeable = append(purgeable, id)
+		}
+	}
+	for _, id := range purgeable {
+		delete(r.comments, id)
+	}
+	return len(purgeable), nil
+}
+
+// Bump mirrors the repository: bumped_at only ever moves forward
+func (r *fakeCommentRepo) Bump(ids []uuid.UUID, at time.Time) error {
+	for _, id := range ids {
+		if at.After(r.bumpedAt[id]) {
+			r.bumpedAt[id] = at
+		}
+	}
+	return nil
+}
+
+// activityOrder returns the post's top-level comments as sort=activity lists them
+func (r *fakeCommentRepo) activityOrder(postID uuid.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, c := range r.comments {
+		if c.PostID == postID && c.ParentID == nil && c.DeletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return r.bumpedAt[ids[i]].After(r.bumpedAt[ids[j]]) })
+	return ids
+}
+
+// fakeTokenRepo keeps the denylist in memory. IsRevoked can be told to miss entries, to
+// reproduce two requests that both pass the check before either revokes the token.
+type fakeTokenRepo struct {
+	repository.TokenRepository
+	revoked        map[uuid.UUID]bool
+	ignoreDenylist bool
+}
+
+func newFakeTokenRepo() *fakeTokenRepo {
+	return &fakeTokenRepo{revoked: make(map[uuid.UUID]bool)}
+}
+
+func (r *fakeTokenRepo) Revoke(jti, userID uuid.UUID, tokenType string, expiresAt time.Time) (bool, error) {
+	if r.revoked[jti] {
+		return false, nil
+	}
+	r.revoked[jti] = true
+	return true, nil
+}
+
+func (r *fakeTokenRepo) IsRevoked(jti uuid.UUID) (bool, error) {
+	return r.revoked[jti] && !r.ignoreDenylist, nil
+}
+
+// fakePasswordResetRepo keeps issued tokens in memory
+type fakePasswordResetRepo struct {
+	repository.PasswordResetRepository
+	tokens []models.PasswordResetToken
+}
+
+func (r *fakePasswordResetRepo) CreateIfAllowed(token *models.PasswordResetToken, since time.Time, allow func(history models.PasswordResetSendHistory) bool) (bool, error) {
+	var history models.PasswordResetSendHistory
+	for _, t := range r.tokens {
+		if t.CreatedAt.Before(since) {
+			continue
+		}
+		if token.UserID != uuid.Nil && t.UserID == token.UserID {
+			history.SentToUser++
+			if history.LastSentAt == nil || t.CreatedAt.After(*history.LastSentAt) {
+				createdAt := t.CreatedAt
+				history.LastSentAt = &createdAt
+			}
+		}
+		if token.RequestedIP != "" && t.RequestedIP == token.RequestedIP {
+			history.SentFromIP++
+		}
+	}
+	if !allow(history) {
+		return false, nil
+	}
+	r.tokens = append(r.tokens, *token)
+	return true, nil
+}
+
+// fakeEmailSender records who each email went to
+type fakeEmailSender struct {
+	sent []string
+}
+
+func (s *fakeEmailSender) Send(ctx context.Context, to, subject, body string) error {
+	s.sent = append(s.sent, to)
+	return nil
+}
+
+// newTestCommentService wires a comment service over the fakes with the given config
+func newTestCommentService(cfg *config.CommentConfig, comments *fakeCommentRepo, posts *fakePostRepo, users *fakeUserRepo) *commentService {
+	return NewCommentService(comments, posts, users, validator.NewValidator(), cfg, nil, nil).(*commentService)
+}
+
+func testUser(role string) *models.User {
+	return &models.User{ID: uuid.New(), Username: "user_" + uuid.NewString()[:8], Role: role}
+}
+
+func testPost(author uuid.UUID) *models.Post {
+	return &models.Post{ID: uuid.New(), Title: "Test post", CreatedBy: &author, CreatedAt: time.Now()}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// StartIdempotencyKeyCleanupJob removes expired idempotency keys every interval in the background.
+// It returns a function that stops the job; a non-positive interval disables it.
+func StartIdempotencyKeyCleanupJob(idempotencyService IdempotencyService, interval time.Duration) func() {
+	if interval <= 0 {
+		utils.LogInfo("Idempotency key cleanup job disabled", nil)
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := idempotencyService.PurgeExpired()
+				if err != nil {
+					utils.LogError("Idempotency key cleanup job failed", err, nil)
+					continue
+				}
+				utils.LogInfo("Expired idempotency keys removed", utils.LogFields{"deleted": deleted})
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	utils.LogInfo("Idempotency key cleanup job started", utils.LogFields{"interval": interval.String()})
+
+	return func() {
+		close(done)
+	}
+}
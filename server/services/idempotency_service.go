@@ -0,0 +1,82 @@
+package services
+
+import (
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyTTL is how long a key keeps returning the resource it created.
+// After that the key can be reused and is removed by the cleanup job.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength matches the idempotency_keys.idempotency_key column
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyService interface defines Idempotency-Key handling shared by create endpoints
+type IdempotencyService interface {
+	Begin(userID uuid.UUID, key, resourceType, requestHash string) (*models.IdempotencyKey, error)
+	Complete(userID uuid.UUID, key string, resourceID uuid.UUID, statusCode int) error
+	Release(userID uuid.UUID, key string) error
+	PurgeExpired() (int, error)
+}
+
+// idempotencyService implements IdempotencyService interface
+type idempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepository
+}
+
+// NewIdempotencyService creates a new idempotency service instance
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{idempotencyRepo: idempotencyRepo}
+}
+
+// Begin claims key for a new create request of resourceType whose body fingerprints to
+// requestHash. It returns nil when the caller should go ahead and create the resource, or
+// the completed record when the key was already used for the same request and the
+// original result should be replayed. A key that is still in flight, or was used for a
+// different resource type or a different body, is a conflict.
+func (s *idempotencyService) Begin(userID uuid.UUID, key, resourceType, requestHash string) (*models.IdempotencyKey, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "Idempotency-Key must be at most 255 characters")
+	}
+
+	now := time.Now()
+	existing, err := s.idempotencyRepo.Reserve(&models.IdempotencyKey{
+		UserID:       userID,
+		Key:          key,
+		ResourceType: resourceType,
+		RequestHash:  requestHash,
+		CreatedAt:    now,
+	}, now.Add(-IdempotencyKeyTTL))
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if existing.ResourceType != resourceType || (existing.RequestHash != "" && existing.RequestHash != requestHash) {
+		return nil, utils.WrapError(utils.ErrIdempotencyKeyInUse, "Idempotency-Key was already used for a different request")
+	}
+	if existing.IsPending() {
+		return nil, utils.WrapError(utils.ErrIdempotencyKeyInUse, "a request with this Idempotency-Key is still being processed")
+	}
+
+	return existing, nil
+}
+
+// Complete records the resource created under key so later replays return it
+func (s *idempotencyService) Complete(userID uuid.UUID, key string, resourceID uuid.UUID, statusCode int) error {
+	return s.idempotencyRepo.Complete(userID, key, resourceID, statusCode)
+}
+
+// Release frees a key whose create request failed so the client can retry with it
+func (s *idempotencyService) Release(userID uuid.UUID, key string) error {
+	return s.idempotencyRepo.Release(userID, key)
+}
+
+// PurgeExpired removes keys older than IdempotencyKeyTTL
+func (s *idempotencyService) PurgeExpired() (int, error) {
+	return s.idempotencyRepo.DeleteBefore(time.Now().Add(-IdempotencyKeyTTL))
+}
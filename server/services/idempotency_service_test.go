@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+// fakeIdempotencyRepo holds a single existing record, or none
+type fakeIdempotencyRepo struct {
+	repository.IdempotencyRepository
+	existing *models.IdempotencyKey
+}
+
+func (r *fakeIdempotencyRepo) Reserve(record *models.IdempotencyKey, expiredBefore time.Time) (*models.IdempotencyKey, error) {
+	if r.existing == nil {
+		r.existing = record
+		return nil, nil
+	}
+	return r.existing, nil
+}
+
+func TestIdempotencyBeginComparesRequestBody(t *testing.T) {
+	resourceID := uuid.New()
+	status := 201
+
+	tests := []struct {
+		name       string
+		storedHash string
+		pending    bool
+		wantReplay bool
+		wantErr    error
+	}{
+		{"same body replays", "hash-a", false, true, nil},
+		{"different body is rejected", "hash-b", false, false, utils.ErrIdempotencyKeyInUse},
+		{"key recorded without a fingerprint replays", "", false, true, nil},
+		{"request still in flight is rejected", "hash-a", true, false, utils.ErrIdempotencyKeyInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &models.IdempotencyKey{Key: "key", ResourceType: models.IdempotencyResourceComment, RequestHash: tt.storedHash}
+			if !tt.pending {
+				existing.ResourceID = &resourceID
+				existing.StatusCode = &status
+			}
+			svc := NewIdempotencyService(&fakeIdempotencyRepo{existing: existing})
+
+			replay, err := svc.Begin(uuid.New(), "key", models.IdempotencyResourceComment, "hash-a")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if (replay != nil) != tt.wantReplay {
+				t.Errorf("got replay %+v, want replay %v", replay, tt.wantReplay)
+			}
+		})
+	}
+}
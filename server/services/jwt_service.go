@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"errors"
-	"os"
 	"time"
 
+	"github.com/TejasThombare20/post-comments-service/config"
 	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/repository"
 	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -15,32 +17,33 @@ type JWTService struct {
 	secretKey       []byte
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	tokenRepo       repository.TokenRepository
 }
 
-// NewJWTService creates a new JWT service instance
-func NewJWTService() *JWTService {
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		secretKey = "your-secret-key-change-this-in-production" // Default for development
-		utils.LogWarn("Using default JWT secret key - change this in production", nil)
+// NewJWTService creates a new JWT service instance from the loaded JWT configuration.
+// It fails when no secret key is configured rather than signing with a guessable default.
+func NewJWTService(jwtConfig *config.JWTConfig, tokenRepo repository.TokenRepository) (*JWTService, error) {
+	if jwtConfig == nil || jwtConfig.SecretKey == "" {
+		return nil, errors.New("JWT secret key is required")
 	}
 
 	utils.LogInfo("Initializing JWT service", utils.LogFields{
 		"component":         "jwt_service",
-		"access_token_ttl":  "15m",
-		"refresh_token_ttl": "7d",
+		"access_token_ttl":  jwtConfig.AccessTokenDuration.String(),
+		"refresh_token_ttl": jwtConfig.RefreshTokenDuration.String(),
 	})
 
 	return &JWTService{
-		secretKey:       []byte(secretKey),
-		accessTokenTTL:  15 * time.Minute,   // Access token expires in 15 minutes
-		refreshTokenTTL: 7 * 24 * time.Hour, // Refresh token expires in 7 days
-	}
+		secretKey:       []byte(jwtConfig.SecretKey),
+		accessTokenTTL:  jwtConfig.AccessTokenDuration,
+		refreshTokenTTL: jwtConfig.RefreshTokenDuration,
+		tokenRepo:       tokenRepo,
+	}, nil
 }
 
 // GenerateTokenPair generates both access and refresh tokens
-func (j *JWTService) GenerateTokenPair(user *models.User) (*models.AuthResponse, error) {
-	utils.LogInfo("Generating token pair", utils.LogFields{
+func (j *JWTService) GenerateTokenPair(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	utils.LogInfoContext(ctx, "Generating token pair", utils.LogFields{
 		"user_id":  user.ID,
 		"username": user.Username,
 	})
@@ -48,7 +51,7 @@ func (j *JWTService) GenerateTokenPair(user *models.User) (*models.AuthResponse,
 	// Generate access token
 	accessToken, accessExpiresAt, err := j.generateToken(user, "access", j.accessTokenTTL)
 	if err != nil {
-		utils.LogError("Failed to generate access token", err, utils.LogFields{
+		utils.LogErrorContext(ctx, "Failed to generate access token", err, utils.LogFields{
 			"user_id": user.ID,
 		})
 		return nil, err
@@ -57,13 +60,13 @@ func (j *JWTService) GenerateTokenPair(user *models.User) (*models.AuthResponse,
 	// Generate refresh token
 	refreshToken, _, err := j.generateToken(user, "refresh", j.refreshTokenTTL)
 	if err != nil {
-		utils.LogError("Failed to generate refresh token", err, utils.LogFields{
+		utils.LogErrorContext(ctx, "Failed to generate refresh token", err, utils.LogFields{
 			"user_id": user.ID,
 		})
 		return nil, err
 	}
 
-	utils.LogInfo("Token pair generated successfully", utils.LogFields{
+	utils.LogInfoContext(ctx, "Token pair generated successfully", utils.LogFields{
 		"user_id":           user.ID,
 		"access_expires_at": accessExpiresAt,
 	})
@@ -84,7 +87,9 @@ func (j *JWTService) generateToken(user *models.User, tokenType string, ttl time
 		"user_id":  user.ID.String(),
 		"username": user.Username,
 		"email":    user.Email,
+		"role":     user.Role,
 		"type":     tokenType,
+		"jti":      uuid.New().String(),
 		"exp":      expiresAt.Unix(),
 		"iat":      time.Now().Unix(),
 	}
@@ -99,8 +104,8 @@ func (j *JWTService) generateToken(user *models.User, tokenType string, ttl time
 }
 
 // ValidateToken validates a JWT token and returns the claims
-func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error) {
-	utils.LogInfo("Validating JWT token", utils.LogFields{
+func (j *JWTService) ValidateToken(ctx context.Context, tokenString string) (*models.JWTClaims, error) {
+	utils.LogInfoContext(ctx, "Validating JWT token", utils.LogFields{
 		"token_length": len(tokenString),
 	})
 
@@ -112,31 +117,31 @@ func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error
 	})
 
 	if err != nil {
-		utils.LogError("JWT token parsing failed", err, nil)
+		utils.LogErrorContext(ctx, "JWT token parsing failed", err, nil)
 		return nil, err
 	}
 
 	if !token.Valid {
-		utils.LogError("JWT token is invalid", nil, nil)
+		utils.LogErrorContext(ctx, "JWT token is invalid", nil, nil)
 		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		utils.LogError("JWT token claims are invalid", nil, nil)
+		utils.LogErrorContext(ctx, "JWT token claims are invalid", nil, nil)
 		return nil, errors.New("invalid token claims")
 	}
 
 	// Parse user ID
 	userIDStr, ok := claims["user_id"].(string)
 	if !ok {
-		utils.LogError("Invalid user_id in JWT token", nil, nil)
+		utils.LogErrorContext(ctx, "Invalid user_id in JWT token", nil, nil)
 		return nil, errors.New("invalid user_id in token")
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		utils.LogError("Invalid user_id format in JWT token", err, utils.LogFields{
+		utils.LogErrorContext(ctx, "Invalid user_id format in JWT token", err, utils.LogFields{
 			"user_id_str": userIDStr,
 		})
 		return nil, errors.New("invalid user_id format")
@@ -145,7 +150,7 @@ func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error
 	// Parse username
 	username, ok := claims["username"].(string)
 	if !ok {
-		utils.LogError("Invalid username in JWT token", nil, nil)
+		utils.LogErrorContext(ctx, "Invalid username in JWT token", nil, nil)
 		return nil, errors.New("invalid username in token")
 	}
 
@@ -157,43 +162,114 @@ func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error
 		}
 	}
 
+	// Parse role (tokens issued before roles existed default to a regular user)
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		role = models.RoleUser
+	}
+
 	// Parse token type
 	tokenType, ok := claims["type"].(string)
 	if !ok {
-		utils.LogError("Invalid token type in JWT token", nil, nil)
+		utils.LogErrorContext(ctx, "Invalid token type in JWT token", nil, nil)
 		return nil, errors.New("invalid token type")
 	}
 
-	utils.LogInfo("JWT token validated successfully", utils.LogFields{
+	// Parse token ID; tokens without one cannot be revoked and are rejected
+	jtiStr, ok := claims["jti"].(string)
+	if !ok {
+		utils.LogErrorContext(ctx, "Missing jti in JWT token", nil, nil)
+		return nil, errors.New("invalid token id")
+	}
+
+	jti, err := uuid.Parse(jtiStr)
+	if err != nil {
+		utils.LogErrorContext(ctx, "Invalid jti format in JWT token", err, nil)
+		return nil, errors.New("invalid token id format")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		utils.LogErrorContext(ctx, "Invalid exp in JWT token", err, nil)
+		return nil, errors.New("invalid token expiry")
+	}
+
+	// Reject tokens that were revoked by logout or refresh rotation
+	revoked, err := j.tokenRepo.IsRevoked(jti)
+	if err != nil {
+		utils.LogErrorContext(ctx, "Failed to check token revocation", err, utils.LogFields{
+			"jti": jti,
+		})
+		return nil, err
+	}
+	if revoked {
+		utils.LogWarnContext(ctx, "Revoked JWT token presented", utils.LogFields{
+			"jti":     jti,
+			"user_id": userID,
+		})
+		return nil, errors.New("token has been revoked")
+	}
+
+	utils.LogInfoContext(ctx, "JWT token validated successfully", utils.LogFields{
 		"user_id":    userID,
 		"username":   username,
 		"token_type": tokenType,
 	})
 
 	return &models.JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Type:     tokenType,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		Type:      tokenType,
+		JTI:       jti,
+		ExpiresAt: exp.Time,
 	}, nil
 }
 
+// RevokeToken adds the token described by claims to the denylist until it expires
+func (j *JWTService) RevokeToken(ctx context.Context, claims *models.JWTClaims) error {
+	_, err := j.revokeToken(ctx, claims)
+	return err
+}
+
+// revokeToken adds the token described by claims to the denylist and reports whether
+// this call revoked it, rather than finding it already revoked
+func (j *JWTService) revokeToken(ctx context.Context, claims *models.JWTClaims) (bool, error) {
+	revoked, err := j.tokenRepo.Revoke(claims.JTI, claims.UserID, claims.Type, claims.ExpiresAt)
+	if err != nil {
+		utils.LogErrorContext(ctx, "Failed to revoke token", err, utils.LogFields{
+			"jti":     claims.JTI,
+			"user_id": claims.UserID,
+		})
+		return false, err
+	}
+
+	utils.LogInfoContext(ctx, "Token revoked", utils.LogFields{
+		"jti":        claims.JTI,
+		"user_id":    claims.UserID,
+		"token_type": claims.Type,
+	})
+
+	return revoked, nil
+}
+
 // RefreshToken generates a new access token using a valid refresh token
-func (j *JWTService) RefreshToken(refreshTokenString string, userService UserService) (*models.AuthResponse, error) {
-	utils.LogInfo("Starting token refresh process", utils.LogFields{
+func (j *JWTService) RefreshToken(ctx context.Context, refreshTokenString string, userService UserService) (*models.AuthResponse, error) {
+	utils.LogInfoContext(ctx, "Starting token refresh process", utils.LogFields{
 		"refresh_token_length": len(refreshTokenString),
 	})
 
 	// Validate the refresh token
-	claims, err := j.ValidateToken(refreshTokenString)
+	claims, err := j.ValidateToken(ctx, refreshTokenString)
 	if err != nil {
-		utils.LogError("Refresh token validation failed", err, nil)
+		utils.LogErrorContext(ctx, "Refresh token validation failed", err, nil)
 		return nil, err
 	}
 
 	// Check if it's a refresh token
 	if claims.Type != "refresh" {
-		utils.LogError("Invalid token type for refresh", nil, utils.LogFields{
+		utils.LogErrorContext(ctx, "Invalid token type for refresh", nil, utils.LogFields{
 			"token_type": claims.Type,
 		})
 		return nil, errors.New("invalid token type for refresh")
@@ -202,22 +278,37 @@ func (j *JWTService) RefreshToken(refreshTokenString string, userService UserSer
 	// Get the user from database to ensure they still exist
 	user, err := userService.GetUserByID(claims.UserID)
 	if err != nil {
-		utils.LogError("User not found during token refresh", err, utils.LogFields{
+		utils.LogErrorContext(ctx, "User not found during token refresh", err, utils.LogFields{
 			"user_id": claims.UserID,
 		})
 		return nil, errors.New("user not found")
 	}
 
+	// Revoke the old refresh token so it cannot be replayed after rotation. Two requests
+	// replaying the same token can both pass the revocation check above; only the one
+	// whose revocation inserts the denylist row may rotate it.
+	revoked, err := j.revokeToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		utils.LogWarnContext(ctx, "Refresh token replayed during rotation", utils.LogFields{
+			"jti":     claims.JTI,
+			"user_id": claims.UserID,
+		})
+		return nil, errors.New("token has been revoked")
+	}
+
 	// Generate new token pair
-	authResponse, err := j.GenerateTokenPair(user)
+	authResponse, err := j.GenerateTokenPair(ctx, user)
 	if err != nil {
-		utils.LogError("Failed to generate new token pair during refresh", err, utils.LogFields{
+		utils.LogErrorContext(ctx, "Failed to generate new token pair during refresh", err, utils.LogFields{
 			"user_id": claims.UserID,
 		})
 		return nil, err
 	}
 
-	utils.LogInfo("Token refresh completed successfully", utils.LogFields{
+	utils.LogInfoContext(ctx, "Token refresh completed successfully", utils.LogFields{
 		"user_id": claims.UserID,
 	})
 
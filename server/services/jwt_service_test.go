@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/validator"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tests := []struct {
+		name string
+		// concurrent makes the denylist check miss the first rotation, as when two
+		// requests replaying one token both validate it before either revokes it
+		concurrent bool
+	}{
+		{"sequential replay", false},
+		{"concurrent replay", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := newFakeTokenRepo()
+			tokens.ignoreDenylist = tt.concurrent
+			jwtService, err := NewJWTService(&config.JWTConfig{
+				SecretKey:            "test-secret",
+				AccessTokenDuration:  time.Minute,
+				RefreshTokenDuration: time.Hour,
+			}, tokens)
+			if err != nil {
+				t.Fatalf("failed to create JWT service: %v", err)
+			}
+
+			user := testUser(models.RoleUser)
+			userService := NewUserService(newFakeUserRepo(user), nil, validator.NewValidator(), nil, models.UserDeletePolicyAnonymize)
+
+			pair, err := jwtService.GenerateTokenPair(context.Background(), user)
+			if err != nil {
+				t.Fatalf("failed to generate tokens: %v", err)
+			}
+
+			if _, err := jwtService.RefreshToken(context.Background(), pair.RefreshToken, userService); err != nil {
+				t.Fatalf("first refresh: unexpected error %v", err)
+			}
+			if _, err := jwtService.RefreshToken(context.Background(), pair.RefreshToken, userService); err == nil {
+				t.Fatal("replayed refresh token was accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"github.com/TejasThombare20/post-comments-service/config"
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/TejasThombare20/post-comments-service/validator"
+)
+
+// MetaService interface defines methods describing the API itself
+type MetaService interface {
+	GetConstraints() *models.ValidationConstraints
+}
+
+// metaService implements MetaService interface
+type metaService struct {
+	commentConfig *config.CommentConfig
+}
+
+// NewMetaService creates a new meta service instance
+func NewMetaService(commentConfig *config.CommentConfig) MetaService {
+	return &metaService{
+		commentConfig: commentConfig,
+	}
+}
+
+// GetConstraints reports the input limits the server enforces. Field limits are read
+// from the request structs' validate tags and the rest from the constants the services
+// check against, so the published values cannot drift from the enforced ones.
+func (s *metaService) GetConstraints() *models.ValidationConstraints {
+	minTagLength := 1
+	maxPostTagLength := maxPostTagLength
+	maxPostTags := maxPostTags
+
+	// CreateComment checks for missing content itself rather than through the tag, and
+	// may enforce a higher minimum (in characters of text) and a lower maximum than the
+	// tag. The minimum may not apply to replies.
+	commentContent := lengthConstraint(models.CreateCommentRequest{}, "content")
+	commentContent.Required = true
+	replyContent := commentContent
+	maxReplyDepth := 0
+	if s.commentConfig != nil {
+		if s.commentConfig.MaxContentLength > 0 &&
+			(commentContent.Max == nil || s.commentConfig.MaxContentLength < *commentContent.Max) {
+			maxContentLength := s.commentConfig.MaxContentLength
+			commentContent.Max = &maxContentLength
+			replyContent.Max = &maxContentLength
+		}
+		if s.commentConfig.MinContentLength > 0 &&
+			(commentContent.Min == nil || s.commentConfig.MinContentLength > *commentContent.Min) {
+			minContentLength := s.commentConfig.MinContentLength
+			commentContent.Min = &minContentLength
+			if s.commentConfig.MinContentLengthReplies {
+				replyContent.Min = &minContentLength
+			}
+		}
+		maxReplyDepth = s.commentConfig.MaxReplyDepth
+	}
+
+	return &models.ValidationConstraints{
+		User: models.UserConstraints{
+			Username:    lengthConstraint(models.RegisterRequest{}, "username"),
+			DisplayName: lengthConstraint(models.RegisterRequest{}, "display_name"),
+			Password:    lengthConstraint(models.RegisterRequest{}, "password"),
+		},
+		Post: models.PostConstraints{
+			Title:     lengthConstraint(models.CreatePostRequest{}, "title"),
+			Content:   lengthConstraint(models.CreatePostRequest{}, "content"),
+			Tags:      models.LengthConstraint{Max: &maxPostTags},
+			TagLength: models.LengthConstraint{Required: true, Min: &minTagLength, Max: &maxPostTagLength},
+		},
+		Comment: models.CommentConstraints{
+			Content:          commentContent,
+			ReplyContent:     replyContent,
+			MaxReplyDepth:    maxReplyDepth,
+			DefaultTreeDepth: defaultCommentTreeDepth,
+			MaxTreeDepth:     maxCommentTreeDepth,
+			MaxTreeSize:      maxCommentTreeSize,
+		},
+		Pagination: models.PaginationConstraints{
+			MaxLimit:  utils.MaxPageLimit(),
+			MaxOffset: utils.MaxPaginationOffset(),
+		},
+	}
+}
+
+// lengthConstraint converts the validate tag bounds of a request field
+func lengthConstraint(req interface{}, jsonField string) models.LengthConstraint {
+	rules := validator.Rules(req, jsonField)
+	return models.LengthConstraint{
+		Required: rules.Required,
+		Min:      rules.Min,
+		Max:      rules.Max,
+	}
+}
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/repository"
@@ -11,15 +14,40 @@ import (
 
 // PostService interface defines post business logic methods
 type PostService interface {
-	CreatePost(req *models.CreatePostRequest, userID uuid.UUID) (*models.Post, error)
-	GetPostByID(id uuid.UUID) (*models.Post, error)
-	GetPostWithComments(id uuid.UUID) (*models.Post, error)
+	CreatePost(req *models.CreatePostRequest, userID uuid.UUID, withAuthor bool) (*models.Post, error)
+	GetPostByID(id uuid.UUID, viewerID uuid.UUID) (*models.Post, error)
+	GetPostWithComments(ctx context.Context, id uuid.UUID, limit, offset int) (*models.Post, error)
+	GetPostCommentsStamp(ctx context.Context, id uuid.UUID) (*models.PostCommentsStamp, error)
 	UpdatePost(id uuid.UUID, req *models.UpdatePostRequest, userID uuid.UUID) (*models.Post, error)
 	DeletePost(id uuid.UUID, userID uuid.UUID) error
-	ListPosts(limit, offset int) ([]models.Post, error)
-	ListPostsByUser(userID uuid.UUID, limit, offset int) ([]models.Post, error)
+	GetDeleteImpact(id uuid.UUID, userID uuid.UUID, role string) (*models.PostDeleteImpact, error)
+	RestorePost(id uuid.UUID) error
+	SetCommentsLocked(id uuid.UUID, userID uuid.UUID, role string, locked bool) (*models.Post, error)
+	ListPosts(ctx context.Context, limit, offset int) ([]models.Post, int, error)
+	ListPostsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error)
+	ListScheduledPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
+	ListPostsByTag(ctx context.Context, tag string, limit, offset int) ([]models.Post, error)
+	ListTrending(ctx context.Context, window time.Duration, limit, offset int) ([]models.Post, int, error)
+	GetPostPermissions(postID uuid.UUID, userID uuid.UUID, role string) (*models.PostPermissions, error)
 }
 
+// postCommentReplyPreviewSize is the number of replies loaded under each top-level
+// comment by GetPostWithComments; the rest are available via the replies endpoint
+const postCommentReplyPreviewSize = 3
+
+// Tag limits for posts
+const (
+	maxPostTags      = 10
+	maxPostTagLength = 30
+)
+
+// Trending window bounds. A zero window falls back to the default.
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	minTrendingWindow     = time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+)
+
 // postService implements PostService interface
 type postService struct {
 	postRepo repository.PostRepository
@@ -34,21 +62,44 @@ func NewPostService(postRepo repository.PostRepository, userRepo repository.User
 	}
 }
 
-// CreatePost creates a new post
-func (s *postService) CreatePost(req *models.CreatePostRequest, userID uuid.UUID) (*models.Post, error) {
+// CreatePost creates a new post. When withAuthor is false the stored post is returned
+// as-is, skipping the extra query that loads author information.
+func (s *postService) CreatePost(req *models.CreatePostRequest, userID uuid.UUID, withAuthor bool) (*models.Post, error) {
 	// Verify user exists
 	if _, err := s.userRepo.GetByID(userID); err != nil {
 		return nil, err
 	}
 
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create post model
+	// A new post has not been edited, so both timestamps share one reading of the clock
+	now := time.Now()
+
+	var publishAt *time.Time
+	if req.PublishAt != nil {
+		if !req.PublishAt.After(now) {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "publish_at must be in the future")
+		}
+		// Stored without a zone, like every other timestamp in the schema
+		utc := req.PublishAt.UTC()
+		publishAt = &utc
+	}
 	post := &models.Post{
 		ID:        uuid.New(),
 		Title:     req.Title,
 		Content:   req.Content,
-		CreatedBy: userID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedBy: &userID,
+		Tags:      tags,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+
+		AllowAnonymousComments: req.AllowAnonymousComments,
+		PublishAt:              publishAt,
 	}
 
 	// Save post to database
@@ -56,8 +107,12 @@ func (s *postService) CreatePost(req *models.CreatePostRequest, userID uuid.UUID
 		return nil, utils.WrapError(err, "failed to create post")
 	}
 
+	if !withAuthor {
+		return post, nil
+	}
+
 	// Return post with author information
-	createdPost, err := s.postRepo.GetByIDWithAuthor(post.ID)
+	createdPost, err := s.postRepo.GetAnyByIDWithAuthor(post.ID)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to get created post with author")
 	}
@@ -65,37 +120,71 @@ func (s *postService) CreatePost(req *models.CreatePostRequest, userID uuid.UUID
 	return createdPost, nil
 }
 
-// GetPostByID retrieves a post by ID with author information
-func (s *postService) GetPostByID(id uuid.UUID) (*models.Post, error) {
-	post, err := s.postRepo.GetByIDWithAuthor(id)
+// GetPostByID retrieves a post by ID with author information. A post scheduled for later
+// is only returned to its author; pass uuid.Nil for anonymous viewers.
+func (s *postService) GetPostByID(id uuid.UUID, viewerID uuid.UUID) (*models.Post, error) {
+	post, err := s.postRepo.GetAnyByIDWithAuthor(id)
 	if err != nil {
 		return nil, err
 	}
+	if post.IsScheduled(time.Now()) && !isPostAuthor(post, viewerID) {
+		return nil, utils.ErrPostNotFound
+	}
 	return post, nil
 }
 
-// GetPostWithComments retrieves a post by ID with comments and authors
-func (s *postService) GetPostWithComments(id uuid.UUID) (*models.Post, error) {
-	post, err := s.postRepo.GetByIDWithComments(id)
+// GetPostWithComments retrieves a post by ID with a page of top-level comments,
+// each carrying a preview of its first replies
+func (s *postService) GetPostWithComments(ctx context.Context, id uuid.UUID, limit, offset int) (*models.Post, error) {
+	// Set default and maximum limits
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	post, err := s.postRepo.GetByIDWithComments(ctx, id, limit, offset, postCommentReplyPreviewSize)
 	if err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
+// GetPostCommentsStamp returns what identifies the current version of a post and its
+// comments, so conditional requests can be answered without loading them
+func (s *postService) GetPostCommentsStamp(ctx context.Context, id uuid.UUID) (*models.PostCommentsStamp, error) {
+	stamp, err := s.postRepo.GetCommentsStamp(ctx, id)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get post comments stamp")
+	}
+	return stamp, nil
+}
+
 // UpdatePost updates a post (only by the author)
 func (s *postService) UpdatePost(id uuid.UUID, req *models.UpdatePostRequest, userID uuid.UUID) (*models.Post, error) {
 	// Get existing post
-	existingPost, err := s.postRepo.GetByID(id)
+	existingPost, err := s.postRepo.GetAnyByID(id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check if user is the author
-	if existingPost.CreatedBy != userID {
+	if !isPostAuthor(existingPost, userID) {
 		return nil, utils.ErrForbidden
 	}
 
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		req.Tags = &tags
+	}
+
 	// Update post
 	updatedPost, err := s.postRepo.Update(id, req)
 	if err != nil {
@@ -108,13 +197,13 @@ func (s *postService) UpdatePost(id uuid.UUID, req *models.UpdatePostRequest, us
 // DeletePost deletes a post (only by the author)
 func (s *postService) DeletePost(id uuid.UUID, userID uuid.UUID) error {
 	// Get existing post
-	existingPost, err := s.postRepo.GetByID(id)
+	existingPost, err := s.postRepo.GetAnyByID(id)
 	if err != nil {
 		return err
 	}
 
 	// Check if user is the author
-	if existingPost.CreatedBy != userID {
+	if !isPostAuthor(existingPost, userID) {
 		return utils.ErrForbidden
 	}
 
@@ -126,31 +215,85 @@ func (s *postService) DeletePost(id uuid.UUID, userID uuid.UUID) error {
 	return nil
 }
 
-// ListPosts retrieves a paginated list of posts with authors
-func (s *postService) ListPosts(limit, offset int) ([]models.Post, error) {
+// SetCommentsLocked closes (or reopens) a post to new comments. Only the post's author
+// and admins may change it. Returns the updated post with its author.
+func (s *postService) SetCommentsLocked(id uuid.UUID, userID uuid.UUID, role string, locked bool) (*models.Post, error) {
+	post, err := s.postRepo.GetAnyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPostAuthor(post, userID) && role != models.RoleAdmin {
+		return nil, utils.ErrForbidden
+	}
+
+	if err := s.postRepo.SetCommentsLocked(id, locked); err != nil {
+		return nil, err
+	}
+
+	return s.postRepo.GetAnyByIDWithAuthor(id)
+}
+
+// GetDeleteImpact reports how many comments and authors deleting a post would affect.
+// Only the post's author and moderators may see it.
+func (s *postService) GetDeleteImpact(id uuid.UUID, userID uuid.UUID, role string) (*models.PostDeleteImpact, error) {
+	post, err := s.postRepo.GetAnyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPostAuthor(post, userID) && !models.IsModeratorRole(role) {
+		return nil, utils.ErrForbidden
+	}
+
+	impact, err := s.postRepo.DeleteImpact(id)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to get post delete impact")
+	}
+
+	return impact, nil
+}
+
+// RestorePost undoes a soft delete of a post (admin only)
+func (s *postService) RestorePost(id uuid.UUID) error {
+	if err := s.postRepo.Restore(id); err != nil {
+		return utils.WrapError(err, "failed to restore post")
+	}
+
+	return nil
+}
+
+// ListPosts retrieves a paginated list of posts with authors and the total post count
+func (s *postService) ListPosts(ctx context.Context, limit, offset int) ([]models.Post, int, error) {
 	// Set default and maximum limits
 	if limit <= 0 {
 		limit = 10
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
 	}
 	if offset < 0 {
 		offset = 0
 	}
 
-	posts, err := s.postRepo.List(limit, offset)
+	posts, err := s.postRepo.List(ctx, limit, offset)
 	if err != nil {
-		return nil, utils.WrapError(err, "failed to list posts")
+		return nil, 0, utils.WrapError(err, "failed to list posts")
 	}
 
-	return posts, nil
+	total, err := s.postRepo.Count(ctx)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count posts")
+	}
+
+	return posts, total, nil
 }
 
 // ListPostsByUser retrieves a paginated list of posts by a specific user
-func (s *postService) ListPostsByUser(userID uuid.UUID, limit, offset int) ([]models.Post, error) {
-	// Verify user exists
-	if _, err := s.userRepo.GetByID(userID); err != nil {
+func (s *postService) ListPostsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, error) {
+	// Verify user exists; the user is also the author of every post listed
+	author, err := s.userRepo.GetByID(userID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -158,17 +301,171 @@ func (s *postService) ListPostsByUser(userID uuid.UUID, limit, offset int) ([]mo
 	if limit <= 0 {
 		limit = 10
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
 	}
 	if offset < 0 {
 		offset = 0
 	}
 
-	posts, err := s.postRepo.ListByUser(userID, limit, offset)
+	posts, err := s.postRepo.ListByUser(ctx, userID, limit, offset)
 	if err != nil {
 		return nil, utils.WrapError(err, "failed to list posts by user")
 	}
 
+	for i := range posts {
+		posts[i].Author = author
+	}
+
 	return posts, nil
 }
+
+// ListScheduledPosts retrieves a page of the user's own posts that are scheduled to go
+// public later, soonest first, along with how many there are in total
+func (s *postService) ListScheduledPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+	author, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Set default and maximum limits
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	posts, err := s.postRepo.ListScheduledByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to list scheduled posts")
+	}
+
+	total, err := s.postRepo.CountScheduledByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count scheduled posts")
+	}
+
+	for i := range posts {
+		posts[i].Author = author
+	}
+
+	return posts, total, nil
+}
+
+// ListPostsByTag retrieves a paginated list of posts with the given tag
+func (s *postService) ListPostsByTag(ctx context.Context, tag string, limit, offset int) ([]models.Post, error) {
+	// Set default and maximum limits
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	posts, err := s.postRepo.ListByTag(ctx, strings.ToLower(strings.TrimSpace(tag)), limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to list posts by tag")
+	}
+
+	return posts, nil
+}
+
+// ListTrending retrieves posts ranked by how many comments they received within the
+// window, and the total number of such posts. A zero window uses the default.
+func (s *postService) ListTrending(ctx context.Context, window time.Duration, limit, offset int) ([]models.Post, int, error) {
+	if window == 0 {
+		window = defaultTrendingWindow
+	}
+	if window < minTrendingWindow || window > maxTrendingWindow {
+		return nil, 0, utils.ErrInvalidInput
+	}
+
+	// Set default and maximum limits
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Both queries count from the same instant so the total matches the pages
+	since := time.Now().Add(-window)
+
+	posts, err := s.postRepo.ListTrending(ctx, since, limit, offset)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to list trending posts")
+	}
+
+	total, err := s.postRepo.CountTrending(ctx, since)
+	if err != nil {
+		return nil, 0, utils.WrapError(err, "failed to count trending posts")
+	}
+
+	return posts, total, nil
+}
+
+// GetPostPermissions reports what the given user may do with a post, using the same
+// checks the mutation endpoints enforce. Pass uuid.Nil for anonymous users.
+func (s *postService) GetPostPermissions(postID uuid.UUID, userID uuid.UUID, role string) (*models.PostPermissions, error) {
+	post, err := s.postRepo.GetAnyByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsScheduled(time.Now()) && !isPostAuthor(post, userID) {
+		return nil, utils.ErrPostNotFound
+	}
+
+	// Visitors who are not signed in can only comment, and only where guests are allowed
+	if userID == uuid.Nil {
+		return &models.PostPermissions{
+			CanComment: post.AllowAnonymousComments && !post.CommentsLocked,
+		}, nil
+	}
+
+	return &models.PostPermissions{
+		CanEdit:     isPostAuthor(post, userID),
+		CanDelete:   isPostAuthor(post, userID),
+		CanModerate: models.IsModeratorRole(role),
+		CanComment:  !post.CommentsLocked,
+	}, nil
+}
+
+// isPostAuthor reports whether the user wrote the post
+func isPostAuthor(post *models.Post, userID uuid.UUID) bool {
+	return post.CreatedBy != nil && *post.CreatedBy == userID
+}
+
+// normalizeTags trims, lowercases and dedupes tag names, keeping their first-seen
+// order, and enforces the per-post tag count and tag length limits
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		length := utf8.RuneCountInString(tag)
+		if length < 1 || length > maxPostTagLength {
+			return nil, utils.WrapError(utils.ErrInvalidInput, "tags must be 1-30 characters")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > maxPostTags {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "a post can have at most 10 tags")
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/google/uuid"
+)
+
+func TestGetPostPermissions(t *testing.T) {
+	owner := testUser(models.RoleUser)
+	stranger := testUser(models.RoleUser)
+	moderator := testUser(models.RoleModerator)
+
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		role        string
+		locked      bool
+		allowGuests bool
+		want        models.PostPermissions
+	}{
+		{"owner", owner.ID, owner.Role, false, false, models.PostPermissions{CanEdit: true, CanDelete: true, CanComment: true}},
+		{"moderator", moderator.ID, moderator.Role, false, false, models.PostPermissions{CanModerate: true, CanComment: true}},
+		{"stranger", stranger.ID, stranger.Role, false, false, models.PostPermissions{CanComment: true}},
+		{"stranger on a locked post", stranger.ID, stranger.Role, true, false, models.PostPermissions{}},
+		{"anonymous", uuid.Nil, "", false, false, models.PostPermissions{}},
+		{"anonymous on a post with guest comments", uuid.Nil, "", false, true, models.PostPermissions{CanComment: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := testPost(owner.ID)
+			post.CommentsLocked = tt.locked
+			post.AllowAnonymousComments = tt.allowGuests
+			svc := NewPostService(newFakePostRepo(post), newFakeUserRepo(owner, stranger, moderator))
+
+			got, err := svc.GetPostPermissions(post.ID, tt.userID, tt.role)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDeleteImpact(t *testing.T) {
+	owner := testUser(models.RoleUser)
+	stranger := testUser(models.RoleUser)
+	moderator := testUser(models.RoleModerator)
+	post := testPost(owner.ID)
+	other := testPost(owner.ID)
+
+	guest := "visitor"
+	top := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &owner.ID}
+	reply := &models.Comment{ID: uuid.New(), PostID: post.ID, ParentID: &top.ID, CreatedBy: &stranger.ID}
+	guestReply := &models.Comment{ID: uuid.New(), PostID: post.ID, ParentID: &top.ID, GuestName: &guest}
+	secondTop := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &stranger.ID}
+	deletedAt := time.Now()
+	deleted := &models.Comment{ID: uuid.New(), PostID: post.ID, CreatedBy: &moderator.ID, DeletedAt: &deletedAt}
+	elsewhere := &models.Comment{ID: uuid.New(), PostID: other.ID, CreatedBy: &moderator.ID}
+
+	posts := newFakePostRepo(post, other)
+	posts.comments = []*models.Comment{top, reply, guestReply, secondTop, deleted, elsewhere}
+	svc := NewPostService(posts, newFakeUserRepo(owner, stranger, moderator))
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		wantErr error
+	}{
+		{"owner", owner, nil},
+		{"moderator", moderator, nil},
+		{"stranger", stranger, utils.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impact, err := svc.GetDeleteImpact(post.ID, tt.user.ID, tt.user.Role)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			want := models.PostDeleteImpact{PostID: post.ID, Comments: 4, TopLevel: 2, Replies: 2, AffectedAuthors: 3}
+			if *impact != want {
+				t.Errorf("got impact %+v, want %+v", *impact, want)
+			}
+		})
+	}
+}
+
+func TestListTrendingTotal(t *testing.T) {
+	author := testUser(models.RoleUser)
+	busy, quiet, stale := testPost(author.ID), testPost(author.ID), testPost(author.ID)
+	now := time.Now()
+	posts := newFakePostRepo(busy, quiet, stale)
+	posts.comments = []*models.Comment{
+		{ID: uuid.New(), PostID: busy.ID, CreatedAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), PostID: busy.ID, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: uuid.New(), PostID: quiet.ID, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: uuid.New(), PostID: stale.ID, CreatedAt: now.Add(-48 * time.Hour)},
+	}
+	svc := NewPostService(posts, newFakeUserRepo(author))
+
+	page, total, err := svc.ListTrending(context.Background(), 0, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if total != 2 {
+		t.Errorf("got total %d, want 2 posts commented on within the default window", total)
+	}
+	if len(page) != 1 || page[0].ID != busy.ID {
+		t.Errorf("got first page %v, want the busiest post only", page)
+	}
+	if len(posts.trendingSince) != 2 || !posts.trendingSince[0].Equal(posts.trendingSince[1]) {
+		t.Errorf("list and count used different cutoffs: %v", posts.trendingSince)
+	}
+
+	if _, _, err := svc.ListTrending(context.Background(), time.Minute, 10, 0); !errors.Is(err, utils.ErrInvalidInput) {
+		t.Errorf("window below the minimum: got error %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestListScheduledPostsTotal(t *testing.T) {
+	author, other := testUser(models.RoleUser), testUser(models.RoleUser)
+	now := time.Now()
+	var ownPosts []*models.Post
+	for _, in := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour} {
+		post := testPost(author.ID)
+		publishAt := now.Add(in)
+		post.PublishAt = &publishAt
+		ownPosts = append(ownPosts, post)
+	}
+	published := testPost(author.ID)
+	othersPost := testPost(other.ID)
+	later := now.Add(time.Hour)
+	othersPost.PublishAt = &later
+
+	posts := newFakePostRepo(append(ownPosts, published, othersPost)...)
+	svc := NewPostService(posts, newFakeUserRepo(author, other))
+
+	page, total, err := svc.ListScheduledPosts(context.Background(), author.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if total != 3 {
+		t.Errorf("got total %d, want the author's 3 scheduled posts", total)
+	}
+	if len(page) != 2 || page[0].ID != ownPosts[1].ID || page[1].ID != ownPosts[2].ID {
+		t.Errorf("got first page %v, want the two soonest scheduled posts", page)
+	}
+	for _, post := range page {
+		if post.Author == nil || post.Author.ID != author.ID {
+			t.Errorf("post %s: author not set", post.ID)
+		}
+	}
+}
+
+func TestCreateSetsTimestamps(t *testing.T) {
+	author := testUser(models.RoleUser)
+	posts := newFakePostRepo()
+	before := time.Now()
+
+	post, err := NewPostService(posts, newFakeUserRepo(author)).CreatePost(&models.CreatePostRequest{Title: "Hello", Content: "World"}, author.ID, false)
+	if err != nil {
+		t.Fatalf("create post: unexpected error %v", err)
+	}
+	stored := posts.posts[post.ID]
+	if stored.CreatedAt.Before(before) || !stored.UpdatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("stored post has created_at %v and updated_at %v, want both set to the creation time", stored.CreatedAt, stored.UpdatedAt)
+	}
+
+	content := "First!"
+	svc := newTestCommentService(nil, newFakeCommentRepo(), posts, newFakeUserRepo(author))
+	comment, err := svc.CreateComment(context.Background(), author.ID, &models.CreateCommentRequest{PostID: post.ID, Content: &content})
+	if err != nil {
+		t.Fatalf("create comment: unexpected error %v", err)
+	}
+	if comment.CreatedAt.Before(before) || !comment.UpdatedAt.Equal(comment.CreatedAt) {
+		t.Errorf("comment has created_at %v and updated_at %v, want both set to the creation time", comment.CreatedAt, comment.UpdatedAt)
+	}
+}
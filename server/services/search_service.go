@@ -0,0 +1,107 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// Search query length bounds (in characters, after trimming)
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 200
+)
+
+// SearchService interface defines search business logic methods
+type SearchService interface {
+	SearchPosts(query string, limit, offset int) ([]models.PostSearchResult, error)
+	SearchComments(query string, limit, offset int) ([]models.CommentSearchResult, error)
+}
+
+// searchService implements SearchService interface
+type searchService struct {
+	searchRepo    repository.SearchRepository
+	htmlSanitizer *utils.HTMLSanitizer
+}
+
+// NewSearchService creates a new search service instance
+func NewSearchService(searchRepo repository.SearchRepository) SearchService {
+	return &searchService{
+		searchRepo:    searchRepo,
+		htmlSanitizer: utils.NewHTMLSanitizer(),
+	}
+}
+
+// SearchPosts searches posts by title and content
+func (s *searchService) SearchPosts(query string, limit, offset int) ([]models.PostSearchResult, error) {
+	query, err := normalizeSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, offset = normalizeSearchPage(limit, offset)
+
+	results, err := s.searchRepo.SearchPosts(query, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to search posts")
+	}
+
+	for i := range results {
+		results[i].Snippet = s.htmlSanitizer.SanitizeSnippet(results[i].Snippet)
+	}
+
+	return results, nil
+}
+
+// SearchComments searches comment content
+func (s *searchService) SearchComments(query string, limit, offset int) ([]models.CommentSearchResult, error) {
+	query, err := normalizeSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, offset = normalizeSearchPage(limit, offset)
+
+	results, err := s.searchRepo.SearchComments(query, limit, offset)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to search comments")
+	}
+
+	for i := range results {
+		results[i].Snippet = s.htmlSanitizer.SanitizeSnippet(results[i].Snippet)
+	}
+
+	return results, nil
+}
+
+// normalizeSearchQuery trims the query and enforces its length bounds
+func normalizeSearchQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+
+	length := utf8.RuneCountInString(query)
+	if length < minSearchQueryLength {
+		return "", utils.WrapError(utils.ErrInvalidInput, "search query must be at least 2 characters")
+	}
+	if length > maxSearchQueryLength {
+		return "", utils.WrapError(utils.ErrInvalidInput, "search query must be at most 200 characters")
+	}
+
+	return query, nil
+}
+
+// normalizeSearchPage applies the default and maximum page size
+func normalizeSearchPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+	return limit, offset
+}
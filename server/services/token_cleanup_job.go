@@ -0,0 +1,43 @@
+package services
+
+import (
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/repository"
+	"github.com/TejasThombare20/post-comments-service/utils"
+)
+
+// StartRevokedTokenCleanupJob removes expired denylist entries every interval in the background.
+// It returns a function that stops the job; a non-positive interval disables it.
+func StartRevokedTokenCleanupJob(tokenRepo repository.TokenRepository, interval time.Duration) func() {
+	if interval <= 0 {
+		utils.LogInfo("Revoked token cleanup job disabled", nil)
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := tokenRepo.DeleteExpired()
+				if err != nil {
+					utils.LogError("Revoked token cleanup job failed", err, nil)
+					continue
+				}
+				utils.LogInfo("Expired revoked tokens removed", utils.LogFields{"deleted": deleted})
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	utils.LogInfo("Revoked token cleanup job started", utils.LogFields{"interval": interval.String()})
+
+	return func() {
+		close(done)
+	}
+}
@@ -1,51 +1,82 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/TejasThombare20/post-comments-service/models"
 	"github.com/TejasThombare20/post-comments-service/repository"
 	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/TejasThombare20/post-comments-service/validator"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// usernameChangeCooldown is the minimum time between two username changes
+const usernameChangeCooldown = 30 * 24 * time.Hour
+
+// MaxAvatarBytes is the largest avatar image a user may upload
+const MaxAvatarBytes = 2 << 20
+
 // UserService interface defines user business logic methods
 type UserService interface {
 	CreateUser(req *models.CreateUserRequest) (*models.User, error)
 	GetUserByID(id uuid.UUID) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
-	UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error)
+	UpdateAvatar(ctx context.Context, id uuid.UUID, data []byte) (*models.User, error)
 	UpdatePassword(id uuid.UUID, hashedPassword string) error
-	DeleteUser(id uuid.UUID) error
-	ListUsers(limit, offset int) ([]models.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(limit, offset int) ([]models.User, int, error)
+	AdminListUsers(filter *models.AdminUserFilter, limit, offset int) ([]models.User, int, error)
 }
 
 // userService implements UserService interface
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo       repository.UserRepository
+	passwordHasher utils.PasswordHasher
+	validator      *validator.Validator
+	blobStore      BlobStore
+	deletePolicy   string
 }
 
-// NewUserService creates a new user service instance
-func NewUserService(userRepo repository.UserRepository) UserService {
+// NewUserService creates a new user service instance. deletePolicy decides what happens
+// to a deleted user's posts and comments (see models.UserDeletePolicyAnonymize).
+// Uploaded avatars are stored in blobStore.
+func NewUserService(userRepo repository.UserRepository, passwordHasher utils.PasswordHasher, validator *validator.Validator, blobStore BlobStore, deletePolicy string) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		passwordHasher: passwordHasher,
+		validator:      validator,
+		blobStore:      blobStore,
+		deletePolicy:   deletePolicy,
 	}
 }
 
 // CreateUser creates a new user with hashed password
 func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.User, error) {
-	if existingUser, err := s.userRepo.GetByUsername(req.Username); err == nil && existingUser != nil {
+	exists, err := s.userRepo.ExistsByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
 		return nil, utils.ErrUserExists
 	}
 
 	if req.Email != nil && *req.Email != "" {
-		if existingUser, err := s.userRepo.GetByEmail(*req.Email); err == nil && existingUser != nil {
+		exists, err := s.userRepo.ExistsByEmail(*req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
 			return nil, utils.ErrUserExists
 		}
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +85,10 @@ func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 		ID:           uuid.New(),
 		Username:     req.Username,
 		Email:        req.Email,
-		PasswordHash: &[]string{string(hashedPassword)}[0],
+		PasswordHash: &hashedPassword,
 		DisplayName:  req.DisplayName,
 		AvatarURL:    req.AvatarURL,
+		Role:         models.RoleUser,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -88,14 +120,30 @@ func (s *userService) GetUserByUsername(username string) (*models.User, error) {
 }
 
 // UpdateUser updates user information
-func (s *userService) UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error) {
-	if _, err := s.userRepo.GetByID(id); err != nil {
+func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, req *models.UpdateUserRequest) (*models.User, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
 		return nil, err
 	}
 
-	if req.Email != nil && *req.Email != "" {
-		if existingUser, err := s.userRepo.GetByEmail(*req.Email); err == nil && existingUser != nil && existingUser.ID != id {
-			return nil, utils.ErrUserExists
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keeping the current email is not a conflict with the user's own account
+	if req.Email != nil && *req.Email != "" && (user.Email == nil || *user.Email != *req.Email) {
+		exists, err := s.userRepo.ExistsByEmail(*req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, utils.ErrEmailAlreadyExists
+		}
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		if err := s.changeUsername(ctx, user, *req.Username); err != nil {
+			return nil, err
 		}
 	}
 
@@ -104,9 +152,115 @@ func (s *userService) UpdateUser(id uuid.UUID, req *models.UpdateUserRequest) (*
 		return nil, err
 	}
 
+	if req.AvatarURL.Set {
+		s.deleteStoredAvatar(ctx, user, req.AvatarURL.Ptr())
+	}
+
+	return updatedUser, nil
+}
+
+// UpdateAvatar checks and re-encodes an uploaded avatar image, stores it and points the
+// user's avatar_url at it. A previous avatar held in the same store is removed.
+func (s *userService) UpdateAvatar(ctx context.Context, id uuid.UUID, data []byte) (*models.User, error) {
+	if len(data) > MaxAvatarBytes {
+		return nil, utils.WrapError(utils.ErrInvalidInput, "avatar image is too large")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized, contentType, err := utils.SanitizeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := avatarKey(id, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.blobStore.Put(ctx, key, contentType, sanitized)
+	if err != nil {
+		return nil, utils.WrapError(err, "failed to store avatar")
+	}
+
+	updatedUser, err := s.userRepo.Update(id, &models.UpdateUserRequest{AvatarURL: models.NewNullableString(url)})
+	if err != nil {
+		// The new file is unreferenced if the user row could not be updated
+		if deleteErr := s.blobStore.Delete(ctx, key); deleteErr != nil {
+			utils.LogErrorContext(ctx, "Failed to delete orphaned avatar", deleteErr, utils.LogFields{"user_id": id, "key": key})
+		}
+		return nil, err
+	}
+
+	s.deleteStoredAvatar(ctx, user, &url)
+
 	return updatedUser, nil
 }
 
+// deleteStoredAvatar removes the user's previous avatar file when it was uploaded to the
+// blob store and has been replaced by newURL. Failures are logged, not returned, since the
+// user update has already been applied.
+func (s *userService) deleteStoredAvatar(ctx context.Context, user *models.User, newURL *string) {
+	if user.AvatarURL == nil || (newURL != nil && *newURL == *user.AvatarURL) {
+		return
+	}
+
+	key, ok := s.blobStore.KeyFromURL(*user.AvatarURL)
+	if !ok {
+		return
+	}
+	if err := s.blobStore.Delete(ctx, key); err != nil {
+		utils.LogErrorContext(ctx, "Failed to delete previous avatar", err, utils.LogFields{"user_id": user.ID, "key": key})
+	}
+}
+
+// avatarKey returns a fresh blob key for a user's avatar. The random part changes the
+// URL on every upload so caches never serve a stale image.
+func avatarKey(userID uuid.UUID, contentType string) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", utils.WrapError(err, "failed to generate avatar name")
+	}
+
+	extension := ".png"
+	if contentType == utils.ImageTypeJPEG {
+		extension = ".jpg"
+	}
+
+	return fmt.Sprintf("avatars/%s/%s%s", userID, hex.EncodeToString(suffix), extension), nil
+}
+
+// changeUsername applies a username change after enforcing the change cooldown.
+// Case-insensitive uniqueness among active users is enforced by the unique index when
+// the new name is written, so two concurrent changes to the same name cannot both win.
+func (s *userService) changeUsername(ctx context.Context, user *models.User, newUsername string) error {
+	// A change in casing only is not subject to the cooldown and is not recorded in history
+	if !strings.EqualFold(user.Username, newUsername) {
+		lastChange, err := s.userRepo.GetLastUsernameChange(user.ID)
+		if err != nil {
+			return err
+		}
+		if lastChange != nil && time.Since(*lastChange) < usernameChangeCooldown {
+			return utils.ErrUsernameChangeTooSoon
+		}
+	}
+
+	if err := s.userRepo.ChangeUsername(user.ID, user.Username, newUsername); err != nil {
+		return err
+	}
+
+	utils.LogInfoContext(ctx, "Username changed", utils.LogFields{
+		"user_id":      user.ID,
+		"old_username": user.Username,
+		"new_username": newUsername,
+	})
+
+	return nil
+}
+
 // UpdatePassword updates the password for a user
 func (s *userService) UpdatePassword(id uuid.UUID, hashedPassword string) error {
 	if _, err := s.userRepo.GetByID(id); err != nil {
@@ -122,37 +276,73 @@ func (s *userService) UpdatePassword(id uuid.UUID, hashedPassword string) error
 }
 
 // DeleteUser deletes a user
-func (s *userService) DeleteUser(id uuid.UUID) error {
+func (s *userService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	if _, err := s.userRepo.GetByID(id); err != nil {
 		return err
 	}
 
-	err := s.userRepo.Delete(id)
+	result, err := s.userRepo.DeleteWithContent(id, s.deletePolicy)
 	if err != nil {
 		return err
 	}
 
+	utils.LogInfoContext(ctx, "User deleted", utils.LogFields{
+		"user_id":  result.UserID,
+		"policy":   result.Policy,
+		"posts":    result.Posts,
+		"comments": result.Comments,
+	})
+
 	return nil
 }
 
-// ListUsers retrieves a paginated list of users
-func (s *userService) ListUsers(limit, offset int) ([]models.User, error) {
+// ListUsers retrieves a paginated list of users along with the total user count
+func (s *userService) ListUsers(limit, offset int) ([]models.User, int, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	if offset < 0 {
 		offset = 0
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
 	}
 
 	users, err := s.userRepo.List(limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	total, err := s.userRepo.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// AdminListUsers retrieves a filtered, paginated list of users with the total match count
+func (s *userService) AdminListUsers(filter *models.AdminUserFilter, limit, offset int) ([]models.User, int, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit > utils.MaxPageLimit() {
+		limit = utils.MaxPageLimit()
+	}
+
+	if filter != nil && filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedFrom.After(*filter.CreatedTo) {
+		return nil, 0, utils.WrapError(utils.ErrInvalidInput, "from must not be after to")
+	}
+
+	users, total, err := s.userRepo.AdminList(filter, limit, offset)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 // Helper function to convert string to *string
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
+	"github.com/TejasThombare20/post-comments-service/validator"
+)
+
+func TestUpdateUserUsername(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      string
+		lastChange   time.Duration // how long ago the previous change was; 0 for never
+		taken        string
+		newUsername  string
+		wantErr      error
+		wantUsername string
+		wantHistory  int
+	}{
+		{"successful change", "alice", 0, "", "alice_new", nil, "alice_new", 1},
+		{"change after the cooldown", "alice", usernameChangeCooldown + time.Hour, "", "alice_new", nil, "alice_new", 2},
+		{"name held by another user", "alice", 0, "bob", "bob", utils.ErrUsernameAlreadyExists, "alice", 0},
+		{"name held by another user in other casing", "alice", 0, "bob", "BOB", utils.ErrUsernameAlreadyExists, "alice", 0},
+		{"second change within the cooldown", "alice", time.Hour, "", "alice_new", utils.ErrUsernameChangeTooSoon, "alice", 1},
+		{"case-only change within the cooldown", "alice", time.Hour, "", "Alice", nil, "Alice", 1},
+		{"case-only change is not recorded", "alice", 0, "", "ALICE", nil, "ALICE", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{ID: testUser(models.RoleUser).ID, Username: tt.current}
+			repo := newFakeUserRepo(user)
+			if tt.taken != "" {
+				other := testUser(models.RoleUser)
+				other.Username = tt.taken
+				repo.users[other.ID] = other
+			}
+			if tt.lastChange > 0 {
+				repo.usernameChanges[user.ID] = []time.Time{time.Now().Add(-tt.lastChange)}
+			}
+
+			svc := NewUserService(repo, nil, validator.NewValidator(), nil, models.UserDeletePolicyAnonymize)
+			newUsername := tt.newUsername
+			_, err := svc.UpdateUser(context.Background(), user.ID, &models.UpdateUserRequest{Username: &newUsername})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if user.Username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUsername)
+			}
+			if got := len(repo.usernameChanges[user.ID]); got != tt.wantHistory {
+				t.Errorf("history has %d entries, want %d", got, tt.wantHistory)
+			}
+		})
+	}
+}
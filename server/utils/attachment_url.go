@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// attachmentExtensions are the file types a comment attachment may link to
+var attachmentExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+}
+
+// SanitizeAttachmentURL checks that raw is an absolute http(s) link to an allowed image
+// file and returns it in normalized form. Script and inline data URLs are always rejected.
+func SanitizeAttachmentURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("attachment URL is empty")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", errors.New("attachment URL is malformed")
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "javascript", "data", "vbscript":
+		return "", errors.New("attachment URL scheme is not allowed")
+	default:
+		return "", errors.New("attachment URL must use http or https")
+	}
+
+	if u.Host == "" || u.User != nil {
+		return "", errors.New("attachment URL must have a host and no credentials")
+	}
+
+	if !attachmentExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return "", errors.New("attachment URL must point to a .jpg, .jpeg, .png, .gif or .webp file")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u.String(), nil
+}
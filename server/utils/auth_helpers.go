@@ -21,3 +21,11 @@ func GetUserIDFromContext(c *gin.Context) (uuid.UUID, error) {
 
 	return userID, nil
 }
+
+// GetUserRoleFromContext extracts the user role from the Gin context.
+// It returns an empty string when the request is not authenticated.
+func GetUserRoleFromContext(c *gin.Context) string {
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+	return roleStr
+}
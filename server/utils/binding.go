@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// StrictJSONContextKey is the gin context key that turns on strict JSON decoding for a request
+const StrictJSONContextKey = "strict_json"
+
+// BodyTooLargeContextKey is the gin context key set once reading the request body hit the
+// size limit, so the resulting bind error is reported as 413 rather than 400
+const BodyTooLargeContextKey = "body_too_large"
+
+// BindJSON decodes the request body into obj. When strict decoding is enabled for the
+// request, unknown fields are rejected (e.g. `json: unknown field "contnet"`);
+// otherwise it behaves like ShouldBindJSON and ignores them.
+func BindJSON(c *gin.Context, obj interface{}) error {
+	if !c.GetBool(StrictJSONContextKey) {
+		return c.ShouldBindJSON(obj)
+	}
+
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
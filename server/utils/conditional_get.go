@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns a weak entity tag hashing parts, which should together identify one version
+// of a resource (e.g. its ID and updated_at). The tag is weak because the response
+// envelope carries a per-request request_id, so two bodies for the same version are
+// equivalent but not byte-for-byte identical.
+func ETag(parts ...interface{}) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		if t, ok := part.(time.Time); ok {
+			part = t.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(hash, "%v|", part)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// NotModified sets the ETag and Last-Modified headers for the resource version being
+// served and checks the request's If-None-Match and If-Modified-Since headers. When the
+// client already holds this version it responds 304 Not Modified and returns true, and
+// the handler should return without writing a body.
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	// Let clients and shared caches store the response but revalidate before reusing it
+	c.Header("Cache-Control", "no-cache")
+
+	if !isNotModified(c.Request, etag, lastModified) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+	return true
+}
+
+// isNotModified evaluates the conditional headers as RFC 9110 does for GET: If-None-Match
+// takes precedence, and If-Modified-Since is only consulted when it is absent
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagListMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have one-second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagListMatches reports whether the If-None-Match value lists etag, using the weak
+// comparison that ignores the W/ prefix, or is "*"
+func etagListMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
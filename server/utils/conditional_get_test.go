@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETagListMatches(t *testing.T) {
+	etag := `W/"abc123"`
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"same weak tag", `W/"abc123"`, true},
+		{"strong form of the tag", `"abc123"`, true},
+		{"listed among others", `"zzz", W/"abc123" , "yyy"`, true},
+		{"wildcard", ` * `, true},
+		{"different tag", `W/"abc124"`, false},
+		{"unquoted tag", `abc123`, false},
+		{"tag only as a prefix", `W/"abc1234"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagListMatches(tt.header, etag); got != tt.want {
+				t.Errorf("etagListMatches(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	etag := `W/"abc123"`
+	lastModified := time.Date(2024, 1, 15, 10, 30, 0, 500_000_000, time.UTC)
+	httpDate := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	tests := []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		lastModified    time.Time
+		want            bool
+	}{
+		{"no conditionals", "", "", lastModified, false},
+		{"matching ETag", etag, "", lastModified, true},
+		{"stale ETag", `W/"old"`, "", lastModified, false},
+		{"stale ETag wins over a current date", `W/"old"`, httpDate(lastModified), lastModified, false},
+		{"matching ETag wins over an old date", etag, httpDate(lastModified.Add(-time.Hour)), lastModified, true},
+		{"unchanged within the same second", "", httpDate(lastModified), lastModified, true},
+		{"unchanged since a later date", "", httpDate(lastModified.Add(time.Hour)), lastModified, true},
+		{"changed since", "", httpDate(lastModified.Add(-time.Second)), lastModified, false},
+		{"unparseable date", "", "yesterday", lastModified, false},
+		{"no Last-Modified to compare", "", httpDate(lastModified), time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.ifModifiedSince != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			if got := isNotModified(r, etag, tt.lastModified); got != tt.want {
+				t.Errorf("isNotModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
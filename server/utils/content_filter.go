@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Content filter modes: reject content containing a banned word, or mask the word
+const (
+	ContentFilterModeReject = "reject"
+	ContentFilterModeMask   = "mask"
+)
+
+// ContentFilter finds banned words in content. Matching is case-insensitive and
+// whole-word, so a banned word inside a longer word (the Scunthorpe problem) is ignored.
+type ContentFilter struct {
+	pattern *regexp.Regexp
+}
+
+// NewContentFilter creates a content filter for the given words or phrases.
+// Blank entries are ignored; with no words the filter matches nothing.
+func NewContentFilter(words []string) *ContentFilter {
+	var quoted []string
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &ContentFilter{}
+	}
+
+	// Longest first, so a phrase wins over a banned word it starts with
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+	return &ContentFilter{
+		pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`),
+	}
+}
+
+// LoadContentFilter reads a banned word list from path, one word or phrase per line.
+// Blank lines and lines starting with # are skipped.
+func LoadContentFilter(path string) (*ContentFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, WrapError(err, "failed to open banned words file")
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, WrapError(err, "failed to read banned words file")
+	}
+
+	return NewContentFilter(words), nil
+}
+
+// Contains reports whether content contains a banned word
+func (f *ContentFilter) Contains(content string) bool {
+	return len(f.matches(content)) > 0
+}
+
+// Mask replaces every character of each banned word in content with '*'
+func (f *ContentFilter) Mask(content string) string {
+	matches := f.matches(content)
+	if len(matches) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range matches {
+		b.WriteString(content[last:loc[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(content[loc[0]:loc[1]])))
+		last = loc[1]
+	}
+	b.WriteString(content[last:])
+
+	return b.String()
+}
+
+// ContainsHTML reports whether the text of an HTML fragment contains a banned word.
+// Tag names and attribute values are not checked.
+func (f *ContentFilter) ContainsHTML(content string) bool {
+	found := false
+	eachHTMLToken(content, func(raw, text string, isText bool) {
+		found = found || (isText && f.Contains(text))
+	})
+	return found
+}
+
+// MaskHTML masks banned words in the text of an HTML fragment. Tags and attribute
+// values such as link targets are copied unchanged so the markup stays valid.
+func (f *ContentFilter) MaskHTML(content string) string {
+	if f == nil || f.pattern == nil {
+		return content
+	}
+
+	var b strings.Builder
+	eachHTMLToken(content, func(raw, text string, isText bool) {
+		if isText && f.Contains(text) {
+			b.WriteString(html.EscapeString(f.Mask(text)))
+			return
+		}
+		b.WriteString(raw)
+	})
+
+	return b.String()
+}
+
+// eachHTMLToken calls fn with the source of each token in content and, for text
+// nodes, the unescaped text
+func eachHTMLToken(content string, fn func(raw, text string, isText bool)) {
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			if z.Err() != io.EOF {
+				fn(string(z.Raw()), "", false)
+			}
+			return
+		}
+
+		// Copy the source first: Text unescapes in place
+		raw := string(z.Raw())
+		if tokenType == html.TextToken {
+			fn(raw, string(z.Text()), true)
+		} else {
+			fn(raw, "", false)
+		}
+	}
+}
+
+// matches returns the byte ranges of banned words in content that stand as whole words
+func (f *ContentFilter) matches(content string) [][]int {
+	if f == nil || f.pattern == nil {
+		return nil
+	}
+
+	var matches [][]int
+	for _, loc := range f.pattern.FindAllStringIndex(content, -1) {
+		before, _ := utf8.DecodeLastRuneInString(content[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(content[loc[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			matches = append(matches, loc)
+		}
+	}
+
+	return matches
+}
+
+// isWordRune reports whether r continues a word; utf8.RuneError marks the start or
+// end of the content
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
@@ -0,0 +1,99 @@
+package utils
+
+import "testing"
+
+func TestContentFilter(t *testing.T) {
+	filter := NewContentFilter([]string{"darn", "heck no", "  ", "c++"})
+
+	tests := []struct {
+		name         string
+		content      string
+		wantContains bool
+		wantMasked   string
+	}{
+		{"whole word", "well darn it", true, "well **** it"},
+		{"case-insensitive", "DARN", true, "****"},
+		{"next to punctuation", "darn!darn.", true, "****!****."},
+		{"inside a longer word", "darned darnation adarn", false, "darned darnation adarn"},
+		{"next to a digit or underscore", "darn1 _darn", false, "darn1 _darn"},
+		{"phrase", "oh heck no, not again", true, "oh *******, not again"},
+		{"regexp characters are literal", "I like c++ a lot", true, "I like *** a lot"},
+		{"non-ASCII neighbours", "ädarn darnö", false, "ädarn darnö"},
+		{"clean", "nothing to see", false, "nothing to see"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Contains(tt.content); got != tt.wantContains {
+				t.Errorf("Contains = %v, want %v", got, tt.wantContains)
+			}
+			if got := filter.Mask(tt.content); got != tt.wantMasked {
+				t.Errorf("Mask = %q, want %q", got, tt.wantMasked)
+			}
+		})
+	}
+}
+
+func TestContentFilterHTML(t *testing.T) {
+	filter := NewContentFilter([]string{"darn", "nofollow", "span"})
+
+	tests := []struct {
+		name         string
+		content      string
+		wantContains bool
+		wantMasked   string
+	}{
+		{
+			name:         "text is masked",
+			content:      "<span>well darn it</span>",
+			wantContains: true,
+			wantMasked:   "<span>well **** it</span>",
+		},
+		{
+			name:         "link target and attributes are kept",
+			content:      `<a href="https://darn.example.com/darn" rel="nofollow">darn site</a>`,
+			wantContains: true,
+			wantMasked:   `<a href="https://darn.example.com/darn" rel="nofollow">**** site</a>`,
+		},
+		{
+			name:         "words only in markup do not count",
+			content:      `<span><a href="https://example.com/darn" rel="nofollow">link</a></span>`,
+			wantContains: false,
+			wantMasked:   `<span><a href="https://example.com/darn" rel="nofollow">link</a></span>`,
+		},
+		{
+			name:         "entities are decoded before matching",
+			content:      "<p>&lt;darn&gt; &amp; more</p>",
+			wantContains: true,
+			wantMasked:   "<p>&lt;****&gt; &amp; more</p>",
+		},
+		{
+			name:         "unmatched text keeps its escaping",
+			content:      "<p>it&#39;s fine</p><p>darn</p>",
+			wantContains: true,
+			wantMasked:   "<p>it&#39;s fine</p><p>****</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.ContainsHTML(tt.content); got != tt.wantContains {
+				t.Errorf("ContainsHTML = %v, want %v", got, tt.wantContains)
+			}
+			if got := filter.MaskHTML(tt.content); got != tt.wantMasked {
+				t.Errorf("MaskHTML = %q, want %q", got, tt.wantMasked)
+			}
+		})
+	}
+}
+
+func TestEmptyContentFilter(t *testing.T) {
+	for _, filter := range []*ContentFilter{nil, NewContentFilter(nil), NewContentFilter([]string{"", " "})} {
+		if filter.Contains("anything") || filter.ContainsHTML("<p>anything</p>") {
+			t.Errorf("filter %+v matched with no words", filter)
+		}
+		if got := filter.MaskHTML("<p>anything</p>"); got != "<p>anything</p>" {
+			t.Errorf("filter %+v masked %q", filter, got)
+		}
+	}
+}
@@ -17,10 +17,88 @@ var (
 	ErrUnauthorized          = errors.New("unauthorized access")
 	ErrForbidden             = errors.New("forbidden access")
 	ErrInvalidInput          = errors.New("invalid input")
+	ErrDuplicateComment      = errors.New("duplicate comment")
+	ErrUsernameChangeTooSoon = errors.New("username was changed too recently")
+	ErrParentMismatch        = errors.New("parent comment does not belong to the same post")
+	ErrNotDeleted            = errors.New("resource is not deleted")
+	ErrIdempotencyKeyInUse   = errors.New("idempotency key is already in use")
+	ErrVersionConflict       = errors.New("resource was modified by another request")
+	ErrCommentsLocked        = errors.New("comments are locked on this post")
+	ErrGuestRateLimited      = errors.New("too many guest comments from this address")
 	ErrDatabaseError         = errors.New("database error")
 	ErrInternalServer        = errors.New("internal server error")
 )
 
+// Machine-readable codes sent as error_code in error responses, so clients can branch on
+// the kind of failure without parsing error_message
+const (
+	ErrorCodeValidationFailed      = "VALIDATION_FAILED"
+	ErrorCodeUnauthorized          = "UNAUTHORIZED"
+	ErrorCodeInvalidCredentials    = "INVALID_CREDENTIALS"
+	ErrorCodeForbidden             = "FORBIDDEN"
+	ErrorCodeNotFound              = "NOT_FOUND"
+	ErrorCodeUserNotFound          = "USER_NOT_FOUND"
+	ErrorCodePostNotFound          = "POST_NOT_FOUND"
+	ErrorCodeCommentNotFound       = "COMMENT_NOT_FOUND"
+	ErrorCodeConflict              = "CONFLICT"
+	ErrorCodeUserExists            = "USER_EXISTS"
+	ErrorCodeUsernameExists        = "USERNAME_EXISTS"
+	ErrorCodeEmailExists           = "EMAIL_EXISTS"
+	ErrorCodeDuplicateComment      = "DUPLICATE_COMMENT"
+	ErrorCodeNotDeleted            = "NOT_DELETED"
+	ErrorCodeIdempotencyKeyInUse   = "IDEMPOTENCY_KEY_IN_USE"
+	ErrorCodeVersionConflict       = "VERSION_CONFLICT"
+	ErrorCodeParentMismatch        = "PARENT_MISMATCH"
+	ErrorCodeLocked                = "LOCKED"
+	ErrorCodeCommentsLocked        = "COMMENTS_LOCKED"
+	ErrorCodeUsernameChangeTooSoon = "USERNAME_CHANGE_TOO_SOON"
+	ErrorCodeTooManyRequests       = "TOO_MANY_REQUESTS"
+	ErrorCodeRequestTooLarge       = "REQUEST_TOO_LARGE"
+	ErrorCodeUnsupportedMediaType  = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeInvalidImage          = "INVALID_IMAGE"
+	ErrorCodeServiceUnavailable    = "SERVICE_UNAVAILABLE"
+	ErrorCodeInternal              = "INTERNAL_ERROR"
+)
+
+// errorCodes maps the sentinel errors to their error codes. It is a list rather than a map
+// because errors.Is has to be tried against each entry.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrUserNotFound, ErrorCodeUserNotFound},
+	{ErrPostNotFound, ErrorCodePostNotFound},
+	{ErrCommentNotFound, ErrorCodeCommentNotFound},
+	{ErrUserExists, ErrorCodeUserExists},
+	{ErrUsernameAlreadyExists, ErrorCodeUsernameExists},
+	{ErrEmailAlreadyExists, ErrorCodeEmailExists},
+	{ErrInvalidCredentials, ErrorCodeInvalidCredentials},
+	{ErrUnauthorized, ErrorCodeUnauthorized},
+	{ErrForbidden, ErrorCodeForbidden},
+	{ErrInvalidInput, ErrorCodeValidationFailed},
+	{ErrDuplicateComment, ErrorCodeDuplicateComment},
+	{ErrUsernameChangeTooSoon, ErrorCodeUsernameChangeTooSoon},
+	{ErrParentMismatch, ErrorCodeParentMismatch},
+	{ErrNotDeleted, ErrorCodeNotDeleted},
+	{ErrIdempotencyKeyInUse, ErrorCodeIdempotencyKeyInUse},
+	{ErrVersionConflict, ErrorCodeVersionConflict},
+	{ErrCommentsLocked, ErrorCodeCommentsLocked},
+	{ErrGuestRateLimited, ErrorCodeTooManyRequests},
+	{ErrDatabaseError, ErrorCodeInternal},
+	{ErrInternalServer, ErrorCodeInternal},
+}
+
+// ErrorCode returns the error code for the sentinel error wrapped by err, or an empty
+// string when err wraps none of them
+func ErrorCode(err error) string {
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+	return ""
+}
+
 // ErrorMessages contains predefined error messages for different scenarios
 type ErrorMessages struct {
 	UserNotFound        string
@@ -72,7 +150,11 @@ func IsNotFoundError(err error) bool {
 func IsConflictError(err error) bool {
 	return errors.Is(err, ErrUserExists) ||
 		errors.Is(err, ErrUsernameAlreadyExists) ||
-		errors.Is(err, ErrEmailAlreadyExists)
+		errors.Is(err, ErrEmailAlreadyExists) ||
+		errors.Is(err, ErrDuplicateComment) ||
+		errors.Is(err, ErrNotDeleted) ||
+		errors.Is(err, ErrIdempotencyKeyInUse) ||
+		errors.Is(err, ErrVersionConflict)
 }
 
 // IsUnauthorizedError checks if the error is an unauthorized error
@@ -88,5 +170,6 @@ func IsForbiddenError(err error) bool {
 
 // IsValidationError checks if the error is a validation error
 func IsValidationError(err error) bool {
-	return errors.Is(err, ErrInvalidInput)
+	return errors.Is(err, ErrInvalidInput) ||
+		errors.Is(err, ErrParentMismatch)
 }
@@ -9,9 +9,15 @@ import (
 	"github.com/microcosm-cc/bluemonday"
 )
 
+// autolinkPattern matches bare http(s) and www. URLs in plain text
+var autolinkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
 // HTMLSanitizer handles HTML content sanitization and processing
 type HTMLSanitizer struct {
-	policy *bluemonday.Policy
+	policy        *bluemonday.Policy
+	snippetPolicy *bluemonday.Policy
+	embedPolicy   *bluemonday.Policy
+	autolink      bool
 }
 
 // NewHTMLSanitizer creates a new HTML sanitizer instance
@@ -33,6 +39,7 @@ func NewHTMLSanitizer() *HTMLSanitizer {
 
 	// Allow links with href attribute
 	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowAttrs("rel").Matching(regexp.MustCompile(`^nofollow$`)).OnElements("a")
 	policy.AllowElements("a")
 
 	// Allow basic styling attributes
@@ -47,16 +54,58 @@ func NewHTMLSanitizer() *HTMLSanitizer {
 	// Allow code elements
 	policy.AllowElements("code", "pre")
 
+	// Search snippets only keep the <mark> highlight tags
+	snippetPolicy := bluemonday.StrictPolicy()
+	snippetPolicy.AllowElements("mark")
+
 	return &HTMLSanitizer{
-		policy: policy,
+		policy:        policy,
+		snippetPolicy: snippetPolicy,
+		embedPolicy:   newEmbedPolicy(),
 	}
 }
 
+// newEmbedPolicy builds the stricter policy used for comments rendered in third-party pages.
+// It allows the same elements as the default policy but no style or class attributes, only
+// http(s) and mailto links, and forces rel="nofollow noopener" so output is safe under a strict CSP.
+func newEmbedPolicy() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+
+	policy.AllowElements("p", "br", "span", "div")
+	policy.AllowElements("strong", "b", "em", "i", "u", "s", "del", "ins")
+	policy.AllowElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowElements("ul", "ol", "li")
+	policy.AllowElements("blockquote", "code", "pre")
+
+	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.RequireParseableURLs(true)
+	policy.RequireNoFollowOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return policy
+}
+
+// SetAutolink enables or disables converting bare URLs in plain text comments into links
+func (h *HTMLSanitizer) SetAutolink(enabled bool) {
+	h.autolink = enabled
+}
+
 // SanitizeHTML sanitizes HTML content using the bluemonday policy
 func (h *HTMLSanitizer) SanitizeHTML(content string) string {
 	return h.policy.Sanitize(content)
 }
 
+// SanitizeSnippet strips all markup from a search snippet except <mark> highlights
+func (h *HTMLSanitizer) SanitizeSnippet(snippet string) string {
+	return h.snippetPolicy.Sanitize(snippet)
+}
+
+// SanitizeEmbed re-sanitizes stored content with the stricter embed policy
+func (h *HTMLSanitizer) SanitizeEmbed(content string) string {
+	return h.embedPolicy.Sanitize(content)
+}
+
 // IsHTMLContent checks if the content appears to be HTML
 func (h *HTMLSanitizer) IsHTMLContent(content string) bool {
 	// Check for common HTML tags
@@ -94,10 +143,16 @@ func (h *HTMLSanitizer) ValidateHTMLContent(content string) error {
 	return nil
 }
 
-// ConvertPlainTextToHTML converts plain text to HTML by wrapping it in a span tag
+// ConvertPlainTextToHTML converts plain text to HTML by wrapping it in a span tag.
+// When autolinking is enabled, bare URLs are wrapped in nofollow links.
 func (h *HTMLSanitizer) ConvertPlainTextToHTML(content string) string {
 	// Escape HTML entities in plain text
-	escaped := html.EscapeString(content)
+	var escaped string
+	if h.autolink {
+		escaped = autolinkPlainText(content)
+	} else {
+		escaped = html.EscapeString(content)
+	}
 
 	// Convert line breaks to <br> tags
 	escaped = strings.ReplaceAll(escaped, "\n", "<br>")
@@ -106,6 +161,39 @@ func (h *HTMLSanitizer) ConvertPlainTextToHTML(content string) string {
 	return "<span>" + escaped + "</span>"
 }
 
+// autolinkPlainText escapes plain text and wraps any bare URLs in anchor tags.
+// Trailing punctuation is left outside the link so "see https://x.com." works.
+func autolinkPlainText(content string) string {
+	var b strings.Builder
+	last := 0
+
+	for _, loc := range autolinkPattern.FindAllStringIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		url := strings.TrimRight(content[start:end], ".,;:!?)]}")
+		// Keep a closing parenthesis that balances one inside the URL
+		if strings.Count(url, "(") > strings.Count(url, ")") && end > start+len(url) && content[start+len(url)] == ')' {
+			url += ")"
+		}
+		end = start + len(url)
+
+		href := url
+		if strings.HasPrefix(strings.ToLower(href), "www.") {
+			href = "http://" + href
+		}
+
+		b.WriteString(html.EscapeString(content[last:start]))
+		b.WriteString(`<a href="`)
+		b.WriteString(html.EscapeString(href))
+		b.WriteString(`" rel="nofollow">`)
+		b.WriteString(html.EscapeString(url))
+		b.WriteString("</a>")
+		last = end
+	}
+
+	b.WriteString(html.EscapeString(content[last:]))
+	return b.String()
+}
+
 // ProcessCommentContent processes comment content based on whether it's HTML or plain text
 func (h *HTMLSanitizer) ProcessCommentContent(content string) string {
 	if content == "" {
@@ -123,6 +211,18 @@ func (h *HTMLSanitizer) ProcessCommentContent(content string) string {
 	return h.SanitizeHTML(htmlContent)
 }
 
+// ProcessGuestCommentContent treats content from a commenter who is not signed in as
+// plain text whatever it looks like: markup is escaped rather than kept, and URLs are
+// never turned into links, so guests cannot post formatting or clickable spam
+func (h *HTMLSanitizer) ProcessGuestCommentContent(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	escaped := strings.ReplaceAll(html.EscapeString(content), "\n", "<br>")
+	return h.SanitizeHTML("<span>" + escaped + "</span>")
+}
+
 // StripHTMLTags removes all HTML tags and returns plain text (useful for previews)
 func (h *HTMLSanitizer) StripHTMLTags(content string) string {
 	// Use bluemonday's StripTagsPolicy to remove all HTML tags
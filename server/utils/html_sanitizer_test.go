@@ -0,0 +1,136 @@
+package utils
+
+import "testing"
+
+func TestProcessCommentContentAutolink(t *testing.T) {
+	tests := []struct {
+		name     string
+		autolink bool
+		content  string
+		want     string
+	}{
+		{
+			name:     "bare URL becomes a nofollow link",
+			autolink: true,
+			content:  "see https://example.com/a?b=1",
+			want:     `<span>see <a href="https://example.com/a?b=1" rel="nofollow">https://example.com/a?b=1</a></span>`,
+		},
+		{
+			name:     "www URL gets a scheme",
+			autolink: true,
+			content:  "www.example.com",
+			want:     `<span><a href="http://www.example.com" rel="nofollow">www.example.com</a></span>`,
+		},
+		{
+			name:     "trailing punctuation stays outside the link",
+			autolink: true,
+			content:  "visit https://example.com.",
+			want:     `<span>visit <a href="https://example.com" rel="nofollow">https://example.com</a>.</span>`,
+		},
+		{
+			name:     "balanced parenthesis is kept",
+			autolink: true,
+			content:  "(https://en.wikipedia.org/wiki/Go_(language))",
+			want:     `<span>(<a href="https://en.wikipedia.org/wiki/Go_(language)" rel="nofollow">https://en.wikipedia.org/wiki/Go_(language)</a>)</span>`,
+		},
+		{
+			name:     "text around the link is escaped",
+			autolink: true,
+			content:  "a & b https://example.com",
+			want:     `<span>a &amp; b <a href="https://example.com" rel="nofollow">https://example.com</a></span>`,
+		},
+		{
+			name:     "existing anchor is not linked twice",
+			autolink: true,
+			content:  `<a href="https://example.com">https://example.com</a>`,
+			want:     `<a href="https://example.com">https://example.com</a>`,
+		},
+		{
+			name:     "javascript URL is not linked",
+			autolink: true,
+			content:  "javascript:alert(1)",
+			want:     `<span>javascript:alert(1)</span>`,
+		},
+		{
+			name:     "autolink disabled leaves URLs as text",
+			autolink: false,
+			content:  "see https://example.com",
+			want:     `<span>see https://example.com</span>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sanitizer := NewHTMLSanitizer()
+			sanitizer.SetAutolink(tt.autolink)
+			if got := sanitizer.ProcessCommentContent(tt.content); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeEmbedIsStricter(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantDefault string
+		wantEmbed   string
+	}{
+		{
+			name:        "inline style and class are dropped",
+			content:     `<p style="color:red" class="big">hi</p>`,
+			wantDefault: `<p style="color:red" class="big">hi</p>`,
+			wantEmbed:   `<p>hi</p>`,
+		},
+		{
+			name:        "style with a URL is dropped",
+			content:     `<div style="background:url(x)"><strong>bold</strong></div>`,
+			wantDefault: `<div style="background:url(x)"><strong>bold</strong></div>`,
+			wantEmbed:   `<div><strong>bold</strong></div>`,
+		},
+		{
+			name:        "links open in a new tab without an opener",
+			content:     `<a href="https://example.com">x</a>`,
+			wantDefault: `<a href="https://example.com">x</a>`,
+			wantEmbed:   `<a href="https://example.com" rel="nofollow noopener" target="_blank">x</a>`,
+		},
+		{
+			name:        "relative links are unwrapped",
+			content:     `<a href="/relative">x</a>`,
+			wantDefault: `<a href="/relative">x</a>`,
+			wantEmbed:   `x`,
+		},
+		{
+			name:        "event handlers are dropped by both",
+			content:     `<span onclick="alert(1)">x</span>`,
+			wantDefault: `<span>x</span>`,
+			wantEmbed:   `<span>x</span>`,
+		},
+	}
+
+	sanitizer := NewHTMLSanitizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizer.SanitizeHTML(tt.content); got != tt.wantDefault {
+				t.Errorf("default policy: got %s, want %s", got, tt.wantDefault)
+			}
+			if got := sanitizer.SanitizeEmbed(tt.content); got != tt.wantEmbed {
+				t.Errorf("embed policy: got %s, want %s", got, tt.wantEmbed)
+			}
+		})
+	}
+}
+
+func TestSanitizeEmbedRejectsScriptURLs(t *testing.T) {
+	sanitizer := NewHTMLSanitizer()
+	for _, content := range []string{
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="data:text/html,<script>alert(1)</script>">x</a>`,
+		`<a href="vbscript:msgbox(1)">x</a>`,
+	} {
+		if got := sanitizer.SanitizeEmbed(content); got != "x" {
+			t.Errorf("SanitizeEmbed(%q) = %q, want the link removed", content, got)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers used for idempotent create requests
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// GetIdempotencyKey returns the trimmed Idempotency-Key header, or "" when it is absent
+func GetIdempotencyKey(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+}
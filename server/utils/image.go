@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/webp"
+)
+
+// Image content types accepted for uploads
+const (
+	ImageTypePNG  = "image/png"
+	ImageTypeJPEG = "image/jpeg"
+	ImageTypeWebP = "image/webp"
+)
+
+// maxImageDimension caps the width and height of an uploaded image so a small,
+// highly compressed file cannot expand into a huge bitmap when decoded
+const maxImageDimension = 4096
+
+// jpegQuality is the quality used when re-encoding JPEG uploads
+const jpegQuality = 90
+
+// IsAllowedImageType reports whether contentType is one of the accepted upload types
+func IsAllowedImageType(contentType string) bool {
+	switch contentType {
+	case ImageTypePNG, ImageTypeJPEG, ImageTypeWebP:
+		return true
+	default:
+		return false
+	}
+}
+
+// SanitizeImage checks that data really is a PNG, JPEG or WebP image by sniffing its
+// magic bytes, then decodes and re-encodes it. Re-encoding drops EXIF and any other
+// metadata or trailing payload. WebP is re-encoded as PNG since the standard library
+// has no WebP encoder. It returns the clean image and its content type.
+func SanitizeImage(data []byte) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+	if !IsAllowedImageType(contentType) {
+		return nil, "", WrapError(ErrInvalidInput, "file is not a PNG, JPEG or WebP image")
+	}
+
+	config, err := decodeImageConfig(data, contentType)
+	if err != nil {
+		return nil, "", WrapError(ErrInvalidInput, "image could not be read")
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxImageDimension || config.Height > maxImageDimension {
+		return nil, "", WrapError(ErrInvalidInput, "image dimensions are out of range")
+	}
+
+	img, err := decodeImage(data, contentType)
+	if err != nil {
+		return nil, "", WrapError(ErrInvalidInput, "image could not be decoded")
+	}
+
+	var out bytes.Buffer
+	if contentType == ImageTypeJPEG {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		contentType = ImageTypePNG
+		err = png.Encode(&out, img)
+	}
+	if err != nil {
+		return nil, "", WrapError(err, "failed to encode image")
+	}
+
+	return out.Bytes(), contentType, nil
+}
+
+// decodeImageConfig reads the image header without decoding the pixels
+func decodeImageConfig(data []byte, contentType string) (image.Config, error) {
+	reader := bytes.NewReader(data)
+	switch contentType {
+	case ImageTypePNG:
+		return png.DecodeConfig(reader)
+	case ImageTypeJPEG:
+		return jpeg.DecodeConfig(reader)
+	default:
+		return webp.DecodeConfig(reader)
+	}
+}
+
+// decodeImage decodes the full image with the decoder matching its sniffed type
+func decodeImage(data []byte, contentType string) (image.Image, error) {
+	reader := bytes.NewReader(data)
+	switch contentType {
+	case ImageTypePNG:
+		return png.Decode(reader)
+	case ImageTypeJPEG:
+		return jpeg.Decode(reader)
+	default:
+		return webp.Decode(reader)
+	}
+}
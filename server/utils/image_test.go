@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encodeTestImage returns a width x height image encoded as PNG or JPEG
+func encodeTestImage(t *testing.T, contentType string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	var err error
+	if contentType == ImageTypeJPEG {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 EXIF segment carrying marker right after the JPEG SOI
+func withExif(data []byte, marker string) []byte {
+	payload := append([]byte("Exif\x00\x00"), marker...)
+	length := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestSanitizeImage(t *testing.T) {
+	pngData := encodeTestImage(t, ImageTypePNG, 8, 8)
+	jpegData := encodeTestImage(t, ImageTypeJPEG, 8, 8)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantErr  bool
+	}{
+		{"png", pngData, ImageTypePNG, false},
+		{"jpeg", jpegData, ImageTypeJPEG, false},
+		{"jpeg with EXIF", withExif(jpegData, "GPS 51.5N 0.1W"), ImageTypeJPEG, false},
+		{"png with a trailing payload", append(append([]byte{}, pngData...), "<script>alert(1)</script>"...), ImageTypePNG, false},
+		{"text", []byte("just some text, not an image"), "", true},
+		{"html", []byte("<html><body>hi</body></html>"), "", true},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), "", true},
+		{"truncated png", pngData[:len(pngData)/2], "", true},
+		{"png header only", pngData[:16], "", true},
+		{"too wide", encodeTestImage(t, ImageTypePNG, maxImageDimension+1, 1), "", true},
+		{"empty", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, contentType, err := SanitizeImage(tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("got error %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if contentType != tt.wantType {
+				t.Errorf("got content type %q, want %q", contentType, tt.wantType)
+			}
+			if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("GPS")) || bytes.Contains(out, []byte("<script>")) {
+				t.Error("sanitized image still carries metadata or a trailing payload")
+			}
+			if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+				t.Errorf("sanitized image does not decode: %v", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"sync"
 	"time"
@@ -9,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Logger is the global logger instance
@@ -27,6 +30,17 @@ type RequestContext struct {
 	IP        string
 }
 
+// logLevels maps the LOG_LEVEL values to logrus levels
+var logLevels = map[string]logrus.Level{
+	"debug": logrus.DebugLevel,
+	"info":  logrus.InfoLevel,
+	"warn":  logrus.WarnLevel,
+	"error": logrus.ErrorLevel,
+}
+
+// requestContextKey is the context key under which the RequestContext is stored
+type requestContextKey struct{}
+
 // init automatically initializes the logger when the package is imported
 func init() {
 	ensureLoggerInitialized()
@@ -56,19 +70,78 @@ func initializeLogger() {
 		},
 	})
 
-	// Set log level based on environment
-	env := os.Getenv("ENV")
-	if env == "production" {
-		Logger.SetLevel(logrus.InfoLevel)
-	} else {
-		Logger.SetLevel(logrus.DebugLevel)
+	// Log at info until InitLogger applies the configured LOG_LEVEL; honour it from the
+	// process environment already so startup messages are filtered the same way
+	Logger.SetLevel(logrus.InfoLevel)
+	if level, ok := logLevels[os.Getenv("LOG_LEVEL")]; ok {
+		Logger.SetLevel(level)
 	}
 }
 
-// InitLogger initializes the global logger (public function for explicit initialization)
-func InitLogger() {
+// Log output destinations accepted by InitLogger
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+	LogOutputFile   = "file"
+)
+
+// LoggerOptions configures where and at what level the global logger writes
+type LoggerOptions struct {
+	Level  string
+	Output string
+
+	// File rotation settings, used when Output is LogOutputFile
+	FilePath       string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+	FileMaxAgeDays int
+}
+
+// InitLogger applies the configured level and output to the global logger. Until it is
+// called the logger writes to stdout at the LOG_LEVEL in the process environment, or info.
+func InitLogger(options LoggerOptions) error {
 	ensureLoggerInitialized()
+
+	switch options.Output {
+	case LogOutputStdout, "":
+		Logger.SetOutput(os.Stdout)
+	case LogOutputStderr:
+		Logger.SetOutput(os.Stderr)
+	case LogOutputFile:
+		if options.FilePath == "" {
+			return errors.New("log file path is required when logging to a file")
+		}
+		// lumberjack rotates the file once it reaches FileMaxSizeMB
+		Logger.SetOutput(&lumberjack.Logger{
+			Filename:   options.FilePath,
+			MaxSize:    options.FileMaxSizeMB,
+			MaxBackups: options.FileMaxBackups,
+			MaxAge:     options.FileMaxAgeDays,
+		})
+	default:
+		return fmt.Errorf("unknown log output %q", options.Output)
+	}
+
+	if options.Level != "" {
+		if err := SetLogLevel(options.Level); err != nil {
+			return err
+		}
+	}
+
 	Logger.Info("Logger initialized successfully")
+	return nil
+}
+
+// SetLogLevel sets the minimum level logged. level is one of debug, info, warn or error,
+// the values LOG_LEVEL accepts.
+func SetLogLevel(level string) error {
+	ensureLoggerInitialized()
+	parsed, ok := logLevels[level]
+	if !ok {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	Logger.SetLevel(parsed)
+	return nil
 }
 
 // LogInfo logs info level messages with optional fields
@@ -114,35 +187,102 @@ func LogDebug(message string, fields LogFields) {
 	}
 }
 
-// LogWithContext logs with request context information
-func LogWithContext(ctx context.Context, level logrus.Level, message string, fields LogFields) {
+// ContextWithRequestContext returns a copy of ctx carrying the request context
+func ContextWithRequestContext(ctx context.Context, reqCtx *RequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, reqCtx)
+}
+
+// RequestContextFromContext returns the request context carried by ctx, or nil if there is none
+func RequestContextFromContext(ctx context.Context) *RequestContext {
+	if ctx == nil {
+		return nil
+	}
+	reqCtx, _ := ctx.Value(requestContextKey{}).(*RequestContext)
+	return reqCtx
+}
+
+// LoggerFromContext returns a log entry pre-populated with the request fields carried by ctx.
+// Without a request context it returns a plain entry on the global logger.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
 	ensureLoggerInitialized()
-	logFields := logrus.Fields{}
-	if fields != nil {
-		logFields = logrus.Fields(fields)
+	entry := logrus.NewEntry(Logger)
+
+	reqCtx := RequestContextFromContext(ctx)
+	if reqCtx == nil {
+		return entry
+	}
+
+	fields := logrus.Fields{
+		"request_id": reqCtx.RequestID,
+		"method":     reqCtx.Method,
+		"path":       reqCtx.Path,
+		"ip":         reqCtx.IP,
 	}
+	if reqCtx.UserID != "" {
+		fields["user_id"] = reqCtx.UserID
+	}
+
+	return entry.WithFields(fields)
+}
+
+// LogInfoContext logs info level messages with the request fields carried by ctx
+func LogInfoContext(ctx context.Context, message string, fields LogFields) {
+	LogWithContext(ctx, logrus.InfoLevel, message, fields)
+}
+
+// LogWarnContext logs warning level messages with the request fields carried by ctx
+func LogWarnContext(ctx context.Context, message string, fields LogFields) {
+	LogWithContext(ctx, logrus.WarnLevel, message, fields)
+}
 
-	// Add context information if available
-	if reqCtx, ok := ctx.Value("request_context").(*RequestContext); ok {
-		logFields["request_id"] = reqCtx.RequestID
-		logFields["user_id"] = reqCtx.UserID
-		logFields["method"] = reqCtx.Method
-		logFields["path"] = reqCtx.Path
-		logFields["ip"] = reqCtx.IP
+// LogDebugContext logs debug level messages with the request fields carried by ctx
+func LogDebugContext(ctx context.Context, message string, fields LogFields) {
+	LogWithContext(ctx, logrus.DebugLevel, message, fields)
+}
+
+// LogErrorContext logs error level messages with the request fields carried by ctx
+func LogErrorContext(ctx context.Context, message string, err error, fields LogFields) {
+	if fields == nil {
+		fields = LogFields{}
 	}
+	if err != nil {
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+	}
+	LogWithContext(ctx, logrus.ErrorLevel, message, fields)
+}
 
-	Logger.WithFields(logFields).Log(level, message)
+// LogWithContext logs with request context information
+func LogWithContext(ctx context.Context, level logrus.Level, message string, fields LogFields) {
+	entry := LoggerFromContext(ctx)
+	if fields != nil {
+		entry = entry.WithFields(logrus.Fields(fields))
+	}
+
+	entry.Log(level, message)
 }
 
-// GetRequestContext extracts request context from Gin context
+// GetRequestContext extracts request context from Gin context, reusing the one
+// seeded by the request context middleware when present
 func GetRequestContext(c *gin.Context) *RequestContext {
+	if reqCtx := RequestContextFromContext(c.Request.Context()); reqCtx != nil {
+		return reqCtx
+	}
+
 	requestID := c.GetHeader("X-Request-ID")
 	if requestID == "" {
 		requestID = uuid.New().String()
-		c.Header("X-Request-ID", requestID)
 	}
+	c.Header("X-Request-ID", requestID)
 
-	userID := c.GetHeader("X-User-ID")
+	// The user ID comes from the authenticated token, never from client headers
+	var userID string
+	if id, exists := c.Get("user_id"); exists {
+		if uid, ok := id.(uuid.UUID); ok {
+			userID = uid.String()
+		}
+	}
 
 	return &RequestContext{
 		RequestID: requestID,
@@ -155,22 +295,28 @@ func GetRequestContext(c *gin.Context) *RequestContext {
 
 // LogRequest logs HTTP request information
 func LogRequest(c *gin.Context, message string, fields LogFields) {
-	reqCtx := GetRequestContext(c)
-	ctx := context.WithValue(context.Background(), "request_context", reqCtx)
+	ctx := ContextWithRequestContext(context.Background(), GetRequestContext(c))
 	LogWithContext(ctx, logrus.InfoLevel, message, fields)
 }
 
-// LogRequestError logs HTTP request errors
+// LogRequestError logs HTTP request errors. When the client has already gone away the
+// failure is usually just the cancelled query, so it is logged at info level instead.
 func LogRequestError(c *gin.Context, message string, err error, fields LogFields) {
-	reqCtx := GetRequestContext(c)
-	ctx := context.WithValue(context.Background(), "request_context", reqCtx)
-
-	if fields == nil {
-		fields = LogFields{}
-	}
-	if err != nil {
-		fields["error"] = err.Error()
+	ctx := ContextWithRequestContext(context.Background(), GetRequestContext(c))
+	if IsRequestCanceled(c) {
+		if fields == nil {
+			fields = LogFields{}
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		LogInfoContext(ctx, message+" (request canceled by client)", fields)
+		return
 	}
+	LogErrorContext(ctx, message, err, fields)
+}
 
-	LogWithContext(ctx, logrus.ErrorLevel, message, fields)
+// IsRequestCanceled reports whether the client disconnected before the request finished
+func IsRequestCanceled(c *gin.Context) bool {
+	return c.Request != nil && errors.Is(c.Request.Context().Err(), context.Canceled)
 }
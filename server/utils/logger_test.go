@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitLoggerFileOutput(t *testing.T) {
+	previous, previousLevel := Logger.Out, Logger.GetLevel()
+	defer func() {
+		if closer, ok := Logger.Out.(io.Closer); ok {
+			closer.Close()
+		}
+		Logger.SetOutput(previous)
+		Logger.SetLevel(previousLevel)
+	}()
+
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+	err := InitLogger(LoggerOptions{Level: "info", Output: LogOutputFile, FilePath: path, FileMaxSizeMB: 1, FileMaxBackups: 1, FileMaxAgeDays: 1})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	LogInfo("written to a file", LogFields{"post_id": "abc"})
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("log file was not created: %v", err)
+	}
+	defer file.Close()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("log line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d log lines, want the initialization line and one more", len(entries))
+	}
+	if last := entries[1]; last["message"] != "written to a file" || last["post_id"] != "abc" || last["level"] != "info" {
+		t.Errorf("got entry %v, want the logged message with its fields", last)
+	}
+}
+
+func TestInitLoggerInvalidOptions(t *testing.T) {
+	previous := Logger.Out
+	defer Logger.SetOutput(previous)
+
+	for _, options := range []LoggerOptions{
+		{Output: LogOutputFile},
+		{Output: "syslog"},
+	} {
+		if err := InitLogger(options); err == nil {
+			t.Errorf("InitLogger(%+v): want an error", options)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxPaginationOffset is the default ceiling on the offset query parameter
+const DefaultMaxPaginationOffset = 10000
+
+// maxPaginationOffset caps how deep offset pagination may go, since large offsets make
+// the database scan and discard every skipped row
+var maxPaginationOffset = DefaultMaxPaginationOffset
+
+// DefaultMaxPageLimit is the default ceiling on the limit query parameter
+const DefaultMaxPageLimit = 100
+
+// maxPageLimit is the largest page size list endpoints accept. Larger limits are rejected
+// rather than clamped, so clients notice they are not getting the page size they asked for.
+var maxPageLimit = DefaultMaxPageLimit
+
+// SetMaxPageLimit sets the limit ceiling enforced by ParsePagination
+func SetMaxPageLimit(max int) {
+	maxPageLimit = max
+}
+
+// MaxPageLimit returns the limit ceiling enforced by ParsePagination. Services cap at
+// the same value, so callers that skip ParsePagination get the same bound.
+func MaxPageLimit() int {
+	return maxPageLimit
+}
+
+// SetMaxPaginationOffset sets the offset ceiling enforced by ParsePagination
+func SetMaxPaginationOffset(max int) {
+	maxPaginationOffset = max
+}
+
+// MaxPaginationOffset returns the offset ceiling enforced by ParsePagination
+func MaxPaginationOffset() int {
+	return maxPaginationOffset
+}
+
+// ParsePagination parses the limit and offset query parameters, using defaultLimit when
+// limit is absent. A limit outside 1..MaxPageLimit() is an error rather than being
+// clamped. A 1-based page parameter may be sent instead of offset and is translated to
+// offset = (page-1)*limit.
+func ParsePagination(c *gin.Context, defaultLimit int) (int, int, error) {
+	limit := min(defaultLimit, maxPageLimit)
+	if limitParam, ok := c.GetQuery("limit"); ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			return 0, 0, errors.New("Invalid limit parameter")
+		}
+		if limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+
+	var offset int
+	if pageParam, ok := c.GetQuery("page"); ok {
+		if _, hasOffset := c.GetQuery("offset"); hasOffset {
+			return 0, 0, errors.New("Use either page or offset, not both")
+		}
+
+		page, err := strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("Invalid page parameter, must be 1 or greater")
+		}
+		// Checked before multiplying, since a huge page would overflow into a small offset
+		if page-1 > maxPaginationOffset/limit {
+			return 0, 0, offsetTooDeepError()
+		}
+		offset = PageToOffset(page, limit)
+	} else {
+		var err error
+		offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("Invalid offset parameter")
+		}
+	}
+
+	if err := ValidatePaginationOffset(offset); err != nil {
+		return 0, 0, err
+	}
+
+	return limit, offset, nil
+}
+
+// ValidatePaginationOffset rejects offsets beyond the configured ceiling
+func ValidatePaginationOffset(offset int) error {
+	if offset > maxPaginationOffset {
+		return offsetTooDeepError()
+	}
+	return nil
+}
+
+// offsetTooDeepError reports an offset, or page, beyond the configured ceiling
+func offsetTooDeepError() error {
+	return fmt.Errorf("offset must be at most %d; use search or filters to reach results further down the list", maxPaginationOffset)
+}
+
+// PageToOffset converts a 1-based page number into an offset
+func PageToOffset(page, limit int) int {
+	if page < 1 {
+		page = 1
+	}
+	return (page - 1) * limit
+}
+
+// PageNumber returns the 1-based page that starts at or contains offset
+func PageNumber(offset, limit int) int {
+	if limit <= 0 {
+		return 1
+	}
+	return offset/limit + 1
+}
+
+// TotalPages returns how many pages of size limit are needed for total items
+func TotalPages(total, limit int) int {
+	if limit <= 0 || total <= 0 {
+		return 0
+	}
+	return (total + limit - 1) / limit
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paginationContext returns a gin context for a GET request with the given query string
+func paginationContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return c
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    string
+	}{
+		{"defaults", "", 20, 0, ""},
+		{"explicit limit and offset", "limit=50&offset=100", 50, 100, ""},
+		{"offset at the ceiling", "offset=10000", 20, 10000, ""},
+		{"offset over the ceiling", "offset=10001", 0, 0, "offset must be at most 10000"},
+		{"negative offset", "offset=-1", 0, 0, "Invalid offset parameter"},
+		{"non-numeric offset", "offset=ten", 0, 0, "Invalid offset parameter"},
+		{"limit over the ceiling", "limit=101", 0, 0, "limit must be between 1 and 100"},
+		{"zero limit", "limit=0", 0, 0, "limit must be between 1 and 100"},
+		{"non-numeric limit", "limit=all", 0, 0, "Invalid limit parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset, err := ParsePagination(paginationContext(tt.query), 20)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Errorf("got limit=%d offset=%d, want limit=%d offset=%d", limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestParsePaginationConfiguredOffsetCeiling(t *testing.T) {
+	SetMaxPaginationOffset(500)
+	defer SetMaxPaginationOffset(DefaultMaxPaginationOffset)
+
+	if _, _, err := ParsePagination(paginationContext("offset=500"), 20); err != nil {
+		t.Fatalf("offset at the configured ceiling: unexpected error %v", err)
+	}
+	_, _, err := ParsePagination(paginationContext("offset=501"), 20)
+	if err == nil || !strings.Contains(err.Error(), "offset must be at most 500") {
+		t.Fatalf("offset over the configured ceiling: got error %v", err)
+	}
+}
+
+func TestParsePaginationPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantOffset int
+		wantErr    string
+	}{
+		{"first page", "page=1&limit=10", 0, ""},
+		{"third page", "page=3&limit=10", 20, ""},
+		{"page with default limit", "page=2", 20, ""},
+		{"page zero", "page=0", 0, "Invalid page parameter"},
+		{"negative page", "page=-2", 0, "Invalid page parameter"},
+		{"non-numeric page", "page=last", 0, "Invalid page parameter"},
+		{"page with offset", "page=2&offset=10", 0, "Use either page or offset"},
+		{"page past the offset ceiling", "page=1002&limit=10", 0, "offset must be at most 10000"},
+		{"last page within the offset ceiling", "page=1001&limit=10", 10000, ""},
+		{"page that would overflow the offset", "page=2305843009213693954&limit=4", 0, "offset must be at most 10000"},
+		{"page past the int range", "page=9223372036854775808", 0, "Invalid page parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, offset, err := ParsePagination(paginationContext(tt.query), 20)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if offset != tt.wantOffset {
+				t.Errorf("got offset %d, want %d", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestPageNumbers(t *testing.T) {
+	tests := []struct {
+		offset, limit, total int
+		wantPage, wantTotal  int
+	}{
+		{0, 10, 0, 1, 0},
+		{0, 10, 1, 1, 1},
+		{0, 10, 10, 1, 1},
+		{0, 10, 11, 1, 2},
+		{20, 10, 25, 3, 3},
+		{15, 10, 25, 2, 3},
+		{0, 0, 25, 1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := PageNumber(tt.offset, tt.limit); got != tt.wantPage {
+			t.Errorf("PageNumber(%d, %d) = %d, want %d", tt.offset, tt.limit, got, tt.wantPage)
+		}
+		if got := TotalPages(tt.total, tt.limit); got != tt.wantTotal {
+			t.Errorf("TotalPages(%d, %d) = %d, want %d", tt.total, tt.limit, got, tt.wantTotal)
+		}
+	}
+
+	for page := 1; page <= 5; page++ {
+		if got := PageNumber(PageToOffset(page, 10), 10); got != page {
+			t.Errorf("page %d round-trips to page %d", page, got)
+		}
+	}
+}
+
+func TestPaginatedResponsePageInfo(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/items?page=2&limit=10", nil)
+
+	PaginatedResponse(c, []int{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, PageInfo{Limit: 10, Offset: 10, Total: 25})
+
+	var body struct {
+		Data PaginatedData[int] `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	want := PageInfo{Limit: 10, Offset: 10, Total: 25, PageNumber: 2, TotalPages: 3, HasMore: true}
+	if body.Data.Page != want {
+		t.Errorf("got page info %+v, want %+v", body.Data.Page, want)
+	}
+}
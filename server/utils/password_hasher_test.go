@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasherRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm  string
+		wantPrefix string
+	}{
+		{PasswordHashBcrypt, "$2a$"},
+		{PasswordHashArgon2id, "$argon2id$v=19$m=65536,t=3,p=4$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(tt.algorithm, bcrypt.MinCost)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			hash, err := hasher.Hash("secret1")
+			if err != nil {
+				t.Fatalf("hash: unexpected error %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Errorf("got hash %q, want prefix %q", hash, tt.wantPrefix)
+			}
+			if err := hasher.Compare(hash, "secret1"); err != nil {
+				t.Errorf("matching password: unexpected error %v", err)
+			}
+			if err := hasher.Compare(hash, "secret2"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("wrong password: got error %v, want ErrInvalidCredentials", err)
+			}
+
+			again, err := hasher.Hash("secret1")
+			if err != nil {
+				t.Fatalf("second hash: unexpected error %v", err)
+			}
+			if again == hash {
+				t.Error("two hashes of the same password are identical, want a fresh salt each time")
+			}
+		})
+	}
+}
+
+func TestPasswordHasherBcryptCost(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordHashBcrypt, 5)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	hash, err := hasher.Hash("secret1")
+	if err != nil {
+		t.Fatalf("hash: unexpected error %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != 5 {
+		t.Errorf("got cost %d (error %v), want 5", cost, err)
+	}
+}
+
+// TestPasswordHasherVerifiesEitherAlgorithm checks that switching PASSWORD_HASH_ALGORITHM
+// does not lock out users whose passwords were hashed with the other one
+func TestPasswordHasherVerifiesEitherAlgorithm(t *testing.T) {
+	bcryptHasher, err := NewPasswordHasher(PasswordHashBcrypt, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	argonHasher, err := NewPasswordHasher(PasswordHashArgon2id, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	bcryptHash, err := bcryptHasher.Hash("secret1")
+	if err != nil {
+		t.Fatalf("bcrypt hash: unexpected error %v", err)
+	}
+	argonHash, err := argonHasher.Hash("secret1")
+	if err != nil {
+		t.Fatalf("argon2id hash: unexpected error %v", err)
+	}
+
+	if err := argonHasher.Compare(bcryptHash, "secret1"); err != nil {
+		t.Errorf("argon2id hasher with a bcrypt hash: unexpected error %v", err)
+	}
+	if err := bcryptHasher.Compare(argonHash, "secret1"); err != nil {
+		t.Errorf("bcrypt hasher with an argon2id hash: unexpected error %v", err)
+	}
+}
+
+func TestPasswordHasherRejectsMalformedHashes(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordHashArgon2id, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	valid, err := hasher.Hash("secret1")
+	if err != nil {
+		t.Fatalf("hash: unexpected error %v", err)
+	}
+	parts := strings.Split(valid, "$")
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"not a hash", "secret1"},
+		{"missing key", strings.Join(parts[:5], "$")},
+		{"unknown version", strings.Replace(valid, "v=19", "v=16", 1)},
+		{"bad parameters", strings.Replace(valid, "m=65536", "m=lots", 1)},
+		{"bad salt encoding", strings.Join([]string{"", parts[1], parts[2], parts[3], "!!", parts[5]}, "$")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := hasher.Compare(tt.hash, "secret1"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("got error %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestNewPasswordHasherRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		bcryptCost int
+	}{
+		{"unknown algorithm", "md5", bcrypt.DefaultCost},
+		{"bcrypt cost too low", PasswordHashBcrypt, bcrypt.MinCost - 1},
+		{"bcrypt cost too high", PasswordHashBcrypt, bcrypt.MaxCost + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPasswordHasher(tt.algorithm, tt.bcryptCost); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
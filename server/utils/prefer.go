@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Return preferences accepted in the Prefer header (RFC 7240)
+const (
+	PreferReturnMinimal        = "minimal"
+	PreferReturnRepresentation = "representation"
+)
+
+// GetReturnPreference parses the "return" preference from the Prefer header.
+// It defaults to PreferReturnRepresentation when the header is absent or the value is unknown.
+func GetReturnPreference(c *gin.Context) string {
+	preference := PreferReturnRepresentation
+
+	for _, header := range c.Request.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			// Ignore any preference parameters, e.g. "return=minimal; foo=bar"
+			token := strings.TrimSpace(strings.SplitN(pref, ";", 2)[0])
+			name, value, found := strings.Cut(token, "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "return") {
+				continue
+			}
+
+			switch value = strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`)); value {
+			case PreferReturnMinimal, PreferReturnRepresentation:
+				preference = value
+			}
+		}
+	}
+
+	return preference
+}
+
+// CreatedResponse sends a 201 response for a newly created resource, honoring the
+// client's return preference. With "minimal" only the id is returned alongside
+// the Location header; otherwise the full representation is returned.
+func CreatedResponse(c *gin.Context, id uuid.UUID, location string, representation interface{}) {
+	CreatedResponseWithStatus(c, http.StatusCreated, id, location, representation)
+}
+
+// CreatedResponseWithStatus is CreatedResponse with the given status code, for replaying
+// the status an earlier create request was answered with
+func CreatedResponseWithStatus(c *gin.Context, statusCode int, id uuid.UUID, location string, representation interface{}) {
+	c.Header("Location", location)
+
+	if GetReturnPreference(c) == PreferReturnMinimal {
+		c.Header("Preference-Applied", "return="+PreferReturnMinimal)
+		SuccessResponse(c, statusCode, gin.H{"id": id})
+		return
+	}
+
+	SuccessResponse(c, statusCode, representation)
+}
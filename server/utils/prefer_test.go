@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestGetReturnPreference(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{"no header", nil, PreferReturnRepresentation},
+		{"minimal", []string{"return=minimal"}, PreferReturnMinimal},
+		{"representation", []string{"return=representation"}, PreferReturnRepresentation},
+		{"case and quotes are ignored", []string{`Return="MINIMAL"`}, PreferReturnMinimal},
+		{"parameters are ignored", []string{"return=minimal; foo=bar"}, PreferReturnMinimal},
+		{"among other preferences", []string{"respond-async, return=minimal, wait=5"}, PreferReturnMinimal},
+		{"across several headers", []string{"wait=5", "return=minimal"}, PreferReturnMinimal},
+		{"unknown value", []string{"return=nothing"}, PreferReturnRepresentation},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			for _, h := range tt.headers {
+				c.Request.Header.Add("Prefer", h)
+			}
+			if got := GetReturnPreference(c); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatedResponse(t *testing.T) {
+	id := uuid.New()
+	representation := gin.H{"id": id, "title": "Hello"}
+
+	tests := []struct {
+		name          string
+		prefer        string
+		wantKeys      []string
+		wantPreferred string
+	}{
+		{"minimal returns only the id", "return=minimal", []string{"id"}, "return=minimal"},
+		{"representation returns the full object", "return=representation", []string{"id", "title"}, ""},
+		{"default returns the full object", "", []string{"id", "title"}, ""},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/posts", nil)
+			if tt.prefer != "" {
+				c.Request.Header.Set("Prefer", tt.prefer)
+			}
+
+			CreatedResponse(c, id, "/api/v1/posts/"+id.String(), representation)
+
+			if w.Code != http.StatusCreated {
+				t.Errorf("status = %d, want 201", w.Code)
+			}
+			if got := w.Header().Get("Location"); got != "/api/v1/posts/"+id.String() {
+				t.Errorf("Location = %q", got)
+			}
+			if got := w.Header().Get("Preference-Applied"); got != tt.wantPreferred {
+				t.Errorf("Preference-Applied = %q, want %q", got, tt.wantPreferred)
+			}
+
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			if len(body.Data) != len(tt.wantKeys) {
+				t.Errorf("data = %v, want keys %v", body.Data, tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := body.Data[key]; !ok {
+					t.Errorf("data is missing %q", key)
+				}
+			}
+		})
+	}
+}
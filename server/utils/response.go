@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +14,37 @@ type APIResponse struct {
 	Success      bool        `json:"success"`
 	ErrorMessage *string     `json:"error_message"`
 	Data         interface{} `json:"data"`
+	ErrorCode    string      `json:"error_code,omitempty"`
+	// ValidationErrors lists the offending fields when a request fails validation
+	ValidationErrors []FieldError `json:"validation_errors,omitempty"`
+	RequestID        string       `json:"request_id,omitempty"`
+}
+
+// FieldError is a validation failure for a single request field, named as in the JSON body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrorer is implemented by errors that carry per-field validation failures
+type FieldErrorer interface {
+	FieldErrors() []FieldError
+}
+
+// PageInfo describes where a page sits within a paginated list
+type PageInfo struct {
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	Total      int  `json:"total"`
+	PageNumber int  `json:"page"`
+	TotalPages int  `json:"total_pages"`
+	HasMore    bool `json:"has_more"`
+}
+
+// PaginatedData is the data payload shared by all paginated list responses
+type PaginatedData[T any] struct {
+	Items []T      `json:"items"`
+	Page  PageInfo `json:"page"`
 }
 
 // SuccessResponse sends a successful response with data
@@ -21,24 +54,130 @@ func SuccessResponse(c *gin.Context, statusCode int, data interface{}) {
 		Success:      true,
 		ErrorMessage: nil,
 		Data:         data,
+		RequestID:    responseRequestID(c),
 	})
 }
 
-// ErrorResponse sends an error response with error message
+// PaginatedResponse sends a successful list response in the shared {items, page} shape.
+// HasMore is derived from Total and Offset plus the number of items returned, and
+// PageNumber and TotalPages from Limit, Offset and Total.
+func PaginatedResponse[T any](c *gin.Context, items []T, page PageInfo) {
+	if items == nil {
+		items = []T{}
+	}
+	page.HasMore = page.Offset+len(items) < page.Total
+	page.PageNumber = PageNumber(page.Offset, page.Limit)
+	page.TotalPages = TotalPages(page.Total, page.Limit)
+
+	SuccessResponse(c, http.StatusOK, PaginatedData[T]{
+		Items: items,
+		Page:  page,
+	})
+}
+
+// ErrorResponse sends an error response with error message and the generic error code
+// for the status
 func ErrorResponse(c *gin.Context, statusCode int, message string) {
+	ErrorResponseWithCode(c, statusCode, "", message)
+}
+
+// ErrorResponseWithCode sends an error response with a specific error code, usually
+// ErrorCode(err) for the error being reported. An empty code falls back to the generic
+// code for the status. A 400 for a request whose body was cut off at the size limit is
+// sent as 413, since handlers only see it as a malformed payload.
+func ErrorResponseWithCode(c *gin.Context, statusCode int, code string, message string) {
+	if statusCode == http.StatusBadRequest && c.GetBool(BodyTooLargeContextKey) {
+		statusCode = http.StatusRequestEntityTooLarge
+		code = ErrorCodeRequestTooLarge
+		message = "Request body is too large"
+	}
+	if code == "" {
+		code = statusErrorCode(statusCode)
+	}
 	c.JSON(statusCode, APIResponse{
 		StatusCode:   statusCode,
 		Success:      false,
 		ErrorMessage: &message,
 		Data:         nil,
+		ErrorCode:    code,
+		RequestID:    responseRequestID(c),
 	})
 }
 
+// statusErrorCode returns the generic error code for an HTTP error status
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrorCodeValidationFailed
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrorCodeRequestTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrorCodeUnsupportedMediaType
+	case http.StatusLocked:
+		return ErrorCodeLocked
+	case http.StatusTooManyRequests:
+		return ErrorCodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrorCodeServiceUnavailable
+	default:
+		return ErrorCodeInternal
+	}
+}
+
+// responseRequestID returns the ID the RequestContext middleware assigned to the request,
+// which is also sent in the X-Request-ID header, so clients can quote it in bug reports
+func responseRequestID(c *gin.Context) string {
+	if requestID := c.GetString("request_id"); requestID != "" {
+		return requestID
+	}
+	return c.Writer.Header().Get("X-Request-ID")
+}
+
 // ValidationErrorResponse sends a validation error response
 func ValidationErrorResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusBadRequest, message)
 }
 
+// ValidationErrorResponseStructured sends a validation error response listing each
+// offending field under validation_errors, so forms can highlight them. error_message
+// joins the field messages for clients that only show a single string.
+func ValidationErrorResponseStructured(c *gin.Context, errs []FieldError) {
+	messages := make([]string, len(errs))
+	for i, fieldError := range errs {
+		messages[i] = fieldError.Message
+	}
+	message := strings.Join(messages, "; ")
+
+	c.JSON(http.StatusBadRequest, APIResponse{
+		StatusCode:       http.StatusBadRequest,
+		Success:          false,
+		ErrorMessage:     &message,
+		Data:             nil,
+		ErrorCode:        ErrorCodeValidationFailed,
+		ValidationErrors: errs,
+		RequestID:        responseRequestID(c),
+	})
+}
+
+// ValidationErrorResponseFromError sends a validation error response for err, structured
+// by field when err carries field errors and as a plain message otherwise
+func ValidationErrorResponseFromError(c *gin.Context, err error) {
+	var fieldErrorer FieldErrorer
+	if errors.As(err, &fieldErrorer) {
+		ValidationErrorResponseStructured(c, fieldErrorer.FieldErrors())
+		return
+	}
+	ErrorResponseWithCode(c, http.StatusBadRequest, ErrorCode(err), err.Error())
+}
+
 // NotFoundResponse sends a not found error response
 func NotFoundResponse(c *gin.Context, resource string) {
 	ErrorResponse(c, http.StatusNotFound, resource+" not found")
@@ -72,3 +211,8 @@ func ForbiddenResponse(c *gin.Context, message string) {
 func ConflictResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusConflict, message)
 }
+
+// LockedResponse sends a 423 Locked error response
+func LockedResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusLocked, message)
+}
@@ -3,8 +3,12 @@ package validator
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/TejasThombare20/post-comments-service/models"
+	"github.com/TejasThombare20/post-comments-service/utils"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -33,6 +37,28 @@ func (ve ValidationErrors) Error() string {
 	return strings.Join(messages, "; ")
 }
 
+// Is reports validation failures as utils.ErrInvalidInput, so services can return them
+// as they are and controllers still answer 400
+func (ve ValidationErrors) Is(target error) bool {
+	return target == utils.ErrInvalidInput
+}
+
+// FieldErrors returns the field and message of each failure for the API response
+func (ve ValidationErrors) FieldErrors() []utils.FieldError {
+	fieldErrors := make([]utils.FieldError, len(ve))
+	for i, err := range ve {
+		fieldErrors[i] = utils.FieldError{
+			Field:   err.Field,
+			Message: err.Message,
+		}
+	}
+	return fieldErrors
+}
+
+// usernamePattern limits usernames to characters that are safe in URL paths such as
+// /users/username/:username and in @mentions
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
 // NewValidator creates a new validator instance
 func NewValidator() *Validator {
 	v := validator.New()
@@ -46,6 +72,23 @@ func NewValidator() *Validator {
 		return name
 	})
 
+	// Validate nullable fields by their value; an absent or null field counts as empty
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if nullable, ok := field.Interface().(models.NullableString); ok && nullable.Valid {
+			return nullable.Value
+		}
+		return nil
+	}, models.NullableString{})
+
+	// Register custom validation tags
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("attachment_url", func(fl validator.FieldLevel) bool {
+		_, err := utils.SanitizeAttachmentURL(fl.Field().String())
+		return err == nil
+	})
+
 	return &Validator{
 		validate: v,
 	}
@@ -87,8 +130,14 @@ func (v *Validator) generateErrorMessage(err validator.FieldError) string {
 	case "email":
 		return fmt.Sprintf("%s must be a valid email address", field)
 	case "min":
+		if err.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must contain at least %s items", field, param)
+		}
 		return fmt.Sprintf("%s must be at least %s characters long", field, param)
 	case "max":
+		if err.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must contain at most %s items", field, param)
+		}
 		return fmt.Sprintf("%s must be at most %s characters long", field, param)
 	case "url":
 		return fmt.Sprintf("%s must be a valid URL", field)
@@ -104,7 +153,57 @@ func (v *Validator) generateErrorMessage(err validator.FieldError) string {
 		return fmt.Sprintf("%s must be greater than %s", field, param)
 	case "lt":
 		return fmt.Sprintf("%s must be less than %s", field, param)
+	case "username":
+		return fmt.Sprintf("%s may only contain letters, digits and underscores", field)
+	case "attachment_url":
+		return fmt.Sprintf("%s must be an http or https link to a .jpg, .jpeg, .png, .gif or .webp file", field)
 	default:
 		return fmt.Sprintf("%s is invalid", field)
 	}
 }
+
+// FieldRules describes the bounds declared on a field's validate tag
+type FieldRules struct {
+	Required bool
+	Min      *int
+	Max      *int
+}
+
+// Rules returns the bounds declared on the field of struct s whose json name is
+// jsonField. For strings min and max are lengths; for slices they are element counts.
+// Rules applied to slice elements (after "dive") are not included.
+func Rules(s interface{}, jsonField string) FieldRules {
+	t := reflect.TypeOf(s)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var rules FieldRules
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.SplitN(field.Tag.Get("json"), ",", 2)[0] != jsonField {
+			continue
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			name, param, _ := strings.Cut(rule, "=")
+			switch name {
+			case "dive":
+				return rules
+			case "required":
+				rules.Required = true
+			case "min", "gte":
+				if n, err := strconv.Atoi(param); err == nil {
+					rules.Min = &n
+				}
+			case "max", "lte":
+				if n, err := strconv.Atoi(param); err == nil {
+					rules.Max = &n
+				}
+			}
+		}
+		return rules
+	}
+
+	return rules
+}
@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/TejasThombare20/post-comments-service/models"
+)
+
+func TestUsernameTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantErr  bool
+	}{
+		{"letters", "alice", false},
+		{"letters digits and underscores", "Alice_99", false},
+		{"only underscores", "___", false},
+		{"space", "alice smith", true},
+		{"slash", "alice/posts", true},
+		{"path traversal", "../admin", true},
+		{"percent encoding", "alice%2F", true},
+		{"query string", "alice?x=1", true},
+		{"hyphen", "alice-smith", true},
+		{"mention", "@alice", true},
+		{"accented letter", "alicé", true},
+		{"emoji", "alice🎉", true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := map[string]interface{}{
+				"register":    &models.RegisterRequest{Username: tt.username, Password: "secret1"},
+				"create user": &models.CreateUserRequest{Username: tt.username, Password: "secret1"},
+			}
+			for kind, req := range requests {
+				errs := v.ValidateStruct(req)
+				if !tt.wantErr {
+					if errs != nil {
+						t.Errorf("%s: unexpected errors %v", kind, errs)
+					}
+					continue
+				}
+				if len(errs) != 1 || errs[0].Field != "username" || errs[0].Tag != "username" {
+					t.Fatalf("%s: got errors %+v, want a single username tag failure", kind, errs)
+				}
+				if want := "username may only contain letters, digits and underscores"; errs[0].Message != want {
+					t.Errorf("%s: got message %q, want %q", kind, errs[0].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentURLTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantTag string
+	}{
+		{"image link", "https://example.com/a.png", ""},
+		{"javascript URL", "javascript:alert(1)//a.png", "attachment_url"},
+		{"data URL", "data:image/png;base64,iVBORw0KGgo=", "attachment_url"},
+		{"disallowed extension", "https://example.com/a.exe", "attachment_url"},
+		{"not a URL", "a.png", "url"},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "look"
+			errs := v.ValidateStruct(&models.CreateCommentRequest{
+				Content:     &content,
+				Attachments: []models.CommentAttachmentRequest{{URL: tt.url}},
+			})
+			if tt.wantTag == "" {
+				if errs != nil {
+					t.Fatalf("unexpected errors %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != "url" || errs[0].Tag != tt.wantTag {
+				t.Fatalf("got errors %+v, want a single url failure on %q", errs, tt.wantTag)
+			}
+		})
+	}
+
+	urls := make([]models.CommentAttachmentRequest, 5)
+	for i := range urls {
+		urls[i].URL = "https://example.com/a.png"
+	}
+	errs := v.ValidateStruct(&models.CreateCommentRequest{Attachments: urls})
+	if len(errs) != 1 || errs[0].Field != "attachments" || errs[0].Tag != "max" {
+		t.Errorf("five attachments: got errors %+v, want a max failure on attachments", errs)
+	}
+}
+
+func TestNullableFieldValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"absent", `{}`, false},
+		{"null clears the field", `{"avatar_url":null}`, false},
+		{"valid URL", `{"avatar_url":"https://example.com/a.png"}`, false},
+		{"invalid URL", `{"avatar_url":"not a url"}`, true},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.UpdateUserRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			if errs := v.ValidateStruct(&req); (errs != nil) != tt.wantErr {
+				t.Errorf("got errors %v, want errors %v", errs, tt.wantErr)
+			}
+		})
+	}
+}